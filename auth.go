@@ -0,0 +1,149 @@
+package libhoney
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// authTimeout bounds the API key check made by clients with VerifyAPIKey set.
+const authTimeout = 10 * time.Second
+
+// ErrInvalidAPIKey is returned when the Honeycomb API doesn't recognize the
+// API key being verified.
+var ErrInvalidAPIKey = errors.New("API key is invalid")
+
+// AuthInfo describes what an API key can do and who it belongs to, as
+// reported by the Honeycomb API.
+type AuthInfo struct {
+	Team        AuthName `json:"team"`
+	Environment AuthName `json:"environment"`
+	// APIKeyAccess lists the permissions of the key, eg "events" for one
+	// that can send events.
+	APIKeyAccess map[string]bool `json:"api_key_access"`
+}
+
+// AuthName is the name and slug of a team or environment.
+type AuthName struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// WrongEnvironmentError is returned by a client with VerifyAPIKey set when its
+// API key belongs to a different environment than the configured one.
+type WrongEnvironmentError struct {
+	// Want is the configured environment and Got the key's.
+	Want string
+	Got  AuthName
+}
+
+func (e *WrongEnvironmentError) Error() string {
+	got := e.Got.Slug
+	if got == "" {
+		got = "a classic (environment-less) team"
+	}
+	return fmt.Sprintf("API key belongs to %s, not environment %s", got, e.Want)
+}
+
+// LookupAuth asks the Honeycomb API which team and environment config's API
+// key belongs to. It returns ErrInvalidAPIKey if the API rejects the key.
+func LookupAuth(config Config) (AuthInfo, error) {
+	if config.APIKey == "" {
+		config.APIKey = config.WriteKey
+	}
+	if config.APIHost == "" {
+		config.APIHost = defaultAPIHost
+	}
+	client := &http.Client{Transport: config.Transport, Timeout: authTimeout}
+	return lookupAuth(client, config.APIHost, config.APIKey, userAgent(UserAgentAddition))
+}
+
+// userAgent returns the User-Agent libhoney sends batches with, given the
+// sender's UserAgentAddition.
+func userAgent(addition string) string {
+	ua := fmt.Sprintf("libhoney-go/%s", version)
+	if addition != "" {
+		ua = fmt.Sprintf("%s %s", ua, strings.TrimSpace(addition))
+	}
+	return ua
+}
+
+func lookupAuth(client *http.Client, apiHost, apiKey, userAgent string) (AuthInfo, error) {
+	var info AuthInfo
+	if apiKey == "" {
+		return info, errors.New("no API key to verify")
+	}
+	u, err := transmission.APIURL(apiHost, "1", "auth")
+	if err != nil {
+		return info, fmt.Errorf("Error parsing API URL: %s", err)
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return info, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Add("X-Honeycomb-Team", apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return info, ErrInvalidAPIKey
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf(`Abnormal non-200 response verifying Honeycomb API key: %d
+Response body: %s`, resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// verifyAPIKey checks, for a client with VerifyAPIKey set, that conf's API
+// key is valid, can send events and, if conf.Environment is set, belongs to
+// that environment. It uses the transport and user agent of the client's
+// Honeycomb transmission, including the default one, if it has one, so that
+// its proxy and TLS settings apply. The transmission must have been started.
+func (c *Client) verifyAPIKey(conf ClientConfig) error {
+	client := &http.Client{Timeout: authTimeout}
+	addition := UserAgentAddition
+	if h := honeycombSender(c.transmission); h != nil {
+		client.Transport = h.Transport
+		addition = h.UserAgentAddition
+	}
+	info, err := lookupAuth(client, conf.APIHost, conf.APIKey, userAgent(addition))
+	if err != nil {
+		return err
+	}
+	c.logger.Printf("verified API key for team %s, environment %s", info.Team.Slug, info.Environment.Slug)
+	if info.APIKeyAccess != nil && !info.APIKeyAccess["events"] {
+		return errors.New("API key doesn't have permission to send events")
+	}
+	if conf.Environment != "" && conf.Environment != info.Environment.Slug && conf.Environment != info.Environment.Name {
+		return &WrongEnvironmentError{Want: conf.Environment, Got: info.Environment}
+	}
+	return nil
+}
+
+// honeycombSender returns the Honeycomb sender s is or wraps, if any.
+func honeycombSender(s transmission.Sender) *transmission.Honeycomb {
+	for {
+		switch t := s.(type) {
+		case *transmission.Honeycomb:
+			return t
+		case *transmission.ResponseCallbackSender:
+			s = t.Sender
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package libhoney
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAPIKeyOnNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1/auth", r.URL.Path)
+		switch r.Header.Get("X-Honeycomb-Team") {
+		case "good":
+			w.Write([]byte(`{"api_key_access":{"events":true},"team":{"name":"Team","slug":"team"},"environment":{"name":"Prod","slug":"prod"}}`))
+		case "readonly":
+			w.Write([]byte(`{"api_key_access":{"events":false},"team":{"slug":"team"},"environment":{"slug":"prod"}}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	newClient := func(key, env string) (*Client, error) {
+		return NewClient(ClientConfig{
+			APIKey:       key,
+			APIHost:      srv.URL,
+			Environment:  env,
+			VerifyAPIKey: true,
+			Transmission: &transmission.MockSender{},
+		})
+	}
+
+	c, err := newClient("good", "prod")
+	testOK(t, err)
+	c.Close()
+	_, err = newClient("good", "Prod")
+	testOK(t, err)
+
+	_, err = newClient("bad", "")
+	assert.Equal(t, ErrInvalidAPIKey, err)
+	_, err = newClient("readonly", "")
+	testErr(t, err)
+	_, err = newClient("good", "staging")
+	assert.Equal(t, &WrongEnvironmentError{Want: "staging", Got: AuthName{Name: "Prod", Slug: "prod"}}, err)
+
+	info, err := LookupAuth(Config{WriteKey: "good", APIHost: srv.URL})
+	testOK(t, err)
+	assert.Equal(t, "team", info.Team.Slug)
+	assert.Equal(t, "prod", info.Environment.Slug)
+}
+
+type countingRoundTripper struct {
+	requests int
+}
+
+func (c *countingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestVerifyAPIKeyUsesTransmission(t *testing.T) {
+	var userAgents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.Write([]byte(`{"api_key_access":{"events":true}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(ClientConfig{APIKey: "good", APIHost: srv.URL, VerifyAPIKey: true})
+	testOK(t, err)
+	c.Close()
+	assert.Equal(t, []string{"libhoney-go/" + version}, userAgents)
+
+	rt := &countingRoundTripper{}
+	c, err = NewClient(ClientConfig{
+		APIKey:       "good",
+		APIHost:      srv.URL,
+		VerifyAPIKey: true,
+		Transmission: &transmission.Honeycomb{
+			MaxBatchSize:      10,
+			BatchTimeout:      DefaultBatchTimeout,
+			Transport:         rt,
+			UserAgentAddition: " extra ",
+		},
+		ResponseCallback: func(transmission.Response) {},
+	})
+	testOK(t, err)
+	c.Close()
+	assert.Equal(t, 1, rt.requests, "the check should use the transmission's transport")
+	assert.Equal(t, "libhoney-go/"+version+" extra", userAgents[1])
+}
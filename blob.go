@@ -0,0 +1,27 @@
+package libhoney
+
+import "github.com/honeycombio/libhoney-go/transmission"
+
+// AddCompressedField adds blob to the event as the field key, gzipped and
+// base64-encoded, along with a companion field "<key>.encoding" recording the
+// encoding. Use it for fields that legitimately carry a large value, such as a
+// diff, to keep the event under the API's size limit. The original can be
+// recovered with transmission.DecompressBlob, or by reading a spool with
+// DecompressFields set in its SpoolOptions.
+//
+// Adds to an event that happen after it has been sent will return without
+// having any effect.
+func (e *Event) AddCompressedField(key string, blob []byte) error {
+	encoded, err := transmission.CompressBlob(blob)
+	if err != nil {
+		return err
+	}
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent == true {
+		return nil
+	}
+	e.fieldHolder.AddField(key, encoded)
+	e.fieldHolder.AddField(key+transmission.BlobEncodingSuffix, transmission.BlobEncodingGzipBase64)
+	return nil
+}
@@ -0,0 +1,20 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventAddCompressedField(t *testing.T) {
+	c, _ := NewClient(ClientConfig{APIKey: "key", Dataset: "ds", Transmission: &transmission.MockSender{}})
+	ev := c.NewEvent()
+	assert.NoError(t, ev.AddCompressedField("diff", []byte("+ added\n- removed\n")))
+	assert.Equal(t, transmission.BlobEncodingGzipBase64, ev.data["diff.encoding"])
+	enc, ok := ev.data["diff"].(string)
+	assert.True(t, ok)
+	blob, err := transmission.DecompressBlob(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, "+ added\n- removed\n", string(blob))
+}
@@ -0,0 +1,216 @@
+package libhoney
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// BurstConfig configures automatic summarization of event bursts. Events are
+// grouped into shapes by their dataset and the values of KeyFields. Once a
+// shape sends more than Threshold events within a Window, further events of
+// that shape are no longer sent individually; instead, at the end of each
+// window, a single summary event is sent carrying the fields of the first
+// summarized event plus meta.burst_count (how many events it stands in for).
+// The summary's sample rate is scaled by that count so that counts in
+// Honeycomb stay accurate. Shapes go back to sending normally after a window
+// in which they stay at or under the threshold.
+type BurstConfig struct {
+	// KeyFields are the fields whose values identify an event's shape, eg
+	// "error" and "handler" to detect one specific error repeating.
+	KeyFields []string
+	// Threshold is how many events of a single shape to send per Window before
+	// summarizing.
+	Threshold uint
+	// Window is the length of the measurement and summary interval. Defaults
+	// to one second.
+	Window time.Duration
+}
+
+type burstShape struct {
+	windowStart time.Time
+	count       uint
+	bursting    bool
+	summarized  uint
+	example     *transmission.Event
+}
+
+type burstDetector struct {
+	conf   BurstConfig
+	client *Client
+	shapes map[string]*burstShape
+	lock   sync.Mutex
+
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func newBurstDetector(conf BurstConfig, c *Client) *burstDetector {
+	if conf.Window == 0 {
+		conf.Window = time.Second
+	}
+	b := &burstDetector{
+		conf:   conf,
+		client: c,
+		shapes: make(map[string]*burstShape),
+		done:   make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+func (b *burstDetector) run() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.conf.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			b.safeRoll(now)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// safeRoll rolls windows from the background goroutine, recovering from any
+// panic so that later windows still roll.
+func (b *burstDetector) safeRoll(now time.Time) {
+	defer func() {
+		if p := recover(); p != nil {
+			b.client.logPanic("burst summaries", p)
+		}
+	}()
+	b.roll(now, false)
+}
+
+// stop shuts down the background goroutine and sends summaries for any
+// bursts in progress.
+func (b *burstDetector) stop() {
+	b.stopOnce.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+	})
+	b.flush()
+}
+
+// flush sends summaries for any bursts in progress.
+func (b *burstDetector) flush() {
+	b.roll(time.Now(), true)
+}
+
+func (b *burstDetector) shapeKey(dataset string, data map[string]interface{}) string {
+	buf := bytes.NewBufferString(dataset)
+	for _, f := range b.conf.KeyFields {
+		fmt.Fprintf(buf, "\x00%v", data[f])
+	}
+	return buf.String()
+}
+
+// absorb records an event that's about to be sent and reports whether it
+// should be summarized rather than sent.
+func (b *burstDetector) absorb(tx *transmission.Event) bool {
+	key := b.shapeKey(tx.Dataset, tx.Data)
+	now := time.Now()
+	var summary *transmission.Event
+	defer func() { b.send(summary) }()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	s, ok := b.shapes[key]
+	if !ok {
+		s = &burstShape{windowStart: now}
+		b.shapes[key] = s
+	}
+	if now.Sub(s.windowStart) >= b.conf.Window {
+		summary = b.rollShape(s, now)
+	}
+	s.count++
+	if !s.bursting && s.count > b.conf.Threshold {
+		s.bursting = true
+	}
+	if !s.bursting {
+		return false
+	}
+	if s.example == nil {
+		s.example = tx
+	}
+	s.summarized++
+	return true
+}
+
+// roll closes out the window of every shape whose window has ended (or all
+// of them, if force is set), sending summaries and forgetting idle shapes.
+func (b *burstDetector) roll(now time.Time, force bool) {
+	var summaries []*transmission.Event
+	b.lock.Lock()
+	for key, s := range b.shapes {
+		if !force && now.Sub(s.windowStart) < b.conf.Window {
+			continue
+		}
+		if summary := b.rollShape(s, now); summary != nil {
+			summaries = append(summaries, summary)
+		}
+		if s.count == 0 && !s.bursting {
+			delete(b.shapes, key)
+		}
+	}
+	b.lock.Unlock()
+	b.send(summaries...)
+}
+
+// rollShape starts a new window for s, returning the summary of the window
+// just ended if there is one. The caller must hold the lock, and should send
+// the summary once it's released so that a blocking Sender doesn't hold up
+// other events.
+func (b *burstDetector) rollShape(s *burstShape, now time.Time) *transmission.Event {
+	var summary *transmission.Event
+	if s.summarized > 0 {
+		summary = b.summary(s, now.Sub(s.windowStart))
+	}
+	s.bursting = s.bursting && s.count > b.conf.Threshold
+	s.count = 0
+	s.summarized = 0
+	s.example = nil
+	s.windowStart = now
+	return summary
+}
+
+// send hands summaries built by rollShape to the transmission, skipping any
+// nil ones.
+func (b *burstDetector) send(summaries ...*transmission.Event) {
+	for _, summary := range summaries {
+		if summary == nil {
+			continue
+		}
+		b.client.ensureTransmission()
+		b.client.transmission.Add(summary)
+	}
+}
+
+func (b *burstDetector) summary(s *burstShape, window time.Duration) *transmission.Event {
+	ex := s.example
+	data := make(map[string]interface{}, len(ex.Data)+3)
+	for k, v := range ex.Data {
+		data[k] = v
+	}
+	data["meta.burst_summary"] = true
+	data["meta.burst_count"] = s.summarized
+	data["meta.burst_window_ms"] = float64(window) / float64(time.Millisecond)
+	sampleRate := ex.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+	return &transmission.Event{
+		APIHost:    ex.APIHost,
+		APIKey:     ex.APIKey,
+		Dataset:    ex.Dataset,
+		SampleRate: sampleRate * s.summarized,
+		Timestamp:  ex.Timestamp,
+		Data:       data,
+	}
+}
@@ -0,0 +1,113 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurstSummarization(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		BurstDetection: &BurstConfig{
+			KeyFields: []string{"error"},
+			Threshold: 3,
+			Window:    time.Hour,
+		},
+	})
+	send := func(errMsg string, n int) {
+		for i := 0; i < n; i++ {
+			ev := c.NewEvent()
+			ev.AddField("error", errMsg)
+			ev.AddField("i", i)
+			ev.Send()
+		}
+	}
+	send("connection refused", 10)
+	send("timeout", 2)
+	assert.Equal(t, 5, len(mock.Events()), "only events under the threshold should be sent individually")
+
+	c.Flush()
+	events := mock.Events()
+	assert.Equal(t, 6, len(events))
+	summary := events[5]
+	assert.Equal(t, true, summary.Data["meta.burst_summary"])
+	assert.Equal(t, uint(7), summary.Data["meta.burst_count"])
+	assert.Equal(t, "connection refused", summary.Data["error"])
+	assert.Equal(t, 3, summary.Data["i"], "summary should carry the first summarized event's fields")
+	assert.Equal(t, uint(7), summary.SampleRate)
+
+	// the shape was over the threshold in the window that just ended, so it's
+	// still bursting; one quiet window ends it
+	c.Flush()
+	send("connection refused", 1)
+	assert.Equal(t, 7, len(mock.Events()))
+	assert.Nil(t, mock.Events()[6].Data["meta.burst_summary"])
+	c.Close()
+}
+
+// summaryBlockingSender blocks adding burst summaries until released.
+type summaryBlockingSender struct {
+	transmission.MockSender
+	adding  chan struct{}
+	release chan struct{}
+}
+
+func (s *summaryBlockingSender) Add(ev *transmission.Event) {
+	if ev.Data["meta.burst_summary"] == true {
+		close(s.adding)
+		<-s.release
+	}
+	s.MockSender.Add(ev)
+}
+
+func TestBurstSummaryAddedOutsideLock(t *testing.T) {
+	tx := &summaryBlockingSender{
+		adding:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: tx,
+		BurstDetection: &BurstConfig{
+			KeyFields: []string{"error"},
+			Threshold: 1,
+			Window:    time.Hour,
+		},
+	})
+	for i := 0; i < 3; i++ {
+		ev := c.NewEvent()
+		ev.AddField("error", "timeout")
+		ev.Send()
+	}
+	flushed := make(chan struct{})
+	go func() {
+		c.Flush()
+		close(flushed)
+	}()
+	<-tx.adding
+
+	// a blocked summary mustn't hold up other events
+	sent := make(chan struct{})
+	go func() {
+		ev := c.NewEvent()
+		ev.AddField("error", "refused")
+		ev.Send()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Error("event blocked behind a burst summary")
+	}
+	close(tx.release)
+	<-flushed
+	assert.Equal(t, 3, len(tx.Events()))
+	c.Close()
+}
@@ -0,0 +1,55 @@
+package libhoney
+
+// NewEventWithCapacity is like NewEvent, except that the event's field map is
+// allocated with room for n fields up front, so that events which always get
+// many fields don't pay for the map growing as they're added.
+func NewEventWithCapacity(n int) *Event {
+	return dc.NewEventWithCapacity(n)
+}
+
+// NewEventWithCapacity is like NewEvent, except that the event's field map is
+// allocated with room for n fields up front.
+func (c *Client) NewEventWithCapacity(n int) *Event {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	return c.builder.NewEventWithCapacity(n)
+}
+
+// NewEventWithCapacity is like NewEvent, except that the event's field map is
+// allocated with room for n fields up front, or for as many as the builder's
+// SetFieldCapacity if that's more.
+func (b *Builder) NewEventWithCapacity(n int) *Event {
+	e := &Event{}
+	e.data = make(map[string]interface{}, b.eventCapacity(n))
+	b.fillEvent(e)
+	return e
+}
+
+// SetFieldCapacity sets how many fields the maps of events created from the
+// builder are allocated room for up front, for services that add about the
+// same number of fields to every event. Builders cloned from it inherit the
+// setting. It panics if the builder is frozen.
+func (b *Builder) SetFieldCapacity(n int) {
+	if b.frozen {
+		panic(ErrBuilderFrozen)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.fieldCapacity = n
+}
+
+// eventCapacity returns how many fields to allocate a new event's map for:
+// at least n, and enough for the builder's own fields.
+func (b *Builder) eventCapacity(n int) int {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	b.dynFieldsLock.RLock()
+	defer b.dynFieldsLock.RUnlock()
+	if b.fieldCapacity > n {
+		n = b.fieldCapacity
+	}
+	if l := len(b.data) + len(b.dynFields); l > n {
+		n = l
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+package libhoney
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldCapacity(t *testing.T) {
+	c, _ := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	b := c.NewBuilder()
+	b.AddField("static", 1)
+	b.AddDynamicField("dyn", func() interface{} { return 2 })
+	assert.Equal(t, 2, b.eventCapacity(0), "room for the builder's fields")
+	assert.Equal(t, 40, b.eventCapacity(40))
+
+	b.SetFieldCapacity(30)
+	assert.Equal(t, 30, b.eventCapacity(0))
+	assert.Equal(t, 40, b.eventCapacity(40))
+	assert.Equal(t, 30, b.Clone().eventCapacity(0), "clones inherit the capacity")
+
+	ev := b.NewEventWithCapacity(40)
+	assert.Equal(t, map[string]interface{}{"static": 1, "dyn": 2}, ev.Fields())
+
+	frozen := b.Freeze()
+	assert.Panics(t, func() { frozen.SetFieldCapacity(10) })
+}
+
+func BenchmarkNewEventWithCapacity(b *testing.B) {
+	c, _ := NewClient(ClientConfig{Transmission: &transmission.DiscardSender{}})
+	keys := make([]string, 40)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("field%d", i)
+	}
+	for _, n := range []int{0, len(keys)} {
+		b.Run(fmt.Sprintf("capacity=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ev := c.NewEventWithCapacity(n)
+				for _, k := range keys {
+					ev.AddField(k, i)
+				}
+			}
+		})
+	}
+}
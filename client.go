@@ -1,8 +1,11 @@
 package libhoney
 
 import (
+	"context"
 	"errors"
+	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/honeycombio/libhoney-go/transmission"
 )
@@ -19,6 +22,30 @@ type Client struct {
 	logger       Logger
 	builder      *Builder
 
+	fieldEncrypter  *FieldEncrypter
+	fieldTransforms map[string]FieldTransform
+	fieldScrubber   FieldScrubber
+	fieldPolicy     *fieldPolicy
+	maxFields       int
+	consentFields   map[string]struct{}
+	suppressions    suppressions
+	bursts          *burstDetector
+	goroutineDumps  *goroutineDumper
+
+	dynamicFieldErrors  bool
+	strictDynamicFields bool
+
+	sampler            Sampler
+	sampleRate         uint
+	datasetSampleRates map[string]uint
+
+	pressureWatchers pressureWatchers
+	counters         clientCounters
+	hooks            Hooks
+
+	// killSwitch is 1 while the client's kill switch is engaged
+	killSwitch int32
+
 	oneTx      sync.Once
 	oneLogger  sync.Once
 	oneBuilder sync.Once
@@ -46,10 +73,36 @@ type ClientConfig struct {
 	// Send() is called, you would specify 250 here.
 	SampleRate uint
 
+	// DatasetSampleRates sets the sample rate of events created for the given
+	// datasets, in place of SampleRate. It only applies to events from
+	// builders whose SampleRate hasn't been changed from the client's, and
+	// an event can still have its own SampleRate set after it's created.
+	DatasetSampleRates map[string]uint
+
 	// APIHost is the hostname for the Honeycomb API server to which to send this
-	// event. default: https://api.honeycomb.io/
+	// event. It may include a path prefix, eg for a reverse proxy at
+	// https://gw.internal/honeycomb/. default: https://api.honeycomb.io/
 	APIHost string
 
+	// Region, if set, sends events to the API host of that Honeycomb region
+	// (RegionUS or RegionEU). NewClient fails if it's unknown or if APIHost
+	// is also set to a different host.
+	Region Region
+
+	// FromEnv reads the API key, dataset and API host from the
+	// HONEYCOMB_API_KEY, HONEYCOMB_DATASET and HONEYCOMB_API_ENDPOINT
+	// environment variables, for any of them not set here, so deployments
+	// can configure them without code changes.
+	FromEnv bool
+
+	// VerifyAPIKey makes NewClient check the API key with the Honeycomb API
+	// before returning, and fail with a clear error (eg ErrInvalidAPIKey)
+	// rather than leave every event to come back with a 401 Response. If
+	// Environment is also set, the key must belong to that environment,
+	// given by slug or name, or NewClient returns a *WrongEnvironmentError.
+	VerifyAPIKey bool
+	Environment  string
+
 	// Transmission allows you to override what happens to events after you call
 	// Send() on them. By default, events are asynchronously sent to the
 	// Honeycomb API. You can use the MockOutput included in this package in
@@ -62,24 +115,165 @@ type ClientConfig struct {
 	// Intended for human consumption during development to understand what the
 	// SDK is doing and diagnose trouble emitting events.
 	Logger Logger
+
+	// FieldEncrypter, if set, encrypts the values of sensitive fields on every
+	// event sent by this client. See NewFieldEncrypter.
+	FieldEncrypter *FieldEncrypter
+
+	// FieldTransforms maps field names to a transform applied to that field's
+	// value on every event sent by this client, eg HashWithSalt or TruncateIP
+	// to pseudonymize personal data. Transforms run before encryption.
+	FieldTransforms map[string]FieldTransform
+
+	// FieldScrubber, if set, is applied to every field of every event sent
+	// by this client, after FieldTransforms and before encryption, to hash
+	// or drop personal data wherever it was added. See DefaultFieldScrubber.
+	FieldScrubber FieldScrubber
+
+	// FieldAllowlist and FieldDenylist guarantee which fields leave the
+	// process: fields matching a denylist pattern are dropped from every
+	// event sent by this client and, if there's an allowlist, so are fields
+	// matching none of its patterns. It's applied after all other field
+	// processing but encryption. Patterns are globs, where * matches any run of
+	// characters, or regular expressions between slashes, like /^user\./.
+	FieldAllowlist []string
+	FieldDenylist  []string
+
+	// MaxFieldsPerEvent, if set, caps how many fields each event may have,
+	// so that runaway instrumentation can't create unbounded columns. Events
+	// over the cap keep the first fields in sorted order, plus a
+	// libhoney_truncated_fields count of those dropped. Truncations are
+	// logged, counted in the fields_truncated metric and described in the
+	// Warnings of the event's Response.
+	MaxFieldsPerEvent int
+
+	// A panic in a dynamic field's function is recovered and logged, and
+	// the field left out of the event. DynamicFieldErrors also adds a
+	// libhoney_dynamic_field_error field describing the panic to the event.
+	// StrictDynamicFields re-panics instead, eg to catch broken dynamic
+	// fields in tests.
+	DynamicFieldErrors  bool
+	StrictDynamicFields bool
+
+	// DeterministicSampler, if set, makes Send sample events by the hash of
+	// one of their fields, eg the trace ID, instead of at random by their
+	// SampleRate, so that related events are kept or dropped together. Kept
+	// events are sent with the sampler's rate. SendPresampled is unaffected.
+	DeterministicSampler *DeterministicSampler
+
+	// Sampler, if set, is consulted by Send for every event instead of
+	// sampling at random by its SampleRate: events it doesn't keep are
+	// dropped with a sampled Response and kept ones are sent with the rate
+	// it returns. It takes precedence over DeterministicSampler.
+	// SendPresampled is unaffected.
+	Sampler Sampler
+
+	// ConsentFields lists fields that may only be sent on events that have
+	// been granted consent with SetConsent on the event or its Builder. They
+	// are stripped from all other events.
+	ConsentFields []string
+
+	// BurstDetection, if set, replaces bursts of similar events with periodic
+	// summary events. See BurstConfig.
+	BurstDetection *BurstConfig
+
+	// GoroutineDump, if set, attaches a dump of every goroutine's stack to
+	// events meeting its Trigger. See GoroutineDumpConfig.
+	GoroutineDump *GoroutineDumpConfig
+
+	// ResponseCallback, if set, is called with each Response on a dedicated
+	// goroutine, so there's no need to read from TxResponses. The client's
+	// TxResponses channel won't receive anything.
+	ResponseCallback func(transmission.Response)
+
+	// DiscardResponses drains and discards every Response, for applications
+	// that never read TxResponses, so that its queue can't fill up. They're
+	// still counted in Metrics, as responses_discarded and, for those
+	// reporting a failure, discarded_response_errors. It has no effect with
+	// a ResponseCallback.
+	DiscardResponses bool
+
+	// TrackFieldProvenance records where each field of every event was set,
+	// for Event.FieldProvenance. It's a debugging aid and adds overhead to
+	// every field set. ProvenanceCallerSampleRate additionally records the
+	// call site for one in that many field sets (1 for all of them).
+	TrackFieldProvenance       bool
+	ProvenanceCallerSampleRate uint
+
+	// FieldCollisionPolicy sets what happens when a field is added that
+	// already has a value; by default it's overwritten. Builders can override
+	// it with SetFieldCollisionPolicy.
+	FieldCollisionPolicy FieldCollisionPolicy
+
+	// Flatten, if set, flattens nested maps and structs added as field
+	// values into one field per leaf, eg request.headers.user_agent, so that
+	// they can be queried as columns. Builders can override it with
+	// SetFlattening.
+	Flatten *FlattenConfig
+
+	// ConvertFieldValues converts field values that don't otherwise come out
+	// queryable as they're added: a time.Duration becomes float
+	// milliseconds rather than integer nanoseconds, an error its message,
+	// and a fmt.Stringer or encoding.TextMarshaler its text. Values that
+	// marshal themselves to JSON, such as time.Time, are left alone.
+	ConvertFieldValues bool
+
+	// Hooks are called as events are sent, sent in batches and responded
+	// to. See Hooks.
+	Hooks Hooks
 }
 
 // NewClient creates a Client with defaults correctly set
 func NewClient(conf ClientConfig) (*Client, error) {
+	if conf.FromEnv {
+		applyEnv(&conf)
+	}
 	if conf.SampleRate == 0 {
 		conf.SampleRate = defaultSampleRate
 	}
-	if conf.APIHost == "" {
-		conf.APIHost = defaultAPIHost
-	}
-	if conf.Dataset == "" {
-		conf.Dataset = defaultDataset
+	if err := applyRegion(&conf); err != nil {
+		return nil, err
 	}
+	datasetMessage := defaultClientDataset(&conf)
 
 	c := &Client{
-		logger: conf.Logger,
+		logger:          conf.Logger,
+		fieldEncrypter:  conf.FieldEncrypter,
+		fieldTransforms: conf.FieldTransforms,
+		fieldScrubber:   conf.FieldScrubber,
+	}
+	if len(conf.ConsentFields) > 0 {
+		c.consentFields = make(map[string]struct{}, len(conf.ConsentFields))
+		for _, f := range conf.ConsentFields {
+			c.consentFields[f] = struct{}{}
+		}
 	}
 	c.ensureLogger()
+	if datasetMessage != "" {
+		c.logger.Printf("%s", datasetMessage)
+	}
+	policy, err := newFieldPolicy(conf.FieldAllowlist, conf.FieldDenylist)
+	if err != nil {
+		return nil, err
+	}
+	c.fieldPolicy = policy
+	c.maxFields = conf.MaxFieldsPerEvent
+	c.dynamicFieldErrors = conf.DynamicFieldErrors
+	c.strictDynamicFields = conf.StrictDynamicFields
+	c.sampleRate = conf.SampleRate
+	if len(conf.DatasetSampleRates) > 0 {
+		c.datasetSampleRates = make(map[string]uint, len(conf.DatasetSampleRates))
+		for ds, rate := range conf.DatasetSampleRates {
+			if rate == 0 {
+				rate = defaultSampleRate
+			}
+			c.datasetSampleRates[ds] = rate
+		}
+	}
+	c.sampler = conf.Sampler
+	if c.sampler == nil && conf.DeterministicSampler != nil {
+		c.sampler = conf.DeterministicSampler
+	}
 
 	if conf.Transmission == nil {
 		c.transmission = &transmission.Honeycomb{
@@ -94,10 +288,38 @@ func NewClient(conf ClientConfig) (*Client, error) {
 	} else {
 		c.transmission = conf.Transmission
 	}
+	c.hooks = conf.Hooks
+	if h, ok := c.transmission.(*transmission.Honeycomb); ok && h.OnBatchSend == nil {
+		h.OnBatchSend = conf.Hooks.OnBatchSend
+	}
+	callback := conf.ResponseCallback
+	if callback == nil && conf.DiscardResponses {
+		callback = c.discardResponse
+	}
+	if callback != nil || conf.Hooks.OnResponse != nil {
+		cs := transmission.NewResponseCallbackSender(c.transmission, c.responseHooks(callback))
+		cs.Logger = c.logger
+		cs.Relay = callback == nil
+		c.transmission = cs
+	}
 	if err := c.transmission.Start(); err != nil {
 		c.logger.Printf("transmission client failed to start: %s", err.Error())
 		return nil, err
 	}
+	if conf.VerifyAPIKey {
+		if err := c.verifyAPIKey(conf); err != nil {
+			c.logger.Printf("failed to verify API key: %s", err.Error())
+			c.transmission.Stop()
+			return nil, err
+		}
+	}
+
+	if conf.BurstDetection != nil {
+		c.bursts = newBurstDetector(*conf.BurstDetection, c)
+	}
+	if conf.GoroutineDump != nil {
+		c.goroutineDumps = newGoroutineDumper(*conf.GoroutineDump)
+	}
 
 	c.builder = &Builder{
 		WriteKey:   conf.APIKey,
@@ -110,10 +332,56 @@ func NewClient(conf ClientConfig) (*Client, error) {
 		},
 		client: c,
 	}
+	if conf.TrackFieldProvenance {
+		c.builder.prov = newProvenance(ProvenanceGlobal, conf.ProvenanceCallerSampleRate)
+	}
+	c.builder.collisionPolicy = conf.FieldCollisionPolicy
+	c.builder.flatten = conf.Flatten.withDefaults()
+	c.builder.convertValues = conf.ConvertFieldValues
 
 	return c, nil
 }
 
+// sampleRateFor returns the sample rate for a new event in dataset from a
+// builder with the given rate.
+func (c *Client) sampleRateFor(dataset string, rate uint) uint {
+	if c == nil || rate != c.sampleRate {
+		return rate
+	}
+	if dsRate, ok := c.datasetSampleRates[dataset]; ok {
+		return dsRate
+	}
+	return rate
+}
+
+// callResponseCallback hands r to an event's own response callback,
+// recovering from any panic in it.
+func (c *Client) callResponseCallback(cb func(transmission.Response), r transmission.Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.logPanic("response callback", p)
+		}
+	}()
+	cb(r)
+}
+
+// discardResponse is the response callback of a client with DiscardResponses
+// set, which counts Responses rather than queueing them.
+func (c *Client) discardResponse(r transmission.Response) {
+	c.increment("responses_discarded")
+	if r.Err != nil || r.StatusCode >= 400 {
+		c.increment("discarded_response_errors")
+	}
+}
+
+// logPanic records a panic recovered in one of the client's goroutines, which
+// carry on rather than taking down the host application.
+func (c *Client) logPanic(where string, p interface{}) {
+	c.ensureLogger()
+	c.logger.Printf("recovered from panic in %s: %v\n%s", where, p, debug.Stack())
+	c.increment("panics")
+}
+
 func (c *Client) ensureTransmission() {
 	c.oneTx.Do(func() {
 		if c.transmission == nil {
@@ -147,13 +415,44 @@ func (c *Client) ensureBuilder() {
 }
 
 // Close waits for all in-flight messages to be sent. You should
-// call Close() before app termination.
-func (c *Client) Close() {
+// call Close() before app termination. It returns a summary of how many
+// events were sent, left queued and dropped over the client's life, if the
+// transmission keeps count (see transmission.Summarizer).
+func (c *Client) Close() transmission.Summary {
 	c.ensureLogger()
 	c.logger.Printf("closing libhoney client")
-	if c.transmission != nil {
-		c.transmission.Stop()
+	c.stopPressureWatchers()
+	if c.bursts != nil {
+		c.bursts.stop()
+	}
+	if c.transmission == nil {
+		return transmission.Summary{}
+	}
+	c.transmission.Stop()
+	if s, ok := c.transmission.(transmission.Summarizer); ok {
+		return s.Summary()
+	}
+	return transmission.Summary{}
+}
+
+// CloseWithTimeout is like Close, but gives up waiting for in-flight messages
+// to be sent after d, eg so that shutdown isn't held up while the API is
+// unreachable. It returns how many events were abandoned, as far as the
+// transmission can tell (see transmission.ContextStopper).
+func (c *Client) CloseWithTimeout(d time.Duration) (abandoned int) {
+	c.ensureLogger()
+	c.logger.Printf("closing libhoney client with timeout %s", d)
+	c.stopPressureWatchers()
+	if c.bursts != nil {
+		c.bursts.stop()
+	}
+	if c.transmission == nil {
+		return 0
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	abandoned, _ = transmission.StopWithContext(ctx, c.transmission)
+	return abandoned
 }
 
 // Flush closes and reopens the Output interface, ensuring events
@@ -166,12 +465,36 @@ func (c *Client) Close() {
 func (c *Client) Flush() {
 	c.ensureLogger()
 	c.logger.Printf("flushing libhoney client")
+	if c.bursts != nil {
+		c.bursts.flush()
+	}
 	if c.transmission != nil {
 		c.transmission.Stop()
 		c.transmission.Start()
 	}
 }
 
+// FlushWithContext is like Flush, but gives up waiting for events to be sent
+// once ctx is done, returning how many were abandoned. Transmissions that
+// don't implement transmission.ContextStopper are flushed in full.
+func (c *Client) FlushWithContext(ctx context.Context) (abandoned int) {
+	c.ensureLogger()
+	c.logger.Printf("flushing libhoney client")
+	if c.bursts != nil {
+		c.bursts.flush()
+	}
+	if c.transmission == nil {
+		return 0
+	}
+	if cs, ok := c.transmission.(transmission.ContextStopper); ok {
+		abandoned, _ = cs.StopWithContext(ctx)
+	} else {
+		c.transmission.Stop()
+	}
+	c.transmission.Start()
+	return abandoned
+}
+
 // TxResponses returns the channel from which the caller can read the responses
 // to sent events.
 func (c *Client) TxResponses() chan transmission.Response {
@@ -179,6 +502,17 @@ func (c *Client) TxResponses() chan transmission.Response {
 	return c.transmission.TxResponses()
 }
 
+// Done returns a channel that's closed once the client's transmission has
+// stopped and closed its TxResponses channel, so that goroutines reading
+// responses can exit after Close. It's nil, and so never ready, for
+// transmissions that don't implement transmission.DoneNotifier. Flush stops
+// and restarts the transmission, so call TxResponses and Done again after
+// flushing.
+func (c *Client) Done() <-chan struct{} {
+	c.ensureTransmission()
+	return transmission.Done(c.transmission)
+}
+
 // AddDynamicField takes a field name and a function that will generate values
 // for that metric. The function is called once every time a NewEvent() is
 // created and added as a field (with name as the key) to the newly created
@@ -222,13 +556,68 @@ func (c *Client) NewBuilder() *Builder {
 	return c.builder.Clone()
 }
 
+// packageFields returns the fields that should be handed to the transmission
+// for e, after applying any client-level field processing. The event's own
+// fields are never modified; if processing is needed they are copied first.
+// The caller must hold e.lock.
+func (c *Client) packageFields(e *Event) map[string]interface{} {
+	stripConsent := len(c.consentFields) > 0 && !e.consent
+	recordCollisions := e.collisionPolicy == FieldRecordCollisions && len(e.collided) > 0
+	dump := c.goroutineDumps.dumpFor(e.data)
+	if c.fieldEncrypter == nil && len(c.fieldTransforms) == 0 && c.fieldScrubber == nil && c.fieldPolicy == nil &&
+		!stripConsent && !recordCollisions && dump == "" {
+		return e.data
+	}
+	out := make(map[string]interface{}, len(e.data))
+	for k, v := range e.data {
+		out[k] = v
+	}
+	if recordCollisions {
+		out[collisionsField] = e.collided
+	}
+	if dump != "" {
+		out[goroutinesFieldName] = dump
+	}
+	c.processFields(out, e.consent)
+	return out
+}
+
+// processFields applies the client-level field processing - consent
+// stripping, transforms, scrubbing, allow and deny lists and encryption - to
+// data, in place. consent is whether the event has consent to send its
+// consent fields.
+func (c *Client) processFields(data map[string]interface{}, consent bool) {
+	if len(c.consentFields) > 0 && !consent {
+		stripConsentFields(c.consentFields, data)
+	}
+	applyFieldTransforms(c.fieldTransforms, data)
+	if c.fieldScrubber != nil {
+		applyFieldScrubber(c.fieldScrubber, data)
+	}
+	if c.fieldPolicy != nil {
+		c.fieldPolicy.apply(data)
+	}
+	if c.fieldEncrypter != nil {
+		c.fieldEncrypter.apply(data)
+	}
+}
+
 // sendResponse sends a dropped event response down the response channel
 func (c *Client) sendDroppedResponse(e *Event, message string) {
+	c.sendErrResponse(e, errors.New(message))
+}
+
+// sendErrResponse sends a Response for e carrying err.
+func (c *Client) sendErrResponse(e *Event, err error) {
 	c.ensureTransmission()
 	r := transmission.Response{
-		Err:      errors.New(message),
+		Err:      err,
 		Metadata: e.Metadata,
 	}
+	if e.ResponseCallback != nil {
+		c.callResponseCallback(e.ResponseCallback, r)
+		return
+	}
 	c.transmission.SendResponse(r)
 
 }
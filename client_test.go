@@ -221,3 +221,127 @@ func TestEnsureBuilderRaces(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestClientResponseCallback(t *testing.T) {
+	responses := make(chan transmission.Response, 1)
+	c, err := NewClient(ClientConfig{
+		Transmission:     &transmission.MockSender{},
+		SampleRate:       1000000,
+		ResponseCallback: func(r transmission.Response) { responses <- r },
+	})
+	testOK(t, err)
+	ev := c.NewEvent()
+	ev.Metadata = "sampled"
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+	select {
+	case r := <-responses:
+		testEquals(t, r.Metadata, "sampled")
+	case <-time.After(time.Second):
+		t.Error("timed out waiting for the callback")
+	}
+	c.Close()
+}
+
+func TestClientDiscardResponses(t *testing.T) {
+	c, err := NewClient(ClientConfig{
+		Transmission:     &transmission.MockSender{BlockOnResponses: true},
+		SampleRate:       1000000,
+		DiscardResponses: true,
+	})
+	testOK(t, err)
+	for i := 0; i < 3; i++ {
+		ev := c.NewEvent()
+		ev.AddField("a", i)
+		testOK(t, ev.Send())
+	}
+	c.Close()
+	counters := c.Metrics().Counters
+	testEquals(t, counters["responses_discarded"], int64(3))
+	testEquals(t, counters["discarded_response_errors"], int64(3), "sampled events' responses carry errors")
+}
+
+// stuckSender never finishes stopping
+type stuckSender struct {
+	transmission.MockSender
+}
+
+func (s *stuckSender) Stop() error {
+	select {}
+}
+
+func TestClientCloseWithTimeout(t *testing.T) {
+	c, err := NewClient(ClientConfig{Transmission: &stuckSender{}})
+	testOK(t, err)
+	start := time.Now()
+	testEquals(t, c.CloseWithTimeout(10*time.Millisecond), 0)
+	if time.Since(start) > time.Second {
+		t.Error("CloseWithTimeout should give up at the deadline")
+	}
+}
+
+// summarizingSender reports a fixed summary
+type summarizingSender struct {
+	transmission.MockSender
+}
+
+func (s *summarizingSender) Summary() transmission.Summary {
+	return transmission.Summary{Sent: 3, Queued: 1, Dropped: 2}
+}
+
+func TestClientCloseSummary(t *testing.T) {
+	c, err := NewClient(ClientConfig{Transmission: &summarizingSender{}})
+	testOK(t, err)
+	testEquals(t, c.Close(), transmission.Summary{Sent: 3, Queued: 1, Dropped: 2})
+
+	c, err = NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	testEquals(t, c.Close(), transmission.Summary{})
+}
+
+func TestClientDone(t *testing.T) {
+	c, err := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	done := c.Done()
+	select {
+	case <-done:
+		t.Error("Done shouldn't be closed before Close")
+	default:
+	}
+	c.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Done should be closed by Close")
+	}
+	_, ok := <-c.TxResponses()
+	testEquals(t, ok, false)
+}
+
+func TestSendAfterClose(t *testing.T) {
+	c, err := NewClient(ClientConfig{
+		APIKey:     "key",
+		Dataset:    "ds",
+		SampleRate: 1000000,
+	})
+	testOK(t, err)
+	c.Suppress("healthchecks", time.Hour, FieldEquals("path", "/healthz"))
+	c.Close()
+
+	assert.NotPanics(t, func() {
+		ev := c.NewEvent()
+		ev.AddField("a", 1)
+		ev.Send()
+
+		ev = c.NewEvent()
+		ev.SampleRate = 1
+		ev.AddField("path", "/healthz")
+		ev.Send()
+
+		c.SetKillSwitch(true)
+		ev = c.NewEvent()
+		ev.AddField("a", 1)
+		ev.Send()
+	})
+	assert.Equal(t, int64(3), c.Metrics().Counters["responses_after_stop"])
+}
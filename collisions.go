@@ -0,0 +1,99 @@
+package libhoney
+
+import (
+	"fmt"
+	"strings"
+)
+
+// collisionsField lists the fields an event had overwritten, with
+// FieldRecordCollisions.
+const collisionsField = "meta.field_collisions"
+
+// FieldCollisionPolicy is what happens when a field is added to an event or
+// builder that already has a field of that name, whether set directly or
+// inherited from its Builder or the Client.
+type FieldCollisionPolicy int
+
+const (
+	// FieldLastWins overwrites the field. This is the default.
+	FieldLastWins FieldCollisionPolicy = iota
+	// FieldFirstWins keeps the existing value.
+	FieldFirstWins
+	// FieldRecordCollisions overwrites the field, and lists the names of
+	// overwritten fields in meta.field_collisions when the event is sent.
+	FieldRecordCollisions
+	// FieldCollisionsError keeps the existing value, and makes Send fail with
+	// a *FieldCollisionError instead of sending the event. It is meant for
+	// catching clobbered fields in tests and development.
+	FieldCollisionsError
+)
+
+// FieldCollisionError is returned by Send for an event that had fields added
+// more than once under FieldCollisionsError.
+type FieldCollisionError struct {
+	Fields []string
+}
+
+func (e *FieldCollisionError) Error() string {
+	return fmt.Sprintf("fields set more than once: %s", strings.Join(e.Fields, ", "))
+}
+
+// set stores val under key, converting and flattening it if configured to and
+// applying the collision policy. The caller must hold f.lock.
+func (f *fieldHolder) set(key string, val interface{}) {
+	if f.convertValues {
+		val = convertFieldValue(val)
+	}
+	if f.flatten != nil {
+		f.setFlattened(key, val, 1)
+		return
+	}
+	f.setField(key, val)
+}
+
+// setField stores val under key, applying the collision policy. The caller
+// must hold f.lock.
+func (f *fieldHolder) setField(key string, val interface{}) {
+	f.unshare()
+	if f.collisionPolicy != FieldLastWins {
+		if _, ok := f.data[key]; ok {
+			f.noteCollision(key)
+			if f.collisionPolicy != FieldRecordCollisions {
+				return
+			}
+		}
+	}
+	f.data[key] = val
+	f.prov.record(key)
+}
+
+func (f *fieldHolder) noteCollision(key string) {
+	for _, k := range f.collided {
+		if k == key {
+			return
+		}
+	}
+	f.collided = append(f.collided, key)
+}
+
+// inheritCollisions copies the collision policy and any collisions so far
+// from a Builder to an Event or Builder derived from it. The caller must
+// hold both locks.
+func (f *fieldHolder) inheritCollisions(from *fieldHolder) {
+	f.collisionPolicy = from.collisionPolicy
+	if len(from.collided) > 0 {
+		f.collided = append([]string(nil), from.collided...)
+	}
+}
+
+// SetFieldCollisionPolicy sets what happens when a field is added to the
+// builder, or to events created from it, that already has a value. Events
+// and builders cloned from it inherit the policy.
+func (b *Builder) SetFieldCollisionPolicy(p FieldCollisionPolicy) {
+	if b.frozen {
+		panic(ErrBuilderFrozen)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.collisionPolicy = p
+}
@@ -0,0 +1,50 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldCollisionPolicies(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:               "key",
+		Dataset:              "ds",
+		Transmission:         mock,
+		FieldCollisionPolicy: FieldFirstWins,
+	})
+	c.AddField("service.name", "api")
+
+	ev := c.NewEvent()
+	ev.AddField("service.name", "clobbered")
+	ev.Add(map[string]interface{}{"service.name": "clobbered", "a": 1})
+	testOK(t, ev.Send())
+
+	b := c.NewBuilder()
+	b.SetFieldCollisionPolicy(FieldRecordCollisions)
+	ev = b.NewEvent()
+	ev.AddField("service.name", "worker")
+	ev.AddField("service.name", "worker2")
+	testOK(t, ev.Send())
+
+	b.SetFieldCollisionPolicy(FieldCollisionsError)
+	ev = b.Clone().NewEvent()
+	ev.AddField("service.name", "worker")
+	err := ev.Send()
+	assert.Equal(t, &FieldCollisionError{Fields: []string{"service.name"}}, err)
+
+	ev = b.NewEvent()
+	ev.AddField("other", 1)
+	testOK(t, ev.Send())
+
+	events := mock.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "api", events[0].Data["service.name"])
+	assert.Equal(t, 1, events[0].Data["a"])
+	assert.Nil(t, events[0].Data[collisionsField], "only recorded with FieldRecordCollisions")
+	assert.Equal(t, "worker2", events[1].Data["service.name"])
+	assert.Equal(t, []string{"service.name"}, events[1].Data[collisionsField])
+	assert.Equal(t, "api", events[2].Data["service.name"])
+}
@@ -0,0 +1,128 @@
+package libhoney
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the layout of a config file read by ConfigFromFile.
+type fileConfig struct {
+	APIKey               string `json:"api_key" yaml:"api_key"`
+	WriteKey             string `json:"writekey" yaml:"writekey"`
+	Dataset              string `json:"dataset" yaml:"dataset"`
+	APIHost              string `json:"api_host" yaml:"api_host"`
+	Region               string `json:"region" yaml:"region"`
+	SampleRate           uint   `json:"sample_rate" yaml:"sample_rate"`
+	MaxBatchSize         uint   `json:"max_batch_size" yaml:"max_batch_size"`
+	SendFrequency        string `json:"send_frequency" yaml:"send_frequency"`
+	MaxConcurrentBatches uint   `json:"max_concurrent_batches" yaml:"max_concurrent_batches"`
+	PendingWorkCapacity  uint   `json:"pending_work_capacity" yaml:"pending_work_capacity"`
+	MaxRetries           uint   `json:"max_retries" yaml:"max_retries"`
+	RetryBackoff         string `json:"retry_backoff" yaml:"retry_backoff"`
+	Proxy                string `json:"proxy" yaml:"proxy"`
+}
+
+// ConfigFromFile reads a Config from a YAML (*.yaml or *.yml) or JSON file,
+// for programs that want their Honeycomb settings to be edited by operators
+// rather than in code. The keys are:
+//
+//	api_key, writekey, dataset, api_host, region, sample_rate
+//	max_batch_size, send_frequency, max_concurrent_batches, pending_work_capacity
+//	max_retries, retry_backoff
+//	proxy
+//
+// send_frequency and retry_backoff are durations such as "100ms", and proxy
+// is the URL of an HTTP proxy to send events through. Any other key is an
+// error, so that typos don't silently go unapplied. Anything else in the
+// Config can be set on the result before passing it to Init.
+func ConfigFromFile(path string) (Config, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	unmarshal := unmarshalJSONStrict
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshal = unmarshalYAMLStrict
+	}
+	conf, err := ParseConfig(raw, unmarshal)
+	if err != nil {
+		return conf, fmt.Errorf("config file %s: %v", path, err)
+	}
+	return conf, nil
+}
+
+// unmarshalJSONStrict is json.Unmarshal, rejecting unknown keys.
+func unmarshalJSONStrict(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// unmarshalYAMLStrict is yaml.Unmarshal, rejecting unknown keys.
+func unmarshalYAMLStrict(raw []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != io.EOF {
+		return err
+	}
+	// an empty file
+	return nil
+}
+
+// ParseConfig parses a Config with the keys ConfigFromFile reads from raw,
+// using unmarshal, for config held somewhere other than a file or in another
+// format, eg:
+//
+//	conf, err := libhoney.ParseConfig([]byte(os.Getenv("HONEYCOMB_CONFIG")), json.Unmarshal)
+func ParseConfig(raw []byte, unmarshal func([]byte, interface{}) error) (Config, error) {
+	var conf Config
+	var fc fileConfig
+	if err := unmarshal(raw, &fc); err != nil {
+		return conf, fmt.Errorf("failed to parse config: %v", err)
+	}
+
+	conf.APIKey = fc.APIKey
+	conf.WriteKey = fc.WriteKey
+	conf.Dataset = fc.Dataset
+	conf.APIHost = fc.APIHost
+	conf.Region = Region(fc.Region)
+	conf.SampleRate = fc.SampleRate
+	conf.MaxBatchSize = fc.MaxBatchSize
+	conf.MaxConcurrentBatches = fc.MaxConcurrentBatches
+	conf.PendingWorkCapacity = fc.PendingWorkCapacity
+	conf.MaxRetries = fc.MaxRetries
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"send_frequency", fc.SendFrequency, &conf.SendFrequency},
+		{"retry_backoff", fc.RetryBackoff, &conf.RetryBackoff},
+	} {
+		if d.value == "" {
+			continue
+		}
+		var err error
+		if *d.dest, err = time.ParseDuration(d.value); err != nil {
+			return conf, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+	}
+	if fc.Proxy != "" {
+		proxy, err := url.Parse(fc.Proxy)
+		if err != nil {
+			return conf, fmt.Errorf("invalid proxy: %v", err)
+		}
+		conf.ProxyURL = proxy
+	}
+	return conf, nil
+}
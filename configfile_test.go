@@ -0,0 +1,127 @@
+package libhoney
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name, contents string) string {
+	dir, err := ioutil.TempDir("", "libhoney-config")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestConfigFromFile(t *testing.T) {
+	path := writeConfigFile(t, "honeycomb.json", `{
+	"writekey": "abc123",
+	"dataset": "agent",
+	"api_host": "https://refinery.example.com",
+	"sample_rate": 10,
+	"max_batch_size": 20,
+	"send_frequency": "250ms",
+	"max_retries": 3,
+	"retry_backoff": "1s",
+	"proxy": "http://proxy.example.com:3128"
+}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	conf, err := ConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", conf.WriteKey)
+	assert.Equal(t, "agent", conf.Dataset)
+	assert.Equal(t, "https://refinery.example.com", conf.APIHost)
+	assert.Equal(t, uint(10), conf.SampleRate)
+	assert.Equal(t, uint(20), conf.MaxBatchSize)
+	assert.Equal(t, 250*time.Millisecond, conf.SendFrequency)
+	assert.Equal(t, uint(3), conf.MaxRetries)
+	assert.Equal(t, time.Second, conf.RetryBackoff)
+
+	assert.Equal(t, "http://proxy.example.com:3128", conf.ProxyURL.String())
+}
+
+func TestParseConfig(t *testing.T) {
+	// stands in for another format's Unmarshal
+	unmarshal := func(raw []byte, v interface{}) error {
+		if string(raw) == "bad" {
+			return errors.New("syntax error")
+		}
+		fc := v.(*fileConfig)
+		fc.Dataset = string(raw)
+		fc.SendFrequency = "1s"
+		return nil
+	}
+	conf, err := ParseConfig([]byte("agent"), unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, "agent", conf.Dataset)
+	assert.Equal(t, time.Second, conf.SendFrequency)
+
+	_, err = ParseConfig([]byte("bad"), unmarshal)
+	assert.EqualError(t, err, "failed to parse config: syntax error")
+}
+
+func TestConfigFromFileYAML(t *testing.T) {
+	for _, name := range []string{"honeycomb.yaml", "honeycomb.YML"} {
+		path := writeConfigFile(t, name, "writekey: abc123\ndataset: agent\nsend_frequency: 250ms\n")
+		defer os.RemoveAll(filepath.Dir(path))
+		conf, err := ConfigFromFile(path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "abc123", conf.WriteKey, name)
+		assert.Equal(t, "agent", conf.Dataset, name)
+		assert.Equal(t, 250*time.Millisecond, conf.SendFrequency, name)
+	}
+
+	path := writeConfigFile(t, "empty.yaml", "")
+	defer os.RemoveAll(filepath.Dir(path))
+	conf, err := ConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Config{}, conf)
+}
+
+func TestConfigFromFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "honeycomb.json", `{"api_key": "key", "dataset": "ds", "region": "eu", "pending_work_capacity": 50}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	conf, err := ConfigFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", conf.APIKey)
+	assert.Equal(t, "ds", conf.Dataset)
+	assert.Equal(t, RegionEU, conf.Region)
+	assert.Equal(t, uint(50), conf.PendingWorkCapacity)
+	assert.Nil(t, conf.ProxyURL)
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	_, err := ConfigFromFile("/does/not/exist.json")
+	assert.Error(t, err)
+
+	path := writeConfigFile(t, "bad.json", `{"dataset": `)
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = ConfigFromFile(path)
+	assert.Error(t, err)
+
+	path = writeConfigFile(t, "bad_duration.json", `{"send_frequency": "soon"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = ConfigFromFile(path)
+	assert.Error(t, err)
+
+	path = writeConfigFile(t, "unknown.json", `{"dataset": "ds", "sample_rat": 10}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = ConfigFromFile(path)
+	assert.Error(t, err, "unknown keys are rejected")
+
+	path = writeConfigFile(t, "unknown.yaml", "dataset: ds\nsample_rat: 10\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = ConfigFromFile(path)
+	assert.Error(t, err, "unknown keys are rejected")
+
+	path = writeConfigFile(t, "bad.yaml", "dataset: [")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = ConfigFromFile(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package libhoney
+
+// Fields listed in Config.ConsentFields are only sent on events that have
+// been granted consent, either directly with Event.SetConsent or by being
+// created from a Builder on which SetConsent(true) was called. On all other
+// events those fields are stripped just before the event is handed to the
+// transmission, so instrumentation can add them unconditionally.
+
+// SetConsent records whether the user the builder's events describe has
+// consented to having consent-gated fields collected. Events created from
+// this builder (and builders cloned from it) inherit the setting.
+func (b *Builder) SetConsent(consent bool) {
+	if b.frozen {
+		panic(ErrBuilderFrozen)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.consent = consent
+}
+
+// SetConsent records whether consent-gated fields may be sent with this
+// event, overriding whatever it inherited from its Builder.
+//
+// Calls to SetConsent after the event has been sent have no effect.
+func (e *Event) SetConsent(consent bool) {
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent == true {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.consent = consent
+}
+
+// stripConsentFields removes any consent-gated fields from data, in place.
+func stripConsentFields(fields map[string]struct{}, data map[string]interface{}) {
+	for k := range fields {
+		delete(data, k)
+	}
+}
@@ -0,0 +1,42 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsentFields(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:        "key",
+		Dataset:       "ds",
+		Transmission:  mock,
+		ConsentFields: []string{"user.email"},
+	})
+
+	send := func(ev *Event) {
+		ev.AddField("user.email", "pat@example.com")
+		ev.AddField("path", "/")
+		ev.Send()
+	}
+	send(c.NewEvent())
+
+	consenting := c.NewBuilder()
+	consenting.SetConsent(true)
+	send(consenting.NewEvent())
+	send(consenting.Clone().NewEvent())
+
+	revoked := consenting.NewEvent()
+	revoked.SetConsent(false)
+	send(revoked)
+
+	events := mock.Events()
+	assert.Equal(t, 4, len(events))
+	assert.Nil(t, events[0].Data["user.email"], "no consent should strip the field")
+	assert.Equal(t, "/", events[0].Data["path"])
+	assert.Equal(t, "pat@example.com", events[1].Data["user.email"])
+	assert.Equal(t, "pat@example.com", events[2].Data["user.email"], "clones should inherit consent")
+	assert.Nil(t, events[3].Data["user.email"], "event-level consent should override the builder")
+}
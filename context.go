@@ -0,0 +1,27 @@
+package libhoney
+
+import "context"
+
+type eventKey struct{}
+
+// ContextWithEvent returns a copy of ctx carrying ev, so that code deep in a
+// call stack can add fields to the event for the request it's handling with
+// AddFieldToContext, without ev being passed down to it.
+func ContextWithEvent(ctx context.Context, ev *Event) context.Context {
+	return context.WithValue(ctx, eventKey{}, ev)
+}
+
+// EventFromContext returns the event ctx carries, or nil if it has none.
+func EventFromContext(ctx context.Context) *Event {
+	ev, _ := ctx.Value(eventKey{}).(*Event)
+	return ev
+}
+
+// AddFieldToContext adds a field to the event ctx carries. It does nothing
+// if ctx has no event, so it's safe to call whether or not the caller is
+// instrumented.
+func AddFieldToContext(ctx context.Context, key string, val interface{}) {
+	if ev := EventFromContext(ctx); ev != nil {
+		ev.AddField(key, val)
+	}
+}
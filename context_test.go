@@ -0,0 +1,35 @@
+package libhoney
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextWithEvent(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, EventFromContext(ctx))
+	AddFieldToContext(ctx, "ignored", true)
+
+	builder, _ := newHandlerTestBuilder(t)
+	ev := builder.NewEvent()
+	ctx = ContextWithEvent(ctx, ev)
+	assert.Equal(t, ev, EventFromContext(ctx))
+	AddFieldToContext(ctx, "user", "ada")
+	assert.Equal(t, "ada", ev.Fields()["user"])
+}
+
+func TestWrapHandlerContextEvent(t *testing.T) {
+	builder, mock := newHandlerTestBuilder(t)
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddFieldToContext(r.Context(), "user", "ada")
+	}), builder)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "ada", events[0].Data["user"])
+}
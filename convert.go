@@ -0,0 +1,43 @@
+package libhoney
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// convertFieldValue returns val as it's added with ConvertFieldValues: a
+// time.Duration becomes float milliseconds, an error its message, and a
+// fmt.Stringer or encoding.TextMarshaler its text. Values that marshal
+// themselves to JSON, such as time.Time, are left alone, as are nil pointers.
+func convertFieldValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case time.Duration:
+		return float64(v) / float64(time.Millisecond)
+	case *time.Duration:
+		if v == nil {
+			return val
+		}
+		return float64(*v) / float64(time.Millisecond)
+	}
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return val
+	}
+	switch v := val.(type) {
+	case error:
+		return v.Error()
+	case json.Marshaler:
+		return val
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+	return val
+}
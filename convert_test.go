@@ -0,0 +1,58 @@
+package libhoney
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+type stringerValue struct{}
+
+func (*stringerValue) String() string { return "stringer" }
+
+func TestConvertFieldValue(t *testing.T) {
+	d := 1500 * time.Microsecond
+	ts := time.Unix(1500000000, 0)
+	var nilStringer *stringerValue
+	for _, c := range []struct {
+		in, want interface{}
+	}{
+		{d, 1.5},
+		{&d, 1.5},
+		{errors.New("boom"), "boom"},
+		{net.ParseIP("10.0.0.1"), "10.0.0.1"},
+		{&stringerValue{}, "stringer"},
+		{ts, ts},
+		{nilStringer, nilStringer},
+		{3, 3},
+		{nil, nil},
+	} {
+		assert.Equal(t, c.want, convertFieldValue(c.in))
+	}
+}
+
+func TestConvertFieldValues(t *testing.T) {
+	c, _ := NewClient(ClientConfig{
+		Transmission:       &transmission.MockSender{},
+		ConvertFieldValues: true,
+		Flatten:            &FlattenConfig{},
+	})
+	ev := c.NewBuilder().NewEvent()
+	ev.AddField("duration_ms", 2*time.Millisecond)
+	ev.AddField("error", errors.New("boom"))
+	ev.AddField("request", struct{ Took time.Duration }{time.Millisecond})
+	assert.Equal(t, map[string]interface{}{
+		"duration_ms":  2.0,
+		"error":        "boom",
+		"request.Took": 1.0,
+	}, ev.Fields())
+
+	plain, _ := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	ev = plain.NewEvent()
+	ev.AddField("duration", time.Millisecond)
+	assert.Equal(t, time.Millisecond, ev.Fields()["duration"], "conversion is opt-in")
+}
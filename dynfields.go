@@ -0,0 +1,48 @@
+package libhoney
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dynamicFieldErrorField describes the panics of an event's dynamic fields,
+// for clients with ClientConfig.DynamicFieldErrors set.
+const dynamicFieldErrorField = "libhoney_dynamic_field_error"
+
+// evalDynamicField calls f's function, recovering from any panic in it so
+// that a broken dynamic field can't take down the code creating events.
+// Panics are logged and returned as errors, or re-panicked by clients with
+// StrictDynamicFields set. It may be called on a nil Client.
+func (c *Client) evalDynamicField(f dynamicField) (val interface{}, err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if c != nil {
+			c.logPanic("dynamic field "+f.name, p)
+			if c.strictDynamicFields {
+				panic(p)
+			}
+		}
+		err = fmt.Errorf("%s: %v", f.name, p)
+	}()
+	return f.fn(), nil
+}
+
+// addDynamicFields evaluates fields, passing each to add, leaving out those
+// whose functions panicked.
+func (c *Client) addDynamicFields(fields []dynamicField, add func(string, interface{})) {
+	var failures []string
+	for _, f := range fields {
+		val, err := c.evalDynamicField(f)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		add(f.name, val)
+	}
+	if len(failures) > 0 && c != nil && c.dynamicFieldErrors {
+		add(dynamicFieldErrorField, strings.Join(failures, "; "))
+	}
+}
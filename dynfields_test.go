@@ -0,0 +1,35 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicFieldPanics(t *testing.T) {
+	newBuilder := func(conf ClientConfig) *Builder {
+		conf.Transmission = &transmission.MockSender{}
+		c, err := NewClient(conf)
+		testOK(t, err)
+		b := c.NewBuilder()
+		b.AddDynamicField("ok", func() interface{} { return 1 })
+		b.AddDynamicField("broken", func() interface{} { panic("oops") })
+		return b
+	}
+
+	b := newBuilder(ClientConfig{})
+	ev := b.NewEvent()
+	assert.Equal(t, map[string]interface{}{"ok": 1}, ev.Fields())
+	assert.Equal(t, int64(1), b.client.Metrics().Counters["panics"])
+
+	ev = newBuilder(ClientConfig{DynamicFieldErrors: true}).NewEvent()
+	assert.Equal(t, map[string]interface{}{"ok": 1, dynamicFieldErrorField: "broken: oops"}, ev.Fields())
+
+	strict := newBuilder(ClientConfig{StrictDynamicFields: true})
+	assert.PanicsWithValue(t, "oops", func() { strict.NewEvent() })
+
+	b = &Builder{}
+	b.AddDynamicField("broken", func() interface{} { panic("oops") })
+	assert.NotPanics(t, func() { b.NewEvent() }, "builders without a client recover too")
+}
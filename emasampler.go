@@ -0,0 +1,144 @@
+package libhoney
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// EMASamplerConfig configures an EMASampler.
+type EMASamplerConfig struct {
+	// KeyFields are the fields whose values make up an event's sampling key,
+	// eg "http.route" and "http.status_code". Events with the same key share
+	// a sample rate. The dataset is always part of the key.
+	KeyFields []string
+	// GoalThroughputPerSec is roughly how many events per second to keep
+	// across all keys. Required.
+	GoalThroughputPerSec float64
+	// AdjustmentInterval is how often sample rates are recalculated.
+	// Defaults to 15 seconds.
+	AdjustmentInterval time.Duration
+	// Weight is how much each interval's counts move the moving averages,
+	// between 0 and 1. Defaults to 0.5.
+	Weight float64
+	// AgeOutValue is the moving average below which a key is forgotten.
+	// Defaults to 0.5.
+	AgeOutValue float64
+}
+
+// EMASampler is a Sampler that adapts to traffic. It counts events per key
+// and keeps an exponential moving average of each key's count per
+// AdjustmentInterval. Every interval it sets each key's sample rate so that
+// about GoalThroughputPerSec events are kept in total, giving each key a
+// share of that goal that grows with the logarithm of its volume: rare keys
+// are kept at or near a rate of 1 while frequent ones are sampled heavily.
+// Keys it hasn't set a rate for yet are kept.
+type EMASampler struct {
+	conf EMASamplerConfig
+
+	lock       sync.Mutex
+	counts     map[string]float64
+	averages   map[string]float64
+	rates      map[string]uint
+	nextUpdate time.Time
+
+	// now can be replaced for tests
+	now func() time.Time
+}
+
+// NewEMASampler returns an EMASampler for conf.
+func NewEMASampler(conf EMASamplerConfig) *EMASampler {
+	if conf.AdjustmentInterval <= 0 {
+		conf.AdjustmentInterval = 15 * time.Second
+	}
+	if conf.Weight <= 0 || conf.Weight > 1 {
+		conf.Weight = 0.5
+	}
+	if conf.AgeOutValue <= 0 {
+		conf.AgeOutValue = 0.5
+	}
+	return &EMASampler{
+		conf:     conf,
+		counts:   make(map[string]float64),
+		averages: make(map[string]float64),
+		rates:    make(map[string]uint),
+		now:      time.Now,
+	}
+}
+
+// Sample implements Sampler.
+func (s *EMASampler) Sample(ev *Event) (bool, uint) {
+	key := s.key(ev)
+	rate := s.countAndRate(key)
+	return rate <= 1 || rand.Intn(int(rate)) == 0, rate
+}
+
+// key returns ev's sampling key.
+func (s *EMASampler) key(ev *Event) string {
+	buf := bytes.NewBufferString(ev.Dataset)
+	ev.lock.RLock()
+	defer ev.lock.RUnlock()
+	for _, f := range s.conf.KeyFields {
+		fmt.Fprintf(buf, "\x00%v", ev.data[f])
+	}
+	return buf.String()
+}
+
+// countAndRate counts an event for key and returns key's sample rate,
+// recalculating the rates first if the interval is up.
+func (s *EMASampler) countAndRate(key string) uint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.now()
+	if s.nextUpdate.IsZero() {
+		s.nextUpdate = now.Add(s.conf.AdjustmentInterval)
+	} else if !now.Before(s.nextUpdate) {
+		s.updateRates()
+		s.nextUpdate = now.Add(s.conf.AdjustmentInterval)
+	}
+	s.counts[key]++
+	if rate, ok := s.rates[key]; ok {
+		return rate
+	}
+	return 1
+}
+
+// updateRates folds the interval's counts into the moving averages and sets
+// each key's rate from them. The caller must hold s.lock.
+func (s *EMASampler) updateRates() {
+	for key := range s.averages {
+		if _, ok := s.counts[key]; !ok {
+			s.counts[key] = 0
+		}
+	}
+	var total, logSum float64
+	for key, count := range s.counts {
+		avg := s.conf.Weight*count + (1-s.conf.Weight)*s.averages[key]
+		if avg < s.conf.AgeOutValue {
+			delete(s.averages, key)
+			continue
+		}
+		s.averages[key] = avg
+		total += avg
+		logSum += math.Log10(math.Max(avg, 1))
+	}
+	s.counts = make(map[string]float64)
+	s.rates = make(map[string]uint, len(s.averages))
+
+	goal := s.conf.GoalThroughputPerSec * s.conf.AdjustmentInterval.Seconds()
+	if total <= goal || logSum == 0 {
+		// everything fits within the goal
+		return
+	}
+	// each key's share of the goal is proportional to the log of its volume
+	goalRatio := goal / logSum
+	for key, avg := range s.averages {
+		keyGoal := math.Max(1, math.Log10(math.Max(avg, 1))*goalRatio)
+		if rate := uint(math.Floor(avg / keyGoal)); rate > 1 {
+			s.rates[key] = rate
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEMASampler(t *testing.T) {
+	s := NewEMASampler(EMASamplerConfig{
+		KeyFields:            []string{"route"},
+		GoalThroughputPerSec: 10,
+	})
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	event := func(route string) *Event {
+		ev := NewEvent()
+		ev.Dataset = "ds"
+		ev.AddField("route", route)
+		return ev
+	}
+	interval := func(busy, rare int) (busyRate, rareRate uint) {
+		for i := 0; i < busy; i++ {
+			_, busyRate = s.Sample(event("/busy"))
+		}
+		for i := 0; i < rare; i++ {
+			_, rareRate = s.Sample(event("/rare"))
+		}
+		now = now.Add(15 * time.Second)
+		return
+	}
+
+	busyRate, rareRate := interval(1000, 2)
+	assert.Equal(t, uint(1), busyRate, "keys are kept until rates are set")
+	assert.Equal(t, uint(1), rareRate)
+
+	// the first interval sets the rates used in the second
+	interval(1000, 2)
+	busyRate, rareRate = interval(1000, 2)
+	assert.True(t, busyRate > 1, "busy keys are sampled")
+	assert.Equal(t, uint(1), rareRate, "rare keys are kept")
+
+	// under the goal everything is kept
+	for i := 0; i < 10; i++ {
+		interval(10, 2)
+	}
+	busyRate, rareRate = interval(10, 2)
+	assert.Equal(t, uint(1), busyRate)
+	assert.Equal(t, uint(1), rareRate)
+}
+
+func TestEMASamplerAgesOutKeys(t *testing.T) {
+	s := NewEMASampler(EMASamplerConfig{GoalThroughputPerSec: 1})
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ev := NewEvent()
+	ev.Dataset = "ds"
+	s.Sample(ev)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Minute)
+		s.countAndRate("other")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.averages["ds"]
+	assert.False(t, ok, "unseen keys are forgotten")
+}
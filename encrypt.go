@@ -0,0 +1,114 @@
+package libhoney
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// keyIDSuffix is appended to the name of each encrypted field to make the name
+// of the companion field recording which key was used.
+const keyIDSuffix = ".key_id"
+
+// FieldEncrypter encrypts the values of a fixed set of fields using AES-GCM
+// before events leave the process. Each encrypted field's value is replaced
+// by the base64-encoded nonce and ciphertext of its JSON encoding, and a
+// companion field named "<field>.key_id" records the ID of the key used, so
+// backends holding the key (and only those) can recover the original value
+// with Decrypt. The field name and key ID are authenticated along with the
+// value, so an encrypted value can't be moved to another field undetected.
+type FieldEncrypter struct {
+	keyID  string
+	aead   cipher.AEAD
+	fields map[string]struct{}
+}
+
+// NewFieldEncrypter returns a FieldEncrypter that encrypts the named fields
+// with key, which must be 16, 24 or 32 bytes long to select AES-128, AES-192
+// or AES-256. keyID is recorded alongside each encrypted value and should
+// identify the key without revealing it.
+func NewFieldEncrypter(keyID string, key []byte, fields ...string) (*FieldEncrypter, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("no fields given to encrypt")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	fe := &FieldEncrypter{
+		keyID:  keyID,
+		aead:   aead,
+		fields: make(map[string]struct{}, len(fields)),
+	}
+	for _, f := range fields {
+		fe.fields[f] = struct{}{}
+	}
+	return fe, nil
+}
+
+// Encrypts reports whether the named field is encrypted.
+func (fe *FieldEncrypter) Encrypts(field string) bool {
+	_, ok := fe.fields[field]
+	return ok
+}
+
+// Encrypt returns the encrypted form of val as the value of field.
+func (fe *FieldEncrypter) Encrypt(field string, val interface{}) (string, error) {
+	plain, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, fe.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := fe.aead.Seal(nonce, nonce, plain, fe.additionalData(field))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt for the value of field, returning the JSON encoding
+// of the original value.
+func (fe *FieldEncrypter) Decrypt(field, encrypted string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := fe.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("encrypted value is too short")
+	}
+	return fe.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], fe.additionalData(field))
+}
+
+// additionalData returns the data authenticated alongside field's value: the
+// field name and key ID, separated by a NUL so the two can't run together.
+func (fe *FieldEncrypter) additionalData(field string) []byte {
+	return []byte(field + "\x00" + fe.keyID)
+}
+
+// apply encrypts any of the configured fields present in data, in place.
+// Fields whose values can't be encrypted are removed rather than being sent
+// in the clear.
+func (fe *FieldEncrypter) apply(data map[string]interface{}) {
+	for k := range fe.fields {
+		v, ok := data[k]
+		if !ok {
+			continue
+		}
+		enc, err := fe.Encrypt(k, v)
+		if err != nil {
+			delete(data, k)
+			continue
+		}
+		data[k] = enc
+		data[k+keyIDSuffix] = fe.keyID
+	}
+}
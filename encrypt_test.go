@@ -0,0 +1,64 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestNewFieldEncrypter(t *testing.T) {
+	_, err := NewFieldEncrypter("k1", []byte("short"), "ssn")
+	assert.Error(t, err, "bad key length should error")
+	_, err = NewFieldEncrypter("k1", testKey)
+	assert.Error(t, err, "no fields should error")
+	fe, err := NewFieldEncrypter("k1", testKey, "ssn")
+	assert.NoError(t, err)
+	assert.True(t, fe.Encrypts("ssn"))
+	assert.False(t, fe.Encrypts("name"))
+}
+
+func TestFieldEncrypterRoundTrip(t *testing.T) {
+	fe, _ := NewFieldEncrypter("k1", testKey, "ssn")
+	enc, err := fe.Encrypt("ssn", map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	plain, err := fe.Decrypt("ssn", enc)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(plain))
+
+	other, _ := NewFieldEncrypter("k2", []byte("fedcba9876543210fedcba9876543210"), "ssn")
+	_, err = other.Decrypt("ssn", enc)
+	assert.Error(t, err, "decrypting with the wrong key should fail")
+
+	_, err = fe.Decrypt("name", enc)
+	assert.Error(t, err, "a value moved to another field shouldn't decrypt")
+	relabeled, _ := NewFieldEncrypter("k3", testKey, "ssn")
+	_, err = relabeled.Decrypt("ssn", enc)
+	assert.Error(t, err, "a value shouldn't decrypt under another key ID")
+}
+
+func TestClientEncryptsFields(t *testing.T) {
+	fe, _ := NewFieldEncrypter("k1", testKey, "ssn")
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:         "key",
+		Dataset:        "ds",
+		Transmission:   mock,
+		FieldEncrypter: fe,
+	})
+	ev := c.NewEvent()
+	ev.AddField("ssn", "123-45-6789")
+	ev.AddField("name", "pat")
+	assert.NoError(t, ev.Send())
+
+	sent := mock.Events()[0].Data
+	assert.Equal(t, "pat", sent["name"])
+	assert.Equal(t, "k1", sent["ssn.key_id"])
+	assert.NotEqual(t, "123-45-6789", sent["ssn"])
+	plain, err := fe.Decrypt("ssn", sent["ssn"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, `"123-45-6789"`, string(plain))
+	assert.Equal(t, "123-45-6789", ev.Fields()["ssn"], "the event's own fields should be untouched")
+}
@@ -0,0 +1,35 @@
+package libhoney
+
+import "os"
+
+// The environment variables read by clients with FromEnv set.
+const (
+	APIKeyEnv      = "HONEYCOMB_API_KEY"
+	DatasetEnv     = "HONEYCOMB_DATASET"
+	APIEndpointEnv = "HONEYCOMB_API_ENDPOINT"
+)
+
+// applyEnv fills the API key, dataset and API host of conf from the
+// environment where they haven't been set in code. A Region set in code
+// counts as setting the API host.
+func applyEnv(conf *ClientConfig) {
+	apiHost := &conf.APIHost
+	if conf.Region != "" {
+		apiHost = nil
+	}
+	for _, v := range []struct {
+		name  string
+		field *string
+	}{
+		{APIKeyEnv, &conf.APIKey},
+		{DatasetEnv, &conf.Dataset},
+		{APIEndpointEnv, apiHost},
+	} {
+		if v.field == nil || *v.field != "" {
+			continue
+		}
+		if val := os.Getenv(v.name); val != "" {
+			*v.field = val
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package libhoney
+
+import (
+	"os"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientFromEnv(t *testing.T) {
+	for name, val := range map[string]string{
+		APIKeyEnv:      "envkey",
+		DatasetEnv:     "envds",
+		APIEndpointEnv: "http://env.example.com",
+	} {
+		old, had := os.LookupEnv(name)
+		os.Setenv(name, val)
+		defer func(name string) {
+			if had {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name)
+	}
+
+	c, err := NewClient(ClientConfig{
+		Dataset:      "codeds",
+		Transmission: &transmission.MockSender{},
+		FromEnv:      true,
+	})
+	assert.NoError(t, err)
+	ev := c.NewEvent()
+	assert.Equal(t, "envkey", ev.WriteKey)
+	assert.Equal(t, "codeds", ev.Dataset, "settings in code win")
+	assert.Equal(t, "http://env.example.com", ev.APIHost)
+
+	c, _ = NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	ev = c.NewEvent()
+	assert.Equal(t, "", ev.WriteKey, "the environment is only read with FromEnv")
+	assert.Equal(t, defaultAPIHost, ev.APIHost)
+}
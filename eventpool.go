@@ -0,0 +1,54 @@
+package libhoney
+
+import "sync"
+
+var eventPool = sync.Pool{
+	New: func() interface{} {
+		return &Event{}
+	},
+}
+
+// NewEventFromPool is like NewEvent, except that the event (and its field
+// map) may be reused from one that was Released. See Event.Release.
+func NewEventFromPool() *Event {
+	return dc.NewEventFromPool()
+}
+
+// NewEventFromPool is like NewEvent, except that the event (and its field
+// map) may be reused from one that was Released. See Event.Release.
+func (c *Client) NewEventFromPool() *Event {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	return c.builder.NewEventFromPool()
+}
+
+// NewEventFromPool is like NewEvent, except that the event (and its field
+// map) may be reused from one that was Released. See Event.Release.
+func (b *Builder) NewEventFromPool() *Event {
+	e := eventPool.Get().(*Event)
+	if e.data == nil {
+		e.data = make(map[string]interface{})
+	}
+	e.pooled = true
+	b.fillEvent(e)
+	return e
+}
+
+// Release returns an event created by NewEventFromPool to the pool, so that
+// hot paths creating many events don't have to allocate each one. The event
+// must not be used afterwards. Once an event has been sent the transmission
+// may still be reading its fields, so only release it after its Response has
+// been received (or if Send returned an error). Release does nothing for
+// events created by NewEvent.
+func (e *Event) Release() {
+	if !e.pooled {
+		return
+	}
+	data := e.data
+	for k := range data {
+		delete(data, k)
+	}
+	*e = Event{}
+	e.data = data
+	eventPool.Put(e)
+}
@@ -0,0 +1,68 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFromPool(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+	})
+	c.AddField("static", 1)
+
+	ev := c.NewEventFromPool()
+	assert.Equal(t, "ds", ev.Dataset)
+	assert.Equal(t, map[string]interface{}{"static": 1}, ev.Fields())
+	ev.AddField("a", 1)
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, 1, len(mock.Events()))
+	assert.Equal(t, map[string]interface{}{"static": 1, "a": 1}, mock.Events()[0].Data)
+	ev.Release()
+	assert.False(t, ev.sent)
+	assert.Equal(t, 0, len(ev.data), "released events are emptied")
+
+	// whether or not ev is reused, new events don't see its fields
+	ev = c.NewEventFromPool()
+	assert.Equal(t, map[string]interface{}{"static": 1}, ev.Fields())
+	ev.AddField("b", 2)
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, map[string]interface{}{"static": 1, "b": 2}, mock.Events()[1].Data)
+
+	plain := c.NewEvent()
+	plain.AddField("c", 3)
+	plain.Release()
+	assert.Equal(t, map[string]interface{}{"static": 1, "c": 3}, plain.Fields(),
+		"events not from the pool aren't released")
+}
+
+func BenchmarkNewEvent(b *testing.B) {
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: &transmission.DiscardSender{},
+	})
+	c.AddField("static", 1)
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ev := c.NewEvent()
+			ev.AddField("i", i)
+			ev.Send()
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ev := c.NewEventFromPool()
+			ev.AddField("i", i)
+			ev.Send()
+			ev.Release()
+		}
+	})
+}
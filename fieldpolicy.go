@@ -0,0 +1,81 @@
+package libhoney
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fieldPolicy decides which fields may leave the process, from
+// ClientConfig's FieldAllowlist and FieldDenylist.
+type fieldPolicy struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+func newFieldPolicy(allow, deny []string) (*fieldPolicy, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	p := &fieldPolicy{}
+	var err error
+	if p.allow, err = compileFieldPatterns(allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = compileFieldPatterns(deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compileFieldPatterns compiles field name patterns: regular expressions
+// between slashes, like /^user\./, and otherwise globs where * matches any
+// run of characters and ? any one.
+func compileFieldPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := p
+		if len(p) >= 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			expr = p[1 : len(p)-1]
+		} else {
+			expr = regexp.QuoteMeta(p)
+			expr = strings.Replace(expr, `\*`, ".*", -1)
+			expr = strings.Replace(expr, `\?`, ".", -1)
+			expr = "^" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field pattern %q: %v", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// allowed reports whether the field key may be sent: it matches no denylist
+// pattern and, if there's an allowlist, matches one of those.
+func (p *fieldPolicy) allowed(key string) bool {
+	for _, re := range p.deny {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+	if len(p.allow) == 0 {
+		return true
+	}
+	for _, re := range p.allow {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply removes the fields that may not be sent from data, in place.
+func (p *fieldPolicy) apply(data map[string]interface{}) {
+	for k := range data {
+		if !p.allowed(k) {
+			delete(data, k)
+		}
+	}
+}
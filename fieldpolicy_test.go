@@ -0,0 +1,53 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldPolicy(t *testing.T) {
+	p, err := newFieldPolicy([]string{"http.*", "/^user\\.(id|plan)$/", "duration_?s"}, []string{"*.secret"})
+	assert.NoError(t, err)
+	for key, want := range map[string]bool{
+		"http.status":      true,
+		"http.secret":      false,
+		"user.id":          true,
+		"user.email":       false,
+		"duration_ms":      true,
+		"duration_ns":      true,
+		"duration_seconds": false,
+	} {
+		assert.Equal(t, want, p.allowed(key), key)
+	}
+
+	p, _ = newFieldPolicy(nil, []string{"token"})
+	assert.True(t, p.allowed("tokens"), "globs match the whole name")
+	assert.False(t, p.allowed("token"))
+
+	p, err = newFieldPolicy(nil, nil)
+	assert.Nil(t, p)
+	assert.NoError(t, err)
+
+	_, err = newFieldPolicy(nil, []string{"/(/"})
+	assert.Error(t, err)
+}
+
+func TestFieldPolicyAppliedAtSend(t *testing.T) {
+	_, err := NewClient(ClientConfig{FieldDenylist: []string{"/[/"}})
+	assert.Error(t, err)
+
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:         "key",
+		Dataset:        "ds",
+		Transmission:   mock,
+		FieldAllowlist: []string{"app.*"},
+	})
+	ev := c.NewEvent()
+	ev.AddField("app.name", "api")
+	ev.AddField("internal.host", "db1")
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, map[string]interface{}{"app.name": "api"}, mock.Events()[0].Data)
+}
@@ -0,0 +1,102 @@
+package libhoney
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const (
+	defaultFlattenDelimiter = "."
+	defaultFlattenDepth     = 5
+)
+
+// FlattenConfig turns nested maps and structs added as field values into one
+// field per leaf value, named by joining the keys on the way with Delimiter:
+// adding "request" as {"headers": {"user_agent": "curl"}} sets the field
+// request.headers.user_agent, which can be queried as a column. Struct fields
+// are named the same way as by Add. Structs that marshal themselves, such as
+// time.Time, are kept as values.
+type FlattenConfig struct {
+	// Delimiter joins the keys of nested values. Defaults to ".".
+	Delimiter string
+	// MaxDepth is how many levels of nesting are flattened; values nested
+	// deeper are added whole. Defaults to 5.
+	MaxDepth int
+}
+
+func (fc *FlattenConfig) withDefaults() *FlattenConfig {
+	if fc == nil {
+		return nil
+	}
+	c := *fc
+	if c.Delimiter == "" {
+		c.Delimiter = defaultFlattenDelimiter
+	}
+	if c.MaxDepth <= 0 {
+		c.MaxDepth = defaultFlattenDepth
+	}
+	return &c
+}
+
+// SetFlattening sets how nested values added to the builder, or to events
+// created from it, are flattened; nil turns flattening off. Events and
+// builders cloned from it inherit the setting. It panics if the builder is
+// frozen.
+func (b *Builder) SetFlattening(fc *FlattenConfig) {
+	if b.frozen {
+		panic(ErrBuilderFrozen)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.flatten = fc.withDefaults()
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// setFlattened sets key to val, flattening val if it's a map or struct nested
+// no deeper than the holder's MaxDepth. The caller must hold f.lock.
+func (f *fieldHolder) setFlattened(key string, val interface{}, depth int) {
+	if f.convertValues {
+		val = convertFieldValue(val)
+	}
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if depth > f.flatten.MaxDepth || !v.IsValid() || marshalsItself(v.Type()) {
+		f.setField(key, val)
+		return
+	}
+	prefix := key + f.flatten.Delimiter
+	switch v.Kind() {
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			f.setFlattened(prefix+fmt.Sprint(k.Interface()), v.MapIndex(k).Interface(), depth+1)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if name, ok := structFieldName(t.Field(i), v.Field(i)); ok {
+				f.setFlattened(prefix+name, v.Field(i).Interface(), depth+1)
+			}
+		}
+	default:
+		f.setField(key, val)
+	}
+}
+
+// marshalsItself reports whether values of t, or pointers to them, encode
+// themselves and so shouldn't be flattened.
+func marshalsItself(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+}
@@ -0,0 +1,53 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+type flattenRequest struct {
+	Method  string            `honeycomb:"method"`
+	Headers map[string]string `honeycomb:"headers"`
+	Start   time.Time         `honeycomb:"start"`
+	secret  string
+}
+
+func TestFlatten(t *testing.T) {
+	c, _ := NewClient(ClientConfig{
+		Transmission: &transmission.MockSender{},
+		Flatten:      &FlattenConfig{},
+	})
+	start := time.Unix(1500000000, 0)
+	ev := c.NewEvent()
+	ev.AddField("request", &flattenRequest{
+		Method:  "GET",
+		Headers: map[string]string{"user_agent": "curl"},
+		Start:   start,
+		secret:  "x",
+	})
+	ev.Add(map[string]interface{}{"user": map[string]interface{}{"id": 1}, "plain": 2})
+	assert.Equal(t, map[string]interface{}{
+		"request.method":             "GET",
+		"request.headers.user_agent": "curl",
+		"request.start":              start,
+		"user.id":                    1,
+		"plain":                      2,
+	}, ev.Fields())
+
+	b := c.NewBuilder()
+	b.SetFlattening(&FlattenConfig{Delimiter: "_", MaxDepth: 2})
+	ev = b.Clone().NewEvent()
+	deep := map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": 1}}}
+	ev.AddField("a", deep)
+	assert.Equal(t, map[string]interface{}{
+		"a_b_c": map[string]interface{}{"d": 1},
+	}, ev.Fields(), "values nested deeper than MaxDepth are added whole")
+
+	b.SetFlattening(nil)
+	ev = b.NewEvent()
+	ev.AddField("a", deep)
+	assert.Equal(t, deep, ev.Fields()["a"])
+}
@@ -0,0 +1,67 @@
+package libhoney
+
+import "errors"
+
+// ErrBuilderFrozen is returned (or, from methods that can't return an error,
+// panicked with) when a frozen Builder is modified.
+var ErrBuilderFrozen = errors.New("builder is frozen; Clone it to make changes")
+
+// Freeze returns a read-only copy of the builder, safe to share across
+// packages as a telemetry root: nothing holding it can change the fields or
+// settings its events inherit. Adding fields to a frozen builder fails -
+// Add, AddFunc and AddDynamicField return ErrBuilderFrozen, and AddField,
+// SetConsent and SetFieldCollisionPolicy panic with it. Clone a frozen builder
+// to get a mutable one; clones share the frozen builder's fields until their
+// first change, so cloning is cheap. The exported fields (WriteKey, Dataset,
+// etc) can't be protected and should be treated as read-only.
+func (b *Builder) Freeze() *Builder {
+	f := b.Clone()
+	f.frozen = true
+	return f
+}
+
+// Frozen reports whether the builder was created by Freeze.
+func (b *Builder) Frozen() bool {
+	return b.frozen
+}
+
+// AddField adds an individual metric to the builder. It panics if the
+// builder is frozen.
+func (b *Builder) AddField(key string, val interface{}) {
+	if b.frozen {
+		panic(ErrBuilderFrozen)
+	}
+	b.fieldHolder.AddField(key, val)
+}
+
+// Add adds a complex data type to the builder; see Event.Add. It returns
+// ErrBuilderFrozen if the builder is frozen.
+func (b *Builder) Add(data interface{}) error {
+	if b.frozen {
+		return ErrBuilderFrozen
+	}
+	return b.fieldHolder.Add(data)
+}
+
+// AddFunc adds fields to the builder from fn; see Event.AddFunc. It returns
+// ErrBuilderFrozen if the builder is frozen.
+func (b *Builder) AddFunc(fn func() (string, interface{}, error)) error {
+	if b.frozen {
+		return ErrBuilderFrozen
+	}
+	return b.fieldHolder.AddFunc(fn)
+}
+
+// unshare gives the holder its own copy of fields it shares with a frozen
+// builder, before they're changed. The caller must hold f.lock.
+func (f *fieldHolder) unshare() {
+	if !f.shared {
+		return
+	}
+	data := make(map[string]interface{}, len(f.data)+1)
+	for k, v := range f.data {
+		data[k] = v
+	}
+	f.data = data
+	f.shared = false
+}
@@ -0,0 +1,33 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrozenBuilder(t *testing.T) {
+	c, _ := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	b := c.NewBuilder()
+	b.AddField("service.name", "api")
+	root := b.Freeze()
+	b.AddField("service.name", "changed")
+
+	assert.True(t, root.Frozen())
+	assert.False(t, b.Frozen())
+	assert.Equal(t, "api", root.NewEvent().Fields()["service.name"], "freezing takes a copy")
+	assert.Panics(t, func() { root.AddField("a", 1) })
+	assert.Panics(t, func() { root.SetConsent(true) })
+	assert.Equal(t, ErrBuilderFrozen, root.Add(map[string]interface{}{"a": 1}))
+	assert.Equal(t, ErrBuilderFrozen, root.AddDynamicField("a", func() interface{} { return 1 }))
+	assert.Equal(t, ErrBuilderFrozen, root.AddFunc(func() (string, interface{}, error) { return "a", 1, nil }))
+
+	local := root.Clone()
+	assert.False(t, local.Frozen())
+	local.AddField("service.name", "local")
+	local.AddField("b", 2)
+	assert.Equal(t, "local", local.NewEvent().Fields()["service.name"])
+	assert.Equal(t, map[string]interface{}{"service.name": "api"}, root.NewEvent().Fields(),
+		"changes to a clone shouldn't reach the frozen builder")
+}
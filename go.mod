@@ -0,0 +1,20 @@
+module github.com/honeycombio/libhoney-go
+
+go 1.17
+
+require (
+	github.com/facebookgo/muster v0.0.0-20150708232844-fd3d7953fd52
+	github.com/stretchr/testify v1.8.4
+	gopkg.in/alexcesaro/statsd.v2 v2.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
+	github.com/facebookgo/ensure v0.0.0-20200202191622-63f1cf65ac4c // indirect
+	github.com/facebookgo/limitgroup v0.0.0-20150612190941-6abd8d71ec01 // indirect
+	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
+	github.com/facebookgo/subset v0.0.0-20200203212716-c811ad88dec4 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
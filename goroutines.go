@@ -0,0 +1,85 @@
+package libhoney
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// goroutinesFieldName is the field a goroutine dump is attached as.
+const goroutinesFieldName = "goroutines"
+
+// goroutinesTruncated is appended to goroutine dumps cut short by MaxBytes.
+const goroutinesTruncated = "\n...truncated"
+
+const (
+	defaultGoroutineDumpBytes    = 32 * 1024
+	defaultGoroutineDumpInterval = time.Minute
+)
+
+// GoroutineDumpConfig attaches a dump of every goroutine's stack to events
+// that meet a condition, such as requests that timed out, so that stuck
+// requests can be debugged from telemetry alone. The dump is added as the
+// field "goroutines".
+//
+// Dumps briefly stop the world and can be large, so they're capped at
+// MaxBytes and taken at most once per Interval across the whole process;
+// triggering events in between are sent without one.
+type GoroutineDumpConfig struct {
+	// Trigger reports whether an event with the given fields should carry a
+	// dump, eg by checking for an error field of "deadline_exceeded". It
+	// must not modify the fields. Required.
+	Trigger func(fields map[string]interface{}) bool
+	// MaxBytes is the largest dump to attach; longer ones are truncated.
+	// Defaults to 32KB.
+	MaxBytes int
+	// Interval is the least time between dumps. Defaults to one minute.
+	Interval time.Duration
+}
+
+// lastGoroutineDump rate limits goroutine dumps across every client.
+var lastGoroutineDump struct {
+	at   time.Time
+	lock sync.Mutex
+}
+
+// goroutineDumper attaches goroutine dumps for a client.
+type goroutineDumper struct {
+	conf GoroutineDumpConfig
+}
+
+func newGoroutineDumper(conf GoroutineDumpConfig) *goroutineDumper {
+	if conf.MaxBytes <= len(goroutinesTruncated) {
+		conf.MaxBytes = defaultGoroutineDumpBytes
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultGoroutineDumpInterval
+	}
+	return &goroutineDumper{conf: conf}
+}
+
+// dumpFor returns the goroutine dump to attach to an event with the given
+// fields, or "" if it shouldn't get one.
+func (g *goroutineDumper) dumpFor(fields map[string]interface{}) string {
+	if g == nil || g.conf.Trigger == nil || !g.conf.Trigger(fields) || !g.allow(time.Now()) {
+		return ""
+	}
+	// one extra byte shows whether the dump was cut short
+	buf := make([]byte, g.conf.MaxBytes+1)
+	n := runtime.Stack(buf, true)
+	if n <= g.conf.MaxBytes {
+		return string(buf[:n])
+	}
+	return string(buf[:g.conf.MaxBytes-len(goroutinesTruncated)]) + goroutinesTruncated
+}
+
+// allow reports whether a dump may be taken at now, and if so records it.
+func (g *goroutineDumper) allow(now time.Time) bool {
+	lastGoroutineDump.lock.Lock()
+	defer lastGoroutineDump.lock.Unlock()
+	if !lastGoroutineDump.at.IsZero() && now.Sub(lastGoroutineDump.at) < g.conf.Interval {
+		return false
+	}
+	lastGoroutineDump.at = now
+	return true
+}
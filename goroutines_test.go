@@ -0,0 +1,47 @@
+package libhoney
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoroutineDump(t *testing.T) {
+	lastGoroutineDump.at = time.Time{}
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		GoroutineDump: &GoroutineDumpConfig{
+			Trigger: func(fields map[string]interface{}) bool {
+				return fields["error"] == "deadline_exceeded"
+			},
+			MaxBytes: 100,
+			Interval: time.Hour,
+		},
+	})
+	send := func(errMsg string) *Event {
+		ev := c.NewEvent()
+		ev.AddField("error", errMsg)
+		ev.Send()
+		return ev
+	}
+	send("refused")
+	ev := send("deadline_exceeded")
+	send("deadline_exceeded")
+
+	events := mock.Events()
+	assert.Nil(t, events[0].Data["goroutines"], "only triggering events get a dump")
+	dump, ok := events[1].Data["goroutines"].(string)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(dump, "goroutine "), dump)
+	assert.Equal(t, 100, len(dump))
+	assert.True(t, strings.HasSuffix(dump, "...truncated"))
+	assert.Nil(t, events[2].Data["goroutines"], "dumps should be rate limited")
+	assert.Nil(t, ev.Fields()["goroutines"], "the event's own fields should be untouched")
+	c.Close()
+}
@@ -0,0 +1,86 @@
+package libhoney
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WrapHandler returns an http.Handler that calls handler and sends an event
+// for each request it serves, made with builder. The event records the
+// request's method, host and path, the response's status code and size, and
+// how long handler took. If handler is an *http.ServeMux the pattern that
+// matched the request is recorded as request.route. The request's context
+// carries the event, so handlers can add fields to it with
+// AddFieldToContext.
+//
+// If handler panics the event is sent with a status code of 500 and the
+// panic as its error, and the panic is re-raised.
+func WrapHandler(handler http.Handler, builder *Builder) http.Handler {
+	mux, _ := handler.(*http.ServeMux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ev := builder.NewEvent()
+		ev.AddField("request.method", r.Method)
+		ev.AddField("request.host", r.Host)
+		ev.AddField("request.path", r.URL.Path)
+		if mux != nil {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				ev.AddField("request.route", pattern)
+			}
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+		defer func() {
+			ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+			ev.AddField("response.bytes", sw.bytes)
+			if p := recover(); p != nil {
+				ev.AddField("response.status_code", http.StatusInternalServerError)
+				ev.AddField("error", fmt.Sprintf("%v", p))
+				ev.Send()
+				panic(p)
+			}
+			ev.AddField("response.status_code", sw.statusCode())
+			ev.Send()
+		}()
+		handler.ServeHTTP(sw, r.WithContext(ContextWithEvent(r.Context(), ev)))
+	})
+}
+
+// statusWriter records the status code and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush passes on flushes for handlers that stream their responses.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// statusCode is the status code sent, which is 200 if handler didn't write
+// a response at all.
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
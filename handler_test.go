@@ -0,0 +1,61 @@
+package libhoney
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHandlerTestBuilder(t *testing.T) (*Builder, *transmission.MockSender) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "http",
+		Transmission: mock,
+	})
+	assert.NoError(t, err)
+	return c.NewBuilder(), mock
+}
+
+func TestWrapHandler(t *testing.T) {
+	builder, mock := newHandlerTestBuilder(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	h := WrapHandler(mux, builder)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/users/1", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "http://example.com/missing", nil))
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "GET", events[0].Data["request.method"])
+	assert.Equal(t, "example.com", events[0].Data["request.host"])
+	assert.Equal(t, "/users/1", events[0].Data["request.path"])
+	assert.Equal(t, "/users/", events[0].Data["request.route"])
+	assert.Equal(t, 200, events[0].Data["response.status_code"])
+	assert.Equal(t, 5, events[0].Data["response.bytes"])
+	assert.NotNil(t, events[0].Data["duration_ms"])
+	assert.Equal(t, 404, events[1].Data["response.status_code"])
+}
+
+func TestWrapHandlerPanic(t *testing.T) {
+	builder, mock := newHandlerTestBuilder(t)
+	h := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), builder)
+
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, 500, events[0].Data["response.status_code"])
+	assert.Equal(t, "boom", events[0].Data["error"])
+	assert.Nil(t, events[0].Data["request.route"])
+}
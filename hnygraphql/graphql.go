@@ -0,0 +1,54 @@
+// Package hnygraphql sends an event to Honeycomb for each GraphQL resolver
+// invocation, recording the field's path, the type it belongs to, how long it
+// took and any error it returned.
+//
+// Summary
+//
+// Wrap individual resolvers with WrapResolver. For gqlgen servers, register
+// the extension in the hnygqlgen subpackage instead, which does this for every
+// resolver.
+package hnygraphql
+
+import (
+	"context"
+	"time"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+)
+
+// FieldInfo describes the resolver being invoked.
+type FieldInfo struct {
+	// Path is the full path to the field in the response, eg
+	// "user.friends.0.name"
+	Path string
+	// ParentType is the name of the GraphQL type the field belongs to
+	ParentType string
+	// Field is the name of the field in the schema
+	Field string
+}
+
+// ResolverFunc resolves a single field.
+type ResolverFunc func(ctx context.Context) (interface{}, error)
+
+// WrapResolver returns a ResolverFunc that calls fn and sends an event
+// describing the invocation, using builder to create the event.
+func WrapResolver(builder *libhoney.Builder, info FieldInfo, fn ResolverFunc) ResolverFunc {
+	return func(ctx context.Context) (interface{}, error) {
+		return resolve(ctx, builder, info, fn)
+	}
+}
+
+func resolve(ctx context.Context, builder *libhoney.Builder, info FieldInfo, fn ResolverFunc) (interface{}, error) {
+	ev := builder.NewEvent()
+	ev.AddField("graphql.path", info.Path)
+	ev.AddField("graphql.parent_type", info.ParentType)
+	ev.AddField("graphql.field", info.Field)
+	start := time.Now()
+	res, err := fn(ctx)
+	ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+	if err != nil {
+		ev.AddField("graphql.error", err.Error())
+	}
+	ev.Send()
+	return res, err
+}
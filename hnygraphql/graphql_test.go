@@ -0,0 +1,36 @@
+package hnygraphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapResolver(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "graphql")
+	info := FieldInfo{Path: "user.name", ParentType: "User", Field: "name"}
+	r := WrapResolver(builder, info, func(ctx context.Context) (interface{}, error) {
+		return "bob", nil
+	})
+	res, err := r(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", res)
+
+	failing := WrapResolver(builder, info, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("not found")
+	})
+	_, err = failing(context.Background())
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "user.name", events[0].Data["graphql.path"])
+	assert.Equal(t, "User", events[0].Data["graphql.parent_type"])
+	assert.Equal(t, "name", events[0].Data["graphql.field"])
+	assert.NotNil(t, events[0].Data["duration_ms"])
+	assert.Nil(t, events[0].Data["graphql.error"])
+	assert.Equal(t, "not found", events[1].Data["graphql.error"])
+}
@@ -0,0 +1,25 @@
+module github.com/honeycombio/libhoney-go/hnygraphql/hnygqlgen
+
+go 1.18
+
+require (
+	github.com/99designs/gqlgen v0.17.45
+	github.com/honeycombio/libhoney-go v0.0.0
+	github.com/stretchr/testify v1.9.0
+	github.com/vektah/gqlparser/v2 v2.5.11
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
+	github.com/facebookgo/limitgroup v0.0.0-20150612190941-6abd8d71ec01 // indirect
+	github.com/facebookgo/muster v0.0.0-20150708232844-fd3d7953fd52 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sosodev/duration v1.2.0 // indirect
+	gopkg.in/alexcesaro/statsd.v2 v2.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// build against the libhoney tree this module lives in
+replace github.com/honeycombio/libhoney-go => ../..
@@ -0,0 +1,66 @@
+//go:build go1.18
+// +build go1.18
+
+// Package hnygqlgen provides a gqlgen handler extension that sends an event to
+// Honeycomb for each resolver invocation, as hnygraphql.WrapResolver does.
+//
+// Summary
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.Use(hnygqlgen.NewTracer(libhoney.NewBuilder()))
+//
+// It is a separate module, so that only users of gqlgen depend on it.
+package hnygqlgen
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	libhoney "github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/hnygraphql"
+)
+
+// Tracer is a gqlgen handler extension that sends an event for every field
+// backed by a resolver.
+type Tracer struct {
+	builder *libhoney.Builder
+
+	// TraceTrivialFields, if true, also sends events for fields that are read
+	// directly off a struct rather than computed by a resolver. This is usually
+	// far too many events to be useful.
+	TraceTrivialFields bool
+}
+
+// NewTracer returns a Tracer that creates its events from builder.
+func NewTracer(builder *libhoney.Builder) *Tracer {
+	return &Tracer{builder: builder}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = (*Tracer)(nil)
+
+// ExtensionName identifies the extension to gqlgen.
+func (t *Tracer) ExtensionName() string {
+	return "HoneycombTracer"
+}
+
+// Validate is called by gqlgen when the extension is registered.
+func (t *Tracer) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptField wraps the field's resolver.
+func (t *Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !(fc.IsResolver || t.TraceTrivialFields) {
+		return next(ctx)
+	}
+	info := hnygraphql.FieldInfo{
+		Path:       fc.Path().String(),
+		ParentType: fc.Object,
+		Field:      fc.Field.Name,
+	}
+	return hnygraphql.WrapResolver(t.builder, info, hnygraphql.ResolverFunc(next))(ctx)
+}
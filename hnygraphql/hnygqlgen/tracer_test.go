@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package hnygqlgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestTracerInterceptField(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "graphql")
+	tracer := NewTracer(builder)
+	next := func(ctx context.Context) (interface{}, error) { return 1, nil }
+
+	parent := &graphql.FieldContext{
+		Object:     "Query",
+		Field:      graphql.CollectedField{Field: &ast.Field{Name: "user", Alias: "user"}},
+		IsResolver: true,
+	}
+	// WithFieldContext sets the parent from the context
+	child := &graphql.FieldContext{
+		Object: "User",
+		Field:  graphql.CollectedField{Field: &ast.Field{Name: "id", Alias: "id"}},
+	}
+
+	parentCtx := graphql.WithFieldContext(context.Background(), parent)
+	childCtx := graphql.WithFieldContext(parentCtx, child)
+	tracer.InterceptField(parentCtx, next)
+	tracer.InterceptField(childCtx, next)
+	events := mock.Events()
+	assert.Equal(t, 1, len(events), "trivial fields should not be traced by default")
+	assert.Equal(t, "user", events[0].Data["graphql.path"])
+	assert.Equal(t, "Query", events[0].Data["graphql.parent_type"])
+
+	tracer.TraceTrivialFields = true
+	tracer.InterceptField(childCtx, next)
+	events = mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "user.id", events[1].Data["graphql.path"])
+}
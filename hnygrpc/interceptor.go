@@ -0,0 +1,179 @@
+//go:build go1.17
+// +build go1.17
+
+// Package hnygrpc provides gRPC interceptors that send an event to Honeycomb
+// for every RPC a server handles or a client makes.
+//
+// Summary
+//
+//	builder := libhoney.NewBuilder()
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(hnygrpc.UnaryServerInterceptor(builder)),
+//		grpc.StreamInterceptor(hnygrpc.StreamServerInterceptor(builder)),
+//	)
+//	conn, err := grpc.Dial(target,
+//		grpc.WithUnaryInterceptor(hnygrpc.UnaryClientInterceptor(builder)),
+//		grpc.WithStreamInterceptor(hnygrpc.StreamClientInterceptor(builder)),
+//	)
+//
+// Each event carries the full method name, the status code the RPC ended
+// with, how long it took, the peer it was with and any error.
+//
+// It is a separate module, so that only users of gRPC depend on it.
+package hnygrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor that sends an event for each
+// unary RPC the server handles, created with builder. If the handler panics
+// the event is sent with a status code of Internal and the panic is
+// re-raised.
+func UnaryServerInterceptor(builder *libhoney.Builder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ev := newEvent(builder, info.FullMethod, "server")
+		addPeer(ev, ctx)
+		start := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				finishPanic(ev, start, r)
+				panic(r)
+			}
+			finish(ev, start, err)
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that sends an event for
+// each streaming RPC the server handles, once the handler returns, created
+// with builder. Panics are handled as by UnaryServerInterceptor.
+func StreamServerInterceptor(builder *libhoney.Builder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ev := newEvent(builder, info.FullMethod, "server")
+		ev.AddField("grpc.stream", true)
+		addPeer(ev, ss.Context())
+		start := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				finishPanic(ev, start, r)
+				panic(r)
+			}
+			finish(ev, start, err)
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that sends an event for each
+// unary RPC the client makes, created with builder.
+func UnaryClientInterceptor(builder *libhoney.Builder) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ev := newEvent(builder, method, "client")
+		ev.AddField("grpc.peer", cc.Target())
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		finish(ev, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that sends an event for
+// each streaming RPC the client makes, created with builder. The event is
+// sent when the stream ends, which the client sees as RecvMsg returning an
+// error (io.EOF for success) or, for streams with a single response, that
+// response arriving. Streams that are abandoned before then aren't sent.
+func StreamClientInterceptor(builder *libhoney.Builder) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ev := newEvent(builder, method, "client")
+		ev.AddField("grpc.stream", true)
+		ev.AddField("grpc.peer", cc.Target())
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			finish(ev, start, err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, desc: desc, ev: ev, start: start}, nil
+	}
+}
+
+// clientStream sends its event when the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	desc  *grpc.StreamDesc
+	ev    *libhoney.Event
+	start time.Time
+	once  sync.Once
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() { finish(s.ev, s.start, err) })
+}
+
+func newEvent(builder *libhoney.Builder, fullMethod, kind string) *libhoney.Event {
+	ev := builder.NewEvent()
+	ev.AddField("grpc.method", fullMethod)
+	// full methods are "/package.Service/Method"
+	if parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2); len(parts) == 2 {
+		ev.AddField("grpc.service", parts[0])
+	}
+	ev.AddField("grpc.kind", kind)
+	return ev
+}
+
+func addPeer(ev *libhoney.Event, ctx context.Context) {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ev.AddField("grpc.peer", p.Addr.String())
+	}
+}
+
+func finish(ev *libhoney.Event, start time.Time, err error) {
+	ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+	ev.AddField("grpc.status_code", statusCode(err).String())
+	if err != nil {
+		ev.AddField("error", err.Error())
+	}
+	ev.Send()
+}
+
+// statusCode returns err's gRPC code, mapping context errors, which clients
+// return before a call reaches the server, to the codes gRPC uses for them.
+func statusCode(err error) codes.Code {
+	if _, ok := status.FromError(err); ok {
+		return status.Code(err)
+	}
+	return status.FromContextError(err).Code()
+}
+
+func finishPanic(ev *libhoney.Event, start time.Time, r interface{}) {
+	ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+	ev.AddField("grpc.status_code", codes.Internal.String())
+	ev.AddField("error", fmt.Sprintf("%v", r))
+	ev.Send()
+}
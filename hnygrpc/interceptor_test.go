@@ -0,0 +1,129 @@
+//go:build go1.17
+// +build go1.17
+
+package hnygrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "rpc")
+	intercept := UnaryServerInterceptor(builder)
+	addr, _ := net.ResolveTCPAddr("tcp", "10.0.0.1:5000")
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}
+
+	_, err := intercept(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	assert.NoError(t, err)
+	_, err = intercept(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such user")
+	})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		intercept(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	})
+
+	events := mock.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "/users.Users/Get", events[0].Data["grpc.method"])
+	assert.Equal(t, "users.Users", events[0].Data["grpc.service"])
+	assert.Equal(t, "server", events[0].Data["grpc.kind"])
+	assert.Equal(t, "10.0.0.1:5000", events[0].Data["grpc.peer"])
+	assert.Equal(t, "OK", events[0].Data["grpc.status_code"])
+	assert.NotNil(t, events[0].Data["duration_ms"])
+	assert.Nil(t, events[0].Data["error"])
+	assert.Equal(t, "NotFound", events[1].Data["grpc.status_code"])
+	assert.NotNil(t, events[1].Data["error"])
+	assert.Equal(t, "Internal", events[2].Data["grpc.status_code"])
+	assert.Equal(t, "boom", events[2].Data["error"])
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "rpc")
+	intercept := StreamServerInterceptor(builder)
+	info := &grpc.StreamServerInfo{FullMethod: "/users.Users/List", IsServerStream: true}
+
+	err := intercept(nil, fakeServerStream{ctx: context.Background()}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "shutting down")
+	})
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, true, events[0].Data["grpc.stream"])
+	assert.Equal(t, "Unavailable", events[0].Data["grpc.status_code"])
+	assert.Nil(t, events[0].Data["grpc.peer"])
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "rpc")
+	intercept := UnaryClientInterceptor(builder)
+
+	err := intercept(context.Background(), "/users.Users/Get", "req", nil, &grpc.ClientConn{},
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return context.DeadlineExceeded
+		})
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "client", events[0].Data["grpc.kind"])
+	assert.Equal(t, "DeadlineExceeded", events[0].Data["grpc.status_code"])
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	msgs int
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	if s.msgs == 0 {
+		return io.EOF
+	}
+	s.msgs--
+	return nil
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "rpc")
+	intercept := StreamClientInterceptor(builder)
+	desc := &grpc.StreamDesc{ServerStreams: true}
+
+	cs, err := intercept(context.Background(), desc, &grpc.ClientConn{}, "/users.Users/List",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &fakeClientStream{msgs: 2}, nil
+		})
+	assert.NoError(t, err)
+	assert.NoError(t, cs.RecvMsg(nil))
+	assert.NoError(t, cs.RecvMsg(nil))
+	assert.Equal(t, 0, len(mock.Events()), "sent once the stream ends")
+	assert.Equal(t, io.EOF, cs.RecvMsg(nil))
+	assert.Equal(t, io.EOF, cs.RecvMsg(nil))
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "OK", events[0].Data["grpc.status_code"])
+	assert.Equal(t, true, events[0].Data["grpc.stream"])
+}
@@ -0,0 +1,94 @@
+//go:build go1.17
+// +build go1.17
+
+// Package hnylogrus provides a logrus hook that sends every log entry to
+// Honeycomb as an event, through a libhoney client's batching and sampling.
+//
+// Summary
+//
+//	log := logrus.New()
+//	log.AddHook(hnylogrus.NewHook(libhoney.NewBuilder(), "logs"))
+//
+// Each event carries the entry's message, level and fields, and the file and
+// line that logged it when the logger reports callers.
+//
+// It is a separate module, so that only users of logrus depend on it.
+package hnylogrus
+
+import (
+	"fmt"
+	"sync"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook implements logrus.Hook and creates events using its Builder.
+type Hook struct {
+	builder *libhoney.Builder
+
+	lock   sync.RWMutex
+	levels []logrus.Level
+}
+
+// NewHook returns a Hook that creates its events from builder, sending them
+// to dataset if it isn't empty and otherwise to the builder's dataset. It
+// fires for entries of every level; use SetLevels to change that.
+func NewHook(builder *libhoney.Builder, dataset string) *Hook {
+	if dataset != "" {
+		builder = builder.Clone()
+		builder.Dataset = dataset
+	}
+	return &Hook{builder: builder, levels: logrus.AllLevels}
+}
+
+var _ logrus.Hook = (*Hook)(nil)
+
+// SetLevels sets the levels of the entries the hook sends. It takes effect
+// even after the hook has been added to a logger.
+func (h *Hook) SetLevels(levels ...logrus.Level) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.levels = levels
+}
+
+// Levels returns every level, since logrus only asks when the hook is added;
+// Fire skips entries of levels that haven't been set.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// sends reports whether the hook sends entries of level.
+func (h *Hook) sends(level logrus.Level) bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for _, l := range h.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire sends an event describing entry, if it's of one of the hook's levels.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if !h.sends(entry.Level) {
+		return nil
+	}
+	ev := h.builder.NewEvent()
+	ev.Timestamp = entry.Time
+	for k, v := range entry.Data {
+		// errors don't marshal to JSON usefully
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		ev.AddField(k, v)
+	}
+	ev.AddField("message", entry.Message)
+	ev.AddField("level", entry.Level.String())
+	if entry.Caller != nil {
+		ev.AddField("caller", fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line))
+		ev.AddField("caller.function", entry.Caller.Function)
+	}
+	return ev.Send()
+}
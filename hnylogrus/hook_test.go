@@ -0,0 +1,53 @@
+//go:build go1.17
+// +build go1.17
+
+package hnylogrus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(t *testing.T, dataset string) (*logrus.Logger, *Hook, *transmission.MockSender) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "app")
+	hook := NewHook(builder, dataset)
+	log := logrus.New()
+	log.AddHook(hook)
+	return log, hook, mock
+}
+
+func TestHook(t *testing.T) {
+	log, _, mock := newTestLogger(t, "logs")
+	log.SetReportCaller(true)
+	log.WithField("user", "ada").WithError(errors.New("timeout")).Warn("slow request")
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	ev := events[0]
+	assert.Equal(t, "logs", ev.Dataset)
+	assert.Equal(t, "slow request", ev.Data["message"])
+	assert.Equal(t, "warning", ev.Data["level"])
+	assert.Equal(t, "ada", ev.Data["user"])
+	assert.Equal(t, "timeout", ev.Data["error"])
+	assert.True(t, strings.Contains(ev.Data["caller"].(string), "hook_test.go:"))
+	assert.False(t, ev.Timestamp.IsZero())
+}
+
+func TestHookLevels(t *testing.T) {
+	log, hook, mock := newTestLogger(t, "")
+	hook.SetLevels(logrus.ErrorLevel)
+	log.Info("ignored")
+	log.Error("failed")
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "app", events[0].Dataset)
+	assert.Equal(t, "failed", events[0].Data["message"])
+	assert.Nil(t, events[0].Data["caller"])
+}
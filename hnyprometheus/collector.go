@@ -0,0 +1,101 @@
+//go:build go1.17
+// +build go1.17
+
+// Package hnyprometheus exposes libhoney's own metrics as Prometheus
+// collectors, so services already scraped by Prometheus can alert when their
+// telemetry is being dropped.
+//
+// Summary
+//
+//   prometheus.MustRegister(hnyprometheus.NewCollector(client, nil))
+//
+// The collector reports the client's queue depth, the events it has queued,
+// sent and dropped (by reason), failed batch sends and batch latency, all
+// under the libhoney_ prefix. See libhoney.Client.Metrics.
+//
+// It is a separate module, so that only users of Prometheus depend on it.
+package hnyprometheus
+
+import (
+	"sort"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsSource is anything with libhoney metrics, such as a *libhoney.Client.
+type MetricsSource interface {
+	Metrics() transmission.MetricsSnapshot
+}
+
+// Collector implements prometheus.Collector for a MetricsSource.
+type Collector struct {
+	source MetricsSource
+
+	queued, sent, dropped, queueDepth   *prometheus.Desc
+	batches, sendErrors, bytes, latency *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector reporting source's metrics, labelled with
+// constLabels, eg to tell several clients apart.
+func NewCollector(source MetricsSource, constLabels prometheus.Labels) *Collector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc("libhoney_"+name, help, labels, constLabels)
+	}
+	return &Collector{
+		source:     source,
+		queued:     desc("events_queued_total", "Events queued to be sent."),
+		sent:       desc("events_sent_total", "Events accepted by the Honeycomb API."),
+		dropped:    desc("events_dropped_total", "Events dropped without being sent, by reason.", "reason"),
+		queueDepth: desc("queue_depth", "Events waiting to be sent."),
+		batches:    desc("batches_sent_total", "Batches successfully sent."),
+		sendErrors: desc("send_errors_total", "Batches that failed to send."),
+		bytes:      desc("bytes_sent_total", "Bytes of batches sent, after compression."),
+		latency:    desc("batch_duration_seconds", "How long each batch took to send, including retries."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.queued, c.sent, c.dropped, c.queueDepth, c.batches, c.sendErrors, c.bytes, c.latency} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	m := c.source.Metrics()
+	counter := func(d *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v), labels...)
+	}
+	counter(c.queued, m.EventsQueued)
+	counter(c.sent, m.EventsSent)
+	reasons := make([]string, 0, len(m.Dropped))
+	for reason := range m.Dropped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		counter(c.dropped, m.Dropped[reason], reason)
+	}
+	ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(m.QueueDepth))
+	counter(c.batches, m.BatchesSent)
+	counter(c.sendErrors, m.SendErrors)
+	counter(c.bytes, m.BytesSent)
+	ch <- latencyHistogram(c.latency, m.SendLatency)
+}
+
+// latencyHistogram converts h to a Prometheus histogram in seconds.
+func latencyHistogram(d *prometheus.Desc, h transmission.Histogram) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		if i < len(h.Counts) {
+			cumulative += uint64(h.Counts[i])
+		}
+		buckets[bound.Seconds()] = cumulative
+	}
+	return prometheus.MustNewConstHistogram(d, uint64(h.Count), h.Sum.Seconds(), buckets)
+}
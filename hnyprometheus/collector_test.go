@@ -0,0 +1,68 @@
+//go:build go1.17
+// +build go1.17
+
+package hnyprometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedMetrics transmission.MetricsSnapshot
+
+func (f fixedMetrics) Metrics() transmission.MetricsSnapshot {
+	return transmission.MetricsSnapshot(f)
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(fixedMetrics{
+		EventsQueued: 10,
+		EventsSent:   7,
+		Dropped:      map[string]int64{"queue_overflow": 2, "sampled": 5},
+		QueueDepth:   1,
+		BatchesSent:  3,
+		SendErrors:   1,
+		BytesSent:    512,
+		SendLatency: transmission.Histogram{
+			Bounds: []time.Duration{100 * time.Millisecond, time.Second},
+			Counts: []int64{2, 1, 0},
+			Count:  3,
+			Sum:    1500 * time.Millisecond,
+		},
+	}, prometheus.Labels{"client": "api"})
+
+	expected := `
+# HELP libhoney_events_dropped_total Events dropped without being sent, by reason.
+# TYPE libhoney_events_dropped_total counter
+libhoney_events_dropped_total{client="api",reason="queue_overflow"} 2
+libhoney_events_dropped_total{client="api",reason="sampled"} 5
+# HELP libhoney_queue_depth Events waiting to be sent.
+# TYPE libhoney_queue_depth gauge
+libhoney_queue_depth{client="api"} 1
+# HELP libhoney_batch_duration_seconds How long each batch took to send, including retries.
+# TYPE libhoney_batch_duration_seconds histogram
+libhoney_batch_duration_seconds_bucket{client="api",le="0.1"} 2
+libhoney_batch_duration_seconds_bucket{client="api",le="1"} 3
+libhoney_batch_duration_seconds_bucket{client="api",le="+Inf"} 3
+libhoney_batch_duration_seconds_sum{client="api"} 1.5
+libhoney_batch_duration_seconds_count{client="api"} 3
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"libhoney_events_dropped_total", "libhoney_queue_depth", "libhoney_batch_duration_seconds"))
+	assert.Equal(t, 9, testutil.CollectAndCount(c), "a metric per dropped reason, and one histogram")
+}
+
+func TestCollectorRegistersClient(t *testing.T) {
+	client, err := libhoney.NewClient(libhoney.ClientConfig{Transmission: &transmission.MockSender{}})
+	assert.NoError(t, err)
+	reg := prometheus.NewRegistry()
+	assert.NoError(t, reg.Register(NewCollector(client, nil)))
+	assert.Error(t, reg.Register(NewCollector(client, nil)), "a second collector without distinct labels clashes")
+}
@@ -0,0 +1,24 @@
+module github.com/honeycombio/libhoney-go/hnyredis
+
+go 1.17
+
+require (
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/honeycombio/libhoney-go v0.0.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
+	github.com/facebookgo/limitgroup v0.0.0-20150612190941-6abd8d71ec01 // indirect
+	github.com/facebookgo/muster v0.0.0-20150708232844-fd3d7953fd52 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/alexcesaro/statsd.v2 v2.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// build against the libhoney tree this module lives in
+replace github.com/honeycombio/libhoney-go => ..
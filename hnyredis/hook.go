@@ -0,0 +1,131 @@
+//go:build go1.17
+// +build go1.17
+
+// Package hnyredis provides a go-redis hook that sends an event to Honeycomb
+// for every command (or pipeline) executed by a redis client.
+//
+// Summary
+//
+//   client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//   client.AddHook(hnyredis.NewHook(libhoney.NewBuilder()))
+//
+// Each event carries the command name, a normalized key pattern, the duration
+// of the call and any error. Cache misses (redis.Nil) are recorded as
+// redis.miss rather than as errors.
+//
+// It is a separate module, so that only users of go-redis depend on it.
+package hnyredis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	libhoney "github.com/honeycombio/libhoney-go"
+)
+
+type startKey struct{}
+
+// Hook implements redis.Hook and creates events using its Builder.
+type Hook struct {
+	builder *libhoney.Builder
+}
+
+// NewHook returns a Hook that creates its events from builder.
+func NewHook(builder *libhoney.Builder) *Hook {
+	return &Hook{builder: builder}
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// BeforeProcess records the start time of the command.
+func (h *Hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+// AfterProcess sends an event describing the command.
+func (h *Hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	ev := h.builder.NewEvent()
+	addCmdFields(ev, cmd)
+	addDuration(ev, ctx)
+	ev.Send()
+	return nil
+}
+
+// BeforeProcessPipeline records the start time of the pipeline.
+func (h *Hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline sends a single event describing the whole pipeline.
+// The command name is the space-separated list of command names in the
+// pipeline, and the error is the first error returned by any of them.
+func (h *Hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	ev := h.builder.NewEvent()
+	names := make([]string, 0, len(cmds))
+	var errored int
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			if errored == 0 {
+				ev.AddField("redis.error", err.Error())
+			}
+			errored++
+		}
+	}
+	ev.AddField("redis.command", strings.Join(names, " "))
+	ev.AddField("redis.pipeline_size", len(cmds))
+	ev.AddField("redis.pipeline_errors", errored)
+	addDuration(ev, ctx)
+	ev.Send()
+	return nil
+}
+
+func addCmdFields(ev *libhoney.Event, cmd redis.Cmder) {
+	ev.AddField("redis.command", cmd.Name())
+	args := cmd.Args()
+	if len(args) > 1 {
+		ev.AddField("redis.key_pattern", KeyPattern(fmt.Sprint(args[1])))
+	}
+	switch err := cmd.Err(); {
+	case err == redis.Nil:
+		ev.AddField("redis.miss", true)
+	case err != nil:
+		ev.AddField("redis.error", err.Error())
+	}
+}
+
+func addDuration(ev *libhoney.Event, ctx context.Context) {
+	if start, ok := ctx.Value(startKey{}).(time.Time); ok {
+		ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+	}
+}
+
+// KeyPattern reduces a redis key to a low-cardinality pattern by replacing
+// each segment (as delimited by ':', '/', '.' or '-') that contains a digit
+// with '*'. For example "user:1234:profile" becomes "user:*:profile".
+func KeyPattern(key string) string {
+	var out bytes.Buffer
+	segStart := 0
+	flush := func(end int) {
+		seg := key[segStart:end]
+		if strings.ContainsAny(seg, "0123456789") {
+			out.WriteByte('*')
+		} else {
+			out.WriteString(seg)
+		}
+	}
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case ':', '/', '.', '-':
+			flush(i)
+			out.WriteByte(key[i])
+			segStart = i + 1
+		}
+	}
+	flush(len(key))
+	return out.String()
+}
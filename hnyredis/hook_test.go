@@ -0,0 +1,82 @@
+//go:build go1.17
+// +build go1.17
+
+package hnyredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHook(t *testing.T) (*Hook, *transmission.MockSender) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "redis")
+	return NewHook(builder), mock
+}
+
+func TestKeyPattern(t *testing.T) {
+	cases := map[string]string{
+		"user:1234:profile": "user:*:profile",
+		"sessions":          "sessions",
+		"cache/v2/thing":    "cache/*/thing",
+		"a.b7.c":            "a.*.c",
+		"":                  "",
+	}
+	for in, expected := range cases {
+		assert.Equal(t, expected, KeyPattern(in), in)
+	}
+}
+
+func TestHookProcess(t *testing.T) {
+	hook, mock := newTestHook(t)
+	ctx := context.Background()
+
+	cmd := redis.NewStringCmd(ctx, "get", "user:42")
+	ctx2, err := hook.BeforeProcess(ctx, cmd)
+	assert.NoError(t, err)
+	assert.NoError(t, hook.AfterProcess(ctx2, cmd))
+
+	missing := redis.NewStringCmd(ctx, "get", "user:43")
+	missing.SetErr(redis.Nil)
+	ctx2, _ = hook.BeforeProcess(ctx, missing)
+	hook.AfterProcess(ctx2, missing)
+
+	broken := redis.NewStatusCmd(ctx, "set", "k", "v")
+	broken.SetErr(errors.New("READONLY"))
+	ctx2, _ = hook.BeforeProcess(ctx, broken)
+	hook.AfterProcess(ctx2, broken)
+
+	events := mock.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "get", events[0].Data["redis.command"])
+	assert.Equal(t, "user:*", events[0].Data["redis.key_pattern"])
+	assert.NotNil(t, events[0].Data["duration_ms"])
+	assert.Nil(t, events[0].Data["redis.error"])
+	assert.Equal(t, true, events[1].Data["redis.miss"])
+	assert.Nil(t, events[1].Data["redis.error"])
+	assert.Equal(t, "READONLY", events[2].Data["redis.error"])
+}
+
+func TestHookPipeline(t *testing.T) {
+	hook, mock := newTestHook(t)
+	ctx := context.Background()
+	first := redis.NewStringCmd(ctx, "get", "a")
+	second := redis.NewStatusCmd(ctx, "set", "b", "1")
+	second.SetErr(errors.New("nope"))
+	cmds := []redis.Cmder{first, second}
+
+	ctx2, _ := hook.BeforeProcessPipeline(ctx, cmds)
+	assert.NoError(t, hook.AfterProcessPipeline(ctx2, cmds))
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "get set", events[0].Data["redis.command"])
+	assert.Equal(t, 2, events[0].Data["redis.pipeline_size"])
+	assert.Equal(t, 1, events[0].Data["redis.pipeline_errors"])
+	assert.Equal(t, "nope", events[0].Data["redis.error"])
+}
@@ -0,0 +1,124 @@
+// Package hnyworker instruments background job consumers. Wrap a message
+// handler to get one event per processed message, and WrapBatch to also get a
+// summary event for each batch of messages pulled off a queue.
+//
+// The package doesn't depend on any particular queueing system; adapt the
+// messages your job framework hands you (asynq tasks, machinery signatures,
+// values read off a channel) into a *Message and call the wrapped handler.
+package hnyworker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+)
+
+// Outcomes recorded in the worker.outcome field
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomePanic   = "panic"
+)
+
+// Message describes a single unit of work taken off a queue.
+type Message struct {
+	// Queue is the name of the queue or topic the message was read from
+	Queue string
+	// ID is the queue's identifier for the message, if it has one
+	ID string
+	// EnqueuedAt is when the message was put on the queue. If set, the time the
+	// message spent waiting is recorded as worker.wait_ms
+	EnqueuedAt time.Time
+	// Retries is how many times this message has previously been attempted
+	Retries int
+	// Payload is the message body. It is handed to the handler untouched and is
+	// not added to the event.
+	Payload interface{}
+}
+
+// Handler processes a single message.
+type Handler func(ctx context.Context, msg *Message) error
+
+// BatchHandler processes a batch of messages.
+type BatchHandler func(ctx context.Context, msgs []*Message) error
+
+// Wrap returns a Handler that calls handler and sends an event describing the
+// message and how processing went, using builder to create the event. If
+// handler panics the event is sent with an outcome of "panic" and the panic
+// is re-raised.
+func Wrap(builder *libhoney.Builder, handler Handler) Handler {
+	return func(ctx context.Context, msg *Message) (err error) {
+		ev := builder.NewEvent()
+		addMessageFields(ev, msg)
+		start := time.Now()
+		defer func() {
+			ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+			if r := recover(); r != nil {
+				ev.AddField("worker.outcome", OutcomePanic)
+				ev.AddField("error", fmt.Sprintf("%v", r))
+				ev.Send()
+				panic(r)
+			}
+			if err != nil {
+				ev.AddField("worker.outcome", OutcomeError)
+				ev.AddField("error", err.Error())
+			} else {
+				ev.AddField("worker.outcome", OutcomeSuccess)
+			}
+			ev.Send()
+		}()
+		return handler(ctx, msg)
+	}
+}
+
+// WrapBatch returns a BatchHandler that runs each message in the batch
+// through Wrap(builder, handler) in order, then sends one summary event for
+// the whole batch. The returned error is the first error any message
+// returned; processing continues past failed messages.
+func WrapBatch(builder *libhoney.Builder, handler Handler) BatchHandler {
+	wrapped := Wrap(builder, handler)
+	return func(ctx context.Context, msgs []*Message) error {
+		start := time.Now()
+		var firstErr error
+		var failed int
+		var maxWait time.Duration
+		for _, msg := range msgs {
+			if !msg.EnqueuedAt.IsZero() {
+				if wait := start.Sub(msg.EnqueuedAt); wait > maxWait {
+					maxWait = wait
+				}
+			}
+			if err := wrapped(ctx, msg); err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		ev := builder.NewEvent()
+		ev.AddField("meta.type", "batch_summary")
+		if len(msgs) > 0 {
+			ev.AddField("worker.queue", msgs[0].Queue)
+		}
+		ev.AddField("worker.batch_size", len(msgs))
+		ev.AddField("worker.succeeded", len(msgs)-failed)
+		ev.AddField("worker.failed", failed)
+		ev.AddField("worker.max_wait_ms", float64(maxWait)/float64(time.Millisecond))
+		ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+		ev.Send()
+		return firstErr
+	}
+}
+
+func addMessageFields(ev *libhoney.Event, msg *Message) {
+	ev.AddField("worker.queue", msg.Queue)
+	if msg.ID != "" {
+		ev.AddField("worker.message_id", msg.ID)
+	}
+	ev.AddField("worker.retries", msg.Retries)
+	if !msg.EnqueuedAt.IsZero() {
+		ev.AddField("worker.wait_ms", float64(time.Since(msg.EnqueuedAt))/float64(time.Millisecond))
+	}
+}
@@ -0,0 +1,78 @@
+package hnyworker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "jobs")
+	h := Wrap(builder, func(ctx context.Context, msg *Message) error {
+		if msg.ID == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	err := h(context.Background(), &Message{
+		Queue:      "emails",
+		ID:         "good",
+		EnqueuedAt: time.Now().Add(-time.Second),
+		Retries:    2,
+	})
+	assert.NoError(t, err)
+	err = h(context.Background(), &Message{Queue: "emails", ID: "bad"})
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "emails", events[0].Data["worker.queue"])
+	assert.Equal(t, 2, events[0].Data["worker.retries"])
+	assert.Equal(t, OutcomeSuccess, events[0].Data["worker.outcome"])
+	assert.True(t, events[0].Data["worker.wait_ms"].(float64) >= 1000)
+	assert.Equal(t, OutcomeError, events[1].Data["worker.outcome"])
+	assert.Equal(t, "boom", events[1].Data["error"])
+	assert.Nil(t, events[1].Data["worker.wait_ms"])
+}
+
+func TestWrapPanic(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "jobs")
+	h := Wrap(builder, func(ctx context.Context, msg *Message) error {
+		panic("oh no")
+	})
+	assert.Panics(t, func() { h(context.Background(), &Message{Queue: "q"}) })
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, OutcomePanic, events[0].Data["worker.outcome"])
+	assert.Equal(t, "oh no", events[0].Data["error"])
+}
+
+func TestWrapBatch(t *testing.T) {
+	builder, mock := transmissiontest.NewMockBuilder(t, "jobs")
+	h := WrapBatch(builder, func(ctx context.Context, msg *Message) error {
+		if msg.ID == "2" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	err := h(context.Background(), []*Message{
+		{Queue: "q", ID: "1"},
+		{Queue: "q", ID: "2"},
+		{Queue: "q", ID: "3"},
+	})
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 4, len(events))
+	summary := events[3].Data
+	assert.Equal(t, "batch_summary", summary["meta.type"])
+	assert.Equal(t, 3, summary["worker.batch_size"])
+	assert.Equal(t, 2, summary["worker.succeeded"])
+	assert.Equal(t, 1, summary["worker.failed"])
+}
@@ -0,0 +1,59 @@
+package libhoney
+
+import "github.com/honeycombio/libhoney-go/transmission"
+
+// Hooks are called at points in the life of a client's events, so wrappers
+// can measure the SDK's overhead, add last-second fields or export their
+// own metrics. Any of them may be nil. Panics in them are recovered.
+type Hooks struct {
+	// OnEnqueue is called with each event as it's sent, once it has been
+	// kept by sampling and has passed validation and suppression rules, and
+	// before its fields are packaged up, so fields it adds are sent. Events
+	// summarized by BurstDetection are still passed to it.
+	OnEnqueue func(*Event)
+	// OnBatchSend is called before each batch is sent. It's only called by
+	// a transmission.Honeycomb, such as the default transmission, and only
+	// if it doesn't have an OnBatchSend of its own.
+	OnBatchSend func(transmission.BatchInfo)
+	// OnResponse is called with each Response, on a dedicated goroutine,
+	// before it's passed on to TxResponses or ResponseCallback. Responses
+	// for events with their own ResponseCallback go straight to it.
+	OnResponse func(transmission.Response)
+}
+
+// onEnqueue calls the OnEnqueue hook with e, if there is one.
+func (c *Client) onEnqueue(e *Event) {
+	if c.hooks.OnEnqueue == nil {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			c.logPanic("OnEnqueue hook", p)
+		}
+	}()
+	c.hooks.OnEnqueue(e)
+}
+
+// responseHooks returns the callback for a ResponseCallbackSender calling
+// the OnResponse hook and then cb, either of which may be nil.
+func (c *Client) responseHooks(cb func(transmission.Response)) func(transmission.Response) {
+	onResponse := c.hooks.OnResponse
+	if onResponse == nil {
+		return cb
+	}
+	return func(r transmission.Response) {
+		c.callOnResponse(onResponse, r)
+		if cb != nil {
+			cb(r)
+		}
+	}
+}
+
+func (c *Client) callOnResponse(hook func(transmission.Response), r transmission.Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.logPanic("OnResponse hook", p)
+		}
+	}()
+	hook(r)
+}
@@ -0,0 +1,133 @@
+package libhoney
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	var lock sync.Mutex
+	var batches []transmission.BatchInfo
+	var responses int
+	c, err := NewClient(ClientConfig{
+		APIKey:  "key",
+		Dataset: "ds",
+		APIHost: server.URL,
+		Transmission: &transmission.Honeycomb{
+			MaxBatchSize:         1,
+			BatchTimeout:         10 * time.Millisecond,
+			MaxConcurrentBatches: 1,
+			PendingWorkCapacity:  10,
+		},
+		Hooks: Hooks{
+			OnEnqueue: func(ev *Event) {
+				ev.AddField("enqueued", true)
+			},
+			OnBatchSend: func(b transmission.BatchInfo) {
+				lock.Lock()
+				defer lock.Unlock()
+				batches = append(batches, b)
+			},
+			OnResponse: func(r transmission.Response) {
+				lock.Lock()
+				defer lock.Unlock()
+				responses++
+			},
+		},
+	})
+	assert.NoError(t, err)
+	ev := c.NewEvent()
+	ev.Metadata = "m"
+	ev.AddField("a", 1)
+	assert.NoError(t, ev.Send())
+	r := <-c.TxResponses()
+	assert.Equal(t, "m", r.Metadata, "responses are still passed on")
+	assert.Equal(t, 202, r.StatusCode)
+	c.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, responses)
+	assert.Equal(t, 1, len(batches))
+	assert.Equal(t, "ds", batches[0].Dataset)
+	assert.Equal(t, 1, batches[0].Events)
+	assert.True(t, batches[0].Bytes > 0)
+}
+
+func TestOnEnqueueAddsFields(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		Hooks: Hooks{OnEnqueue: func(ev *Event) {
+			if ev.Fields()["panic"] != nil {
+				panic("hook bug")
+			}
+			ev.AddField("late", "yes")
+		}},
+	})
+	ev := c.NewEvent()
+	ev.AddField("a", 1)
+	assert.NoError(t, ev.Send())
+	ev = c.NewEvent()
+	ev.AddField("panic", true)
+	assert.NoError(t, ev.Send(), "panics in hooks are recovered")
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "yes", events[0].Data["late"])
+}
+
+func TestOnEnqueueSkipsDroppedEvents(t *testing.T) {
+	var enqueued int
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: &transmission.MockSender{},
+		Hooks:        Hooks{OnEnqueue: func(ev *Event) { enqueued++ }},
+	})
+	c.Suppress("healthchecks", time.Hour, FieldEquals("path", "/healthz"))
+
+	assert.Error(t, c.NewEvent().Send(), "empty events aren't sent")
+	ev := c.NewEvent()
+	ev.Dataset = ""
+	ev.AddField("a", 1)
+	assert.Error(t, ev.Send())
+	ev = c.NewEvent()
+	ev.AddField("path", "/healthz")
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, 0, enqueued)
+
+	ev = c.NewEvent()
+	ev.AddField("path", "/users")
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, 1, enqueued)
+}
+
+func TestOnResponseWithResponseCallback(t *testing.T) {
+	var order []string
+	c, _ := NewClient(ClientConfig{
+		APIKey:           "key",
+		Dataset:          "ds",
+		Transmission:     &transmission.MockSender{},
+		ResponseCallback: func(transmission.Response) { order = append(order, "callback") },
+		Hooks: Hooks{OnResponse: func(transmission.Response) {
+			order = append(order, "hook")
+		}},
+	})
+	c.transmission.SendResponse(transmission.Response{})
+	c.Close()
+	assert.Equal(t, []string{"hook", "callback"}, order)
+}
@@ -0,0 +1,60 @@
+package libhoney
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultServiceDataset is the dataset of events sent with an environment API
+// key and no dataset, where it stands in for the service name.
+const defaultServiceDataset = "unknown_dataset"
+
+var (
+	classicKeyRegex       = regexp.MustCompile(`^[a-f0-9]*$`)
+	classicIngestKeyRegex = regexp.MustCompile(`^hc[a-z]ic_[a-z0-9]*$`)
+)
+
+// IsClassicKey reports whether key is a Honeycomb Classic API key, rather
+// than one belonging to an environment. Classic keys send each event to a
+// dataset of the team, while an environment key's dataset names the service
+// the events come from. An empty key counts as classic.
+func IsClassicKey(key string) bool {
+	switch len(key) {
+	case 0:
+		return true
+	case 32:
+		return classicKeyRegex.MatchString(key)
+	case 64:
+		return classicIngestKeyRegex.MatchString(key)
+	}
+	return false
+}
+
+// defaultClientDataset fills in conf's dataset according to the kind of its
+// API key, and returns a message to log if it's changed.
+func defaultClientDataset(conf *ClientConfig) string {
+	if IsClassicKey(conf.APIKey) {
+		if conf.Dataset == "" {
+			conf.Dataset = defaultDataset
+		}
+		return ""
+	}
+	if conf.Dataset == "" {
+		conf.Dataset = defaultServiceDataset
+		return "no dataset set for an environment API key; sending events as service " + defaultServiceDataset
+	}
+	if trimmed := strings.TrimSpace(conf.Dataset); trimmed != conf.Dataset {
+		conf.Dataset = trimmed
+		return "trimmed whitespace from dataset, which is the service name for environment API keys"
+	}
+	return ""
+}
+
+// missingDatasetMessage explains why an event with apiKey but no dataset
+// can't be sent.
+func missingDatasetMessage(apiKey string) string {
+	if IsClassicKey(apiKey) {
+		return "No Dataset for Honeycomb. Can't send datasetless; classic API keys need a dataset set on the Config, Builder or Event."
+	}
+	return "No Dataset for Honeycomb. Can't send datasetless; with an environment API key the dataset is the service name, so set it on the Config, Builder or Event."
+}
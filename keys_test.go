@@ -0,0 +1,42 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsClassicKey(t *testing.T) {
+	for key, classic := range map[string]bool{
+		"":                                 true,
+		"c1a551c000d68f9ed1e96432ac1a3380": true,
+		"hcaic_1234567890123456789012345678901234567890123456789012345678": true,
+		"d68f9ed1e96432ac1a3380": false,
+		"hcaik_01hshz4vmbz8b4cq5sfax4mgd2r5ndhfxtvjbxktjr3vw1tshgd2djgqwv": false,
+		"C1A551C000D68F9ED1E96432AC1A3380":                                 false,
+	} {
+		assert.Equal(t, classic, IsClassicKey(key), key)
+	}
+}
+
+func TestClientDatasetByKeyKind(t *testing.T) {
+	for _, tt := range []struct {
+		key, dataset, expected string
+	}{
+		{"c1a551c000d68f9ed1e96432ac1a3380", "", defaultDataset},
+		{"c1a551c000d68f9ed1e96432ac1a3380", " ds ", " ds "},
+		{"d68f9ed1e96432ac1a3380", "", defaultServiceDataset},
+		{"d68f9ed1e96432ac1a3380", " svc ", "svc"},
+	} {
+		c, err := NewClient(ClientConfig{
+			APIKey:       tt.key,
+			Dataset:      tt.dataset,
+			Transmission: &transmission.MockSender{},
+		})
+		testOK(t, err)
+		assert.Equal(t, tt.expected, c.NewEvent().Dataset)
+	}
+	assert.Contains(t, missingDatasetMessage("c1a551c000d68f9ed1e96432ac1a3380"), "classic")
+	assert.Contains(t, missingDatasetMessage("d68f9ed1e96432ac1a3380"), "service name")
+}
@@ -0,0 +1,77 @@
+package libhoney
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// KillSwitchEnv is the environment variable that, set to a true value such as
+// "1" or "true", engages the process-wide kill switch from startup.
+const KillSwitchEnv = "LIBHONEY_KILL_SWITCH"
+
+const killSwitchMessage = "event dropped by kill switch"
+
+// killSwitch is the process-wide kill switch; 1 when engaged
+var killSwitch int32
+
+func init() {
+	if on, _ := strconv.ParseBool(os.Getenv(KillSwitchEnv)); on {
+		killSwitch = 1
+	}
+}
+
+// SetKillSwitch engages or releases the process-wide kill switch. While it's
+// engaged every client drops the events it's asked to send, with a Response
+// saying so, and counts them as kill_switch_dropped. It's meant for incident
+// response, when telemetry itself is suspected of causing overload or
+// leaking data. Events already queued by a transmission are still sent; use
+// Client.SetKillSwitch to drop those too.
+func SetKillSwitch(on bool) {
+	atomic.StoreInt32(&killSwitch, boolToInt32(on))
+}
+
+// KillSwitchEngaged reports whether the process-wide kill switch is engaged.
+func KillSwitchEngaged() bool {
+	return atomic.LoadInt32(&killSwitch) == 1
+}
+
+// SetKillSwitch engages or releases the client's kill switch, which works
+// like the process-wide one (see SetKillSwitch) for this client alone.
+// Engaging it also drops any events the client's transmission has queued but
+// not yet sent, if it can give them up (see transmission.PendingTaker).
+func (c *Client) SetKillSwitch(on bool) {
+	atomic.StoreInt32(&c.killSwitch, boolToInt32(on))
+	if !on || c.transmission == nil {
+		return
+	}
+	pt, ok := c.transmission.(transmission.PendingTaker)
+	if !ok {
+		return
+	}
+	for _, ev := range pt.TakePending() {
+		c.increment("kill_switch_dropped")
+		c.transmission.SendResponse(transmission.Response{
+			Err:      errKillSwitch,
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+// KillSwitchEngaged reports whether the client's kill switch, or the
+// process-wide one, is engaged.
+func (c *Client) KillSwitchEngaged() bool {
+	return KillSwitchEngaged() || atomic.LoadInt32(&c.killSwitch) == 1
+}
+
+var errKillSwitch = errors.New(killSwitchMessage)
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+// pendingMockSender is a MockSender with events queued that it can give up.
+type pendingMockSender struct {
+	transmission.MockSender
+	pending []*transmission.Event
+}
+
+func (p *pendingMockSender) TakePending() []*transmission.Event {
+	taken := p.pending
+	p.pending = nil
+	return taken
+}
+
+func TestKillSwitch(t *testing.T) {
+	mock := &pendingMockSender{pending: []*transmission.Event{{Metadata: "queued"}}}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+	})
+	send := func(meta string) {
+		ev := c.NewEvent()
+		ev.AddField("a", 1)
+		ev.Metadata = meta
+		assert.NoError(t, ev.Send())
+	}
+
+	c.SetKillSwitch(true)
+	assert.True(t, c.KillSwitchEngaged())
+	r := <-c.TxResponses()
+	assert.Equal(t, "queued", r.Metadata, "queued events are dropped")
+	assert.Equal(t, errKillSwitch, r.Err)
+	send("killed")
+	r = <-c.TxResponses()
+	assert.Equal(t, "killed", r.Metadata)
+	assert.Equal(t, killSwitchMessage, r.Err.Error())
+	assert.Equal(t, 0, len(mock.Events()))
+
+	c.SetKillSwitch(false)
+	send("sent")
+	assert.Equal(t, 1, len(mock.Events()))
+
+	SetKillSwitch(true)
+	defer SetKillSwitch(false)
+	assert.True(t, KillSwitchEngaged())
+	assert.True(t, c.KillSwitchEngaged(), "the process-wide switch covers every client")
+	send("killed globally")
+	assert.Equal(t, 1, len(mock.Events()))
+}
+
+func TestKillSwitchWithResponseCallback(t *testing.T) {
+	mock := &pendingMockSender{pending: []*transmission.Event{{Metadata: "queued"}}}
+	responses := make(chan transmission.Response, 1)
+	c, _ := NewClient(ClientConfig{
+		APIKey:           "key",
+		Dataset:          "ds",
+		Transmission:     mock,
+		ResponseCallback: func(r transmission.Response) { responses <- r },
+	})
+	defer c.Close()
+
+	c.SetKillSwitch(true)
+	r := <-responses
+	assert.Equal(t, "queued", r.Metadata, "queued events are dropped through the callback sender")
+	assert.Equal(t, errKillSwitch, r.Err)
+	assert.Equal(t, int64(1), c.Metrics().Counters["kill_switch_dropped"])
+}
@@ -0,0 +1,26 @@
+package libhoney
+
+// AddLazyField adds a field whose value is fn's result, evaluated only when
+// the event is sent, once it has been kept by sampling and passed
+// validation, suppression rules and burst detection, rather than now. It's
+// for values that are expensive to compute, eg stack traces or queue depths,
+// and only worth having on events that are kept. Lazy fields aren't seen by
+// Samplers, hooks, burst detection or Fields, and a panic in fn is handled
+// like one in a dynamic field (see ClientConfig.DynamicFieldErrors). Like
+// other fields, they aren't added to an event that's already been sent.
+func (e *Event) AddLazyField(key string, fn func() interface{}) {
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent {
+		return
+	}
+	e.lazyFields = append(e.lazyFields, dynamicField{name: key, fn: fn})
+}
+
+// hasLazyFields reports whether the event has lazy fields still to be
+// evaluated.
+func (e *Event) hasLazyFields() bool {
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	return len(e.lazyFields) > 0
+}
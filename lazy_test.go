@@ -0,0 +1,78 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLazyField(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{APIKey: "key", Dataset: "ds", Transmission: mock})
+	testOK(t, err)
+
+	var calls int
+	ev := c.NewEvent()
+	ev.AddField("a", 1)
+	ev.AddLazyField("expensive", func() interface{} { calls++; return "computed" })
+	assert.Equal(t, 0, calls)
+	assert.Nil(t, ev.Fields()["expensive"])
+	testOK(t, ev.Send())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "computed", mock.Events()[0].Data["expensive"])
+
+	ev.AddLazyField("late", func() interface{} { return 1 })
+	assert.Nil(t, ev.lazyFields, "sent events take no more lazy fields")
+
+	ev = c.NewEvent()
+	ev.SampleRate = 1000000
+	ev.AddField("a", 1)
+	ev.AddLazyField("expensive", func() interface{} { calls++; return "computed" })
+	testOK(t, ev.Send())
+	assert.Equal(t, 1, calls, "sampled events don't evaluate lazy fields")
+
+	ev = c.NewEvent()
+	ev.AddLazyField("only", func() interface{} { return 2 })
+	testOK(t, ev.Send())
+	assert.Equal(t, 2, mock.Events()[1].Data["only"])
+}
+
+func TestEventLazyFieldSkippedForDroppedEvents(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		BurstDetection: &BurstConfig{
+			KeyFields: []string{"error"},
+			Threshold: 1,
+			Window:    time.Hour,
+		},
+	})
+	testOK(t, err)
+	defer c.Close()
+	c.Suppress("healthchecks", time.Hour, FieldEquals("path", "/healthz"))
+
+	var calls int
+	send := func(field string, val interface{}) error {
+		ev := c.NewEvent()
+		ev.AddField(field, val)
+		ev.AddLazyField("expensive", func() interface{} { calls++; return "computed" })
+		return ev.Send()
+	}
+	testOK(t, send("path", "/healthz"))
+	assert.Equal(t, 0, calls, "suppressed events don't evaluate lazy fields")
+	testOK(t, send("error", "timeout"))
+	assert.Equal(t, 1, calls)
+	testOK(t, send("error", "timeout"))
+	assert.Equal(t, 1, calls, "events summarized in a burst don't evaluate lazy fields")
+
+	ev := c.NewEvent()
+	ev.Dataset = ""
+	ev.AddLazyField("expensive", func() interface{} { calls++; return "computed" })
+	assert.Error(t, ev.Send())
+	assert.Equal(t, 1, calls, "invalid events don't evaluate lazy fields")
+	assert.Equal(t, "computed", mock.Events()[0].Data["expensive"])
+}
@@ -6,6 +6,8 @@ package libhoney
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +15,6 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
-	"path"
 	"reflect"
 	"sort"
 	"strings"
@@ -31,7 +32,7 @@ func init() {
 
 const (
 	defaultSampleRate = 1
-	defaultAPIHost    = "https://api.honeycomb.io/"
+	defaultAPIHost    = APIHostUS
 	defaultDataset    = "libhoney-go dataset"
 	version           = "1.9.5"
 
@@ -97,15 +98,58 @@ type Config struct {
 	// Send() is called, you would specify 250 here.
 	SampleRate uint
 
+	// DatasetSampleRates sets per-dataset sample rates; see ClientConfig.
+	DatasetSampleRates map[string]uint
+
 	// APIHost is the hostname for the Honeycomb API server to which to send this
-	// event. default: https://api.honeycomb.io/
+	// event. It may include a path prefix, eg for a reverse proxy at
+	// https://gw.internal/honeycomb/. default: https://api.honeycomb.io/
 	APIHost string
 
+	// Region, if set, sends events to the API host of that Honeycomb region;
+	// see ClientConfig.
+	Region Region
+
+	// FromEnv reads unset settings from the environment; see ClientConfig.
+	FromEnv bool
+
+	// VerifyAPIKey makes Init check the API key with the Honeycomb API, and
+	// Environment is the environment it must belong to; see ClientConfig.
+	VerifyAPIKey bool
+	Environment  string
+
+	// FallbackAPIHost, if set, is another Honeycomb API server (eg in a
+	// different region) to send events to while APIHost is failing. Traffic
+	// returns to APIHost automatically once it recovers.
+	FallbackAPIHost string
+
+	// RaceFirstConnect makes the first connection race IPv4 against IPv6,
+	// and APIHost against FallbackAPIHost, sticking with whichever connects
+	// first. It helps get startup events out on networks with broken IPv6.
+	// It has no effect with a custom Transport.
+	RaceFirstConnect bool
+
+	// ProxyURL, if set, is the HTTP proxy to send events through, in place
+	// of any set by the HTTP_PROXY and HTTPS_PROXY environment variables.
+	// It has no effect with a custom Transport.
+	ProxyURL *url.URL
+
+	// TLSConfig, if set, configures TLS to the API host, eg for a gateway
+	// requiring client certificates; see transmission.NewTLSConfig. It has
+	// no effect with a custom Transport.
+	TLSConfig *tls.Config
+
 	// BlockOnSend determines if libhoney should block or drop packets that exceed
 	// the size of the send channel (set by PendingWorkCapacity). Defaults to
 	// False - events overflowing the send channel will be dropped.
 	BlockOnSend bool
 
+	// OverflowPolicy chooses what to drop when the send channel is full and
+	// BlockOnSend is off: the new event (the default), the oldest queued
+	// event, or the new event after waiting up to OverflowTimeout for room.
+	OverflowPolicy  transmission.OverflowPolicy
+	OverflowTimeout time.Duration
+
 	// BlockOnResponse determines if libhoney should block trying to hand
 	// responses back to the caller. If this is true and there is nothing reading
 	// from the Responses channel, it will fill up and prevent events from being
@@ -127,10 +171,34 @@ type Config struct {
 	// Configuration for the underlying sender. It is safe (and recommended) to
 	// leave these values at their defaults. You cannot change these values
 	// after calling Init()
-	MaxBatchSize         uint          // how many events to collect into a batch before sending. Overrides DefaultMaxBatchSize.
-	SendFrequency        time.Duration // how often to send off batches. Overrides DefaultBatchTimeout.
-	MaxConcurrentBatches uint          // how many batches can be inflight simultaneously. Overrides DefaultMaxConcurrentBatches.
-	PendingWorkCapacity  uint          // how many events to allow to pile up. Overrides DefaultPendingWorkCapacity
+	MaxBatchSize           uint          // how many events to collect into a batch before sending. Overrides DefaultMaxBatchSize.
+	SendFrequency          time.Duration // how often to send off batches. Overrides DefaultBatchTimeout.
+	MaxConcurrentBatches   uint          // how many batches can be inflight simultaneously. Overrides DefaultMaxConcurrentBatches.
+	PendingWorkCapacity    uint          // how many events to allow to pile up. Overrides DefaultPendingWorkCapacity
+	MaxPendingWorkCapacity uint          // how far the queue may grow past PendingWorkCapacity during bursts rather than dropping events
+	SendFrequencyJitter    float64       // lengthen SendFrequency by a random fraction up to this, so processes started together don't send in sync
+	SendSplay              time.Duration // delay each batch send by a random duration up to this
+	StampQueueTime         bool          // add meta.queue_time_ms, how long each event spent queued in the SDK
+	MaxBatchSizeBytes      int           // largest encoded batch to send, for proxies with a different limit. Defaults to 5MB
+	MaxEventSizeBytes      int           // largest encoded event to send; larger events are rejected. Defaults to 100KB
+	MaxQueueAge            time.Duration // drop events still unsent after this long, eg after an outage
+	MaxRetries             uint          // how many times to retry a batch that failed with a network error or a 429 or 5xx response
+	RetryBackoff           time.Duration // how long to wait before the first retry, doubling each time. Defaults to 100ms
+	RequestTimeout         time.Duration // how long each batch request may take, independent of SendFrequency. Defaults to 60s
+
+	// MaxConcurrentBatchesPerHost caps how many requests can be in flight to
+	// each API host at once. Defaults to unlimited.
+	MaxConcurrentBatchesPerHost uint
+
+	// DebugRequests logs the URL, headers, body and response of every batch
+	// sent by the Honeycomb transmission, with the write key redacted. Set a
+	// Logger to see them.
+	DebugRequests bool
+
+	// PublishExpvars publishes the queue depth, overflow count and responses
+	// by status code of the Honeycomb transmission in the expvar map
+	// "libhoney".
+	PublishExpvars bool
 
 	// Transport is deprecated and should not be used. To set the HTTP Transport
 	// set the Transport elements on the Transmission Sender instead.
@@ -141,6 +209,82 @@ type Config struct {
 	// Intended for human consumption during development to understand what the
 	// SDK is doing and diagnose trouble emitting events.
 	Logger Logger
+
+	// FieldEncrypter, if set, encrypts the values of sensitive fields on every
+	// event before it is sent. See NewFieldEncrypter.
+	FieldEncrypter *FieldEncrypter
+
+	// FieldTransforms maps field names to a transform applied to that field's
+	// value on every event before it is sent, eg HashWithSalt or TruncateIP to
+	// pseudonymize personal data. Transforms run before encryption.
+	FieldTransforms map[string]FieldTransform
+
+	// FieldScrubber, if set, is applied to every field of every event before
+	// it is sent; see ClientConfig.
+	FieldScrubber FieldScrubber
+
+	// FieldAllowlist and FieldDenylist restrict which fields may be sent;
+	// see ClientConfig.
+	FieldAllowlist []string
+	FieldDenylist  []string
+
+	// MaxFieldsPerEvent caps how many fields each event may have; see
+	// ClientConfig.
+	MaxFieldsPerEvent int
+
+	// DynamicFieldErrors and StrictDynamicFields set what happens when a
+	// dynamic field's function panics; see ClientConfig.
+	DynamicFieldErrors  bool
+	StrictDynamicFields bool
+
+	// DeterministicSampler, if set, samples events by the hash of one of
+	// their fields instead of at random; see ClientConfig.
+	DeterministicSampler *DeterministicSampler
+
+	// Sampler, if set, decides which events Send keeps; see ClientConfig.
+	Sampler Sampler
+
+	// ConsentFields lists fields that may only be sent on events that have
+	// been granted consent with SetConsent on the event or its Builder. They
+	// are stripped from all other events.
+	ConsentFields []string
+
+	// BurstDetection, if set, replaces bursts of similar events with periodic
+	// summary events. See BurstConfig.
+	BurstDetection *BurstConfig
+
+	// GoroutineDump, if set, attaches a dump of every goroutine's stack to
+	// events meeting its Trigger. See GoroutineDumpConfig.
+	GoroutineDump *GoroutineDumpConfig
+
+	// ResponseCallback, if set, is called with each Response on a dedicated
+	// goroutine, so there's no need to read from Responses.
+	ResponseCallback func(transmission.Response)
+
+	// DiscardResponses drains and discards every Response, so there's no
+	// need to read from Responses; see ClientConfig.
+	DiscardResponses bool
+
+	// TrackFieldProvenance records where each field was set, for
+	// Event.FieldProvenance; see ClientConfig.
+	TrackFieldProvenance       bool
+	ProvenanceCallerSampleRate uint
+
+	// FieldCollisionPolicy sets what happens when a field is added that
+	// already has a value; see ClientConfig.
+	FieldCollisionPolicy FieldCollisionPolicy
+
+	// Flatten, if set, flattens nested maps and structs added as field
+	// values into dot-delimited fields; see ClientConfig.
+	Flatten *FlattenConfig
+
+	// ConvertFieldValues converts durations, errors and values with a text
+	// form as they're added; see ClientConfig.
+	ConvertFieldValues bool
+
+	// Hooks are called as events are sent, batched and responded to; see
+	// Hooks.
+	Hooks Hooks
 }
 
 // Init is called on app initialization and passed a Config struct, which
@@ -169,7 +313,33 @@ func Init(conf Config) error {
 
 	clientConf.Dataset = conf.Dataset
 	clientConf.SampleRate = conf.SampleRate
+	clientConf.DatasetSampleRates = conf.DatasetSampleRates
 	clientConf.APIHost = conf.APIHost
+	clientConf.Region = conf.Region
+	clientConf.FromEnv = conf.FromEnv
+	clientConf.VerifyAPIKey = conf.VerifyAPIKey
+	clientConf.Environment = conf.Environment
+	clientConf.FieldEncrypter = conf.FieldEncrypter
+	clientConf.FieldTransforms = conf.FieldTransforms
+	clientConf.FieldScrubber = conf.FieldScrubber
+	clientConf.FieldAllowlist = conf.FieldAllowlist
+	clientConf.FieldDenylist = conf.FieldDenylist
+	clientConf.MaxFieldsPerEvent = conf.MaxFieldsPerEvent
+	clientConf.DynamicFieldErrors = conf.DynamicFieldErrors
+	clientConf.StrictDynamicFields = conf.StrictDynamicFields
+	clientConf.DeterministicSampler = conf.DeterministicSampler
+	clientConf.Sampler = conf.Sampler
+	clientConf.ConsentFields = conf.ConsentFields
+	clientConf.BurstDetection = conf.BurstDetection
+	clientConf.GoroutineDump = conf.GoroutineDump
+	clientConf.ResponseCallback = conf.ResponseCallback
+	clientConf.DiscardResponses = conf.DiscardResponses
+	clientConf.TrackFieldProvenance = conf.TrackFieldProvenance
+	clientConf.ProvenanceCallerSampleRate = conf.ProvenanceCallerSampleRate
+	clientConf.FieldCollisionPolicy = conf.FieldCollisionPolicy
+	clientConf.Flatten = conf.Flatten
+	clientConf.ConvertFieldValues = conf.ConvertFieldValues
+	clientConf.Hooks = conf.Hooks
 
 	// set up default Logger because we're going to use it for the transmission
 	if conf.Logger == nil {
@@ -205,16 +375,36 @@ func Init(conf Config) error {
 		}
 	default:
 		t = &transmission.Honeycomb{
-			MaxBatchSize:         conf.MaxBatchSize,
-			BatchTimeout:         conf.SendFrequency,
-			MaxConcurrentBatches: conf.MaxConcurrentBatches,
-			PendingWorkCapacity:  conf.PendingWorkCapacity,
-			BlockOnSend:          conf.BlockOnSend,
-			BlockOnResponse:      conf.BlockOnResponse,
-			Transport:            conf.Transport,
-			UserAgentAddition:    UserAgentAddition,
-			Logger:               clientConf.Logger,
-			Metrics:              sd,
+			MaxBatchSize:           conf.MaxBatchSize,
+			BatchTimeout:           conf.SendFrequency,
+			MaxConcurrentBatches:   conf.MaxConcurrentBatches,
+			PendingWorkCapacity:    conf.PendingWorkCapacity,
+			MaxPendingWorkCapacity: conf.MaxPendingWorkCapacity,
+			BatchTimeoutJitter:     conf.SendFrequencyJitter,
+			SendSplay:              conf.SendSplay,
+			StampQueueTime:         conf.StampQueueTime,
+			MaxBatchSizeBytes:      conf.MaxBatchSizeBytes,
+			MaxEventSizeBytes:      conf.MaxEventSizeBytes,
+			MaxQueueAge:            conf.MaxQueueAge,
+			MaxRetries:             conf.MaxRetries,
+			RetryBackoff:           conf.RetryBackoff,
+			RequestTimeout:         conf.RequestTimeout,
+			FallbackAPIHost:        conf.FallbackAPIHost,
+			RaceFirstConnect:       conf.RaceFirstConnect,
+			ProxyURL:               conf.ProxyURL,
+			PublishExpvars:         conf.PublishExpvars,
+			DebugRequests:          conf.DebugRequests,
+			TLSConfig:              conf.TLSConfig,
+			BlockOnSend:            conf.BlockOnSend,
+			OverflowPolicy:         conf.OverflowPolicy,
+			OverflowTimeout:        conf.OverflowTimeout,
+			BlockOnResponse:        conf.BlockOnResponse,
+			Transport:              conf.Transport,
+			UserAgentAddition:      UserAgentAddition,
+			Logger:                 clientConf.Logger,
+			Metrics:                sd,
+
+			MaxConcurrentBatchesPerHost: conf.MaxConcurrentBatchesPerHost,
 		}
 	}
 	clientConf.Transmission = t
@@ -242,6 +432,10 @@ type transitionOutput struct {
 }
 
 func (to *transitionOutput) Add(ev *transmission.Event) {
+	// Outputs don't know about response routes
+	if rr, ok := ev.Metadata.(*transmission.ResponseRoute); ok {
+		ev.Metadata = rr.Metadata
+	}
 	origEvent := &Event{
 		APIHost:     ev.APIHost,
 		WriteKey:    ev.APIKey,
@@ -291,11 +485,10 @@ func VerifyAPIKey(config Config) (team string, err error) {
 	if config.APIHost == "" {
 		config.APIHost = defaultAPIHost
 	}
-	u, err := url.Parse(config.APIHost)
+	u, err := transmission.APIURL(config.APIHost, "1", "team_slug")
 	if err != nil {
 		return team, fmt.Errorf("Error parsing API URL: %s", err)
 	}
-	u.Path = path.Join(u.Path, "1", "team_slug")
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return team, err
@@ -347,6 +540,10 @@ type Event struct {
 	// on the Response object read off the Responses channel. It is not sent to
 	// Honeycomb with the event.
 	Metadata interface{}
+	// ResponseCallback, if set, is called with this event's Response instead
+	// of it going to the client's response channel. Events inherit it from
+	// the Builder that created them.
+	ResponseCallback func(transmission.Response) `json:"-"`
 
 	// fieldHolder contains fields (and methods) common to both events and builders
 	fieldHolder
@@ -354,11 +551,24 @@ type Event struct {
 	// client is the Client to use to send events generated from this builder
 	client *Client
 
+	// consent is whether consent-gated fields may be sent with this event
+	consent bool
+
 	// sent is a bool indicating whether the event has been sent.  Once it's
 	// been sent, all changes to the event should be ignored - any calls to Add
 	// should just return immediately taking no action.
 	sent     bool
 	sendLock sync.Mutex
+
+	// pooled is set for events from NewEventFromPool
+	pooled bool
+
+	// links are sent as link events along with the event; see AddLink
+	links []eventLink
+	// spanEvents are sent along with the event; see AddSpanEvent
+	spanEvents []spanEvent
+	// lazyFields are evaluated as the event is sent; see AddLazyField
+	lazyFields []dynamicField
 }
 
 // Builder is used to create templates for new events, specifying default fields
@@ -372,6 +582,13 @@ type Builder struct {
 	SampleRate uint
 	// APIHost, if set, overrides whatever is found in Config
 	APIHost string
+	// ResponseCallback, if set, receives the Responses for events created by
+	// this builder (and its clones) instead of the client's response channel,
+	// so that eg a library can watch the delivery of its own events without
+	// taking over the application's. It is called on the transmission's
+	// goroutine and should return quickly, eg by sending on a buffered
+	// channel.
+	ResponseCallback func(transmission.Response) `json:"-"`
 
 	// fieldHolder contains fields (and methods) common to both events and builders
 	fieldHolder
@@ -382,11 +599,35 @@ type Builder struct {
 
 	// client is the Client to use to send events generated from this builder
 	client *Client
+
+	// consent is inherited by events created from this builder
+	consent bool
+
+	// frozen builders can't be changed; see Freeze
+	frozen bool
+
+	// fieldCapacity is how many fields its events' maps are allocated for
+	fieldCapacity int
 }
 
 type fieldHolder struct {
 	data marshallableMap
 	lock sync.RWMutex
+	// prov is only set when tracking field provenance
+	prov *provenance
+
+	collisionPolicy FieldCollisionPolicy
+	// fields set more than once, when the policy isn't FieldLastWins
+	collided []string
+
+	// shared is set while data belongs to a frozen builder this was cloned
+	// from; it's copied on the first change
+	shared bool
+
+	// flatten is set when nested values are flattened into fields
+	flatten *FlattenConfig
+	// convertValues is set with ConvertFieldValues
+	convertValues bool
 }
 
 // Wrapper type for custom JSON serialization: individual values that can't be
@@ -451,9 +692,10 @@ type dynamicField struct {
 }
 
 // Close waits for all in-flight messages to be sent. You should
-// call Close() before app termination.
-func Close() {
-	dc.Close()
+// call Close() before app termination. It returns a summary of the events
+// sent, left queued and dropped; see Client.Close.
+func Close() transmission.Summary {
+	return dc.Close()
 }
 
 // Flush closes and reopens the Output interface, ensuring events
@@ -467,6 +709,18 @@ func Flush() {
 	dc.Flush()
 }
 
+// FlushWithContext flushes the package-level client, giving up once ctx is
+// done. See Client.FlushWithContext.
+func FlushWithContext(ctx context.Context) (abandoned int) {
+	return dc.FlushWithContext(ctx)
+}
+
+// CloseWithTimeout closes the package-level client, giving up after d. See
+// Client.CloseWithTimeout.
+func CloseWithTimeout(d time.Duration) (abandoned int) {
+	return dc.CloseWithTimeout(d)
+}
+
 // SendNow is deprecated and may be removed in a future major release.
 // Contrary to its name, SendNow does not block and send data
 // immediately, but only enqueues to be sent asynchronously.
@@ -550,12 +804,16 @@ func NewBuilder() *Builder {
 func (f *fieldHolder) AddField(key string, val interface{}) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
-	f.data[key] = val
+	f.set(key, val)
 }
 
 // Add adds a complex data type to the event or builder on which it's called.
 // For structs, it adds each exported field. For maps, it adds each key/value.
 // Add will error on all other types.
+//
+// A struct field's name and options come from its honeycomb tag, or else its
+// json tag, eg `honeycomb:"user_id,omitempty"`. A name of "-" skips the field
+// and omitempty skips it when it has its zero value.
 func (f *fieldHolder) Add(data interface{}) error {
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Struct:
@@ -586,32 +844,42 @@ func (f *fieldHolder) addStruct(s interface{}) error {
 			continue
 		}
 
-		var fName string
-		fTag := fieldInfo.Tag.Get("json")
-		if fTag != "" {
-			if fTag == "-" {
-				// skip this field
-				continue
-			}
-			// slice off options
-			if idx := strings.Index(fTag, ","); idx != -1 {
-				options := fTag[idx:]
-				fTag = fTag[:idx]
-				if strings.Contains(options, "omitempty") && isEmptyValue(sVal.Field(i)) {
-					// skip empty values if omitempty option is set
-					continue
-				}
-			}
-			fName = fTag
-		} else {
-			fName = fieldInfo.Name
+		fName, ok := structFieldName(fieldInfo, sVal.Field(i))
+		if !ok {
+			continue
 		}
-
-		f.data[fName] = sVal.Field(i).Interface()
+		f.set(fName, sVal.Field(i).Interface())
 	}
 	return nil
 }
 
+// structFieldName returns the name a struct field is added as, from its
+// honeycomb tag or otherwise its json tag, and false if the tag says to skip
+// it: the name "-", or the omitempty option with an empty value.
+func structFieldName(fieldInfo reflect.StructField, val reflect.Value) (string, bool) {
+	fTag := fieldInfo.Tag.Get("honeycomb")
+	if fTag == "" {
+		fTag = fieldInfo.Tag.Get("json")
+	}
+	if fTag == "-" {
+		// skip this field
+		return "", false
+	}
+	// slice off options
+	if idx := strings.Index(fTag, ","); idx != -1 {
+		options := fTag[idx:]
+		fTag = fTag[:idx]
+		if strings.Contains(options, "omitempty") && isEmptyValue(val) {
+			// skip empty values if omitempty option is set
+			return "", false
+		}
+	}
+	if fTag == "" {
+		return fieldInfo.Name, true
+	}
+	return fTag, true
+}
+
 func (f *fieldHolder) addMap(m interface{}) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -632,7 +900,7 @@ func (f *fieldHolder) addMap(m interface{}) error {
 		default:
 			return fmt.Errorf("failed to add map: key type %s unaccepted", key.Type().Kind())
 		}
-		f.data[keyStr] = mVal.MapIndex(key).Interface()
+		f.set(keyStr, mVal.MapIndex(key).Interface())
 	}
 	return nil
 }
@@ -681,6 +949,10 @@ func (e *Event) AddField(key string, val interface{}) {
 // For structs, it adds each exported field. For maps, it adds each key/value.
 // Add will error on all other types.
 //
+// A struct field's name and options come from its honeycomb tag, or else its
+// json tag, eg `honeycomb:"user_id,omitempty"`. A name of "-" skips the field
+// and omitempty skips it when it has its zero value.
+//
 // Adds to an event that happen after it has been sent will return without
 // having any effect.
 func (e *Event) Add(data interface{}) error {
@@ -723,17 +995,31 @@ func (e *Event) AddFunc(fn func() (string, interface{}, error)) error {
 // Once you Send an event, any addition calls to add data to that event will
 // return without doing anything. Once the event is sent, it becomes immutable.
 func (e *Event) Send() error {
+	return e.SendWithContext(context.Background())
+}
+
+// SendWithContext is like Send, except that if BlockOnSend is set and the
+// queue is full it only waits for room until ctx is done, eg when the request
+// the event describes has been cancelled. The event is then dropped and its
+// Response carries ctx's error.
+func (e *Event) SendWithContext(ctx context.Context) error {
 	if e.client == nil {
 		e.client = &Client{}
 	}
 	e.client.ensureLogger()
-	if shouldDrop(e.SampleRate) {
+	drop := shouldDrop(e.SampleRate)
+	if s := e.client.sampler; s != nil {
+		keep, rate := s.Sample(e)
+		drop = !keep
+		e.SampleRate = rate
+	}
+	if drop {
 		e.client.logger.Printf("dropping event due to sampling")
-		sd.Increment("sampled")
+		e.client.increment("sampled")
 		e.client.sendDroppedResponse(e, "event dropped due to sampling")
 		return nil
 	}
-	return e.SendPresampled()
+	return e.sendPresampled(ctx)
 }
 
 // SendPresampled dispatches the event to be sent to Honeycomb.
@@ -750,11 +1036,20 @@ func (e *Event) Send() error {
 //
 // Once you Send an event, any addition calls to add data to that event will
 // return without doing anything. Once the event is sent, it becomes immutable.
-func (e *Event) SendPresampled() (err error) {
+func (e *Event) SendPresampled() error {
+	return e.sendPresampled(context.Background())
+}
+
+func (e *Event) sendPresampled(ctx context.Context) (err error) {
 	if e.client == nil {
 		e.client = &Client{}
 	}
 	e.client.ensureLogger()
+	if e.client.KillSwitchEngaged() {
+		e.client.increment("kill_switch_dropped")
+		e.client.sendDroppedResponse(e, killSwitchMessage)
+		return nil
+	}
 	defer func() {
 		if err != nil {
 			e.client.logger.Printf("Failed to send event. err: %s, event: %+v", err, e)
@@ -762,43 +1057,117 @@ func (e *Event) SendPresampled() (err error) {
 			e.client.logger.Printf("Send enqueued event: %+v", e)
 		}
 	}()
-	e.lock.RLock()
-	defer e.lock.RUnlock()
-	if len(e.data) == 0 {
-		return errors.New("No metrics added to event. Won't send empty event.")
-	}
-	// Consider making these restrictions optional; for non-Honeycomb based
-	// Sender implementations (eg STDOUT) it's totally possible to send events
-	// without an API key etc.
-	if e.APIHost == "" {
-		return errors.New("No APIHost for Honeycomb. Can't send to the Great Unknown.")
-	}
-	if e.WriteKey == "" {
-		return errors.New("No WriteKey specified. Can't send event.")
+	rule, err := e.validate()
+	if err != nil {
+		return err
 	}
-	if e.Dataset == "" {
-		return errors.New("No Dataset for Honeycomb. Can't send datasetless.")
+	if rule != "" {
+		e.client.logger.Printf("dropping event due to suppression rule %s", rule)
+		e.client.increment("suppressed")
+		e.client.sendDroppedResponse(e, suppressedMessage(rule))
+		return nil
 	}
+	e.client.onEnqueue(e)
 
 	// lock the sent bool and then mark the event as sent. No more changes!
 	e.sendLock.Lock()
 	defer e.sendLock.Unlock()
 	e.sent = true
+	lazy := e.lazyFields
+	e.lazyFields = nil
 
 	e.client.ensureTransmission()
+	e.lock.RLock()
 	txEvent := &transmission.Event{
 		APIHost:    e.APIHost,
 		APIKey:     e.WriteKey,
 		Dataset:    e.Dataset,
 		SampleRate: e.SampleRate,
 		Timestamp:  e.Timestamp,
-		Metadata:   e.Metadata,
-		Data:       e.data,
+		Metadata:   e.responseMetadata(),
+		Data:       e.client.packageFields(e),
+	}
+	e.lock.RUnlock()
+	var truncated int
+	txEvent.Data, truncated = capFields(txEvent.Data, e.client.maxFields)
+	if e.client.bursts != nil && e.client.bursts.absorb(txEvent) {
+		e.client.increment("burst_summarized")
+		e.client.sendDroppedResponse(e, "event summarized due to burst")
+		return nil
+	}
+	if len(lazy) > 0 {
+		// only now is the event sure to be sent
+		e.client.addDynamicFields(lazy, e.fieldHolder.AddField)
+		e.lock.RLock()
+		txEvent.Data, truncated = capFields(e.client.packageFields(e), e.client.maxFields)
+		e.lock.RUnlock()
+	}
+	if truncated > 0 {
+		warning := fmt.Sprintf("dropped %d fields over the limit of %d from event", truncated, e.client.maxFields)
+		e.client.increment("fields_truncated")
+		e.client.logger.Printf("%s", warning)
+		txEvent.Metadata = e.client.warnOnResponse(txEvent.Metadata, warning)
 	}
-	e.client.transmission.Add(txEvent)
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	transmission.AddWithContext(ctx, e.client.transmission, txEvent)
+	e.sendLinks(ctx)
+	e.sendSpanEvents(ctx)
 	return nil
 }
 
+// validate returns an error if the event can't be sent, or else the name of
+// the suppression rule that drops it, if any.
+func (e *Event) validate() (string, error) {
+	hasLazy := e.hasLazyFields()
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	if len(e.data) == 0 && !hasLazy {
+		return "", errors.New("No metrics added to event. Won't send empty event.")
+	}
+	// Consider making these restrictions optional; for non-Honeycomb based
+	// Sender implementations (eg STDOUT) it's totally possible to send events
+	// without an API key etc.
+	if e.APIHost == "" {
+		return "", errors.New("No APIHost for Honeycomb. Can't send to the Great Unknown.")
+	}
+	if e.WriteKey == "" {
+		return "", errors.New("No WriteKey specified. Can't send event.")
+	}
+	if e.Dataset == "" {
+		return "", errors.New(missingDatasetMessage(e.WriteKey))
+	}
+	if e.collisionPolicy == FieldCollisionsError && len(e.collided) > 0 {
+		return "", &FieldCollisionError{Fields: e.collided}
+	}
+	return e.client.suppressedBy(e.Dataset, e.data), nil
+}
+
+// responseMetadata returns the Metadata to send with the event, wrapped in a
+// route to its ResponseCallback if it has one.
+func (e *Event) responseMetadata() interface{} {
+	if e.ResponseCallback == nil {
+		return e.Metadata
+	}
+	return &transmission.ResponseRoute{
+		Metadata: e.Metadata,
+		Callback: e.ResponseCallback,
+	}
+}
+
+// warnOnResponse returns Metadata for an event whose own Metadata is
+// metadata, routing its Response back through the client's transmission with
+// warning added.
+func (c *Client) warnOnResponse(metadata interface{}, warning string) *transmission.ResponseRoute {
+	return &transmission.ResponseRoute{
+		Metadata: metadata,
+		Callback: func(r transmission.Response) {
+			r.Warnings = append(r.Warnings, warning)
+			c.transmission.SendResponse(r)
+		},
+	}
+}
+
 // returns true if the sample should be dropped
 func shouldDrop(rate uint) bool {
 	if rate <= 1 {
@@ -811,6 +1180,9 @@ func shouldDrop(rate uint) bool {
 // AddDynamicField adds a dynamic field to the builder. Any events
 // created from this builder will get this metric added.
 func (b *Builder) AddDynamicField(name string, fn func() interface{}) error {
+	if b.frozen {
+		return ErrBuilderFrozen
+	}
 	b.dynFieldsLock.Lock()
 	defer b.dynFieldsLock.Unlock()
 	dynFn := dynamicField{
@@ -840,28 +1212,37 @@ func (b *Builder) SendNow(data interface{}) error {
 // NewEvent creates a new Event prepopulated with fields, dynamic
 // field values, and configuration inherited from the builder.
 func (b *Builder) NewEvent() *Event {
-	e := &Event{
-		WriteKey:   b.WriteKey,
-		Dataset:    b.Dataset,
-		SampleRate: b.SampleRate,
-		APIHost:    b.APIHost,
-		Timestamp:  time.Now(),
-		client:     b.client,
-	}
-	e.data = make(map[string]interface{})
+	return b.NewEventWithCapacity(0)
+}
+
+// fillEvent sets up e, which must be empty apart from its data map, as a new
+// event from the builder.
+func (b *Builder) fillEvent(e *Event) {
+	e.WriteKey = b.WriteKey
+	e.Dataset = b.Dataset
+	e.SampleRate = b.client.sampleRateFor(b.Dataset, b.SampleRate)
+	e.APIHost = b.APIHost
+	e.Timestamp = time.Now()
+	e.client = b.client
+	e.ResponseCallback = b.ResponseCallback
 
 	b.lock.RLock()
 	defer b.lock.RUnlock()
+	e.consent = b.consent
 	for k, v := range b.data {
 		e.data[k] = v
 	}
+	e.prov = b.prov.inherit(ProvenanceDynamic)
+	e.inheritCollisions(&b.fieldHolder)
+	e.flatten = b.flatten
+	e.convertValues = b.convertValues
 	// create dynamic metrics
 	b.dynFieldsLock.RLock()
 	defer b.dynFieldsLock.RUnlock()
-	for _, dynField := range b.dynFields {
-		e.AddField(dynField.name, dynField.fn())
+	b.client.addDynamicFields(b.dynFields, e.AddField)
+	if e.prov != nil {
+		e.prov.source = ProvenanceEvent
 	}
-	return e
 }
 
 // Clone creates a new builder that inherits all traits of this builder and
@@ -874,13 +1255,27 @@ func (b *Builder) Clone() *Builder {
 		APIHost:    b.APIHost,
 		dynFields:  make([]dynamicField, 0, len(b.dynFields)),
 		client:     b.client,
+
+		ResponseCallback: b.ResponseCallback,
 	}
-	newB.data = make(map[string]interface{})
 	b.lock.RLock()
 	defer b.lock.RUnlock()
-	for k, v := range b.data {
-		newB.data[k] = v
+	newB.consent = b.consent
+	newB.fieldCapacity = b.fieldCapacity
+	if b.frozen {
+		// nothing can change a frozen builder's fields, so share them
+		newB.data = b.data
+		newB.shared = true
+	} else {
+		newB.data = make(map[string]interface{}, len(b.data))
+		for k, v := range b.data {
+			newB.data[k] = v
+		}
 	}
+	newB.prov = b.prov.inherit(ProvenanceBuilder)
+	newB.inheritCollisions(&b.fieldHolder)
+	newB.flatten = b.flatten
+	newB.convertValues = b.convertValues
 	// copy dynamic metric generators
 	b.dynFieldsLock.RLock()
 	defer b.dynFieldsLock.RUnlock()
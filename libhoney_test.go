@@ -2,6 +2,7 @@ package libhoney
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -218,6 +219,35 @@ func TestAddStructPtr(t *testing.T) {
 		string(marshalled))
 }
 
+type Tagged struct {
+	UserID   string `honeycomb:"user_id"`
+	Name     string `honeycomb:"name,omitempty" json:"full_name"`
+	Retries  int    `honeycomb:",omitempty"`
+	Password string `honeycomb:"-" json:"password"`
+	Region   string `json:"region"`
+	Skipped  string `json:",omitempty"`
+}
+
+func TestAddStructHoneycombTags(t *testing.T) {
+	ev := &Event{}
+	ev.data = make(map[string]interface{})
+	ev.Add(Tagged{UserID: "u1", Password: "hunter2", Region: "eu"})
+	assert.Equal(t, map[string]interface{}{
+		"user_id": "u1",
+		"region":  "eu",
+	}, ev.Fields())
+
+	ev.data = make(map[string]interface{})
+	ev.Add(Tagged{Name: "Ann", Retries: 2, Skipped: "x"})
+	assert.Equal(t, map[string]interface{}{
+		"user_id": "",
+		"name":    "Ann",
+		"Retries": 2,
+		"region":  "",
+		"Skipped": "x",
+	}, ev.Fields(), "the honeycomb tag wins over json, and an empty name keeps the field's")
+}
+
 type Jay struct {
 	F1 string
 	F2 Aich
@@ -619,7 +649,7 @@ func TestSendPresampledErrors(t *testing.T) {
 				WriteKey: "bar",
 				client:   dc,
 			},
-			expErr: errors.New("No Dataset for Honeycomb. Can't send datasetless."),
+			expErr: errors.New(missingDatasetMessage("bar")),
 		},
 		{
 			ev: &Event{
@@ -670,6 +700,72 @@ func TestPresampledSendSamplerate(t *testing.T) {
 	}
 }
 
+// contextAdder records the context it was given
+type contextAdder struct {
+	transmission.MockSender
+	ctx context.Context
+}
+
+func (c *contextAdder) AddWithContext(ctx context.Context, ev *transmission.Event) {
+	c.ctx = ctx
+	c.Add(ev)
+}
+
+func TestSendWithContext(t *testing.T) {
+	testTx := &contextAdder{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "bar",
+		Dataset:      "baz",
+		Transmission: testTx,
+	})
+	ev := c.NewEvent()
+	ev.AddField("a", 1)
+	ctx := context.WithValue(context.Background(), contextKey{}, "v")
+	testOK(t, ev.SendWithContext(ctx))
+	testEquals(t, len(testTx.Events()), 1)
+	testEquals(t, testTx.ctx, ctx)
+}
+
+type contextKey struct{}
+
+func TestBuilderResponseCallback(t *testing.T) {
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "bar",
+		Dataset:      "baz",
+		Transmission: &transmission.WriterSender{W: ioutil.Discard},
+	})
+	defer c.Close()
+	routed := make(chan transmission.Response, 10)
+	b := c.NewBuilder()
+	b.ResponseCallback = func(r transmission.Response) { routed <- r }
+
+	ev := b.Clone().NewEvent()
+	ev.Metadata = "mine"
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+	// dropped events are routed too
+	ev = b.NewEvent()
+	ev.Metadata = "sampled"
+	ev.SampleRate = 1000000
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+	ev = c.NewEvent()
+	ev.Metadata = "theirs"
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+
+	r := <-routed
+	testEquals(t, r.Metadata, "mine")
+	testOK(t, r.Err)
+	r = <-routed
+	testEquals(t, r.Metadata, "sampled")
+	testErr(t, r.Err)
+	r = <-c.TxResponses()
+	testEquals(t, r.Metadata, "theirs")
+	testEquals(t, len(routed), 0)
+	testEquals(t, len(c.TxResponses()), 0)
+}
+
 // TestSendSamplerate verifies that Send samples
 func TestSendSamplerate(t *testing.T) {
 	resetPackageVars()
@@ -0,0 +1,90 @@
+package libhoney
+
+import (
+	"context"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// Fields of the link events AddLink sends, following Honeycomb's span link
+// conventions.
+const (
+	linkTraceIDField    = "trace.link.trace_id"
+	linkSpanIDField     = "trace.link.span_id"
+	traceIDField        = "trace.trace_id"
+	spanIDField         = "trace.span_id"
+	parentIDField       = "trace.parent_id"
+	annotationTypeField = "meta.annotation_type"
+)
+
+type eventLink struct {
+	traceID string
+	spanID  string
+}
+
+// AddLink records a link from the event, a span, to the span spanID in the
+// trace traceID, eg from a queue consumer's span to the one that enqueued
+// the message, so that fan-out and fan-in work is connected in trace views.
+// When the event is sent each link is sent alongside it as a link event with
+// the event's trace.trace_id, its trace.span_id as trace.parent_id, the
+// linked span as trace.link.trace_id and trace.link.span_id, and
+// meta.annotation_type "link". Link events are sampled with the event and
+// don't get Responses of their own.
+//
+// Calls to AddLink after the event has been sent have no effect.
+func (e *Event) AddLink(traceID, spanID string) {
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.links = append(e.links, eventLink{traceID: traceID, spanID: spanID})
+}
+
+// sendLinks sends the link events for e, which must have been sent already.
+// The caller must hold e.lock.
+func (e *Event) sendLinks(ctx context.Context) {
+	for _, l := range e.links {
+		e.sendAnnotation(ctx, "link", e.Timestamp, map[string]interface{}{
+			linkTraceIDField: l.traceID,
+			linkSpanIDField:  l.spanID,
+		})
+	}
+}
+
+// sendAnnotation sends an annotation of e, such as a link, with the given
+// fields, parented to e's span. Its fields go through the same client-level
+// processing and MaxFieldsPerEvent cap as e's, modifying data. The caller
+// must hold e.lock.
+func (e *Event) sendAnnotation(ctx context.Context, annotationType string, ts time.Time, data map[string]interface{}) {
+	data[annotationTypeField] = annotationType
+	if v, ok := e.data[traceIDField]; ok {
+		data[traceIDField] = v
+	}
+	if v, ok := e.data[spanIDField]; ok {
+		data[parentIDField] = v
+	}
+	e.client.processFields(data, e.consent)
+	data, truncated := capFields(data, e.client.maxFields)
+	if truncated > 0 {
+		e.client.increment("fields_truncated")
+	}
+	transmission.AddWithContext(ctx, e.client.transmission, &transmission.Event{
+		APIHost:    e.APIHost,
+		APIKey:     e.WriteKey,
+		Dataset:    e.Dataset,
+		SampleRate: e.SampleRate,
+		Timestamp:  ts,
+		Metadata:   discardedResponse,
+		Data:       data,
+	})
+}
+
+// discardedResponse routes the Responses of events sent on the library's own
+// behalf, such as link and span events, nowhere.
+var discardedResponse = &transmission.ResponseRoute{
+	Callback: func(transmission.Response) {},
+}
@@ -0,0 +1,61 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddLink(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+	})
+	ev := c.NewEvent()
+	ev.AddField(traceIDField, "t1")
+	ev.AddField(spanIDField, "s1")
+	ev.AddLink("t0", "s0")
+	ev.AddLink("t2", "s2")
+	assert.NoError(t, ev.Send())
+	ev.AddLink("t3", "s3")
+
+	events := mock.Events()
+	assert.Equal(t, 3, len(events), "sent events get their links, later links are ignored")
+	assert.Equal(t, map[string]interface{}{
+		traceIDField:        "t1",
+		parentIDField:       "s1",
+		linkTraceIDField:    "t0",
+		linkSpanIDField:     "s0",
+		annotationTypeField: "link",
+	}, events[1].Data)
+	assert.Equal(t, "ds", events[1].Dataset)
+	assert.Equal(t, events[0].Timestamp, events[1].Timestamp)
+	assert.Equal(t, "s2", events[2].Data[linkSpanIDField])
+}
+
+func TestLinkFieldProcessing(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:        "key",
+		Dataset:       "ds",
+		Transmission:  mock,
+		FieldDenylist: []string{linkTraceIDField},
+		ConsentFields: []string{traceIDField},
+	})
+	ev := c.NewEvent()
+	ev.AddField(traceIDField, "t1")
+	ev.AddField(spanIDField, "s1")
+	ev.AddLink("t0", "s0")
+	assert.NoError(t, ev.Send())
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, map[string]interface{}{
+		parentIDField:       "s1",
+		linkSpanIDField:     "s0",
+		annotationTypeField: "link",
+	}, events[1].Data, "links are denylisted and consent stripped like their span")
+}
@@ -3,6 +3,8 @@ package libhoney
 import (
 	"fmt"
 	"log"
+
+	"github.com/honeycombio/libhoney-go/transmission"
 )
 
 // Logger is used to log extra info within the SDK detailing what's happening.
@@ -10,7 +12,9 @@ import (
 // logging will happen. If you set it to the DefaultLogger, you'll get
 // timestamped lines sent to STDOUT. Pass in your own implementation of the
 // interface to send it in to your own logger. An instance of the go package
-// log.Logger satisfies this interface.
+// log.Logger satisfies this interface. Loggers that also implement
+// transmission.LeveledLogger get the transmission's messages at their
+// severity, with key-value context.
 type Logger interface {
 	// Printf accepts the same msg, args style as fmt.Printf().
 	Printf(msg string, args ...interface{})
@@ -20,6 +24,8 @@ type Logger interface {
 // timestamp (RFC3339 formatted)
 type DefaultLogger struct{}
 
+var _ transmission.LeveledLogger = (*DefaultLogger)(nil)
+
 // Printf prints the message to stdout.
 func (d *DefaultLogger) Printf(msg string, args ...interface{}) {
 	// use the same format as the python libhoney:
@@ -29,6 +35,25 @@ func (d *DefaultLogger) Printf(msg string, args ...interface{}) {
 	log.Printf(msg+"\n", args...)
 }
 
+// Debugf prints a debug message with its fields to stdout.
+func (d *DefaultLogger) Debugf(fields transmission.Fields, msg string, args ...interface{}) {
+	d.leveled("DEBUG", fields, msg, args)
+}
+
+// Warnf prints a warning with its fields to stdout.
+func (d *DefaultLogger) Warnf(fields transmission.Fields, msg string, args ...interface{}) {
+	d.leveled("WARN", fields, msg, args)
+}
+
+// Errorf prints an error with its fields to stdout.
+func (d *DefaultLogger) Errorf(fields transmission.Fields, msg string, args ...interface{}) {
+	d.leveled("ERROR", fields, msg, args)
+}
+
+func (d *DefaultLogger) leveled(level string, fields transmission.Fields, msg string, args []interface{}) {
+	log.Printf("%s - %s - %s%s\n", "libhoney", level, fmt.Sprintf(msg, args...), transmission.FormatFields(fields))
+}
+
 type nullLogger struct{}
 
 // Printf swallows messages
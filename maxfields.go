@@ -0,0 +1,30 @@
+package libhoney
+
+import "sort"
+
+// truncatedFieldsField counts the fields dropped from an event over
+// ClientConfig.MaxFieldsPerEvent.
+const truncatedFieldsField = "libhoney_truncated_fields"
+
+// capFields returns data cut down to max fields, including a
+// libhoney_truncated_fields count of those dropped, and how many were
+// dropped. Fields are kept in sorted order so the same fields are always
+// kept. data itself is never modified.
+func capFields(data map[string]interface{}, max int) (map[string]interface{}, int) {
+	if max <= 0 || len(data) <= max {
+		return data, 0
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	keep := max - 1
+	out := make(map[string]interface{}, max)
+	for _, k := range keys[:keep] {
+		out[k] = data[k]
+	}
+	dropped := len(keys) - keep
+	out[truncatedFieldsField] = dropped
+	return out, dropped
+}
@@ -0,0 +1,58 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxFieldsPerEvent(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:            "key",
+		Dataset:           "ds",
+		Transmission:      mock,
+		MaxFieldsPerEvent: 3,
+	})
+	ev := c.NewEvent()
+	ev.Metadata = "big"
+	for _, k := range []string{"e", "d", "c", "b", "a"} {
+		ev.AddField(k, 1)
+	}
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, map[string]interface{}{
+		"a":                  1,
+		"b":                  1,
+		truncatedFieldsField: 3,
+	}, mock.Events()[0].Data)
+	assert.Equal(t, 5, len(ev.Fields()), "the event's own fields are untouched")
+
+	assert.Equal(t, 0, len(c.TxResponses()), "no extra response for the truncation")
+	assert.Equal(t, int64(1), c.Metrics().Counters["fields_truncated"])
+	mock.SendResponse(transmission.Response{StatusCode: 202, Metadata: mock.Events()[0].Metadata})
+	r := <-c.TxResponses()
+	assert.Equal(t, "big", r.Metadata)
+	assert.Equal(t, 202, r.StatusCode)
+	assert.Equal(t, []string{"dropped 3 fields over the limit of 3 from event"}, r.Warnings,
+		"the event's own response carries the warning")
+
+	routed := make(chan transmission.Response, 1)
+	ev = c.NewEvent()
+	ev.Metadata = "routed"
+	ev.ResponseCallback = func(r transmission.Response) { routed <- r }
+	for _, k := range []string{"a", "b", "c", "d"} {
+		ev.AddField(k, 1)
+	}
+	assert.NoError(t, ev.Send())
+	mock.SendResponse(transmission.Response{Metadata: mock.Events()[1].Metadata})
+	r = <-routed
+	assert.Equal(t, "routed", r.Metadata)
+	assert.Equal(t, 1, len(r.Warnings), "warnings reach the event's ResponseCallback too")
+
+	ev = c.NewEvent()
+	ev.AddField("a", 1)
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, map[string]interface{}{"a": 1}, mock.Events()[2].Data)
+	assert.Equal(t, int64(2), c.Metrics().Counters["fields_truncated"])
+}
@@ -0,0 +1,69 @@
+package libhoney
+
+import (
+	"sync"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// clientDropReasons are the client's counters that count events it dropped
+// before they reached its transmission.
+var clientDropReasons = []string{"sampled", "kill_switch_dropped", "suppressed", "burst_summarized"}
+
+// clientCounters counts what a client reports to the package's statsd
+// client, for Metrics.
+type clientCounters struct {
+	lock   sync.Mutex
+	counts map[string]int64
+}
+
+// increment counts name on the package's statsd client and for Metrics.
+func (c *Client) increment(name string) {
+	sd.Increment(name)
+	c.counters.lock.Lock()
+	defer c.counters.lock.Unlock()
+	if c.counters.counts == nil {
+		c.counters.counts = make(map[string]int64)
+	}
+	c.counters.counts[name]++
+}
+
+// Metrics returns a snapshot of the client's own metrics, such as how many
+// events it has queued, sent and dropped and why, so operators can tell
+// whether libhoney itself is losing data. Events dropped before they reach
+// the transmission, eg by sampling, are counted in Dropped and Counters
+// along with the transmission's own drops, which are only included if it's
+// a transmission.MetricsReporter, as the default Honeycomb one is.
+func (c *Client) Metrics() transmission.MetricsSnapshot {
+	var snap transmission.MetricsSnapshot
+	if r, ok := c.transmission.(transmission.MetricsReporter); ok {
+		snap = r.MetricsSnapshot()
+	}
+	snap.Dropped = copyCounts(snap.Dropped)
+	snap.Counters = copyCounts(snap.Counters)
+	c.counters.lock.Lock()
+	defer c.counters.lock.Unlock()
+	for name, n := range c.counters.counts {
+		snap.Counters[name] += n
+	}
+	for _, reason := range clientDropReasons {
+		if n := c.counters.counts[reason]; n > 0 {
+			snap.Dropped[reason] += n
+		}
+	}
+	return snap
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	c := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	return c
+}
+
+// Metrics returns a snapshot of the default client's own metrics; see
+// Client.Metrics.
+func Metrics() transmission.MetricsSnapshot {
+	return dc.Metrics()
+}
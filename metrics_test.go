@@ -0,0 +1,47 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+// reportingMockSender is a MockSender with a fixed MetricsSnapshot.
+type reportingMockSender struct {
+	transmission.MockSender
+	snap transmission.MetricsSnapshot
+}
+
+func (r *reportingMockSender) MetricsSnapshot() transmission.MetricsSnapshot {
+	return r.snap
+}
+
+func TestClientMetrics(t *testing.T) {
+	tx := &reportingMockSender{snap: transmission.MetricsSnapshot{
+		EventsQueued: 1,
+		Dropped:      map[string]int64{"queue_overflow": 4},
+		Counters:     map[string]int64{"messages_queued": 1, "queue_overflow": 4},
+	}}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: tx,
+		Sampler:      &everyOtherSampler{},
+	})
+	for i := 0; i < 4; i++ {
+		ev := c.NewEvent()
+		ev.AddField("i", i)
+		assert.NoError(t, ev.Send())
+	}
+	m := c.Metrics()
+	assert.Equal(t, int64(1), m.EventsQueued)
+	assert.Equal(t, map[string]int64{"queue_overflow": 4, "sampled": 2}, m.Dropped)
+	assert.Equal(t, int64(2), m.Counters["sampled"])
+	assert.Equal(t, int64(1), m.Counters["messages_queued"])
+	assert.Equal(t, int64(2), c.Metrics().Dropped["sampled"], "the transmission's snapshot isn't changed")
+
+	c, _ = NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	m = c.Metrics()
+	assert.Equal(t, 0, len(m.Dropped), "other transmissions only contribute the client's counts")
+}
@@ -0,0 +1,33 @@
+package libhoney
+
+import "github.com/honeycombio/libhoney-go/transmission"
+
+// PendingWork is what a client has yet to finish sending.
+type PendingWork struct {
+	// Events is how many events are queued or being sent, awaiting their
+	// response.
+	Events int64
+	// Batches is how many batches are being sent right now.
+	Batches int64
+}
+
+// Pending reports how many events the client's transmission has yet to send
+// and how many batches it's sending, eg for a health check, or to wait for
+// the queue to drain before shutting down. It's zero for transmissions that
+// don't keep count (see transmission.Summarizer).
+func (c *Client) Pending() PendingWork {
+	if c.transmission == nil {
+		return PendingWork{}
+	}
+	if s, ok := c.transmission.(transmission.Summarizer); ok {
+		summary := s.Summary()
+		return PendingWork{Events: summary.Queued, Batches: summary.InFlight}
+	}
+	return PendingWork{}
+}
+
+// Pending reports what the default client has yet to send; see
+// Client.Pending.
+func Pending() PendingWork {
+	return dc.Pending()
+}
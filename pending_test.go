@@ -0,0 +1,47 @@
+package libhoney
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientPending(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(ClientConfig{
+		APIKey:  "key",
+		APIHost: server.URL,
+		Transmission: &transmission.Honeycomb{
+			MaxBatchSize:         1,
+			BatchTimeout:         time.Millisecond,
+			MaxConcurrentBatches: 1,
+			PendingWorkCapacity:  10,
+		},
+	})
+	testOK(t, err)
+	assert.Equal(t, PendingWork{}, c.Pending())
+
+	ev := c.NewEvent()
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+	deadline := time.Now().Add(time.Second)
+	for c.Pending().Batches == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, PendingWork{Events: 1, Batches: 1}, c.Pending())
+
+	close(release)
+	c.Close()
+	assert.Equal(t, PendingWork{}, c.Pending())
+	assert.Equal(t, PendingWork{}, (&Client{}).Pending())
+}
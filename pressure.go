@@ -0,0 +1,93 @@
+package libhoney
+
+import (
+	"sync"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// pressureWatchers holds the stop channels of a client's OnPressure
+// subscriptions so that Close can end them.
+type pressureWatchers struct {
+	lock  sync.Mutex
+	next  int
+	stops map[int]chan struct{}
+}
+
+// Pressure reports how close the client's transmission is to dropping events,
+// from 0.0 (idle) to 1.0 (its queue is full or it's dropping events). It
+// combines queue saturation with the fraction of recent events dropped. It is
+// always 0 for transmissions that don't implement
+// transmission.PressureReporter.
+func (c *Client) Pressure() float64 {
+	c.ensureTransmission()
+	if p, ok := c.transmission.(transmission.PressureReporter); ok {
+		return p.Pressure()
+	}
+	return 0
+}
+
+// OnPressure calls fn with the client's Pressure every interval, so that
+// telemetry backpressure can feed into an application's load shedding. It
+// keeps going until the returned cancel function is called or the client is
+// closed.
+func (c *Client) OnPressure(interval time.Duration, fn func(pressure float64)) (cancel func()) {
+	stop := make(chan struct{})
+	c.pressureWatchers.lock.Lock()
+	if c.pressureWatchers.stops == nil {
+		c.pressureWatchers.stops = make(map[int]chan struct{})
+	}
+	id := c.pressureWatchers.next
+	c.pressureWatchers.next++
+	c.pressureWatchers.stops[id] = stop
+	c.pressureWatchers.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.callPressure(fn)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		c.pressureWatchers.lock.Lock()
+		defer c.pressureWatchers.lock.Unlock()
+		if _, ok := c.pressureWatchers.stops[id]; ok {
+			close(stop)
+			delete(c.pressureWatchers.stops, id)
+		}
+	}
+}
+
+// callPressure calls an OnPressure callback, recovering from any panic so the
+// subscription keeps going.
+func (c *Client) callPressure(fn func(float64)) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.logPanic("pressure callback", p)
+		}
+	}()
+	fn(c.Pressure())
+}
+
+// stopPressureWatchers ends all OnPressure subscriptions.
+func (c *Client) stopPressureWatchers() {
+	c.pressureWatchers.lock.Lock()
+	defer c.pressureWatchers.lock.Unlock()
+	for id, stop := range c.pressureWatchers.stops {
+		close(stop)
+		delete(c.pressureWatchers.stops, id)
+	}
+}
+
+// Pressure reports how close the package-level client's transmission is to
+// dropping events. See Client.Pressure.
+func Pressure() float64 {
+	return dc.Pressure()
+}
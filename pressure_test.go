@@ -0,0 +1,51 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+type pressuredSender struct {
+	transmission.MockSender
+	pressure float64
+}
+
+func (p *pressuredSender) Pressure() float64 { return p.pressure }
+
+func TestClientPressure(t *testing.T) {
+	c, err := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	assert.Equal(t, 0.0, c.Pressure(), "senders that don't report pressure report none")
+
+	ps := &pressuredSender{pressure: 0.75}
+	c, err = NewClient(ClientConfig{Transmission: ps})
+	testOK(t, err)
+	assert.Equal(t, 0.75, c.Pressure())
+
+	seen := make(chan float64, 10)
+	cancel := c.OnPressure(time.Millisecond, func(p float64) { seen <- p })
+	assert.Equal(t, 0.75, <-seen)
+	cancel()
+	cancel()
+
+	c.OnPressure(time.Millisecond, func(p float64) {})
+	c.Close()
+	assert.Empty(t, c.pressureWatchers.stops)
+}
+
+func TestOnPressureRecoversPanics(t *testing.T) {
+	c, err := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	calls := make(chan struct{}, 10)
+	cancel := c.OnPressure(time.Millisecond, func(p float64) {
+		calls <- struct{}{}
+		panic("boom")
+	})
+	<-calls
+	<-calls
+	cancel()
+	c.Close()
+}
@@ -0,0 +1,113 @@
+package libhoney
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sources recorded in FieldProvenance.
+const (
+	// ProvenanceGlobal fields were added to the Client (or package-level).
+	ProvenanceGlobal = "global"
+	// ProvenanceBuilder fields were added to a Builder.
+	ProvenanceBuilder = "builder"
+	// ProvenanceDynamic fields were generated by a dynamic field.
+	ProvenanceDynamic = "dynamic"
+	// ProvenanceEvent fields were added to the Event itself.
+	ProvenanceEvent = "event"
+)
+
+// FieldProvenance records what last set one of an event's fields.
+type FieldProvenance struct {
+	// Source is one of ProvenanceGlobal, ProvenanceBuilder, ProvenanceDynamic
+	// or ProvenanceEvent.
+	Source string
+	// Caller is the file:line of the code that set the field, if the call
+	// was sampled (see ClientConfig.ProvenanceCallerSampleRate).
+	Caller string
+}
+
+// libhoneyDir is the directory of this package's source, used to find the
+// first caller outside it.
+var libhoneyDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// provenance tracks where the fields of one Builder or Event came from. It
+// is guarded by the lock of the fieldHolder it belongs to.
+type provenance struct {
+	// source is recorded for fields set from now on
+	source     string
+	callerRate uint
+	fields     map[string]FieldProvenance
+}
+
+func newProvenance(source string, callerRate uint) *provenance {
+	return &provenance{
+		source:     source,
+		callerRate: callerRate,
+		fields:     make(map[string]FieldProvenance),
+	}
+}
+
+// inherit returns a copy of p for a Builder or Event derived from its owner,
+// recording source for fields set on the copy.
+func (p *provenance) inherit(source string) *provenance {
+	if p == nil {
+		return nil
+	}
+	np := newProvenance(source, p.callerRate)
+	for k, v := range p.fields {
+		np.fields[k] = v
+	}
+	return np
+}
+
+// record notes that key was just set. It may be called on a nil provenance.
+func (p *provenance) record(key string) {
+	if p == nil {
+		return
+	}
+	fp := FieldProvenance{Source: p.source}
+	if p.callerRate > 0 && (p.callerRate == 1 || rand.Intn(int(p.callerRate)) == 0) {
+		fp.Caller = externalCaller()
+	}
+	p.fields[key] = fp
+}
+
+// externalCaller returns the file:line of the innermost caller outside this
+// package (tests of this package count as outside).
+func externalCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if filepath.Dir(f.File) != libhoneyDir || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// FieldProvenance returns where each of the event's fields was last set, if
+// the client was configured with TrackFieldProvenance, or nil otherwise. It is
+// meant for debugging which code keeps overwriting a field.
+func (e *Event) FieldProvenance() map[string]FieldProvenance {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	if e.prov == nil {
+		return nil
+	}
+	out := make(map[string]FieldProvenance, len(e.prov.fields))
+	for k, v := range e.prov.fields {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package libhoney
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldProvenance(t *testing.T) {
+	c, _ := NewClient(ClientConfig{
+		APIKey:                     "key",
+		Dataset:                    "ds",
+		Transmission:               &transmission.MockSender{},
+		TrackFieldProvenance:       true,
+		ProvenanceCallerSampleRate: 1,
+	})
+	c.AddField("service.name", "api")
+	c.AddField("region", "us")
+	b := c.NewBuilder()
+	b.AddField("service.name", "worker")
+	b.AddDynamicField("goroutines", func() interface{} { return 1 })
+	ev := b.NewEvent()
+	ev.Add(map[string]interface{}{"region": "eu"})
+
+	prov := ev.FieldProvenance()
+	assert.Equal(t, 3, len(prov))
+	assert.Equal(t, ProvenanceBuilder, prov["service.name"].Source)
+	assert.Equal(t, ProvenanceDynamic, prov["goroutines"].Source)
+	assert.Equal(t, ProvenanceEvent, prov["region"].Source)
+	assert.True(t, strings.Contains(prov["service.name"].Caller, "provenance_test.go:"), prov["service.name"].Caller)
+
+	assert.Equal(t, ProvenanceGlobal, c.NewEvent().FieldProvenance()["service.name"].Source)
+
+	// without tracking there's nothing to report
+	c, _ = NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	c.AddField("a", 1)
+	assert.Nil(t, c.NewEvent().FieldProvenance())
+}
@@ -0,0 +1,94 @@
+package libhoney
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// FieldTransform rewrites the value of a single field before the event is
+// sent. Configure them per field with Config.FieldTransforms.
+type FieldTransform func(val interface{}) interface{}
+
+// HashWithSalt returns a FieldTransform that replaces a value with the
+// hex-encoded HMAC-SHA256 of its string form, keyed by the salt returned from
+// salt. Equal values hash to equal strings for as long as the salt stays the
+// same, so the field can still be grouped and counted without revealing the
+// original value. Nil values pass through unchanged.
+func HashWithSalt(salt func() []byte) FieldTransform {
+	return func(val interface{}) interface{} {
+		if val == nil {
+			return nil
+		}
+		mac := hmac.New(sha256.New, salt())
+		fmt.Fprint(mac, val)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+}
+
+// RotatingSalt returns a salt function for HashWithSalt that derives a new
+// salt from secret every period. Hashes are stable within a period but can't
+// be correlated across periods, which limits how long a pseudonym can be used
+// to track an individual. period must be positive.
+func RotatingSalt(secret []byte, period time.Duration) (func() []byte, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("salt rotation period must be positive, not %v", period)
+	}
+	return func() []byte {
+		var epoch [8]byte
+		binary.BigEndian.PutUint64(epoch[:], uint64(time.Now().UnixNano()/int64(period)))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(epoch[:])
+		return mac.Sum(nil)
+	}, nil
+}
+
+// TruncateIP is a FieldTransform that anonymizes IP addresses by zeroing the
+// host part: IPv4 addresses are truncated to their /24 and IPv6 addresses to
+// their /48. Strings may include a port, as in "10.1.2.3:443" or "[::1]:80",
+// and IPv6 zones, both of which are dropped. Values that still aren't an IP
+// address (as a string or a net.IP) are replaced with nil rather than sent
+// as they are, since they may hold an address in a form this doesn't know.
+func TruncateIP(val interface{}) interface{} {
+	var ip net.IP
+	switch v := val.(type) {
+	case string:
+		ip = parseIP(v)
+	case net.IP:
+		ip = v.To16()
+	}
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// parseIP parses s as an IP address, ignoring any port and IPv6 zone.
+func parseIP(s string) net.IP {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	} else if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return net.ParseIP(s)
+}
+
+// applyFieldTransforms runs each field's transform over its value, in place.
+func applyFieldTransforms(transforms map[string]FieldTransform, data map[string]interface{}) {
+	for k, fn := range transforms {
+		if v, ok := data[k]; ok {
+			data[k] = fn(v)
+		}
+	}
+}
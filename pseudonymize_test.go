@@ -0,0 +1,69 @@
+package libhoney
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashWithSalt(t *testing.T) {
+	salt := []byte("pepper")
+	hash := HashWithSalt(func() []byte { return salt })
+	a := hash("bob@example.com")
+	assert.Equal(t, a, hash("bob@example.com"), "same value and salt should hash the same")
+	assert.NotEqual(t, a, hash("alice@example.com"))
+	assert.Equal(t, 64, len(a.(string)))
+	assert.Nil(t, hash(nil))
+
+	salt = []byte("salt")
+	assert.NotEqual(t, a, hash("bob@example.com"), "changing the salt should change the hash")
+}
+
+func TestRotatingSalt(t *testing.T) {
+	stable, err := RotatingSalt([]byte("secret"), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, stable(), stable())
+	other, _ := RotatingSalt([]byte("other"), time.Hour)
+	assert.NotEqual(t, stable(), other())
+
+	_, err = RotatingSalt([]byte("secret"), 0)
+	assert.Error(t, err, "a zero period can't rotate")
+}
+
+func TestTruncateIP(t *testing.T) {
+	assert.Equal(t, "10.1.2.0", TruncateIP("10.1.2.3"))
+	assert.Equal(t, "192.168.7.0", TruncateIP(net.ParseIP("192.168.7.200")))
+	assert.Equal(t, "2001:db8:85a3::", TruncateIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "10.1.2.0", TruncateIP("10.1.2.3:443"))
+	assert.Equal(t, "::", TruncateIP("[::1]:80"))
+	assert.Equal(t, "::", TruncateIP("[::1]"))
+	assert.Equal(t, "fe80::", TruncateIP("fe80::1%eth0"))
+	assert.Equal(t, "fe80::", TruncateIP("[fe80::1%eth0]:80"))
+	assert.Nil(t, TruncateIP("not an ip"), "unparseable values aren't sent as they are")
+	assert.Nil(t, TruncateIP(net.IP{1, 2, 3}))
+	assert.Nil(t, TruncateIP(5))
+}
+
+func TestClientFieldTransforms(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		FieldTransforms: map[string]FieldTransform{
+			"client_ip": TruncateIP,
+		},
+	})
+	ev := c.NewEvent()
+	ev.AddField("client_ip", "1.2.3.4")
+	ev.AddField("path", "/")
+	ev.Send()
+
+	sent := mock.Events()[0].Data
+	assert.Equal(t, "1.2.3.0", sent["client_ip"])
+	assert.Equal(t, "/", sent["path"])
+	assert.Equal(t, "1.2.3.4", ev.Fields()["client_ip"])
+}
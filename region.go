@@ -0,0 +1,49 @@
+package libhoney
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is a Honeycomb region, which determines the API host events are sent
+// to. Set it in the Config instead of APIHost to avoid sending data to the
+// wrong region over a mistyped host.
+type Region string
+
+// The Honeycomb regions and their API hosts.
+const (
+	RegionUS Region = "us"
+	RegionEU Region = "eu"
+
+	APIHostUS = "https://api.honeycomb.io/"
+	APIHostEU = "https://api.eu1.honeycomb.io/"
+)
+
+var regionAPIHosts = map[Region]string{
+	RegionUS: APIHostUS,
+	RegionEU: APIHostEU,
+}
+
+// applyRegion sets conf's API host from its region, or to the default if
+// there's neither. It's an error for the region to be unknown or for it to
+// disagree with an API host that's also been set.
+func applyRegion(conf *ClientConfig) error {
+	if conf.Region == "" {
+		if conf.APIHost == "" {
+			conf.APIHost = defaultAPIHost
+		}
+		return nil
+	}
+	host, ok := regionAPIHosts[Region(strings.ToLower(string(conf.Region)))]
+	if !ok {
+		return fmt.Errorf("unknown Honeycomb region %q; use RegionUS or RegionEU", conf.Region)
+	}
+	if conf.APIHost == "" {
+		conf.APIHost = host
+		return nil
+	}
+	if strings.TrimSuffix(conf.APIHost, "/") != strings.TrimSuffix(host, "/") {
+		return fmt.Errorf("APIHost %s is not the API host of region %s (%s); set only one of them", conf.APIHost, conf.Region, host)
+	}
+	return nil
+}
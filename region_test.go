@@ -0,0 +1,36 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRegion(t *testing.T) {
+	for _, tt := range []struct {
+		region  Region
+		apiHost string
+		want    string
+		wantErr bool
+	}{
+		{"", "", APIHostUS, false},
+		{RegionEU, "", APIHostEU, false},
+		{"EU", "", APIHostEU, false},
+		{RegionUS, "https://api.honeycomb.io", "https://api.honeycomb.io", false},
+		{RegionEU, APIHostUS, "", true},
+		{"ap", "", "", true},
+	} {
+		c, err := NewClient(ClientConfig{
+			Region:       tt.region,
+			APIHost:      tt.apiHost,
+			Transmission: &transmission.MockSender{},
+		})
+		if tt.wantErr {
+			testErr(t, err)
+			continue
+		}
+		testOK(t, err)
+		assert.Equal(t, tt.want, c.NewEvent().APIHost)
+	}
+}
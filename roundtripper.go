@@ -0,0 +1,45 @@
+package libhoney
+
+import (
+	"net/http"
+	"time"
+)
+
+// WrapRoundTripper returns an http.RoundTripper that sends each request
+// through rt and sends an event for it, made with builder. The event records
+// the request's method, host and path, the response's status code or the
+// error the request failed with, and how long rt took. If rt is nil
+// http.DefaultTransport is used.
+//
+// Use it as the Transport of an http.Client to instrument every call the
+// client makes:
+//
+//	client := &http.Client{Transport: libhoney.WrapRoundTripper(nil, builder)}
+func WrapRoundTripper(rt http.RoundTripper, builder *Builder) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &eventRoundTripper{rt: rt, builder: builder}
+}
+
+type eventRoundTripper struct {
+	rt      http.RoundTripper
+	builder *Builder
+}
+
+func (t *eventRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ev := t.builder.NewEvent()
+	ev.AddField("request.method", r.Method)
+	ev.AddField("request.host", r.URL.Host)
+	ev.AddField("request.path", r.URL.Path)
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(r)
+	ev.AddField("duration_ms", float64(time.Since(start))/float64(time.Millisecond))
+	if err != nil {
+		ev.AddField("error", err.Error())
+	} else {
+		ev.AddField("response.status_code", resp.StatusCode)
+	}
+	ev.Send()
+	return resp, err
+}
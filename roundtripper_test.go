@@ -0,0 +1,57 @@
+package libhoney
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWrapRoundTripper(t *testing.T) {
+	builder, mock := newHandlerTestBuilder(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	client := &http.Client{Transport: WrapRoundTripper(nil, builder)}
+	resp, err := client.Get(server.URL + "/brew")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "GET", events[0].Data["request.method"])
+	assert.Equal(t, u.Host, events[0].Data["request.host"])
+	assert.Equal(t, "/brew", events[0].Data["request.path"])
+	assert.Equal(t, http.StatusTeapot, events[0].Data["response.status_code"])
+	assert.NotNil(t, events[0].Data["duration_ms"])
+	assert.Nil(t, events[0].Data["error"])
+}
+
+func TestWrapRoundTripperError(t *testing.T) {
+	builder, mock := newHandlerTestBuilder(t)
+	rt := WrapRoundTripper(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}), builder)
+
+	req, _ := http.NewRequest("POST", "http://api.example.com/v1/things", nil)
+	_, err := rt.RoundTrip(req)
+	assert.Error(t, err)
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "api.example.com", events[0].Data["request.host"])
+	assert.Equal(t, "connection refused", events[0].Data["error"])
+	assert.Nil(t, events[0].Data["response.status_code"])
+}
@@ -0,0 +1,71 @@
+package libhoney
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Sampler decides which events Send keeps, for samplers that adapt to
+// traffic or look at an event's fields. Sample returns whether to keep the
+// event and the sample rate to send it with. It's called without the event
+// locked, so it may call its methods, eg Fields.
+type Sampler interface {
+	Sample(ev *Event) (keep bool, rate uint)
+}
+
+// DeterministicSampler keeps or drops events by hashing the value of one of
+// their fields, so that every event with the same value, such as all the spans
+// of a trace, gets the same decision, in this process and any other using the
+// same field and rate. It uses the same hash as the Honeycomb Beelines, so
+// decisions agree with theirs.
+type DeterministicSampler struct {
+	field      string
+	rate       uint
+	upperBound uint32
+}
+
+// NewDeterministicSampler returns a DeterministicSampler keeping one in rate
+// events, judged by the value of field (eg "trace.trace_id"). A rate of 0 or
+// 1 keeps everything.
+func NewDeterministicSampler(field string, rate uint) *DeterministicSampler {
+	if rate < 1 {
+		rate = 1
+	}
+	return &DeterministicSampler{
+		field:      field,
+		rate:       rate,
+		upperBound: math.MaxUint32 / uint32(rate),
+	}
+}
+
+// Rate returns the sample rate events kept by the sampler are sent with.
+func (d *DeterministicSampler) Rate() uint {
+	return d.rate
+}
+
+// Keep reports whether events whose field has the given value are kept.
+func (d *DeterministicSampler) Keep(value string) bool {
+	if d.rate == 1 {
+		return true
+	}
+	sum := sha1.Sum([]byte(value))
+	return binary.BigEndian.Uint32(sum[:4]) <= d.upperBound
+}
+
+// Sample implements Sampler. Events without the field are sampled at random
+// at the same rate.
+func (d *DeterministicSampler) Sample(ev *Event) (bool, uint) {
+	ev.lock.RLock()
+	v, ok := ev.data[d.field]
+	ev.lock.RUnlock()
+	if !ok {
+		return !shouldDrop(d.rate), d.rate
+	}
+	s, isString := v.(string)
+	if !isString {
+		s = fmt.Sprint(v)
+	}
+	return d.Keep(s), d.rate
+}
@@ -0,0 +1,111 @@
+package libhoney
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeterministicSampler(t *testing.T) {
+	s := NewDeterministicSampler("trace.trace_id", 10)
+	assert.Equal(t, uint(10), s.Rate())
+	var kept int
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		keep := s.Keep(id)
+		assert.Equal(t, keep, s.Keep(id), "decisions are consistent")
+		if keep {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 150)
+
+	// the first four bytes of the SHA-1 are compared to MaxUint32/rate
+	half := NewDeterministicSampler("f", 2)
+	assert.True(t, half.Keep("1"), "sha1 356a192b is under the bound")
+	assert.False(t, half.Keep("2"), "sha1 da4b9237 is over the bound")
+
+	all := NewDeterministicSampler("f", 0)
+	assert.Equal(t, uint(1), all.Rate())
+	assert.True(t, all.Keep("anything"))
+}
+
+func TestClientDeterministicSampler(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:               "key",
+		Dataset:              "ds",
+		Transmission:         mock,
+		DeterministicSampler: NewDeterministicSampler("trace.trace_id", 4),
+	})
+	var sent int
+	for i := 0; i < 200; i++ {
+		ev := c.NewEvent()
+		ev.AddField("trace.trace_id", fmt.Sprintf("t%d", i))
+		assert.NoError(t, ev.Send())
+		if c.sampler.(*DeterministicSampler).Keep(fmt.Sprintf("t%d", i)) {
+			sent++
+		}
+	}
+	events := mock.Events()
+	assert.Equal(t, sent, len(events))
+	for _, ev := range events {
+		assert.Equal(t, uint(4), ev.SampleRate)
+	}
+}
+
+// everyOtherSampler keeps alternate events, sent at a rate of 2.
+type everyOtherSampler struct {
+	n int
+}
+
+func (s *everyOtherSampler) Sample(ev *Event) (bool, uint) {
+	s.n++
+	return s.n%2 == 1, 2
+}
+
+func TestClientSampler(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:               "key",
+		Dataset:              "ds",
+		Transmission:         mock,
+		Sampler:              &everyOtherSampler{},
+		DeterministicSampler: NewDeterministicSampler("f", 1000),
+	})
+	for i := 0; i < 4; i++ {
+		ev := c.NewEvent()
+		ev.AddField("i", i)
+		assert.NoError(t, ev.Send())
+	}
+	events := mock.Events()
+	assert.Equal(t, 2, len(events), "the Sampler takes precedence")
+	assert.Equal(t, 0, events[0].Data["i"])
+	assert.Equal(t, uint(2), events[0].SampleRate)
+	assert.Equal(t, 2, events[1].Data["i"])
+	r := <-c.TxResponses()
+	assert.Equal(t, "event dropped due to sampling", r.Err.Error())
+}
+
+func TestDatasetSampleRates(t *testing.T) {
+	c, _ := NewClient(ClientConfig{
+		APIKey:             "key",
+		Dataset:            "ds",
+		SampleRate:         2,
+		Transmission:       &transmission.MockSender{},
+		DatasetSampleRates: map[string]uint{"ds": 5, "noisy": 100},
+	})
+	assert.Equal(t, uint(5), c.NewEvent().SampleRate)
+
+	b := c.NewBuilder()
+	b.Dataset = "noisy"
+	assert.Equal(t, uint(100), b.NewEvent().SampleRate)
+	b.Dataset = "quiet"
+	assert.Equal(t, uint(2), b.NewEvent().SampleRate, "other datasets use SampleRate")
+
+	b.Dataset = "noisy"
+	b.SampleRate = 10
+	assert.Equal(t, uint(10), b.NewEvent().SampleRate, "builder rates take precedence")
+}
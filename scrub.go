@@ -0,0 +1,96 @@
+package libhoney
+
+import "regexp"
+
+// FieldScrubber is called with each field of every event just before it's
+// sent, whichever code path added it, and returns the value to send in its
+// place, or false to drop the field. Configure one with
+// ClientConfig.FieldScrubber to hash or remove personal data centrally.
+type FieldScrubber func(key string, val interface{}) (interface{}, bool)
+
+// Patterns for common kinds of sensitive data, for RedactMatching.
+var (
+	EmailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	BearerTokenPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	JWTPattern         = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+	CardNumberPattern  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+
+	// SensitiveFieldNamePattern matches field names that usually hold
+	// secrets, for DropFieldsNamed.
+	SensitiveFieldNamePattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|authorization|cookie)`)
+)
+
+// redacted replaces the parts of values removed by RedactMatching.
+const redacted = "[REDACTED]"
+
+// RedactMatching returns a FieldScrubber that replaces each match of any of
+// patterns within string values with "[REDACTED]". Other values are left
+// alone.
+func RedactMatching(patterns ...*regexp.Regexp) FieldScrubber {
+	return func(key string, val interface{}) (interface{}, bool) {
+		s, ok := val.(string)
+		if !ok {
+			return val, true
+		}
+		for _, p := range patterns {
+			s = p.ReplaceAllLiteralString(s, redacted)
+		}
+		return s, true
+	}
+}
+
+// DropFieldsNamed returns a FieldScrubber that drops fields whose names match
+// pattern, eg SensitiveFieldNamePattern.
+func DropFieldsNamed(pattern *regexp.Regexp) FieldScrubber {
+	return func(key string, val interface{}) (interface{}, bool) {
+		return val, !pattern.MatchString(key)
+	}
+}
+
+// HashFields returns a FieldScrubber that replaces the values of the named
+// fields with transform, eg HashWithSalt, so they can still be counted and
+// grouped.
+func HashFields(transform FieldTransform, fields ...string) FieldScrubber {
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		set[f] = struct{}{}
+	}
+	return func(key string, val interface{}) (interface{}, bool) {
+		if _, ok := set[key]; ok {
+			return transform(val), true
+		}
+		return val, true
+	}
+}
+
+// ChainScrubbers returns a FieldScrubber running each of scrubbers in turn,
+// stopping if one drops the field.
+func ChainScrubbers(scrubbers ...FieldScrubber) FieldScrubber {
+	return func(key string, val interface{}) (interface{}, bool) {
+		for _, s := range scrubbers {
+			var keep bool
+			if val, keep = s(key, val); !keep {
+				return nil, false
+			}
+		}
+		return val, true
+	}
+}
+
+// DefaultFieldScrubber drops fields whose names suggest secrets and redacts
+// email addresses, bearer tokens, JWTs and card numbers from string values.
+var DefaultFieldScrubber = ChainScrubbers(
+	DropFieldsNamed(SensitiveFieldNamePattern),
+	RedactMatching(EmailPattern, BearerTokenPattern, JWTPattern, CardNumberPattern),
+)
+
+// applyFieldScrubber runs scrub over every field, in place.
+func applyFieldScrubber(scrub FieldScrubber, data map[string]interface{}) {
+	for k, v := range data {
+		if nv, keep := scrub(k, v); keep {
+			data[k] = nv
+		} else {
+			delete(data, k)
+		}
+	}
+}
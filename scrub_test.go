@@ -0,0 +1,54 @@
+package libhoney
+
+import (
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultFieldScrubber(t *testing.T) {
+	for _, c := range []struct {
+		key  string
+		in   interface{}
+		want interface{}
+		keep bool
+	}{
+		{"user.password", "hunter2", nil, false},
+		{"http.authorization", "x", nil, false},
+		{"message", "mail ann@example.com now", "mail [REDACTED] now", true},
+		{"header", "Bearer abc.def-123", "[REDACTED]", true},
+		{"card", "paid with 4111 1111 1111 1111", "paid with [REDACTED]", true},
+		{"jwt", "eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl", "[REDACTED]", true},
+		{"count", 5, 5, true},
+	} {
+		got, keep := DefaultFieldScrubber(c.key, c.in)
+		assert.Equal(t, c.keep, keep, c.key)
+		if keep {
+			assert.Equal(t, c.want, got, c.key)
+		}
+	}
+}
+
+func TestFieldScrubberAppliedAtSend(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+		FieldScrubber: ChainScrubbers(
+			DropFieldsNamed(SensitiveFieldNamePattern),
+			HashFields(func(v interface{}) interface{} { return "hashed" }, "user.email"),
+		),
+	})
+	c.AddField("api_key", "from the client")
+	ev := c.NewEvent()
+	ev.AddField("user.email", "ann@example.com")
+	ev.AddField("n", 1)
+	assert.NoError(t, ev.Send())
+	assert.Equal(t, map[string]interface{}{
+		"user.email": "hashed",
+		"n":          1,
+	}, mock.Events()[0].Data)
+	assert.Equal(t, "ann@example.com", ev.Fields()["user.email"], "the event's own fields are untouched")
+}
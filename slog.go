@@ -0,0 +1,136 @@
+//go:build go1.21
+// +build go1.21
+
+package libhoney
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// SlogHandler is a slog.Handler that sends each log record as an event made
+// with a Builder, so structured logs go to Honeycomb through the client's
+// batching and sampling. The record's message is sent as the field
+// "message" and its level, lower cased, as "level". Attributes become
+// fields, with the names of any groups they're in joined to theirs with
+// dots, eg "request.id".
+type SlogHandler struct {
+	builder *Builder
+	opts    slog.HandlerOptions
+	// prefix is the groups opened with WithGroup, as "a.b."
+	prefix string
+	// attrs are the fields added with WithAttrs, by their full names
+	attrs map[string]interface{}
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler returns a SlogHandler making events with builder. opts may
+// be nil. Records below opts.Level, which defaults to slog.LevelInfo, are
+// ignored, and with opts.AddSource the file and line that logged each record
+// are sent as "caller". opts.ReplaceAttr is applied to every attribute
+// except the message and level.
+func NewSlogHandler(builder *Builder, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{builder: builder}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether records at level are sent.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+// Handle sends r as an event.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ev := h.builder.NewEvent()
+	if !r.Time.IsZero() {
+		ev.Timestamp = r.Time
+	}
+	for k, v := range h.attrs {
+		ev.AddField(k, v)
+	}
+	ev.AddField("message", r.Message)
+	ev.AddField("level", strings.ToLower(r.Level.String()))
+	if h.opts.AddSource && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ev.AddField("caller", fmt.Sprintf("%s:%d", f.File, f.Line))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(ev.AddField, h.prefix, nil, a)
+		return true
+	})
+	return ev.SendWithContext(ctx)
+}
+
+// WithAttrs returns a SlogHandler that adds attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = make(map[string]interface{}, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		h2.attrs[k] = v
+	}
+	for _, a := range attrs {
+		h.addAttr(func(k string, v interface{}) { h2.attrs[k] = v }, h.prefix, nil, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a SlogHandler that puts the attributes of every record
+// in the group name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addAttr adds a, within the groups named by prefix and groups, with add.
+func (h *SlogHandler) addAttr(add func(string, interface{}), prefix string, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(append(h.groups(), groups...), a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		// a group without a key is inlined in its parent
+		if a.Key != "" {
+			prefix += a.Key + "."
+			groups = append(groups, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.addAttr(add, prefix, groups, ga)
+		}
+		return
+	}
+	val := a.Value.Any()
+	if err, ok := val.(error); ok {
+		val = err.Error()
+	}
+	add(prefix+a.Key, val)
+}
+
+// groups returns the names of the groups opened with WithGroup.
+func (h *SlogHandler) groups() []string {
+	if h.prefix == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(h.prefix, "."), ".")
+}
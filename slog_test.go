@@ -0,0 +1,71 @@
+//go:build go1.21
+// +build go1.21
+
+package libhoney
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSlogTestLogger(t *testing.T, opts *slog.HandlerOptions) (*slog.Logger, *transmission.MockSender) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "logs",
+		Transmission: mock,
+	})
+	assert.NoError(t, err)
+	return slog.New(NewSlogHandler(c.NewBuilder(), opts)), mock
+}
+
+func TestSlogHandler(t *testing.T) {
+	logger, mock := newSlogTestLogger(t, nil)
+	logger = logger.With("service", "api").WithGroup("request")
+	logger.Debug("ignored")
+	logger.Warn("slow request",
+		"id", 7,
+		slog.Group("user", "name", "ada"),
+		slog.Any("err", errors.New("timeout")),
+	)
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	data := events[0].Data
+	assert.Equal(t, "slow request", data["message"])
+	assert.Equal(t, "warn", data["level"])
+	assert.Equal(t, "api", data["service"])
+	assert.Equal(t, int64(7), data["request.id"])
+	assert.Equal(t, "ada", data["request.user.name"])
+	assert.Equal(t, "timeout", data["request.err"])
+	assert.Nil(t, data["caller"])
+	assert.False(t, events[0].Timestamp.IsZero())
+}
+
+func TestSlogHandlerOptions(t *testing.T) {
+	logger, mock := newSlogTestLogger(t, &slog.HandlerOptions{
+		Level:     slog.LevelDebug,
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger.Debug("login", "user", "ada", "password", "hunter2")
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	data := events[0].Data
+	assert.Equal(t, "debug", data["level"])
+	assert.Equal(t, "ada", data["user"])
+	_, ok := data["password"]
+	assert.False(t, ok)
+	assert.True(t, strings.Contains(data["caller"].(string), "slog_test.go:"))
+}
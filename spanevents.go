@@ -0,0 +1,47 @@
+package libhoney
+
+import (
+	"context"
+	"time"
+)
+
+type spanEvent struct {
+	at     time.Time
+	fields map[string]interface{}
+}
+
+// AddSpanEvent records a span event on the event, a span: a zero-duration
+// annotation marking a milestone within it, such as a cache miss or a retry.
+// When the event is sent each span event is sent alongside it with the given
+// fields and name, timestamped at (or when AddSpanEvent was called if at is
+// zero), with the event's trace.trace_id, its trace.span_id as
+// trace.parent_id and meta.annotation_type "span_event". Span events are
+// sampled with the event and don't get Responses of their own.
+//
+// Calls to AddSpanEvent after the event has been sent have no effect.
+func (e *Event) AddSpanEvent(name string, at time.Time, fields map[string]interface{}) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	data := make(map[string]interface{}, len(fields)+5)
+	for k, v := range fields {
+		data[k] = v
+	}
+	data["name"] = name
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spanEvents = append(e.spanEvents, spanEvent{at: at, fields: data})
+}
+
+// sendSpanEvents sends the span events for e, which must have been sent
+// already. The caller must hold e.lock.
+func (e *Event) sendSpanEvents(ctx context.Context) {
+	for _, se := range e.spanEvents {
+		e.sendAnnotation(ctx, "span_event", se.at, se.fields)
+	}
+}
@@ -0,0 +1,70 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSpanEvent(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+	})
+	at := time.Unix(1500000000, 0)
+	ev := c.NewEvent()
+	ev.AddField(traceIDField, "t1")
+	ev.AddField(spanIDField, "s1")
+	ev.AddSpanEvent("cache miss", at, map[string]interface{}{"key": "k"})
+	ev.AddSpanEvent("retry", time.Time{}, nil)
+	assert.NoError(t, ev.Send())
+	ev.AddSpanEvent("late", at, nil)
+
+	events := mock.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, map[string]interface{}{
+		"name":              "cache miss",
+		"key":               "k",
+		traceIDField:        "t1",
+		parentIDField:       "s1",
+		annotationTypeField: "span_event",
+	}, events[1].Data)
+	assert.Equal(t, at, events[1].Timestamp)
+	assert.Equal(t, "retry", events[2].Data["name"])
+	assert.False(t, events[2].Timestamp.IsZero())
+}
+
+func TestSpanEventFieldProcessing(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:            "key",
+		Dataset:           "ds",
+		Transmission:      mock,
+		FieldDenylist:     []string{"user.email"},
+		FieldScrubber:     DropFieldsNamed(SensitiveFieldNamePattern),
+		MaxFieldsPerEvent: 4,
+	})
+	ev := c.NewEvent()
+	ev.AddField(traceIDField, "t1")
+	ev.AddField(spanIDField, "s1")
+	ev.AddSpanEvent("login", time.Time{}, map[string]interface{}{
+		"user.email": "ann@example.com",
+		"api_key":    "secret",
+		"a":          1,
+		"b":          2,
+	})
+	assert.NoError(t, ev.Send())
+
+	events := mock.Events()
+	assert.Equal(t, 2, len(events))
+	data := events[1].Data
+	assert.NotContains(t, data, "user.email", "the denylist applies to span events")
+	assert.NotContains(t, data, "api_key", "the scrubber applies to span events")
+	assert.Equal(t, 4, len(data), "span events are capped at MaxFieldsPerEvent")
+	assert.Equal(t, 3, data[truncatedFieldsField])
+	assert.Equal(t, int64(1), c.Metrics().Counters["fields_truncated"])
+}
@@ -0,0 +1,89 @@
+package libhoney
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// defaultStackDepth is how many frames AddStack captures when given a depth
+// of zero or less.
+const defaultStackDepth = 32
+
+// stackFieldName is the field AddStack and AddStackFrames write to.
+const stackFieldName = "stack"
+
+// StackFrame is one frame of a stack trace added with AddStackFrames.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// AddStack adds the current goroutine's stack trace to the event as the field
+// "stack", formatted one frame per pair of lines (function, then file:line)
+// in the style of runtime/debug.Stack. skip is how many frames to omit above
+// the caller of AddStack, and depth is the most frames to include, defaulting
+// to 32. Frames in the Go runtime and in libhoney itself are left out, so the
+// trace starts at your code.
+//
+// Adds to an event that happen after it has been sent will return without
+// having any effect.
+func (e *Event) AddStack(skip, depth int) {
+	frames := captureStack(skip, depth)
+	var buf bytes.Buffer
+	for i, f := range frames {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "%s\n\t%s:%d", f.Function, f.File, f.Line)
+	}
+	e.AddField(stackFieldName, buf.String())
+}
+
+// AddStackFrames is like AddStack, but adds the stack trace as an array of
+// StackFrame objects rather than as a string.
+func (e *Event) AddStackFrames(skip, depth int) {
+	e.AddField(stackFieldName, captureStack(skip, depth))
+}
+
+// captureStack returns up to depth frames of the stack of the caller of its
+// caller, after skipping skip frames and dropping runtime and libhoney frames.
+func captureStack(skip, depth int) []StackFrame {
+	if skip < 0 {
+		skip = 0
+	}
+	if depth <= 0 {
+		depth = defaultStackDepth
+	}
+	// leave room for the frames that get filtered out
+	pcs := make([]uintptr, depth+16)
+	// skip runtime.Callers, captureStack and the AddStack method
+	n := runtime.Callers(3+skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := make([]StackFrame, 0, depth)
+	for len(stack) < depth {
+		f, more := frames.Next()
+		if keepFrame(f) {
+			stack = append(stack, StackFrame{
+				Function: f.Function,
+				File:     f.File,
+				Line:     f.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// keepFrame reports whether f belongs in a captured stack trace.
+func keepFrame(f runtime.Frame) bool {
+	if strings.HasPrefix(f.Function, "runtime.") {
+		return false
+	}
+	return filepath.Dir(f.File) != libhoneyDir || strings.HasSuffix(f.File, "_test.go")
+}
@@ -0,0 +1,45 @@
+package libhoney
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func stackHelper(ev *Event, skip int) {
+	ev.AddStackFrames(skip, 0)
+}
+
+func TestEventAddStack(t *testing.T) {
+	c, _ := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	ev := c.NewEvent()
+	ev.AddStack(0, 1)
+	stack, ok := ev.data["stack"].(string)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(stack, "github.com/honeycombio/libhoney-go.TestEventAddStack\n\t"), stack)
+	assert.Contains(t, stack, "stack_test.go:")
+	assert.Equal(t, 1, strings.Count(stack, "\n"), "depth limits the frames captured")
+
+	ev.AddStack(0, 0)
+	stack = ev.data["stack"].(string)
+	assert.NotContains(t, stack, "runtime.goexit")
+	assert.Contains(t, stack, "testing.tRunner")
+}
+
+func TestEventAddStackFrames(t *testing.T) {
+	c, _ := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	ev := c.NewEvent()
+	stackHelper(ev, 0)
+	frames := ev.data["stack"].([]StackFrame)
+	assert.Equal(t, "github.com/honeycombio/libhoney-go.stackHelper", frames[0].Function)
+	assert.Equal(t, "github.com/honeycombio/libhoney-go.TestEventAddStackFrames", frames[1].Function)
+
+	stackHelper(ev, 1)
+	frames = ev.data["stack"].([]StackFrame)
+	assert.Equal(t, "github.com/honeycombio/libhoney-go.TestEventAddStackFrames", frames[0].Function)
+	for _, f := range frames {
+		assert.False(t, strings.HasPrefix(f.Function, "runtime."), f.Function)
+	}
+}
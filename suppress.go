@@ -0,0 +1,135 @@
+package libhoney
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SuppressionMatcher decides whether an event is covered by a suppression
+// rule. It is given the event's dataset and fields and must not modify them.
+type SuppressionMatcher func(dataset string, fields map[string]interface{}) bool
+
+// FieldEquals returns a SuppressionMatcher that matches events whose field
+// name has exactly the given value. It is convenient for rules built from
+// operator-supplied configuration.
+func FieldEquals(name string, value interface{}) SuppressionMatcher {
+	return func(dataset string, fields map[string]interface{}) bool {
+		v, ok := fields[name]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// DatasetEquals returns a SuppressionMatcher that matches every event sent to
+// dataset.
+func DatasetEquals(dataset string) SuppressionMatcher {
+	return func(ds string, fields map[string]interface{}) bool {
+		return ds == dataset
+	}
+}
+
+type suppressionRule struct {
+	match SuppressionMatcher
+	until time.Time
+}
+
+// suppressions holds a client's active suppression rules, keyed by name.
+type suppressions struct {
+	rules map[string]suppressionRule
+	lock  sync.RWMutex
+}
+
+// Suppress silences every event matching match for the next d, without any
+// code changes to the call sites producing them. Suppressed events are
+// dropped with a Response explaining which rule dropped them. Adding a rule
+// with the name of an existing rule replaces it. Rules must be given a
+// positive duration.
+func (c *Client) Suppress(name string, d time.Duration, match SuppressionMatcher) {
+	if d <= 0 || match == nil {
+		return
+	}
+	c.suppressions.lock.Lock()
+	defer c.suppressions.lock.Unlock()
+	if c.suppressions.rules == nil {
+		c.suppressions.rules = make(map[string]suppressionRule)
+	}
+	c.suppressions.rules[name] = suppressionRule{
+		match: match,
+		until: time.Now().Add(d),
+	}
+}
+
+// Unsuppress removes the named suppression rule before it expires.
+func (c *Client) Unsuppress(name string) {
+	c.suppressions.lock.Lock()
+	defer c.suppressions.lock.Unlock()
+	delete(c.suppressions.rules, name)
+}
+
+// Suppressions returns the names of the suppression rules that are currently
+// in effect, sorted.
+func (c *Client) Suppressions() []string {
+	now := time.Now()
+	c.suppressions.lock.RLock()
+	defer c.suppressions.lock.RUnlock()
+	names := []string{}
+	for name, rule := range c.suppressions.rules {
+		if now.Before(rule.until) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suppressedBy returns the name of a rule that suppresses an event with the
+// given dataset and fields, or "" if none does. Expired rules are cleaned up
+// as they are found.
+func (c *Client) suppressedBy(dataset string, fields map[string]interface{}) string {
+	c.suppressions.lock.RLock()
+	if len(c.suppressions.rules) == 0 {
+		c.suppressions.lock.RUnlock()
+		return ""
+	}
+	now := time.Now()
+	var matched string
+	var expired []string
+	for name, rule := range c.suppressions.rules {
+		if !now.Before(rule.until) {
+			expired = append(expired, name)
+			continue
+		}
+		if matched == "" && rule.match(dataset, fields) {
+			matched = name
+		}
+	}
+	c.suppressions.lock.RUnlock()
+
+	if len(expired) > 0 {
+		c.suppressions.lock.Lock()
+		for _, name := range expired {
+			if rule, ok := c.suppressions.rules[name]; ok && !now.Before(rule.until) {
+				delete(c.suppressions.rules, name)
+			}
+		}
+		c.suppressions.lock.Unlock()
+	}
+	return matched
+}
+
+func suppressedMessage(rule string) string {
+	return fmt.Sprintf("event dropped by suppression rule %q", rule)
+}
+
+// Suppress silences every event sent through the package-level client that
+// matches match for the next d. See Client.Suppress.
+func Suppress(name string, d time.Duration, match SuppressionMatcher) {
+	dc.Suppress(name, d, match)
+}
+
+// Unsuppress removes the named suppression rule from the package-level client.
+func Unsuppress(name string) {
+	dc.Unsuppress(name)
+}
@@ -0,0 +1,40 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppress(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, _ := NewClient(ClientConfig{
+		APIKey:       "key",
+		Dataset:      "ds",
+		Transmission: mock,
+	})
+	send := func(path string) {
+		ev := c.NewEvent()
+		ev.AddField("path", path)
+		ev.Send()
+	}
+
+	c.Suppress("healthchecks", time.Hour, FieldEquals("path", "/healthz"))
+	c.Suppress("expired", time.Nanosecond, DatasetEquals("ds"))
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, []string{"healthchecks"}, c.Suppressions())
+
+	send("/healthz")
+	rsp := <-mock.TxResponses()
+	assert.Equal(t, `event dropped by suppression rule "healthchecks"`, rsp.Err.Error())
+	send("/users")
+	assert.Equal(t, 1, len(mock.Events()))
+	assert.Equal(t, "/users", mock.Events()[0].Data["path"])
+
+	c.Unsuppress("healthchecks")
+	assert.Equal(t, []string{}, c.Suppressions())
+	send("/healthz")
+	assert.Equal(t, 2, len(mock.Events()))
+}
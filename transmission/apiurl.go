@@ -0,0 +1,27 @@
+package transmission
+
+import (
+	"net/url"
+	"strings"
+)
+
+// APIURL returns the URL of an endpoint of the Honeycomb API at apiHost, eg
+// APIURL(host, "1", "batch", dataset). Each element is escaped and appended
+// to apiHost's path rather than replacing it, so an APIHost behind a reverse
+// proxy with a path prefix, like https://gw.internal/honeycomb/, keeps the
+// prefix, and a dataset containing a slash stays one path segment.
+func APIURL(apiHost string, elem ...string) (*url.URL, error) {
+	u, err := url.Parse(apiHost)
+	if err != nil {
+		return nil, err
+	}
+	segments := []string{strings.TrimSuffix(u.EscapedPath(), "/")}
+	for _, e := range elem {
+		segments = append(segments, url.PathEscape(e))
+	}
+	u.RawPath = strings.Join(segments, "/")
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
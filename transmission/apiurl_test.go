@@ -0,0 +1,53 @@
+package transmission
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIURL(t *testing.T) {
+	for host, expected := range map[string]string{
+		"https://api.honeycomb.io/":      "https://api.honeycomb.io/1/batch/my%20ds%2F1",
+		"https://api.honeycomb.io":       "https://api.honeycomb.io/1/batch/my%20ds%2F1",
+		"https://gw.internal/honeycomb/": "https://gw.internal/honeycomb/1/batch/my%20ds%2F1",
+		"https://gw.internal/honeycomb":  "https://gw.internal/honeycomb/1/batch/my%20ds%2F1",
+		"http://gw/a%2Fb/":               "http://gw/a%2Fb/1/batch/my%20ds%2F1",
+	} {
+		u, err := APIURL(host, "1", "batch", "my ds/1")
+		testOK(t, err)
+		assert.Equal(t, expected, u.String(), host)
+	}
+	_, err := APIURL("://bad", "1")
+	testErr(t, err)
+}
+
+func TestHoneycombAPIHostPathPrefix(t *testing.T) {
+	paths := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.EscapedPath()
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         10 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+	}
+	testOK(t, h.Start())
+	defer h.Stop()
+	h.Add(&Event{
+		APIHost:   server.URL + "/honeycomb/",
+		APIKey:    "key",
+		Dataset:   "svc/a",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"a": 1},
+	})
+	testOK(t, (<-h.TxResponses()).Err)
+	assert.Equal(t, "/honeycomb/1/batch/svc%2Fa", <-paths)
+}
@@ -0,0 +1,58 @@
+package transmission
+
+// batchLimit is how much a destination accepts in one batch request. Senders
+// that batch check each encoded event against it and use a batchFill to
+// split their events into requests that fit.
+type batchLimit struct {
+	// destination names the destination in EventTooLargeErrors, eg "API".
+	destination string
+	// maxEvents is the most events in a batch, or unlimited if 0.
+	maxEvents int
+	// maxBytes is the largest encoded batch, including framing and
+	// separators.
+	maxBytes int
+	// maxEventBytes is the largest encoded event.
+	maxEventBytes int
+	// framing is the bytes each batch adds around its events, eg "[" and
+	// "]", and separator the bytes between events, eg ",".
+	framing, separator int
+}
+
+// check returns an EventTooLargeError if an event that encoded to n bytes is
+// too large to ever be sent.
+func (l batchLimit) check(n int) error {
+	if n > l.maxEventBytes {
+		return &EventTooLargeError{Limit: l.maxEventBytes, Destination: l.destination}
+	}
+	return nil
+}
+
+// batchFill tracks the size of a batch as events are added to it.
+type batchFill struct {
+	limit  batchLimit
+	events int
+	bytes  int
+}
+
+// add counts an event that encoded to n bytes into the batch, unless it
+// would take the batch over its limit, in which case it returns false and
+// the event should start the next batch.
+func (f *batchFill) add(n int) bool {
+	size := f.bytes + n
+	if f.events == 0 {
+		size += f.limit.framing
+	} else {
+		size += f.limit.separator
+	}
+	if size > f.limit.maxBytes || f.limit.maxEvents > 0 && f.events >= f.limit.maxEvents {
+		return false
+	}
+	f.events++
+	f.bytes = size
+	return true
+}
+
+// reset empties the batch, for the next one.
+func (f *batchFill) reset() {
+	f.events, f.bytes = 0, 0
+}
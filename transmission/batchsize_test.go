@@ -0,0 +1,26 @@
+package transmission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchFill(t *testing.T) {
+	f := batchFill{limit: batchLimit{maxEvents: 3, maxBytes: 11, framing: 2, separator: 1}}
+	assert.True(t, f.add(3)) // [aaa]
+	assert.True(t, f.add(3)) // [aaa,bbb]
+	assert.False(t, f.add(2), "over maxBytes")
+	assert.True(t, f.add(1)) // [aaa,bbb,c]
+	assert.Equal(t, 11, f.bytes)
+
+	f.reset()
+	assert.True(t, f.add(1))
+	assert.True(t, f.add(1))
+	assert.True(t, f.add(1))
+	assert.False(t, f.add(1), "over maxEvents")
+
+	limit := batchLimit{destination: "API", maxEventBytes: 5}
+	assert.NoError(t, limit.check(5))
+	assert.Equal(t, &EventTooLargeError{Limit: 5, Destination: "API"}, limit.check(6))
+}
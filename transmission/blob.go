@@ -0,0 +1,69 @@
+package transmission
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+)
+
+// BlobEncodingSuffix is appended to the name of a compressed field to make
+// the name of its companion field, which records the encoding used.
+const BlobEncodingSuffix = ".encoding"
+
+// BlobEncodingGzipBase64 is the encoding of a field compressed with
+// CompressBlob: gzipped, then base64-encoded.
+const BlobEncodingGzipBase64 = "gzip+base64"
+
+// CompressBlob gzips and base64-encodes blob, for sending a large value (eg a
+// diff) in a single field without taking the event over the size limit. Set
+// the field "<name>.encoding" to BlobEncodingGzipBase64 alongside it so it
+// can be decoded with DecompressFields.
+func CompressBlob(blob []byte) (string, error) {
+	var buf bytes.Buffer
+	g := gzip.NewWriter(&buf)
+	if _, err := g.Write(blob); err != nil {
+		return "", err
+	}
+	if err := g.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecompressBlob reverses CompressBlob.
+func DecompressBlob(encoded string) ([]byte, error) {
+	g, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, strings.NewReader(encoded)))
+	if err != nil {
+		return nil, err
+	}
+	defer g.Close()
+	return ioutil.ReadAll(g)
+}
+
+// DecompressFields replaces every field in data compressed with CompressBlob
+// (as marked by its companion encoding field) with its original contents as
+// a string, and removes the companion. Fields that fail to decode are left as
+// they are, and the error for the last of them is returned.
+func DecompressFields(data map[string]interface{}) error {
+	var lastErr error
+	for k, v := range data {
+		if !strings.HasSuffix(k, BlobEncodingSuffix) || v != BlobEncodingGzipBase64 {
+			continue
+		}
+		name := strings.TrimSuffix(k, BlobEncodingSuffix)
+		encoded, ok := data[name].(string)
+		if !ok {
+			continue
+		}
+		blob, err := DecompressBlob(encoded)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data[name] = string(blob)
+		delete(data, k)
+	}
+	return lastErr
+}
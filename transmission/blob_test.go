@@ -0,0 +1,67 @@
+package transmission
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressBlobRoundTrip(t *testing.T) {
+	diff := []byte(strings.Repeat("- old line\n+ new line\n", 500))
+	enc, err := CompressBlob(diff)
+	testOK(t, err)
+	assert.True(t, len(enc) < len(diff)/10, "repetitive blob should compress well")
+	dec, err := DecompressBlob(enc)
+	testOK(t, err)
+	assert.Equal(t, diff, dec)
+	_, err = DecompressBlob("not base64!")
+	assert.Error(t, err)
+}
+
+func TestDecompressFields(t *testing.T) {
+	enc, _ := CompressBlob([]byte("hello"))
+	data := map[string]interface{}{
+		"diff":          enc,
+		"diff.encoding": BlobEncodingGzipBase64,
+		"bad":           "garbage",
+		"bad.encoding":  BlobEncodingGzipBase64,
+		"other":         1,
+	}
+	assert.Error(t, DecompressFields(data))
+	assert.Equal(t, "hello", data["diff"])
+	assert.NotContains(t, data, "diff.encoding")
+	assert.Equal(t, "garbage", data["bad"], "undecodable fields are left alone")
+	assert.Equal(t, BlobEncodingGzipBase64, data["bad.encoding"])
+	assert.Equal(t, 1, data["other"])
+}
+
+func TestSpoolDecompressFields(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	f := &FileSender{Path: path, ResponseQueueSize: 5}
+	testOK(t, f.Start())
+	enc, _ := CompressBlob([]byte("a big diff"))
+	f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{
+		"diff":          enc,
+		"diff.encoding": BlobEncodingGzipBase64,
+	}})
+	testOK(t, f.Stop())
+
+	s, err := OpenSpool(path, SpoolOptions{})
+	testOK(t, err)
+	ev, err := s.Next()
+	testOK(t, err)
+	s.Close()
+	assert.Equal(t, enc, ev.Data["diff"], "fields stay compressed by default")
+
+	s, err = OpenSpool(path, SpoolOptions{DecompressFields: true})
+	testOK(t, err)
+	ev, err = s.Next()
+	testOK(t, err)
+	s.Close()
+	assert.Equal(t, "a big diff", ev.Data["diff"])
+	assert.NotContains(t, ev.Data, "diff.encoding")
+}
@@ -0,0 +1,80 @@
+package transmission
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker tracks the health of one API host. After threshold failed
+// batches in a row it opens, and while open it lets a single probe batch
+// through every probeInterval. Any success closes it again.
+type circuitBreaker struct {
+	threshold     uint
+	probeInterval time.Duration
+
+	lock      sync.Mutex
+	failures  uint
+	open      bool
+	nextProbe time.Time
+}
+
+// allow reports whether a batch should be sent to the host, counting it as a
+// probe if the breaker is open.
+func (c *circuitBreaker) allow(now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.open {
+		return true
+	}
+	if now.Before(c.nextProbe) {
+		return false
+	}
+	c.nextProbe = now.Add(c.probeInterval)
+	return true
+}
+
+func (c *circuitBreaker) record(ok bool, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if ok {
+		c.failures = 0
+		c.open = false
+		return
+	}
+	c.failures++
+	if !c.open && c.failures >= c.threshold {
+		c.open = true
+		c.nextProbe = now.Add(c.probeInterval)
+	}
+}
+
+// trip opens the breaker straight away.
+func (c *circuitBreaker) trip(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.open = true
+	c.nextProbe = now.Add(c.probeInterval)
+}
+
+// breakerSet holds a circuitBreaker per API host.
+type breakerSet struct {
+	threshold     uint
+	probeInterval time.Duration
+
+	lock     sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func (s *breakerSet) get(host string) *circuitBreaker {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.breakers == nil {
+		s.breakers = make(map[string]*circuitBreaker)
+	}
+	b, ok := s.breakers[host]
+	if !ok {
+		b = &circuitBreaker{threshold: s.threshold, probeInterval: s.probeInterval}
+		s.breakers[host] = b
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package transmission
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	c := &circuitBreaker{threshold: 2, probeInterval: time.Minute}
+	assert.True(t, c.allow(now))
+	c.record(false, now)
+	assert.True(t, c.allow(now))
+	c.record(false, now)
+	assert.False(t, c.allow(now), "two failures should open the breaker")
+
+	now = now.Add(time.Minute)
+	assert.True(t, c.allow(now), "a probe is allowed after the interval")
+	assert.False(t, c.allow(now), "only one probe per interval")
+	c.record(true, now)
+	assert.True(t, c.allow(now))
+}
+
+// hostRoundTripper fails every request to failHost.
+type hostRoundTripper struct {
+	failHost string
+	lock     sync.Mutex
+	hosts    []string
+	bodies   []string
+}
+
+func (h *hostRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(r.Body)
+	h.lock.Lock()
+	h.hosts = append(h.hosts, r.URL.Host)
+	h.bodies = append(h.bodies, string(body))
+	h.lock.Unlock()
+	if r.URL.Host == h.failHost {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202}]`)),
+	}, nil
+}
+
+func TestFireBatchFailsOverToFallbackHost(t *testing.T) {
+	rt := &hostRoundTripper{failHost: "primary:8080"}
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	b := newRetryTestBatch(rt, 0, nil)
+	b.testNower = nower
+	b.responses = make(chan Response, 10)
+	b.fallbackAPIHost = "http://fallback:8080"
+	b.breakers = &breakerSet{threshold: 2, probeInterval: time.Minute}
+	send := func() {
+		b.fireBatch([]*Event{{APIHost: "http://primary:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}}})
+	}
+
+	send()
+	send()
+	send()
+	assert.Equal(t, []string{"primary:8080", "primary:8080", "fallback:8080"}, rt.hosts)
+	assert.Contains(t, rt.bodies[2], `"meta.failover_from":"http://primary:8080"`)
+
+	// the primary is probed once per interval and fails back when it works
+	nower.now = nower.now.Add(time.Minute)
+	rt.failHost = ""
+	send()
+	send()
+	assert.Equal(t, []string{"primary:8080", "primary:8080"}, rt.hosts[3:])
+	assert.NotContains(t, rt.bodies[4], "failover_from")
+}
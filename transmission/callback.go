@@ -0,0 +1,158 @@
+package transmission
+
+import (
+	"context"
+	"sync"
+)
+
+// ResponseCallbackSender wraps another Sender, calling Callback with each of
+// its Responses on a dedicated goroutine. This removes the need to run a loop
+// draining TxResponses, and keeps the wrapped Sender's response queue from
+// filling up unnoticed. The ResponseCallbackSender's own TxResponses channel
+// never receives anything, unless Relay is set; it is closed by Stop.
+// Responses routed with a ResponseRoute go to their route rather than to
+// Callback.
+type ResponseCallbackSender struct {
+	Sender   Sender
+	Callback func(Response)
+	// Relay passes each Response on to TxResponses once Callback has seen
+	// it, for callbacks that only observe Responses. Like the default
+	// Honeycomb sender it drops them if TxResponses is full.
+	Relay bool
+	// Logger, if set, is told about panics in Callback, which are recovered.
+	Logger Logger
+
+	responses chan Response
+	done      chan struct{}
+	stopped   stopSignal
+	wg        sync.WaitGroup
+}
+
+// NewResponseCallbackSender returns a ResponseCallbackSender calling cb with
+// each Response from s.
+func NewResponseCallbackSender(s Sender, cb func(Response)) *ResponseCallbackSender {
+	return &ResponseCallbackSender{Sender: s, Callback: cb}
+}
+
+func (c *ResponseCallbackSender) Start() error {
+	if err := c.Sender.Start(); err != nil {
+		return err
+	}
+	if c.Relay {
+		c.responses = make(chan Response, cap(c.Sender.TxResponses()))
+	} else {
+		c.responses = make(chan Response)
+	}
+	c.done = make(chan struct{})
+	c.stopped.start()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		relayResponses(c.Sender.TxResponses(), c.done, c.handle)
+	}()
+	return nil
+}
+
+// call runs the callback, recovering from any panic so that later Responses
+// are still delivered.
+func (c *ResponseCallbackSender) call(r Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(c.Logger, nil, "response callback", p)
+		}
+	}()
+	c.Callback(r)
+}
+
+// handle calls the callback with r and relays it if the sender relays.
+func (c *ResponseCallbackSender) handle(r Response) {
+	c.call(r)
+	if c.Relay {
+		c.stopped.writeResponse(c.responses, r, false, c.Logger, nil)
+	}
+}
+
+// Stop stops the wrapped Sender and returns once the callback has been called
+// for all of its Responses.
+func (c *ResponseCallbackSender) Stop() error {
+	err := c.Sender.Stop()
+	c.stopRelay()
+	return err
+}
+
+// StopWithContext stops the wrapped Sender, giving up waiting once ctx is
+// done. See ContextStopper.
+func (c *ResponseCallbackSender) StopWithContext(ctx context.Context) (int, error) {
+	abandoned, err := StopWithContext(ctx, c.Sender)
+	c.stopRelay()
+	return abandoned, err
+}
+
+func (c *ResponseCallbackSender) stopRelay() {
+	if c.done != nil {
+		close(c.done)
+		c.wg.Wait()
+		c.stopped.stop(c.responses)
+		c.done = nil
+	}
+}
+
+func (c *ResponseCallbackSender) Add(ev *Event) {
+	c.Sender.Add(routeThrough(ev, c.deliverRouted))
+}
+
+func (c *ResponseCallbackSender) AddWithContext(ctx context.Context, ev *Event) {
+	AddWithContext(ctx, c.Sender, routeThrough(ev, c.deliverRouted))
+}
+
+// deliverRouted hands a routed Response to its route.
+func (c *ResponseCallbackSender) deliverRouted(r Response) {
+	writeToResponse(nil, r, false, c.Logger, nil)
+}
+
+func (c *ResponseCallbackSender) TxResponses() chan Response {
+	return c.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (c *ResponseCallbackSender) Done() <-chan struct{} {
+	return c.stopped.done()
+}
+
+func (c *ResponseCallbackSender) SendResponse(r Response) bool {
+	return c.Sender.SendResponse(r)
+}
+
+// Pressure passes on the wrapped Sender's pressure, if it reports any.
+func (c *ResponseCallbackSender) Pressure() float64 {
+	if p, ok := c.Sender.(PressureReporter); ok {
+		return p.Pressure()
+	}
+	return 0
+}
+
+// TakePending takes the wrapped Sender's pending events, if it's a
+// PendingTaker.
+func (c *ResponseCallbackSender) TakePending() []*Event {
+	if pt, ok := c.Sender.(PendingTaker); ok {
+		return pt.TakePending()
+	}
+	return nil
+}
+
+// Summary passes on the wrapped Sender's summary, if it reports one.
+func (c *ResponseCallbackSender) Summary() Summary {
+	if s, ok := c.Sender.(Summarizer); ok {
+		return s.Summary()
+	}
+	return Summary{}
+}
+
+// MetricsSnapshot passes on the wrapped Sender's metrics, if it keeps them.
+func (c *ResponseCallbackSender) MetricsSnapshot() MetricsSnapshot {
+	if r, ok := c.Sender.(MetricsReporter); ok {
+		return r.MetricsSnapshot()
+	}
+	return MetricsSnapshot{}
+}
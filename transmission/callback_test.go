@@ -0,0 +1,37 @@
+package transmission
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCallbackSender(t *testing.T) {
+	var lock sync.Mutex
+	var got []interface{}
+	c := NewResponseCallbackSender(&WriterSender{W: ioutil.Discard}, func(r Response) {
+		lock.Lock()
+		got = append(got, r.Metadata)
+		lock.Unlock()
+	})
+	testOK(t, c.Start())
+	c.Add(&Event{Metadata: 1})
+	c.SendResponse(Response{Metadata: 2})
+	testOK(t, c.Stop())
+
+	assert.Equal(t, []interface{}{1, 2}, got)
+	_, open := <-c.TxResponses()
+	assert.False(t, open)
+}
+
+func TestResponseCallbackSenderMetricsSnapshot(t *testing.T) {
+	h := &Honeycomb{stats: newSenderStats()}
+	h.stats.count("messages_queued", 2)
+	c := NewResponseCallbackSender(h, func(Response) {})
+	assert.Equal(t, int64(2), c.MetricsSnapshot().EventsQueued)
+
+	c = NewResponseCallbackSender(&WriterSender{W: ioutil.Discard}, func(Response) {})
+	assert.Equal(t, int64(0), c.MetricsSnapshot().EventsQueued)
+}
@@ -0,0 +1,137 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// canonicalJSON re-encodes the JSON document raw in a canonical form, so that
+// the same event always encodes to the same bytes whatever the map iteration
+// order or Go version: object keys are sorted bytewise, there's no
+// whitespace, strings are escaped only where JSON requires it (no HTML
+// escaping), and numbers are in their shortest form, with floats formatted
+// the way ECMAScript does and integers kept exactly as written.
+func canonicalJSON(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(raw)))
+	writeCanonical(out, v)
+	return out.Bytes(), nil
+}
+
+func writeCanonical(out *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		out.WriteString("null")
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		out.WriteString(canonicalNumber(v))
+	case string:
+		writeCanonicalString(out, v)
+	case []interface{}:
+		out.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeCanonical(out, e)
+		}
+		out.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeCanonicalString(out, k)
+			out.WriteByte(':')
+			writeCanonical(out, v[k])
+		}
+		out.WriteByte('}')
+	}
+}
+
+// canonicalNumber formats a JSON number. Integers are kept as they are, so
+// that large ones don't lose precision; everything else is formatted as a
+// float64 the way ECMAScript's Number.toString does.
+func canonicalNumber(n json.Number) string {
+	s := string(n)
+	if !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			return "0"
+		}
+		return s
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// out of range of a float64; leave it be
+		return s
+	}
+	if f == 0 {
+		return "0"
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	b := []byte(strconv.FormatFloat(f, 'e', -1, 64))
+	// ECMAScript doesn't pad the exponent: 1e-07 becomes 1e-7
+	if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-2] == '0' {
+		b[n-2] = b[n-1]
+		b = b[:n-1]
+	}
+	return string(b)
+}
+
+func writeCanonicalString(out *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			out.WriteRune(r)
+			i += size
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				out.WriteString(`\u00`)
+				out.WriteByte(hex[c>>4])
+				out.WriteByte(hex[c&0xf])
+			} else {
+				out.WriteByte(c)
+			}
+		}
+		i++
+	}
+	out.WriteByte('"')
+}
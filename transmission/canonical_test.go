@@ -0,0 +1,56 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	for in, want := range map[string]string{
+		"0":                       "0",
+		"-0":                      "0",
+		"-0.0":                    "0",
+		"12345678901234567890":    "12345678901234567890",
+		"1.0":                     "1",
+		"1.50":                    "1.5",
+		"1E3":                     "1000",
+		"0.000001":                "0.000001",
+		"0.0000001":               "1e-7",
+		"1e21":                    "1e+21",
+		"123456789012345678901.5": "123456789012345680000",
+		"1e400":                   "1e400",
+	} {
+		assert.Equal(t, want, canonicalNumber(json.Number(in)), in)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	out, err := canonicalJSON([]byte(`{ "b": [1.0, "<a&b>", null], "a": {"y": true, "x": "é\n\u0001"} }`))
+	testOK(t, err)
+	testEquals(t, string(out), `{"a":{"x":"é\n\u0001","y":true},"b":[1,"<a&b>",null]}`)
+
+	_, err = canonicalJSON([]byte(`{"a":`))
+	testErr(t, err)
+}
+
+func TestCanonicalJSONOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := &WriterSender{W: buf, CanonicalJSON: true}
+	testOK(t, w.Start())
+	w.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"z": 2.50, "a": "<b>"}})
+	testOK(t, w.Stop())
+	testEquals(t, buf.String(), `{"data":{"a":"<b>","z":2.5},"dataset":"ds"}`+"\n")
+
+	b := &batchAgg{
+		responses:     make(chan Response, 1),
+		metrics:       &nullMetrics{},
+		canonicalJSON: true,
+	}
+	buf.Reset()
+	n := b.encodeBatch(buf, []*Event{{Data: map[string]interface{}{"z": 1e-7, "a": "<b>"}}})
+	testEquals(t, n, 1)
+	testEquals(t, buf.String(), `[{"data":{"a":"<b>","z":1e-7}}]`)
+}
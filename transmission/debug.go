@@ -0,0 +1,67 @@
+package transmission
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redactedHeaders are the request headers DebugRequests doesn't log the
+// values of.
+var redactedHeaders = []string{"X-Honeycomb-Team", "Authorization"}
+
+// debugRequest logs a batch request and what came of it, for DebugRequests:
+// the request's URL and headers, the batch before compression, and the
+// response's status and body or the error sending it.
+func (b *batchAgg) debugRequest(req *http.Request, batch []byte, status int, respBody []byte, err error) {
+	if req == nil {
+		return
+	}
+	fields := Fields{"url": req.URL.String(), "headers": formatHeaders(req.Header)}
+	if err != nil {
+		fields["error"] = err
+		b.leveled().Debugf(fields, "batch request failed; request body %s", batch)
+		return
+	}
+	fields["status"] = status
+	b.leveled().Debugf(fields, "batch request sent; request body %s; response body %s", batch, bytes.TrimSpace(respBody))
+}
+
+// formatHeaders formats h sorted by name, with secrets redacted.
+func formatHeaders(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(h[name], ",")
+		for _, r := range redactedHeaders {
+			if http.CanonicalHeaderKey(name) == r {
+				value = "[REDACTED]"
+			}
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return "{" + strings.Join(parts, "; ") + "}"
+}
+
+// rawWriter returns w, also writing to the body's uncompressed copy if it's
+// keeping one.
+func (bb *batchBody) rawWriter(w io.Writer) io.Writer {
+	if bb.raw == nil {
+		return w
+	}
+	return io.MultiWriter(w, bb.raw)
+}
+
+// rawBytes returns the body's uncompressed copy, if it's keeping one.
+func (bb *batchBody) rawBytes() []byte {
+	if bb.raw == nil {
+		return nil
+	}
+	return bb.raw.Bytes()
+}
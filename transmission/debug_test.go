@@ -0,0 +1,63 @@
+package transmission
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombDebugRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	logger := &leveledRecorder{}
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         10 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		Logger:               logger,
+		DebugRequests:        true,
+	}
+	testOK(t, h.Start())
+	h.Add(&Event{
+		APIHost:   server.URL,
+		APIKey:    "supersecret",
+		Dataset:   "ds",
+		Timestamp: time.Unix(0, 0).UTC(),
+		Data:      map[string]interface{}{"a": 1},
+	})
+	r := <-h.TxResponses()
+	testOK(t, r.Err)
+	assert.Equal(t, 202, r.StatusCode, "the response is still decoded")
+	testOK(t, h.Stop())
+
+	var logged string
+	for _, m := range logger.messages("DEBUG") {
+		if strings.Contains(m, "batch request sent") {
+			logged = m
+		}
+	}
+	assert.Contains(t, logged, `request body [{"data":{"a":1},"time":"1970-01-01T00:00:00Z"}]`)
+	assert.Contains(t, logged, `response body [{"status":202}]`)
+	assert.Contains(t, logged, "url="+server.URL+"/1/batch/ds")
+	assert.Contains(t, logged, "status=200")
+	assert.Contains(t, logged, "X-Honeycomb-Team: [REDACTED]")
+	assert.Contains(t, logged, "Content-Encoding: gzip")
+	assert.NotContains(t, logged, "supersecret")
+}
+
+func TestFormatHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("User-Agent", "libhoney-go")
+	h.Set("Authorization", "Bearer token")
+	h.Add("X-Multi", "a")
+	h.Add("X-Multi", "b")
+	assert.Equal(t, "{Authorization: [REDACTED]; User-Agent: libhoney-go; X-Multi: a,b}", formatHeaders(h))
+}
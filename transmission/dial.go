@@ -0,0 +1,136 @@
+package transmission
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// racingDialer dials each address over IPv4 and IPv6 at the same time the
+// first time it's used, adopts whichever connects first, and pins that
+// address to the winning network for later dials. On networks with broken
+// IPv6 this avoids waiting for an IPv6 connect to time out before the first
+// batch can be sent.
+type racingDialer struct {
+	dialer net.Dialer
+
+	lock   sync.Mutex
+	pinned map[string]string // address to "tcp4" or "tcp6"
+}
+
+func newRacingDialer() *racingDialer {
+	return &racingDialer{
+		dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		pinned: make(map[string]string),
+	}
+}
+
+func (d *racingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	d.lock.Lock()
+	pinned, ok := d.pinned[addr]
+	d.lock.Unlock()
+	if ok {
+		return d.dialer.DialContext(ctx, pinned, addr)
+	}
+	conn, winner, err := d.race(ctx, addr, "tcp4", "tcp6")
+	if err != nil {
+		return nil, err
+	}
+	d.lock.Lock()
+	d.pinned[addr] = winner
+	d.lock.Unlock()
+	return conn, nil
+}
+
+type dialResult struct {
+	conn    net.Conn
+	network string
+	err     error
+}
+
+// race dials addr over each network concurrently and returns the first
+// connection to succeed, closing any that succeed later. If all fail it
+// returns the first error.
+func (d *racingDialer) race(ctx context.Context, addr string, networks ...string) (net.Conn, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, len(networks))
+	for _, network := range networks {
+		go func(network string) {
+			conn, err := d.dialer.DialContext(ctx, network, addr)
+			results <- dialResult{conn: conn, network: network, err: err}
+		}(network)
+	}
+	var firstErr error
+	for i := range networks {
+		r := <-results
+		if r.err == nil {
+			// close the losers once they finish
+			go func(remaining int) {
+				for ; remaining > 0; remaining-- {
+					if lost := <-results; lost.conn != nil {
+						lost.conn.Close()
+					}
+				}
+			}(len(networks) - i - 1)
+			return r.conn, r.network, nil
+		}
+		if firstErr == nil {
+			firstErr = r.err
+		}
+	}
+	return nil, "", firstErr
+}
+
+// raceHosts connects to each of the given API hosts concurrently and returns
+// the one that connected first, or "" if none could be reached.
+func (d *racingDialer) raceHosts(ctx context.Context, hosts ...string) string {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	winners := make(chan string, len(hosts))
+	for _, host := range hosts {
+		go func(host string) {
+			addr, err := hostAddr(host)
+			if err == nil {
+				var conn net.Conn
+				if conn, err = d.DialContext(ctx, "tcp", addr); err == nil {
+					conn.Close()
+				}
+			}
+			if err != nil {
+				host = ""
+			}
+			winners <- host
+		}(host)
+	}
+	for range hosts {
+		if w := <-winners; w != "" {
+			return w
+		}
+	}
+	return ""
+}
+
+// hostAddr returns the host:port to connect to for an API host URL.
+func hostAddr(apiHost string) (string, error) {
+	u, err := url.Parse(apiHost)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -0,0 +1,77 @@
+package transmission
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRacingDialerPinsWinner(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	testOK(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	d := newRacingDialer()
+	conn, err := d.DialContext(context.Background(), "tcp", l.Addr().String())
+	testOK(t, err)
+	conn.Close()
+	assert.Equal(t, "tcp4", d.pinned[l.Addr().String()])
+
+	// a host that can't be reached loses the race
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	testOK(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+	winner := d.raceHosts(context.Background(), "http://"+closedAddr, "http://"+l.Addr().String())
+	assert.Equal(t, "http://"+l.Addr().String(), winner)
+	assert.Equal(t, "", d.raceHosts(context.Background(), "http://"+closedAddr))
+}
+
+func TestHostAddr(t *testing.T) {
+	for host, expected := range map[string]string{
+		"https://api.honeycomb.io":  "api.honeycomb.io:443",
+		"https://api.honeycomb.io/": "api.honeycomb.io:443",
+		"http://localhost":          "localhost:80",
+		"http://localhost:8080":     "localhost:8080",
+	} {
+		addr, err := hostAddr(host)
+		testOK(t, err)
+		assert.Equal(t, expected, addr)
+	}
+}
+
+func TestRaceFirstConnectNeedsDefaultTransport(t *testing.T) {
+	custom := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	h := &Honeycomb{
+		MaxBatchSize:         10,
+		BatchTimeout:         50 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		RaceFirstConnect:     true,
+		Transport:            custom,
+	}
+	testOK(t, h.Start())
+	assert.Nil(t, h.firstConnect, "a custom Transport shouldn't be raced around")
+	assert.Equal(t, custom, h.Transport)
+	testOK(t, h.Stop())
+
+	h.Transport = nil
+	testOK(t, h.Start())
+	assert.NotNil(t, h.firstConnect)
+	b := h.muster.BatchMaker().(*batchAgg)
+	assert.Equal(t, 50*time.Millisecond, b.firstConnectTimeout, "the host race should wait at most one batch timeout")
+	testOK(t, h.Stop())
+}
@@ -0,0 +1,116 @@
+package transmission
+
+import (
+	"sync"
+)
+
+// elasticQueue holds events that didn't fit in a sender's fixed-size work
+// channel, up to max of them, and feeds them into that channel as room frees
+// up. Its buffer only exists while it is in use, so it absorbs bursts without
+// permanently reserving memory for them.
+type elasticQueue struct {
+	max     int
+	feed    chan<- interface{}
+	logger  Logger
+	metrics Metrics
+
+	lock    sync.Mutex
+	events  []*Event
+	pending int // queued events, including ones being fed
+
+	signal chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newElasticQueue(max int, feed chan<- interface{}, logger Logger, metrics Metrics) *elasticQueue {
+	q := &elasticQueue{
+		max:     max,
+		feed:    feed,
+		logger:  logger,
+		metrics: metrics,
+		signal:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// add queues ev, reporting false if the queue is full.
+func (q *elasticQueue) add(ev *Event) bool {
+	q.lock.Lock()
+	if q.pending >= q.max {
+		q.lock.Unlock()
+		return false
+	}
+	q.events = append(q.events, ev)
+	q.pending++
+	q.lock.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// len returns how many events are waiting for room in the work channel.
+func (q *elasticQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.pending
+}
+
+// take removes and returns everything waiting in the queue.
+func (q *elasticQueue) take() []*Event {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	events := q.events
+	q.events = nil
+	q.pending -= len(events)
+	return events
+}
+
+// stop feeds everything still queued into the work channel and returns once
+// that is done. The work channel must still be being consumed.
+func (q *elasticQueue) stop() {
+	close(q.done)
+	q.wg.Wait()
+}
+
+func (q *elasticQueue) run() {
+	defer q.wg.Done()
+	keepRunning(q.logger, q.metrics, "overflow queue", q.loop)
+}
+
+func (q *elasticQueue) loop() {
+	for {
+		select {
+		case <-q.signal:
+			q.drain()
+		case <-q.done:
+			q.drain()
+			return
+		}
+	}
+}
+
+// drain feeds queued events into the work channel until there are none left.
+// Each round takes the whole buffer, so it is released once it's been fed.
+func (q *elasticQueue) drain() {
+	for {
+		q.lock.Lock()
+		events := q.events
+		q.events = nil
+		q.lock.Unlock()
+		if len(events) == 0 {
+			return
+		}
+		for _, ev := range events {
+			q.feed <- ev
+			q.lock.Lock()
+			q.pending--
+			q.lock.Unlock()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package transmission
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElasticQueue(t *testing.T) {
+	feed := make(chan interface{})
+	q := newElasticQueue(3, feed, nil, nil)
+	for i := 0; i < 3; i++ {
+		assert.True(t, q.add(&Event{Metadata: i}))
+	}
+	assert.False(t, q.add(&Event{Metadata: 3}), "adding past max should fail")
+	assert.Equal(t, 3, q.len())
+
+	for i := 0; i < 3; i++ {
+		ev := (<-feed).(*Event)
+		assert.Equal(t, i, ev.Metadata)
+	}
+	waitFor(t, func() bool { return q.len() == 0 })
+	assert.True(t, q.add(&Event{Metadata: 4}), "room frees up as events are fed")
+
+	go func() { <-feed }()
+	q.stop()
+	assert.Equal(t, 0, q.len())
+	assert.Nil(t, q.events, "the buffer should be released once drained")
+}
+
+type failingRoundTripper struct{}
+
+func (failingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHoneycombElasticQueue(t *testing.T) {
+	h := &Honeycomb{
+		MaxBatchSize:           10,
+		BatchTimeout:           time.Millisecond,
+		PendingWorkCapacity:    1,
+		MaxPendingWorkCapacity: 100,
+		BlockOnResponse:        true,
+		Transport:              failingRoundTripper{},
+	}
+	testOK(t, h.Start())
+	done := make(chan int)
+	go func() {
+		n := 0
+		for r := range h.TxResponses() {
+			assert.NotEqual(t, "queue overflow", r.Err.Error())
+			n++
+		}
+		done <- n
+	}()
+	for i := 0; i < 50; i++ {
+		h.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": i}})
+	}
+	testOK(t, h.Stop())
+	assert.Equal(t, 50, <-done)
+}
@@ -0,0 +1,45 @@
+package transmission
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors set on Response.Err, so callers can tell why an event wasn't sent
+// without matching on error strings.
+var (
+	// ErrQueueOverflow means the event was dropped because the sender's queue
+	// was full.
+	ErrQueueOverflow = errors.New("queue overflow")
+	// ErrEventTooLarge is matched (with errors.Is) by EventTooLargeError.
+	ErrEventTooLarge = errors.New("event too large")
+	// ErrRateLimited means the event was dropped by a RateLimitedSender.
+	ErrRateLimited = errors.New("event dropped by rate limit")
+	// ErrUnauthorized means the API rejected the batch's API key.
+	ErrUnauthorized = errors.New("unauthorized: API key rejected")
+	// ErrEventStale means the event was dropped after being queued (or
+	// retried) for longer than the sender's MaxQueueAge.
+	ErrEventStale = errors.New("event dropped after exceeding max queue age")
+	// ErrSenderStopped means the event was added while the sender was
+	// stopping, so it was dropped. Events added once the sender has stopped
+	// get no Response, since TxResponses is closed.
+	ErrSenderStopped = errors.New("sender stopped")
+)
+
+// EventTooLargeError is the Response error for an event that encoded to more
+// than its destination will accept. It matches ErrEventTooLarge.
+type EventTooLargeError struct {
+	// Limit is the largest encoded event, in bytes, the destination accepts.
+	Limit int
+	// Destination names what rejected the event, eg "API".
+	Destination string
+}
+
+func (e *EventTooLargeError) Error() string {
+	return fmt.Sprintf("event exceeds max event size of %d bytes, %s will not accept this event", e.Limit, e.Destination)
+}
+
+// Is makes errors.Is(err, ErrEventTooLarge) true.
+func (e *EventTooLargeError) Is(target error) bool {
+	return target == ErrEventTooLarge
+}
@@ -0,0 +1,27 @@
+package transmission
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventTooLargeError(t *testing.T) {
+	var err error = &EventTooLargeError{Limit: 10, Destination: "API"}
+	assert.Equal(t, "event exceeds max event size of 10 bytes, API will not accept this event", err.Error())
+	is, ok := err.(interface{ Is(error) bool })
+	assert.True(t, ok)
+	assert.True(t, is.Is(ErrEventTooLarge))
+	assert.False(t, is.Is(ErrQueueOverflow))
+}
+
+func TestFireBatchUnauthorized(t *testing.T) {
+	rt := &sequenceRoundTripper{statuses: []int{http.StatusUnauthorized}}
+	b := newRetryTestBatch(rt, 0, nil)
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+	b.Fire(&testNotifier{})
+	rsp := testGetResponse(t, b.responses)
+	assert.Equal(t, ErrUnauthorized, rsp.Err)
+	assert.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+}
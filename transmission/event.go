@@ -27,6 +27,29 @@ type Event struct {
 
 	// Data contains the content of the event (all the fields and their values)
 	Data map[string]interface{}
+
+	// enqueuedAt is when the sender queued the event, if it stamps queue time
+	enqueuedAt time.Time
+	// staleAt is when the event is too old to send, if the sender has a
+	// MaxQueueAge
+	staleAt time.Time
+}
+
+// stale reports whether the event is past its MaxQueueAge as of now.
+func (e *Event) stale(now time.Time) bool {
+	return !e.staleAt.IsZero() && now.After(e.staleAt)
+}
+
+// withQueueTime returns a copy of the event with meta.queue_time_ms set to
+// how long it has been queued as of now. The original's Data is untouched.
+func (e *Event) withQueueTime(now time.Time) *Event {
+	ev := *e
+	ev.Data = make(map[string]interface{}, len(e.Data)+1)
+	for k, v := range e.Data {
+		ev.Data[k] = v
+	}
+	ev.Data["meta.queue_time_ms"] = float64(now.Sub(e.enqueuedAt)) / float64(time.Millisecond)
+	return &ev
 }
 
 // Marshaling an Event for batching up to the Honeycomb servers. Omits fields
@@ -51,6 +74,29 @@ func (e *Event) MarshalJSON() ([]byte, error) {
 	}{e.Data, sampleRate, tPointer})
 }
 
+// marshalWithDataset encodes the event the same way as MarshalJSON but also
+// includes the dataset, for senders that write events from many datasets to a
+// single destination.
+func marshalWithDataset(ev *Event) ([]byte, error) {
+	tPointer := &(ev.Timestamp)
+	if ev.Timestamp.IsZero() {
+		tPointer = nil
+	}
+
+	// don't include sample rate if it's 1; this is the default
+	sampleRate := ev.SampleRate
+	if sampleRate == 1 {
+		sampleRate = 0
+	}
+
+	return json.Marshal(struct {
+		Data       map[string]interface{} `json:"data"`
+		SampleRate uint                   `json:"samplerate,omitempty"`
+		Timestamp  *time.Time             `json:"time,omitempty"`
+		Dataset    string                 `json:"dataset,omitempty"`
+	}{ev.Data, sampleRate, tPointer, ev.Dataset})
+}
+
 type marshallableMap map[string]interface{}
 
 func (m marshallableMap) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,103 @@
+package transmission
+
+import (
+	"sync"
+	"time"
+)
+
+// The fields an event's trace and span IDs are read from for exemplars,
+// following the Beeline conventions.
+const (
+	ExemplarTraceIDField = "trace.trace_id"
+	ExemplarSpanIDField  = "trace.span_id"
+)
+
+// maxExemplars is how many of the most recently sent traced events Summary
+// reports.
+const maxExemplars = 10
+
+// Exemplar identifies an event behind an aggregate by its trace and span IDs,
+// so that a count can be drilled down into to find the raw events.
+type Exemplar struct {
+	TraceID   string
+	SpanID    string
+	Timestamp time.Time
+}
+
+// ExemplarMetrics is implemented by Metrics that can attach an exemplar to a
+// count. When Honeycomb's Metrics implements it, the "messages_sent" count
+// for each batch is given the exemplar of a traced event in that batch.
+type ExemplarMetrics interface {
+	Metrics
+	CountWithExemplar(name string, val interface{}, ex Exemplar)
+}
+
+// exemplarOf returns the exemplar for ev, if it carries a trace ID.
+func exemplarOf(ev *Event) (Exemplar, bool) {
+	if ev == nil {
+		return Exemplar{}, false
+	}
+	traceID, _ := ev.Data[ExemplarTraceIDField].(string)
+	if traceID == "" {
+		return Exemplar{}, false
+	}
+	spanID, _ := ev.Data[ExemplarSpanIDField].(string)
+	return Exemplar{TraceID: traceID, SpanID: spanID, Timestamp: ev.Timestamp}, true
+}
+
+// exemplarRing keeps the exemplars of the most recently sent traced events.
+type exemplarRing struct {
+	lock  sync.Mutex
+	ring  [maxExemplars]Exemplar
+	next  int
+	count int
+}
+
+// record notes ev as sent. It may be called on a nil ring.
+func (r *exemplarRing) record(ev *Event) {
+	if r == nil {
+		return
+	}
+	ex, ok := exemplarOf(ev)
+	if !ok {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ring[r.next] = ex
+	r.next = (r.next + 1) % maxExemplars
+	if r.count < maxExemplars {
+		r.count++
+	}
+}
+
+// snapshot returns the recorded exemplars, oldest first.
+func (r *exemplarRing) snapshot() []Exemplar {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.count == 0 {
+		return nil
+	}
+	out := make([]Exemplar, 0, r.count)
+	for i := r.next - r.count; i < r.next; i++ {
+		out = append(out, r.ring[(i+maxExemplars)%maxExemplars])
+	}
+	return out
+}
+
+// countSent records the number of events sent in a batch, with the exemplar
+// of the first traced one if the metrics support it.
+func (b *batchAgg) countSent(events []*Event, numEncoded int) {
+	if em, ok := b.metrics.(ExemplarMetrics); ok {
+		for _, ev := range events {
+			if ex, ok := exemplarOf(ev); ok {
+				em.CountWithExemplar("messages_sent", numEncoded, ex)
+				return
+			}
+		}
+	}
+	b.metrics.Count("messages_sent", numEncoded)
+}
@@ -0,0 +1,57 @@
+package transmission
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exemplarMetrics struct {
+	nullMetrics
+	exemplars []Exemplar
+}
+
+func (m *exemplarMetrics) CountWithExemplar(name string, val interface{}, ex Exemplar) {
+	m.exemplars = append(m.exemplars, ex)
+}
+
+func TestExemplars(t *testing.T) {
+	metrics := &exemplarMetrics{}
+	b := &batchAgg{
+		httpClient: &http.Client{Transport: discardRoundTripper{n: 3}},
+		testNower:  &fakeNower{},
+		responses:  make(chan Response, 10),
+		metrics:    metrics,
+		exemplars:  &exemplarRing{},
+	}
+	ts := time.Unix(1500000000, 0)
+	events := []*Event{
+		{APIHost: "http://fakeHost:8080", Data: map[string]interface{}{"a": 1}},
+		{APIHost: "http://fakeHost:8080", Timestamp: ts, Data: map[string]interface{}{
+			"trace.trace_id": "t1",
+			"trace.span_id":  "s1",
+		}},
+		{APIHost: "http://fakeHost:8080", Data: map[string]interface{}{"trace.trace_id": "t2"}},
+	}
+	b.fireBatch(events)
+	assert.Equal(t, []Exemplar{{TraceID: "t1", SpanID: "s1", Timestamp: ts}}, metrics.exemplars,
+		"the batch's count carries its first traced event")
+	assert.Equal(t, []Exemplar{
+		{TraceID: "t1", SpanID: "s1", Timestamp: ts},
+		{TraceID: "t2"},
+	}, b.exemplars.snapshot())
+}
+
+func TestExemplarRingKeepsMostRecent(t *testing.T) {
+	r := &exemplarRing{}
+	assert.Nil(t, r.snapshot())
+	for i := 0; i < maxExemplars+3; i++ {
+		r.record(&Event{Data: map[string]interface{}{"trace.trace_id": string(rune('a' + i))}})
+	}
+	got := r.snapshot()
+	assert.Equal(t, maxExemplars, len(got))
+	assert.Equal(t, "d", got[0].TraceID)
+	assert.Equal(t, string(rune('a'+maxExemplars+2)), got[maxExemplars-1].TraceID)
+}
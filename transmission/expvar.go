@@ -0,0 +1,72 @@
+package transmission
+
+import (
+	"expvar"
+	"strconv"
+	"sync"
+)
+
+// ExpvarName is the expvar map senders with PublishExpvars are published in.
+const ExpvarName = "libhoney"
+
+// published tracks the senders whose stats are published in the expvar map.
+var published struct {
+	once    sync.Once
+	lock    sync.Mutex
+	senders map[*Honeycomb]struct{}
+}
+
+// publishExpvars adds h to the senders summed in the expvar map, publishing
+// the map the first time.
+func publishExpvars(h *Honeycomb) {
+	published.once.Do(func() {
+		published.senders = make(map[*Honeycomb]struct{})
+		m := expvar.NewMap(ExpvarName)
+		m.Set("queue_depth", expvar.Func(func() interface{} {
+			return sumPublished(func(s MetricsSnapshot) int64 { return s.QueueDepth })
+		}))
+		m.Set("queue_overflow", expvar.Func(func() interface{} {
+			return sumPublished(func(s MetricsSnapshot) int64 { return s.Counters["queue_overflow"] })
+		}))
+		m.Set("responses", expvar.Func(publishedResponses))
+	})
+	published.lock.Lock()
+	published.senders[h] = struct{}{}
+	published.lock.Unlock()
+}
+
+// unpublishExpvars stops including h in the expvar map.
+func unpublishExpvars(h *Honeycomb) {
+	published.lock.Lock()
+	delete(published.senders, h)
+	published.lock.Unlock()
+}
+
+func publishedSnapshots() []MetricsSnapshot {
+	published.lock.Lock()
+	defer published.lock.Unlock()
+	snaps := make([]MetricsSnapshot, 0, len(published.senders))
+	for h := range published.senders {
+		snaps = append(snaps, h.MetricsSnapshot())
+	}
+	return snaps
+}
+
+func sumPublished(get func(MetricsSnapshot) int64) int64 {
+	var total int64
+	for _, s := range publishedSnapshots() {
+		total += get(s)
+	}
+	return total
+}
+
+// publishedResponses counts the published senders' responses by status code.
+func publishedResponses() interface{} {
+	counts := make(map[string]int64)
+	for _, s := range publishedSnapshots() {
+		for code, n := range s.Responses {
+			counts[strconv.Itoa(code)] += n
+		}
+	}
+	return counts
+}
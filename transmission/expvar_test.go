@@ -0,0 +1,56 @@
+package transmission
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombPublishExpvars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":202},{"status":400,"error":"bad"}]`))
+	}))
+	defer server.Close()
+
+	h := &Honeycomb{
+		MaxBatchSize:         2,
+		BatchTimeout:         time.Hour,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		PublishExpvars:       true,
+	}
+	testOK(t, h.Start())
+	for i := 0; i < 2; i++ {
+		h.Add(&Event{
+			APIHost:   server.URL,
+			APIKey:    "key",
+			Dataset:   "ds",
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"a": i},
+		})
+	}
+	<-h.TxResponses()
+	<-h.TxResponses()
+
+	var published struct {
+		QueueDepth    int64            `json:"queue_depth"`
+		QueueOverflow int64            `json:"queue_overflow"`
+		Responses     map[string]int64 `json:"responses"`
+	}
+	v := expvar.Get(ExpvarName)
+	assert.NotNil(t, v)
+	testOK(t, json.Unmarshal([]byte(v.String()), &published))
+	assert.Equal(t, int64(0), published.QueueDepth)
+	assert.Equal(t, int64(0), published.QueueOverflow)
+	assert.Equal(t, map[string]int64{"202": 1, "400": 1}, published.Responses)
+
+	testOK(t, h.Stop())
+	published.Responses = nil
+	testOK(t, json.Unmarshal([]byte(v.String()), &published))
+	assert.Equal(t, 0, len(published.Responses), "stopped senders aren't published")
+}
@@ -0,0 +1,205 @@
+package transmission
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FallbackSender implements the Sender interface by sending events to Primary
+// and failing over to Secondary (eg a FileSender) when Primary looks unhealthy.
+// Health is judged from Primary's Responses: after FailureThreshold failures
+// in a row, new events go to Secondary instead. While failed over, a single
+// event is sent to Primary as a probe every ProbeInterval, and the first
+// healthy Response from Primary fails back. Responses from both children are
+// passed through on the FallbackSender's own channel. The children are started
+// and stopped along with the FallbackSender.
+type FallbackSender struct {
+	Primary   Sender
+	Secondary Sender
+
+	// FailureThreshold is how many failed Responses in a row mark Primary as
+	// unhealthy. Defaults to 5.
+	FailureThreshold int
+	// ProbeInterval is how often to try Primary again while failed over.
+	// Defaults to 30 seconds.
+	ProbeInterval time.Duration
+
+	BlockOnResponse   bool
+	ResponseQueueSize uint // defaults to total capacity of the children's response channels
+
+	responses chan Response
+	done      chan struct{}
+	stopped   stopSignal
+	wg        sync.WaitGroup
+
+	lock       sync.Mutex
+	failures   int
+	failedOver bool
+	nextProbe  time.Time
+
+	// allows manipulation of the value of "now" for testing
+	testNower nower
+}
+
+func (f *FallbackSender) Start() error {
+	if f.Primary == nil || f.Secondary == nil {
+		return errors.New("FallbackSender requires a Primary and a Secondary")
+	}
+	if f.FailureThreshold <= 0 {
+		f.FailureThreshold = 5
+	}
+	if f.ProbeInterval == 0 {
+		f.ProbeInterval = 30 * time.Second
+	}
+	if err := f.Primary.Start(); err != nil {
+		return err
+	}
+	if err := f.Secondary.Start(); err != nil {
+		return err
+	}
+	size := f.ResponseQueueSize
+	if size == 0 {
+		size = uint(cap(f.Primary.TxResponses()) + cap(f.Secondary.TxResponses()))
+	}
+	f.responses = make(chan Response, size)
+	f.done = make(chan struct{})
+	f.stopped.start()
+	f.wg.Add(2)
+	go func() {
+		defer f.wg.Done()
+		relayResponses(f.Primary.TxResponses(), f.done, f.relayPrimary)
+	}()
+	go func() {
+		defer f.wg.Done()
+		relayResponses(f.Secondary.TxResponses(), f.done, f.relaySecondary)
+	}()
+	return nil
+}
+
+// Stop stops both children, returning the first error either returned, then
+// closes the responses channel.
+func (f *FallbackSender) Stop() error {
+	err := f.Primary.Stop()
+	if serr := f.Secondary.Stop(); err == nil {
+		err = serr
+	}
+	if f.done != nil {
+		close(f.done)
+		f.wg.Wait()
+		f.stopped.stop(f.responses)
+		f.done = nil
+	}
+	return err
+}
+
+func (f *FallbackSender) Add(ev *Event) {
+	if f.usePrimary() {
+		f.Primary.Add(routeThrough(ev, f.relayPrimary))
+	} else {
+		f.Secondary.Add(routeThrough(ev, f.relaySecondary))
+	}
+}
+
+// relayPrimary passes on one of Primary's Responses, judging its health.
+func (f *FallbackSender) relayPrimary(r Response) {
+	f.observe(r)
+	f.SendResponse(r)
+}
+
+func (f *FallbackSender) relaySecondary(r Response) {
+	f.SendResponse(r)
+}
+
+// usePrimary reports whether the next event should go to Primary, either
+// because it's healthy or because it's time for a probe.
+func (f *FallbackSender) usePrimary() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.failedOver {
+		return true
+	}
+	if now := f.now(); !now.Before(f.nextProbe) {
+		f.nextProbe = now.Add(f.ProbeInterval)
+		return true
+	}
+	return false
+}
+
+// observe updates Primary's health from one of its Responses.
+func (f *FallbackSender) observe(r Response) {
+	if rejectedEvent(r.Err) {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if healthyResponse(r) {
+		f.failures = 0
+		f.failedOver = false
+		return
+	}
+	f.failures++
+	if !f.failedOver && f.failures >= f.FailureThreshold {
+		f.failedOver = true
+		f.nextProbe = f.now().Add(f.ProbeInterval)
+	}
+}
+
+// healthyResponse reports whether r shows the destination accepting events.
+// Rejections of individual events (eg a 400 for a malformed event) don't
+// count against health; failures to deliver at all, such as transport errors,
+// timeouts, throttling and server errors, do. Responses for events rejected
+// before they were sent are screened out beforehand by rejectedEvent.
+func healthyResponse(r Response) bool {
+	if r.Err != nil {
+		return false
+	}
+	return r.StatusCode != http.StatusTooManyRequests && r.StatusCode < 500
+}
+
+// rejectedEvent reports whether err is about a single event rather than the
+// destination: one dropped before it was sent (a full queue, the rate limit,
+// a filter, too old), one that couldn't be encoded or was too large, or a
+// rejected API key. These say nothing about whether the destination is up,
+// so they neither count against its health nor towards it.
+func rejectedEvent(err error) bool {
+	switch err.(type) {
+	case *EventTooLargeError, *json.UnsupportedTypeError, *json.UnsupportedValueError, *json.MarshalerError:
+		return true
+	}
+	switch err {
+	case ErrQueueOverflow, ErrEventTooLarge, ErrRateLimited, ErrEventStale, ErrUnauthorized, errFiltered:
+		return true
+	}
+	return false
+}
+
+// FailedOver reports whether events are currently being sent to Secondary.
+func (f *FallbackSender) FailedOver() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.failedOver
+}
+
+func (f *FallbackSender) now() time.Time {
+	if f.testNower != nil {
+		return f.testNower.Now()
+	}
+	return time.Now()
+}
+
+func (f *FallbackSender) TxResponses() chan Response {
+	return f.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (f *FallbackSender) Done() <-chan struct{} {
+	return f.stopped.done()
+}
+
+func (f *FallbackSender) SendResponse(r Response) bool {
+	return f.stopped.writeResponse(f.responses, r, f.BlockOnResponse, nil, nil)
+}
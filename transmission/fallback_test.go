@@ -0,0 +1,129 @@
+package transmission
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFallbackSenderFailover(t *testing.T) {
+	primary := &MockSender{BlockOnResponses: true}
+	secondary := &MockSender{}
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	f := &FallbackSender{
+		Primary:           primary,
+		Secondary:         secondary,
+		FailureThreshold:  2,
+		ProbeInterval:     time.Minute,
+		ResponseQueueSize: 10,
+		testNower:         nower,
+	}
+	testOK(t, f.Start())
+
+	f.Add(&Event{Metadata: 1})
+	assert.Equal(t, 1, len(primary.Events()))
+
+	// a rejected event isn't a sign of ill health
+	primary.SendResponse(Response{StatusCode: 400})
+	primary.SendResponse(Response{Err: errors.New("boom")})
+	waitFor(t, func() bool { return len(f.TxResponses()) == 2 })
+	assert.False(t, f.FailedOver())
+
+	primary.SendResponse(Response{StatusCode: 503})
+	waitFor(t, f.FailedOver)
+
+	f.Add(&Event{Metadata: 2})
+	assert.Equal(t, 1, len(primary.Events()))
+	assert.Equal(t, 1, len(secondary.Events()))
+
+	// after the probe interval one event goes to the primary
+	nower.now = nower.now.Add(time.Minute)
+	f.Add(&Event{Metadata: 3})
+	f.Add(&Event{Metadata: 4})
+	assert.Equal(t, 2, len(primary.Events()))
+	assert.Equal(t, 2, len(secondary.Events()))
+
+	primary.SendResponse(Response{StatusCode: 202})
+	waitFor(t, func() bool { return !f.FailedOver() })
+	f.Add(&Event{Metadata: 5})
+	assert.Equal(t, 3, len(primary.Events()))
+
+	testOK(t, f.Stop())
+	assert.Equal(t, 1, primary.Stopped)
+	assert.Equal(t, 1, secondary.Stopped)
+	n := 0
+	for range f.TxResponses() {
+		n++
+	}
+	assert.Equal(t, 4, n)
+}
+
+func TestFallbackSenderRequiresChildren(t *testing.T) {
+	testErr(t, (&FallbackSender{Primary: &MockSender{}}).Start())
+}
+
+func TestFallbackSenderSeesRoutedResponses(t *testing.T) {
+	primary := &MockSender{}
+	f := &FallbackSender{
+		Primary:          primary,
+		Secondary:        &MockSender{},
+		FailureThreshold: 2,
+	}
+	testOK(t, f.Start())
+	var routed []Response
+	route := func(meta interface{}) *ResponseRoute {
+		return &ResponseRoute{Metadata: meta, Callback: func(r Response) { routed = append(routed, r) }}
+	}
+	f.Add(&Event{Metadata: route(1)})
+	f.Add(&Event{Metadata: route(2)})
+	// the primary fails both, answering them the way every Sender does
+	for _, ev := range primary.Events() {
+		primary.SendResponse(Response{Err: errors.New("boom"), Metadata: ev.Metadata})
+	}
+	assert.True(t, f.FailedOver(), "routed failures should count against the primary")
+	assert.Equal(t, 2, len(routed))
+	assert.Equal(t, 1, routed[0].Metadata)
+	assert.Equal(t, 0, len(f.TxResponses()), "routed responses don't go to the channel")
+	testOK(t, f.Stop())
+}
+
+func TestFallbackSenderIgnoresRejectedEvents(t *testing.T) {
+	primary := &MockSender{BlockOnResponses: true}
+	f := &FallbackSender{
+		Primary:           primary,
+		Secondary:         &MockSender{},
+		FailureThreshold:  2,
+		ResponseQueueSize: 10,
+	}
+	testOK(t, f.Start())
+	for _, err := range []error{
+		&EventTooLargeError{Limit: 100, Destination: "API"},
+		&EventTooLargeError{Limit: 100, Destination: "API"},
+		ErrQueueOverflow,
+		ErrRateLimited,
+		ErrEventStale,
+	} {
+		primary.SendResponse(Response{Err: err})
+	}
+	waitFor(t, func() bool { return len(f.TxResponses()) == 5 })
+	assert.False(t, f.FailedOver(), "oversized or dropped events shouldn't trip failover")
+
+	// nor do they reset the count of real failures
+	primary.SendResponse(Response{StatusCode: 503})
+	primary.SendResponse(Response{Err: ErrQueueOverflow})
+	primary.SendResponse(Response{StatusCode: 503})
+	waitFor(t, f.FailedOver)
+	testOK(t, f.Stop())
+}
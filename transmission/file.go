@@ -0,0 +1,379 @@
+package transmission
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is the timestamp appended to the names of rotated files.
+// It sorts lexically in time order.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// FileSender implements the Sender interface by appending events as
+// newline-delimited JSON (the same format as WriterSender) to the file at
+// Path. The file is rotated once it grows past MaxSizeBytes or gets older than
+// MaxAge; rotated files are renamed to "<Path>.<timestamp>", optionally
+// gzipped, and pruned down to MaxBackups. This makes it suitable as a durable
+// local event log.
+type FileSender struct {
+	// Path of the file to write to. Required. The directory must exist.
+	Path string
+	// MaxSizeBytes, if non-zero, rotates the file before a write would take
+	// it past this size.
+	MaxSizeBytes int64
+	// MaxAge, if non-zero, rotates the file once it has been open this long.
+	MaxAge time.Duration
+	// Compress gzips rotated files, adding a .gz suffix. This happens in the
+	// background so that writes aren't held up; Stop waits for it to finish.
+	Compress bool
+	// MaxBackups, if non-zero, is how many rotated files to keep; older ones
+	// are deleted.
+	MaxBackups int
+	// Encryption, if set, encrypts each record at rest. Read the file back
+	// with the same cipher in SpoolOptions.
+	Encryption *SpoolCipher
+	// Checksums writes a header at the start of each file and a CRC-32C in
+	// front of each record, so that records torn by a crash mid-write are
+	// detected and skipped when the file is read back. See VerifySpool and
+	// RepairSpool.
+	Checksums bool
+
+	BlockOnResponses  bool
+	ResponseQueueSize uint
+	responses         chan Response
+	stopped           stopSignal
+
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// compressing tracks background compression of rotated files, which
+	// compressLock serializes. compressErr is the first error it hit, to be
+	// returned from Stop. uncompressed holds the rotated files still waiting
+	// for or being compressed, which prune leaves alone.
+	compressing      sync.WaitGroup
+	compressLock     sync.Mutex
+	compressErr      error
+	uncompressedLock sync.Mutex
+	uncompressed     map[string]bool
+
+	// allows manipulation of the value of "now" for testing
+	testNower nower
+
+	sync.Mutex
+}
+
+func (f *FileSender) Start() error {
+	if f.Path == "" {
+		return errors.New("FileSender requires a Path")
+	}
+	if f.Encryption != nil {
+		if err := f.Encryption.check(); err != nil {
+			return err
+		}
+	}
+	if f.ResponseQueueSize == 0 {
+		f.ResponseQueueSize = 100
+	}
+	f.responses = make(chan Response, f.ResponseQueueSize)
+	f.stopped.start()
+	f.Lock()
+	defer f.Unlock()
+	return f.open()
+}
+
+// Stop closes the file, waits for rotated files to be compressed and then
+// closes the responses channel. Events are written synchronously, so there is
+// nothing to flush.
+func (f *FileSender) Stop() error {
+	f.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.Unlock()
+	f.compressing.Wait()
+	f.compressLock.Lock()
+	defer f.compressLock.Unlock()
+	if err == nil {
+		err = f.compressErr
+	}
+	f.compressErr = nil
+	f.stopped.stop(f.responses)
+	return err
+}
+
+func (f *FileSender) Add(ev *Event) {
+	m, err := marshalWithDataset(ev)
+	if err == nil && f.Encryption != nil {
+		m, err = f.Encryption.seal(m)
+	}
+	if err == nil && f.Checksums {
+		m = checksumRecord(m)
+	}
+	if err == nil {
+		m = append(m, '\n')
+		err = f.write(m)
+	}
+	f.SendResponse(Response{
+		Err:      err,
+		Metadata: ev.Metadata,
+	})
+}
+
+func (f *FileSender) write(m []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.file == nil {
+		return errors.New("FileSender is not started")
+	}
+	if f.shouldRotate(len(m)) {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	return f.writeRaw(m)
+}
+
+func (f *FileSender) now() time.Time {
+	if f.testNower != nil {
+		return f.testNower.Now()
+	}
+	return time.Now()
+}
+
+func (f *FileSender) shouldRotate(next int) bool {
+	if f.size == 0 || (f.Checksums && f.size == int64(len(spoolSegmentHeader)+1)) {
+		// never rotate an empty file, even if a single event is over the limit
+		return false
+	}
+	if f.MaxSizeBytes > 0 && f.size+int64(next) > f.MaxSizeBytes {
+		return true
+	}
+	return f.MaxAge > 0 && f.now().Sub(f.opened) >= f.MaxAge
+}
+
+func (f *FileSender) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = f.now()
+	if f.size == 0 && f.Checksums {
+		return f.writeRaw([]byte(spoolSegmentHeader + "\n"))
+	}
+	if f.size > 0 {
+		return f.endTornRecord()
+	}
+	return nil
+}
+
+// writeRaw writes m to the open file. The caller must hold the lock.
+func (f *FileSender) writeRaw(m []byte) error {
+	n, err := f.file.Write(m)
+	f.size += int64(n)
+	return err
+}
+
+// endTornRecord terminates a last record left without its newline, eg by a
+// crash mid-write, so that it doesn't run into the next one. The caller must
+// hold the lock.
+func (f *FileSender) endTornRecord() error {
+	last := make([]byte, 1)
+	r, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := r.ReadAt(last, f.size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	return f.writeRaw([]byte{'\n'})
+}
+
+// rotate moves the current file aside and opens a fresh one. If the file
+// can't be moved it's reopened, so that later writes still have somewhere to
+// go and retry the rotation. The caller must hold the lock.
+func (f *FileSender) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	rotated := f.Path + "." + f.now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(f.Path, rotated); err != nil {
+		if reopenErr := f.open(); reopenErr != nil {
+			return reopenErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if f.Compress {
+		f.setUncompressed(rotated, true)
+		f.compressing.Add(1)
+		go f.compress(rotated)
+		return nil
+	}
+	return f.prune()
+}
+
+// compress gzips a rotated file and then prunes old ones, recording the first
+// error for Stop. It runs in the background.
+func (f *FileSender) compress(rotated string) {
+	defer f.compressing.Done()
+	f.compressLock.Lock()
+	defer f.compressLock.Unlock()
+	err := gzipFile(rotated)
+	f.setUncompressed(rotated, false)
+	if os.IsNotExist(err) {
+		// already pruned while waiting its turn
+		err = nil
+	}
+	if err == nil {
+		err = f.prune()
+	}
+	if err != nil && f.compressErr == nil {
+		f.compressErr = err
+	}
+}
+
+func (f *FileSender) setUncompressed(rotated string, uncompressed bool) {
+	f.uncompressedLock.Lock()
+	defer f.uncompressedLock.Unlock()
+	if f.uncompressed == nil {
+		f.uncompressed = make(map[string]bool)
+	}
+	if uncompressed {
+		f.uncompressed[rotated] = true
+	} else {
+		delete(f.uncompressed, rotated)
+	}
+}
+
+func (f *FileSender) isUncompressed(rotated string) bool {
+	f.uncompressedLock.Lock()
+	defer f.uncompressedLock.Unlock()
+	return f.uncompressed[rotated]
+}
+
+// prune deletes the oldest rotated files beyond MaxBackups. Files still to be
+// compressed are skipped; they're pruned once they have been.
+func (f *FileSender) prune() error {
+	if f.MaxBackups <= 0 {
+		return nil
+	}
+	rotated, err := rotatedFiles(f.Path)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, r := range rotated {
+		if !f.isUncompressed(r) {
+			backups = append(backups, r)
+		}
+	}
+	if len(backups) <= f.MaxBackups {
+		return nil
+	}
+	for _, old := range backups[:len(backups)-f.MaxBackups] {
+		for _, name := range []string{old, old + ".gz"} {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rotatedFiles returns the names, in time order and without any .gz suffix,
+// that the file at path has been rotated to. Other files with path as a
+// prefix, eg a lock file, aren't included.
+func rotatedFiles(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	seen := make(map[string]bool)
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || entry.IsDir() {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err != nil || len(stamp) != len(rotatedTimeFormat) {
+			continue
+		}
+		// a file and its .gz, mid compression, are one backup
+		name = filepath.Join(filepath.Dir(path), prefix+stamp)
+		if !seen[name] {
+			seen[name] = true
+			rotated = append(rotated, name)
+		}
+	}
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// gzipFile replaces the file at path with a gzipped copy at path+".gz".
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	g := gzip.NewWriter(out)
+	if _, err := io.Copy(g, in); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := g.Close(); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (f *FileSender) TxResponses() chan Response {
+	return f.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (f *FileSender) Done() <-chan struct{} {
+	return f.stopped.done()
+}
+
+func (f *FileSender) SendResponse(r Response) bool {
+	return f.stopped.writeResponse(f.responses, r, f.BlockOnResponses, nil, nil)
+}
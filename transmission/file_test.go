@@ -0,0 +1,162 @@
+package transmission
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type manualNower struct {
+	now time.Time
+}
+
+func (m *manualNower) Now() time.Time { return m.now }
+
+func tempLogPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "libhoney-file-sender")
+	testOK(t, err)
+	return filepath.Join(dir, "events.log")
+}
+
+func TestFileSenderWrites(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	f := &FileSender{Path: path}
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "ds", Metadata: "m", Data: map[string]interface{}{"a": 1}})
+	testOK(t, f.Stop())
+
+	contents, err := ioutil.ReadFile(path)
+	testOK(t, err)
+	assert.Equal(t, "{\"data\":{\"a\":1},\"dataset\":\"ds\"}\n", string(contents))
+	r := <-f.TxResponses()
+	assert.Nil(t, r.Err)
+	assert.Equal(t, "m", r.Metadata)
+
+	// restarting appends to the existing file
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"b": 2}})
+	testOK(t, f.Stop())
+	contents, _ = ioutil.ReadFile(path)
+	assert.Equal(t, 2, strings.Count(string(contents), "\n"))
+}
+
+func TestFileSenderRotatesBySize(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	f := &FileSender{
+		Path:              path,
+		MaxSizeBytes:      50,
+		Compress:          true,
+		MaxBackups:        2,
+		ResponseQueueSize: 10,
+		testNower:         nower,
+	}
+	testOK(t, f.Start())
+	for i := 0; i < 5; i++ {
+		// each event is 30 bytes, so every event after the first rotates
+		f.Add(&Event{Data: map[string]interface{}{"n": strings.Repeat("x", 10)}})
+		nower.now = nower.now.Add(time.Second)
+	}
+	testOK(t, f.Stop())
+
+	rotated, _ := filepath.Glob(path + ".*")
+	assert.Equal(t, 2, len(rotated), "retention should cap the number of rotated files")
+	for _, r := range rotated {
+		assert.True(t, strings.HasSuffix(r, ".gz"))
+		fh, err := os.Open(r)
+		testOK(t, err)
+		g, err := gzip.NewReader(fh)
+		testOK(t, err)
+		contents, _ := ioutil.ReadAll(g)
+		fh.Close()
+		assert.Equal(t, "{\"data\":{\"n\":\"xxxxxxxxxx\"}}\n", string(contents))
+	}
+}
+
+func TestFileSenderRotatesByAge(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	f := &FileSender{Path: path, MaxAge: time.Minute, testNower: nower}
+	testOK(t, f.Start())
+	f.Add(&Event{Data: map[string]interface{}{"a": 1}})
+	f.Add(&Event{Data: map[string]interface{}{"a": 2}})
+	rotated, _ := filepath.Glob(path + ".*")
+	assert.Equal(t, 0, len(rotated))
+
+	nower.now = nower.now.Add(2 * time.Minute)
+	f.Add(&Event{Data: map[string]interface{}{"a": 3}})
+	testOK(t, f.Stop())
+	rotated, _ = filepath.Glob(path + ".*")
+	assert.Equal(t, 1, len(rotated))
+	old, _ := ioutil.ReadFile(rotated[0])
+	assert.Equal(t, 2, strings.Count(string(old), "\n"))
+	current, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "{\"data\":{\"a\":3}}\n", string(current))
+}
+
+func TestFileSenderReopensAfterFailedRotation(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	// a non-empty directory in the way of the rotated name makes the rename fail
+	blocker := path + "." + nower.now.UTC().Format(rotatedTimeFormat)
+	testOK(t, os.MkdirAll(filepath.Join(blocker, "x"), 0755))
+	f := &FileSender{
+		Path:              path,
+		MaxSizeBytes:      20,
+		ResponseQueueSize: 10,
+		testNower:         nower,
+	}
+	testOK(t, f.Start())
+	f.Add(&Event{Data: map[string]interface{}{"a": 1}})
+	f.Add(&Event{Data: map[string]interface{}{"a": 2}})
+	nower.now = nower.now.Add(time.Second)
+	f.Add(&Event{Data: map[string]interface{}{"a": 3}})
+	testOK(t, f.Stop())
+
+	assert.Nil(t, (<-f.TxResponses()).Err)
+	assert.NotNil(t, (<-f.TxResponses()).Err, "the failed rotation should fail its event")
+	assert.Nil(t, (<-f.TxResponses()).Err, "later writes should go to the reopened file")
+	current, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "{\"data\":{\"a\":3}}\n", string(current))
+}
+
+func TestFileSenderPruneOnlyRotatedFiles(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	at := func(sec int64) string {
+		return path + "." + time.Unix(sec, 0).UTC().Format(rotatedTimeFormat)
+	}
+	files := []string{
+		path + ".lock",
+		at(1),
+		// mid compression: one backup, not two
+		at(2), at(2) + ".gz",
+		at(3) + ".gz",
+		// waiting to be compressed
+		at(4),
+	}
+	for _, name := range files {
+		testOK(t, ioutil.WriteFile(name, nil, 0644))
+	}
+	f := &FileSender{Path: path, MaxBackups: 1}
+	f.setUncompressed(at(4), true)
+	testOK(t, f.prune())
+
+	var left []string
+	for _, name := range files {
+		if _, err := os.Stat(name); err == nil {
+			left = append(left, name)
+		}
+	}
+	assert.Equal(t, []string{path + ".lock", at(3) + ".gz", at(4)}, left)
+}
@@ -0,0 +1,66 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+)
+
+// errFiltered is the Response error for events dropped by a FilterSender.
+var errFiltered = errors.New("event dropped by filter")
+
+// FilterSender wraps another Sender, passing on only the events for which
+// Filter returns true. Events that don't pass are dropped before they take up
+// any queue or batch space in the wrapped Sender, eg to keep health check
+// events or a noisy dataset out of Honeycomb.
+type FilterSender struct {
+	Sender Sender
+	Filter func(*Event) bool
+
+	// RespondToDropped sends a Response with an error for each event that is
+	// filtered out. By default filtered events get no Response.
+	RespondToDropped bool
+}
+
+// NewFilterSender returns a FilterSender passing events for which filter
+// returns true on to s.
+func NewFilterSender(s Sender, filter func(*Event) bool) *FilterSender {
+	return &FilterSender{Sender: s, Filter: filter}
+}
+
+func (f *FilterSender) Add(ev *Event) {
+	f.AddWithContext(context.Background(), ev)
+}
+
+func (f *FilterSender) AddWithContext(ctx context.Context, ev *Event) {
+	if f.Filter == nil || f.Filter(ev) {
+		AddWithContext(ctx, f.Sender, ev)
+		return
+	}
+	if f.RespondToDropped {
+		f.Sender.SendResponse(Response{
+			Err:      errFiltered,
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+func (f *FilterSender) Start() error {
+	return f.Sender.Start()
+}
+
+func (f *FilterSender) Stop() error {
+	return f.Sender.Stop()
+}
+
+func (f *FilterSender) TxResponses() chan Response {
+	return f.Sender.TxResponses()
+}
+
+// Done returns the wrapped Sender's Done channel. See DoneNotifier.
+func (f *FilterSender) Done() <-chan struct{} {
+	return Done(f.Sender)
+}
+
+func (f *FilterSender) SendResponse(r Response) bool {
+	return f.Sender.SendResponse(r)
+}
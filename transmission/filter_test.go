@@ -0,0 +1,28 @@
+package transmission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSender(t *testing.T) {
+	mock := &MockSender{}
+	f := NewFilterSender(mock, func(ev *Event) bool {
+		return ev.Dataset != "healthchecks"
+	})
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "requests"})
+	f.Add(&Event{Dataset: "healthchecks", Metadata: "dropped"})
+	assert.Equal(t, 1, len(mock.Events()))
+	assert.Equal(t, "requests", mock.Events()[0].Dataset)
+	assert.Equal(t, 0, len(f.TxResponses()), "dropped events get no response by default")
+
+	f.RespondToDropped = true
+	f.Add(&Event{Dataset: "healthchecks", Metadata: "dropped"})
+	rsp := testGetResponse(t, f.TxResponses())
+	testErr(t, rsp.Err)
+	assert.Equal(t, "dropped", rsp.Metadata)
+	testOK(t, f.Stop())
+	assert.Equal(t, 1, mock.Stopped)
+}
@@ -0,0 +1,189 @@
+package transmission
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hedgeSamples is how many recent batch latencies the threshold is
+	// taken from
+	hedgeSamples = 100
+	// hedgeMinSamples is how many latencies are needed before hedging starts
+	hedgeMinSamples = 20
+
+	// batchIDHeader identifies a batch across its retries and hedges, so
+	// that a copy the API or a proxy like Refinery has already accepted can
+	// be recognized and dropped
+	batchIDHeader = "X-Honeycomb-Batch-Id"
+)
+
+// newBatchID returns a random ID for batchIDHeader.
+func newBatchID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// hedger tracks recent batch request latencies to decide when a request has
+// been slow enough to hedge.
+type hedger struct {
+	percentile float64
+
+	lock      sync.Mutex
+	latencies [hedgeSamples]time.Duration
+	n, next   int
+}
+
+func (h *hedger) record(d time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % hedgeSamples
+	if h.n < hedgeSamples {
+		h.n++
+	}
+}
+
+// threshold returns how long to wait before hedging a request, and false
+// until enough latencies have been recorded.
+func (h *hedger) threshold() (time.Duration, bool) {
+	h.lock.Lock()
+	if h.n < hedgeMinSamples {
+		h.lock.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, h.n)
+	copy(sorted, h.latencies[:h.n])
+	h.lock.Unlock()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(h.percentile * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i], true
+}
+
+type hedgeResult struct {
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+	hedge  bool
+}
+
+// ok reports whether the result is worth using over the other request's.
+func (r hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode != http.StatusTooManyRequests && r.resp.StatusCode < 500
+}
+
+// discard cancels the request and throws away its response.
+func (r hedgeResult) discard() {
+	r.cancel()
+	if r.resp != nil {
+		io.Copy(ioutil.Discard, r.resp.Body)
+		r.resp.Body.Close()
+	}
+}
+
+// cancelOnClose releases a winning request's context once its response has
+// been read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// do sends the request made by newRequest for url. With hedging, if it takes
+// longer than the hedger's threshold the same batch is also sent to hedgeURL,
+// and the first good response is returned. The other request is cancelled
+// and its response thrown away, so its events only get Responses once, but
+// the server may already have accepted it; both carry the same batch ID.
+func (b *batchAgg) do(newRequest func(url string) *http.Request, url, hedgeURL string) (*http.Response, error) {
+	if b.hedger == nil {
+		return b.httpClient.Do(newRequest(url))
+	}
+	start := time.Now()
+	wait, ok := b.hedger.threshold()
+	results := make(chan hedgeResult, 2)
+	send := func(url string, hedge bool) context.CancelFunc {
+		req := newRequest(url)
+		ctx, cancel := context.WithCancel(req.Context())
+		go func() {
+			resp, err := b.httpClient.Do(req.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, cancel: cancel, hedge: hedge}
+		}()
+		return cancel
+	}
+	cancelPrimary := send(url, false)
+
+	var timeout <-chan time.Time
+	if ok {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r := <-results:
+		return b.hedgeWon(r, start)
+	case <-timeout:
+	}
+	b.metrics.Increment("send_hedged")
+	cancelHedge := send(hedgeURL, true)
+	first := <-results
+	if first.ok() {
+		// stop the slower request now rather than waiting for it
+		if first.hedge {
+			cancelPrimary()
+		} else {
+			cancelHedge()
+		}
+		go func() { (<-results).discard() }()
+		return b.hedgeWon(first, start)
+	}
+	second := <-results
+	if second.ok() {
+		first.discard()
+		return b.hedgeWon(second, start)
+	}
+	second.discard()
+	return b.hedgeWon(first, start)
+}
+
+// hedgeWon records the winning result's latency and returns its response.
+func (b *batchAgg) hedgeWon(r hedgeResult, start time.Time) (*http.Response, error) {
+	if r.err != nil {
+		r.cancel()
+		return nil, r.err
+	}
+	b.hedger.record(time.Since(start))
+	if r.hedge {
+		b.metrics.Increment("hedge_won")
+	}
+	r.resp.Body = cancelOnClose{ReadCloser: r.resp.Body, cancel: r.cancel}
+	return r.resp, nil
+}
+
+// hedgeURL returns where to send a hedge of a batch bound for batchURL: the
+// fallback API host if there is one, or else batchURL itself.
+func (b *batchAgg) hedgeURL(batchURL, dataset string) string {
+	if b.hedger == nil || b.fallbackAPIHost == "" {
+		return batchURL
+	}
+	u, err := APIURL(b.fallbackAPIHost, "1", "batch", dataset)
+	if err != nil {
+		return batchURL
+	}
+	return u.String()
+}
@@ -0,0 +1,140 @@
+package transmission
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgerThreshold(t *testing.T) {
+	h := &hedger{percentile: 0.9}
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := h.threshold()
+	assert.False(t, ok, "no hedging until there are enough samples")
+	for i := hedgeMinSamples; i <= hedgeSamples+10; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	wait, ok := h.threshold()
+	assert.True(t, ok)
+	// only the most recent hedgeSamples, 11ms to 110ms, are kept
+	assert.Equal(t, 101*time.Millisecond, wait)
+}
+
+// stallingRoundTripper holds requests to stallHost until they're cancelled,
+// and delays the rest by delay.
+type stallingRoundTripper struct {
+	stallHost string
+	delay     time.Duration
+	lock      sync.Mutex
+	hosts     []string
+	batchIDs  []string
+	cancelled chan struct{}
+}
+
+func (s *stallingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	s.lock.Lock()
+	s.hosts = append(s.hosts, r.URL.Host)
+	s.batchIDs = append(s.batchIDs, r.Header.Get(batchIDHeader))
+	s.lock.Unlock()
+	if r.URL.Host == s.stallHost {
+		<-r.Context().Done()
+		close(s.cancelled)
+		return nil, r.Context().Err()
+	}
+	time.Sleep(s.delay)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202}]`)),
+	}, nil
+}
+
+func TestFireBatchHedgesSlowRequests(t *testing.T) {
+	rt := &stallingRoundTripper{stallHost: "primary:8080", cancelled: make(chan struct{})}
+	metrics := &countingMetrics{}
+	b := newRetryTestBatch(rt, 0, nil)
+	b.metrics = metrics
+	b.fallbackAPIHost = "http://fallback:8080"
+	b.hedger = &hedger{percentile: 0.95}
+	for i := 0; i < hedgeMinSamples; i++ {
+		b.hedger.record(time.Millisecond)
+	}
+
+	b.fireBatch([]*Event{{APIHost: "http://primary:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}}})
+	select {
+	case <-rt.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the slow request should be cancelled")
+	}
+	assert.Equal(t, []string{"primary:8080", "fallback:8080"}, rt.hosts)
+	assert.Equal(t, 2, len(rt.batchIDs))
+	assert.NotEmpty(t, rt.batchIDs[0])
+	assert.Equal(t, rt.batchIDs[0], rt.batchIDs[1], "both copies should carry the same batch ID")
+	assert.Equal(t, 1, len(b.responses), "each event should get one response")
+	r := <-b.responses
+	assert.Nil(t, r.Err)
+	assert.Equal(t, 202, r.StatusCode)
+	assert.Equal(t, 1, metrics.counts["send_hedged"])
+	assert.Equal(t, 1, metrics.counts["hedge_won"])
+}
+
+func TestFireBatchCancelsSlowerHedge(t *testing.T) {
+	// the primary is slow enough to be hedged but still answers first, so
+	// the hedge is the request that's cancelled after being received
+	rt := &stallingRoundTripper{stallHost: "fallback:8080", delay: 50 * time.Millisecond, cancelled: make(chan struct{})}
+	metrics := &countingMetrics{}
+	b := newRetryTestBatch(rt, 0, nil)
+	b.metrics = metrics
+	b.fallbackAPIHost = "http://fallback:8080"
+	b.hedger = &hedger{percentile: 0.95}
+	for i := 0; i < hedgeMinSamples; i++ {
+		b.hedger.record(time.Millisecond)
+	}
+
+	b.fireBatch([]*Event{{APIHost: "http://primary:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}}})
+	select {
+	case <-rt.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the slower hedge should be cancelled")
+	}
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	assert.Equal(t, []string{"primary:8080", "fallback:8080"}, rt.hosts)
+	assert.Equal(t, rt.batchIDs[0], rt.batchIDs[1])
+	assert.Equal(t, 1, len(b.responses), "each event should get one response")
+	assert.Nil(t, (<-b.responses).Err)
+	assert.Equal(t, 1, metrics.counts["send_hedged"])
+	assert.Equal(t, 0, metrics.counts["hedge_won"])
+}
+
+func TestFireBatchSendsNewBatchIDs(t *testing.T) {
+	rt := &stallingRoundTripper{cancelled: make(chan struct{})}
+	b := newRetryTestBatch(rt, 0, nil)
+	for i := 0; i < 2; i++ {
+		b.fireBatch([]*Event{{APIHost: "http://primary:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}}})
+		<-b.responses
+	}
+	assert.Equal(t, 2, len(rt.batchIDs))
+	assert.Len(t, rt.batchIDs[0], 32)
+	assert.NotEqual(t, rt.batchIDs[0], rt.batchIDs[1])
+}
+
+func TestFireBatchDoesNotHedgeFastRequests(t *testing.T) {
+	rt := &stallingRoundTripper{cancelled: make(chan struct{})}
+	b := newRetryTestBatch(rt, 0, nil)
+	b.hedger = &hedger{percentile: 0.95}
+	for i := 0; i < hedgeMinSamples; i++ {
+		b.hedger.record(time.Second)
+	}
+	b.fireBatch([]*Event{{APIHost: "http://primary:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}}})
+	assert.Equal(t, []string{"primary:8080"}, rt.hosts)
+	assert.Nil(t, (<-b.responses).Err)
+}
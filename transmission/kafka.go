@@ -0,0 +1,91 @@
+package transmission
+
+import (
+	"errors"
+)
+
+// KafkaProducer is the part of a Kafka client that KafkaSender needs. It
+// should synchronously publish a single message and return any error. A thin
+// adapter around sarama's SyncProducer or segmentio's kafka.Writer is enough
+// to satisfy it; libhoney deliberately doesn't depend on any Kafka client.
+type KafkaProducer interface {
+	Produce(topic string, key, value []byte) error
+}
+
+// KafkaProducerFunc adapts an ordinary function to the KafkaProducer
+// interface.
+type KafkaProducerFunc func(topic string, key, value []byte) error
+
+// Produce calls f(topic, key, value).
+func (f KafkaProducerFunc) Produce(topic string, key, value []byte) error {
+	return f(topic, key, value)
+}
+
+// KafkaSender implements the Sender interface by publishing each event as a
+// JSON-encoded message to a Kafka topic, keyed by the event's dataset so that
+// a dataset's events stay ordered within a partition. Messages are produced
+// from a background goroutine; one Response is generated per event.
+type KafkaSender struct {
+	// Producer publishes the encoded events. Required.
+	Producer KafkaProducer
+	// Topic is the Kafka topic events are published to. Required.
+	Topic string
+
+	PendingWorkCapacity uint // how many events to allow to pile up. Defaults to DefaultPendingWorkCapacity
+	BlockOnSend         bool // whether to block or drop events when the queue fills
+	BlockOnResponse     bool // whether to block or drop responses when the queue fills
+
+	Logger  Logger
+	Metrics Metrics
+
+	queue publishQueue
+}
+
+func (k *KafkaSender) Start() error {
+	if k.Producer == nil {
+		return errors.New("KafkaSender requires a Producer")
+	}
+	if k.Topic == "" {
+		return errors.New("KafkaSender requires a Topic")
+	}
+	k.queue.publish = k.produce
+	k.queue.blockOnSend = k.BlockOnSend
+	k.queue.blockOnResponse = k.BlockOnResponse
+	k.queue.logger = k.Logger
+	k.queue.metrics = k.Metrics
+	k.queue.start(k.PendingWorkCapacity)
+	return nil
+}
+
+// Stop waits for all queued events to be produced, then closes the responses
+// channel.
+func (k *KafkaSender) Stop() error {
+	k.queue.stop()
+	return nil
+}
+
+func (k *KafkaSender) Add(ev *Event) {
+	k.queue.add(ev)
+}
+
+func (k *KafkaSender) produce(ev *Event) error {
+	value, err := marshalWithDataset(ev)
+	if err != nil {
+		return err
+	}
+	return k.Producer.Produce(k.Topic, []byte(ev.Dataset), value)
+}
+
+func (k *KafkaSender) TxResponses() chan Response {
+	return k.queue.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (k *KafkaSender) Done() <-chan struct{} {
+	return k.queue.stopped.done()
+}
+
+func (k *KafkaSender) SendResponse(r Response) bool {
+	return k.queue.sendResponse(r)
+}
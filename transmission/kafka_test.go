@@ -0,0 +1,122 @@
+package transmission
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProducer struct {
+	sync.Mutex
+	topics []string
+	keys   []string
+	values []string
+	err    error
+}
+
+func (f *fakeProducer) Produce(topic string, key, value []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	f.topics = append(f.topics, topic)
+	f.keys = append(f.keys, string(key))
+	f.values = append(f.values, string(value))
+	return f.err
+}
+
+func TestKafkaSenderStartRequiresConfig(t *testing.T) {
+	assert.Error(t, (&KafkaSender{Topic: "t"}).Start())
+	assert.Error(t, (&KafkaSender{Producer: &fakeProducer{}}).Start())
+}
+
+func TestKafkaSender(t *testing.T) {
+	p := &fakeProducer{}
+	k := &KafkaSender{Producer: p, Topic: "telemetry"}
+	testOK(t, k.Start())
+
+	k.Add(&Event{Dataset: "ds1", Metadata: "m1", Data: map[string]interface{}{"a": 1}})
+	k.Add(&Event{Dataset: "ds2", Metadata: "m2", Data: map[string]interface{}{"b": 2}})
+	testOK(t, k.Stop())
+
+	assert.Equal(t, []string{"telemetry", "telemetry"}, p.topics)
+	assert.Equal(t, []string{"ds1", "ds2"}, p.keys)
+	assert.JSONEq(t, `{"data":{"a":1},"dataset":"ds1"}`, p.values[0])
+
+	var metas []interface{}
+	for r := range k.TxResponses() {
+		testOK(t, r.Err)
+		metas = append(metas, r.Metadata)
+	}
+	assert.Equal(t, []interface{}{"m1", "m2"}, metas)
+}
+
+func TestKafkaSenderProduceError(t *testing.T) {
+	p := &fakeProducer{err: errors.New("broker down")}
+	k := &KafkaSender{Producer: p, Topic: "telemetry"}
+	testOK(t, k.Start())
+	k.Add(&Event{Dataset: "ds1", Data: map[string]interface{}{"a": 1}})
+	k.Stop()
+	r := <-k.TxResponses()
+	assert.Equal(t, p.err, r.Err)
+}
+
+func TestKafkaSenderAddAfterStop(t *testing.T) {
+	p := &fakeProducer{}
+	k := &KafkaSender{Producer: p, Topic: "telemetry", BlockOnSend: true}
+	testOK(t, k.Start())
+	testOK(t, k.Stop())
+	testOK(t, k.Stop())
+
+	done := make(chan struct{})
+	go func() {
+		k.Add(&Event{Dataset: "ds1", Data: map[string]interface{}{"a": 1}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Add blocked after Stop")
+	}
+	assert.Empty(t, p.values)
+	_, open := <-k.TxResponses()
+	assert.False(t, open)
+}
+
+func TestKafkaSenderAddDuringStop(t *testing.T) {
+	k := &KafkaSender{Producer: &fakeProducer{}, Topic: "telemetry"}
+	testOK(t, k.Start())
+	k.queue.lock.Lock()
+	k.queue.stopping = true
+	k.queue.lock.Unlock()
+
+	k.Add(&Event{Dataset: "ds1", Metadata: "late"})
+	r := <-k.TxResponses()
+	assert.Equal(t, ErrSenderStopped, r.Err)
+	assert.Equal(t, "late", r.Metadata)
+}
+
+func TestKafkaSenderRestart(t *testing.T) {
+	p := &fakeProducer{}
+	k := &KafkaSender{Producer: p, Topic: "telemetry"}
+	testOK(t, k.Start())
+	testOK(t, k.Stop())
+	testOK(t, k.Start())
+
+	k.Add(&Event{Dataset: "ds1", Metadata: "after restart", Data: map[string]interface{}{"a": 1}})
+	select {
+	case r := <-k.TxResponses():
+		testOK(t, r.Err)
+		assert.Equal(t, "after restart", r.Metadata)
+	case <-time.After(time.Second):
+		t.Fatal("no response after restart")
+	}
+	testOK(t, k.Stop())
+	select {
+	case <-k.Done():
+	case <-time.After(time.Second):
+		t.Fatal("second Stop should stop the sender")
+	}
+	assert.Equal(t, 1, len(p.values))
+}
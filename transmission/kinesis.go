@@ -0,0 +1,259 @@
+package transmission
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/facebookgo/muster"
+)
+
+// Limits imposed by the Firehose PutRecordBatch API
+const (
+	firehoseMaxRecordsPerCall = 500
+	firehoseMaxBytesPerCall   = 4 * 1024 * 1024
+	firehoseMaxRecordSize     = 1000 * 1024
+)
+
+var firehoseLimit = batchLimit{
+	destination:   "Firehose",
+	maxEvents:     firehoseMaxRecordsPerCall,
+	maxBytes:      firehoseMaxBytesPerCall,
+	maxEventBytes: firehoseMaxRecordSize,
+}
+
+// FirehoseClient is the part of an AWS Kinesis Data Firehose client that
+// KinesisSender needs. PutRecordBatch should send all records in one call and
+// return, alongside any error for the call as a whole, a slice holding the
+// error (or nil) for each record in the order they were passed. A small
+// adapter around the AWS SDK's PutRecordBatch is enough to satisfy it.
+type FirehoseClient interface {
+	PutRecordBatch(streamName string, records [][]byte) ([]error, error)
+}
+
+// KinesisSender implements the Sender interface by batching events and
+// sending them to an AWS Kinesis Data Firehose delivery stream. Each record is
+// one newline-terminated JSON-encoded event, including its dataset. Batches
+// that exceed the PutRecordBatch limits are split across multiple calls.
+type KinesisSender struct {
+	// Client sends batches to Firehose. Required.
+	Client FirehoseClient
+	// StreamName is the name of the delivery stream. Required.
+	StreamName string
+
+	MaxBatchSize         uint          // how many events to collect in a batch before sending. Capped at 500.
+	BatchTimeout         time.Duration // how often to send off batches
+	MaxConcurrentBatches uint          // how many batches can be inflight simultaneously
+	PendingWorkCapacity  uint          // how many events to allow to pile up
+	BlockOnSend          bool          // whether to block or drop events when the queue fills
+	BlockOnResponse      bool          // whether to block or drop responses when the queue fills
+
+	Logger  Logger
+	Metrics Metrics
+
+	muster    muster.Client
+	responses chan Response
+	stopped   stopSignal
+	// lock guards stopping, so that Add doesn't send on the queue once Stop
+	// has closed it
+	lock     sync.RWMutex
+	stopping bool
+}
+
+func (k *KinesisSender) Start() error {
+	if k.Client == nil {
+		return errors.New("KinesisSender requires a Client")
+	}
+	if k.StreamName == "" {
+		return errors.New("KinesisSender requires a StreamName")
+	}
+	if k.Logger == nil {
+		k.Logger = &nullLogger{}
+	}
+	if k.Metrics == nil {
+		k.Metrics = &nullMetrics{}
+	}
+	if k.MaxBatchSize == 0 || k.MaxBatchSize > firehoseMaxRecordsPerCall {
+		k.MaxBatchSize = firehoseMaxRecordsPerCall
+	}
+	if k.BatchTimeout == 0 {
+		k.BatchTimeout = time.Second
+	}
+	if k.MaxConcurrentBatches == 0 {
+		k.MaxConcurrentBatches = 10
+	}
+	if k.PendingWorkCapacity == 0 {
+		k.PendingWorkCapacity = DefaultPendingWorkCapacity
+	}
+	k.Logger.Printf("kinesis firehose transmission starting")
+	k.responses = make(chan Response, k.PendingWorkCapacity*2)
+	k.stopped.start()
+	k.lock.Lock()
+	k.stopping = false
+	k.lock.Unlock()
+	k.muster.MaxBatchSize = k.MaxBatchSize
+	k.muster.BatchTimeout = k.BatchTimeout
+	k.muster.MaxConcurrentBatches = k.MaxConcurrentBatches
+	k.muster.PendingWorkCapacity = k.PendingWorkCapacity
+	k.muster.BatchMaker = func() muster.Batch {
+		return &firehoseBatch{sender: k}
+	}
+	return k.muster.Start()
+}
+
+func (k *KinesisSender) Stop() error {
+	k.lock.Lock()
+	if k.stopping {
+		k.lock.Unlock()
+		return nil
+	}
+	k.stopping = true
+	k.lock.Unlock()
+	k.Logger.Printf("kinesis firehose transmission stopping")
+	err := k.muster.Stop()
+	k.stopped.stop(k.responses)
+	return err
+}
+
+func (k *KinesisSender) Add(ev *Event) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	if k.stopping {
+		k.Metrics.Increment("add_after_stop")
+		NewLeveledLogger(k.Logger).Warnf(Fields{"dataset": ev.Dataset}, "dropping event added to stopped sender")
+		// dropped, rather than sent, once the responses channel is closed
+		k.SendResponse(Response{
+			Err:      ErrSenderStopped,
+			Metadata: ev.Metadata,
+		})
+		return
+	}
+	if k.BlockOnSend {
+		k.muster.Work <- ev
+		k.Metrics.Increment("messages_queued")
+		return
+	}
+	select {
+	case k.muster.Work <- ev:
+		k.Metrics.Increment("messages_queued")
+	default:
+		k.Metrics.Increment("queue_overflow")
+		k.SendResponse(Response{
+			Err:      ErrQueueOverflow,
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+func (k *KinesisSender) TxResponses() chan Response {
+	return k.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (k *KinesisSender) Done() <-chan struct{} {
+	return k.stopped.done()
+}
+
+func (k *KinesisSender) SendResponse(r Response) bool {
+	return k.stopped.writeResponse(k.responses, r, k.BlockOnResponse, k.Logger, k.Metrics)
+}
+
+// firehoseBatch collects events for one muster batch. Unlike batchAgg it
+// doesn't need to group by destination, since every record goes to the same
+// delivery stream.
+type firehoseBatch struct {
+	sender *KinesisSender
+	events []*Event
+	// answered tracks the events that have had their Response
+	answered map[*Event]bool
+}
+
+func (f *firehoseBatch) Add(ev interface{}) {
+	f.events = append(f.events, ev.(*Event))
+}
+
+func (f *firehoseBatch) Fire(notifier muster.Notifier) {
+	defer notifier.Done()
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(f.sender.Logger, f.sender.Metrics, "Firehose send", p)
+			for _, ev := range f.events {
+				if !f.answered[ev] {
+					f.respond(ev, Response{Err: panicError(p)})
+				}
+			}
+		}
+	}()
+
+	// encode everything up front, erroring out events that can't be encoded
+	// or that are too large for Firehose to ever accept
+	var records [][]byte
+	var events []*Event
+	for _, ev := range f.events {
+		rec, err := marshalWithDataset(ev)
+		if err != nil {
+			f.respond(ev, Response{Err: err})
+			continue
+		}
+		rec = append(rec, '\n')
+		if err := firehoseLimit.check(len(rec)); err != nil {
+			f.respond(ev, Response{Err: err})
+			continue
+		}
+		records = append(records, rec)
+		events = append(events, ev)
+	}
+
+	// split into calls that fit under the per-call size and count limits
+	fill := batchFill{limit: firehoseLimit}
+	start := 0
+	for i, rec := range records {
+		if !fill.add(len(rec)) {
+			f.put(records[start:i], events[start:i])
+			start = i
+			fill.reset()
+			fill.add(len(rec))
+		}
+	}
+	if start < len(records) {
+		f.put(records[start:], events[start:])
+	}
+}
+
+func (f *firehoseBatch) put(records [][]byte, events []*Event) {
+	start := time.Now()
+	recErrs, err := f.sender.Client.PutRecordBatch(f.sender.StreamName, records)
+	dur := time.Since(start) / time.Duration(len(records))
+	if err != nil {
+		f.sender.Metrics.Increment("send_errors")
+	} else {
+		f.sender.Metrics.Increment("batches_sent")
+	}
+	var sent int
+	for i, ev := range events {
+		r := Response{
+			Err:      err,
+			Duration: dur,
+		}
+		if err == nil && i < len(recErrs) {
+			r.Err = recErrs[i]
+		}
+		if r.Err == nil {
+			sent++
+		}
+		f.respond(ev, r)
+	}
+	// records can fail individually even when the call succeeds
+	f.sender.Metrics.Count("messages_sent", sent)
+}
+
+// respond sends ev's Response.
+func (f *firehoseBatch) respond(ev *Event, r Response) {
+	if f.answered == nil {
+		f.answered = make(map[*Event]bool, len(f.events))
+	}
+	f.answered[ev] = true
+	r.Metadata = ev.Metadata
+	f.sender.SendResponse(r)
+}
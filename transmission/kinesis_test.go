@@ -0,0 +1,167 @@
+package transmission
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeFirehose struct {
+	sync.Mutex
+	calls   [][][]byte
+	recErrs []error
+	err     error
+}
+
+func (f *fakeFirehose) PutRecordBatch(stream string, records [][]byte) ([]error, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = append(f.calls, records)
+	return f.recErrs, f.err
+}
+
+func newTestFirehoseBatch(fh *fakeFirehose) (*firehoseBatch, *KinesisSender) {
+	k := &KinesisSender{
+		Client:     fh,
+		StreamName: "stream",
+		Logger:     &nullLogger{},
+		Metrics:    &nullMetrics{},
+		responses:  make(chan Response, 2000),
+	}
+	return &firehoseBatch{sender: k}, k
+}
+
+func TestKinesisSenderStartRequiresConfig(t *testing.T) {
+	assert.Error(t, (&KinesisSender{StreamName: "s"}).Start())
+	assert.Error(t, (&KinesisSender{Client: &fakeFirehose{}}).Start())
+}
+
+func TestKinesisSender(t *testing.T) {
+	fh := &fakeFirehose{}
+	k := &KinesisSender{Client: fh, StreamName: "stream"}
+	testOK(t, k.Start())
+	k.Add(&Event{Dataset: "ds", Metadata: 1, Data: map[string]interface{}{"a": 1}})
+	k.Add(&Event{Dataset: "ds", Metadata: 2, Data: map[string]interface{}{"b": 2}})
+	testOK(t, k.Stop())
+
+	assert.Equal(t, 1, len(fh.calls))
+	assert.Equal(t, 2, len(fh.calls[0]))
+	assert.Equal(t, "{\"data\":{\"a\":1},\"dataset\":\"ds\"}\n", string(fh.calls[0][0]))
+	var n int
+	for r := range k.TxResponses() {
+		testOK(t, r.Err)
+		n++
+	}
+	assert.Equal(t, 2, n)
+}
+
+func TestKinesisSenderAddAfterStop(t *testing.T) {
+	metrics := &countingMetrics{}
+	k := &KinesisSender{Client: &fakeFirehose{}, StreamName: "stream", Metrics: metrics}
+	testOK(t, k.Start())
+
+	// while stopping, the drop is reported
+	k.lock.Lock()
+	k.stopping = true
+	k.lock.Unlock()
+	k.Add(&Event{Dataset: "ds", Metadata: "stopping", Data: map[string]interface{}{"a": 1}})
+	r := <-k.TxResponses()
+	assert.Equal(t, ErrSenderStopped, r.Err)
+	assert.Equal(t, "stopping", r.Metadata)
+	k.lock.Lock()
+	k.stopping = false
+	k.lock.Unlock()
+
+	testOK(t, k.Stop())
+	testOK(t, k.Stop())
+	k.Add(&Event{Dataset: "ds", Metadata: "stopped", Data: map[string]interface{}{"a": 1}})
+	assert.Equal(t, 2, metrics.counts["add_after_stop"])
+	assert.Equal(t, 1, metrics.counts["responses_after_stop"], "no Response once TxResponses is closed")
+}
+
+func TestFirehoseBatchSplitsOnRecordCount(t *testing.T) {
+	fh := &fakeFirehose{}
+	b, _ := newTestFirehoseBatch(fh)
+	for i := 0; i < firehoseMaxRecordsPerCall+10; i++ {
+		b.Add(&Event{Data: map[string]interface{}{"i": i}})
+	}
+	b.Fire(&testNotifier{})
+	assert.Equal(t, 2, len(fh.calls))
+	assert.Equal(t, firehoseMaxRecordsPerCall, len(fh.calls[0]))
+	assert.Equal(t, 10, len(fh.calls[1]))
+}
+
+func TestFirehoseBatchSplitsOnSize(t *testing.T) {
+	fh := &fakeFirehose{}
+	b, _ := newTestFirehoseBatch(fh)
+	big := strings.Repeat("x", firehoseMaxRecordSize-100)
+	for i := 0; i < 6; i++ {
+		b.Add(&Event{Data: map[string]interface{}{"big": big}})
+	}
+	b.Fire(&testNotifier{})
+	assert.Equal(t, 2, len(fh.calls))
+	assert.Equal(t, 4, len(fh.calls[0]))
+	assert.Equal(t, 2, len(fh.calls[1]))
+}
+
+func TestFirehoseBatchErrors(t *testing.T) {
+	fh := &fakeFirehose{recErrs: []error{nil, errors.New("throttled")}}
+	b, k := newTestFirehoseBatch(fh)
+	stats := newSenderStats()
+	k.Metrics = recordMetrics(k.Metrics, stats)
+	b.Add(&Event{Metadata: "ok", Data: map[string]interface{}{"a": 1}})
+	b.Add(&Event{Metadata: "throttled", Data: map[string]interface{}{"a": 2}})
+	b.Add(&Event{Metadata: "huge", Data: map[string]interface{}{"a": strings.Repeat("x", firehoseMaxRecordSize)}})
+	b.Fire(&testNotifier{})
+
+	results := map[interface{}]error{}
+	for i := 0; i < 3; i++ {
+		r := <-k.responses
+		results[r.Metadata] = r.Err
+	}
+	assert.Nil(t, results["ok"])
+	assert.Equal(t, "throttled", results["throttled"].Error())
+	assert.Error(t, results["huge"])
+	assert.Equal(t, 1, len(fh.calls))
+	assert.Equal(t, 2, len(fh.calls[0]))
+	assert.Equal(t, int64(1), stats.snapshot().Counters["messages_sent"], "failed records aren't counted as sent")
+}
+
+// panickyFirehose accepts its first call and panics on the rest.
+type panickyFirehose struct {
+	calls int
+}
+
+func (p *panickyFirehose) PutRecordBatch(stream string, records [][]byte) ([]error, error) {
+	p.calls++
+	if p.calls > 1 {
+		panic("client bug")
+	}
+	return nil, nil
+}
+
+func TestFirehoseBatchPanicAnswersEachEventOnce(t *testing.T) {
+	b, k := newTestFirehoseBatch(nil)
+	k.Client = &panickyFirehose{}
+	for i := 0; i < firehoseMaxRecordsPerCall+10; i++ {
+		b.Add(&Event{Metadata: i, Data: map[string]interface{}{"i": i}})
+	}
+	b.Fire(&testNotifier{})
+	close(k.responses)
+	seen := map[interface{}]int{}
+	var panicked int
+	for r := range k.responses {
+		seen[r.Metadata]++
+		if r.Err != nil {
+			panicked++
+		}
+	}
+	assert.Equal(t, firehoseMaxRecordsPerCall+10, len(seen))
+	for _, n := range seen {
+		assert.Equal(t, 1, n, "each event should get exactly one response")
+	}
+	assert.Equal(t, 10, panicked, "only events in the call that panicked should fail")
+}
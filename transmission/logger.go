@@ -1,13 +1,96 @@
 package transmission
 
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
 type Logger interface {
 	// Printf accepts the same msg, args style as fmt.Printf().
 	Printf(msg string, args ...interface{})
 }
 
+// Fields is the key-value context of a LeveledLogger message.
+type Fields map[string]interface{}
+
+// LeveledLogger is a Logger that also logs at a severity, with key-value
+// context. Senders log through these methods when their Logger implements
+// them, so that eg overflows and rejected batches can be told apart from
+// routine debug output; other Loggers are adapted with NewLeveledLogger.
+type LeveledLogger interface {
+	Logger
+	Debugf(fields Fields, msg string, args ...interface{})
+	Warnf(fields Fields, msg string, args ...interface{})
+	Errorf(fields Fields, msg string, args ...interface{})
+}
+
+// NewLeveledLogger returns l as a LeveledLogger. Loggers with only Printf
+// get every message through it, prefixed with its level and followed by its
+// fields, eg "WARN queue overflow dataset=api".
+func NewLeveledLogger(l Logger) LeveledLogger {
+	switch l := l.(type) {
+	case nil:
+		return &nullLogger{}
+	case LeveledLogger:
+		return l
+	default:
+		return printfLogger{l}
+	}
+}
+
+// leveled returns the sender's Logger as a LeveledLogger.
+func (h *Honeycomb) leveled() LeveledLogger {
+	return NewLeveledLogger(h.Logger)
+}
+
+func (b *batchAgg) leveled() LeveledLogger {
+	return NewLeveledLogger(b.logger)
+}
+
+// printfLogger adapts a Logger with only Printf.
+type printfLogger struct {
+	Logger
+}
+
+func (p printfLogger) Debugf(fields Fields, msg string, args ...interface{}) {
+	p.log("DEBUG", fields, msg, args)
+}
+
+func (p printfLogger) Warnf(fields Fields, msg string, args ...interface{}) {
+	p.log("WARN", fields, msg, args)
+}
+
+func (p printfLogger) Errorf(fields Fields, msg string, args ...interface{}) {
+	p.log("ERROR", fields, msg, args)
+}
+
+func (p printfLogger) log(level string, fields Fields, msg string, args []interface{}) {
+	p.Printf("%s %s%s", level, fmt.Sprintf(msg, args...), FormatFields(fields))
+}
+
+// FormatFields formats fields as " key=value" pairs sorted by key, for
+// LeveledLoggers writing plain text.
+func FormatFields(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, " %s=%v", k, fields[k])
+	}
+	return buf.String()
+}
+
 type nullLogger struct{}
 
 // Printf swallows messages
 func (n *nullLogger) Printf(msg string, args ...interface{}) {
 	// nothing to see here.
 }
+
+func (n *nullLogger) Debugf(Fields, string, ...interface{}) {}
+func (n *nullLogger) Warnf(Fields, string, ...interface{})  {}
+func (n *nullLogger) Errorf(Fields, string, ...interface{}) {}
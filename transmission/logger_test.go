@@ -0,0 +1,108 @@
+package transmission
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// leveledRecorder records the level and fields of each message.
+type leveledRecorder struct {
+	nullLogger
+	lock sync.Mutex
+	msgs []string
+}
+
+func (l *leveledRecorder) record(level string, fields Fields, msg string, args []interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.msgs = append(l.msgs, level+" "+fmt.Sprintf(msg, args...)+FormatFields(fields))
+}
+
+func (l *leveledRecorder) Debugf(fields Fields, msg string, args ...interface{}) {
+	l.record("DEBUG", fields, msg, args)
+}
+
+func (l *leveledRecorder) Warnf(fields Fields, msg string, args ...interface{}) {
+	l.record("WARN", fields, msg, args)
+}
+
+func (l *leveledRecorder) Errorf(fields Fields, msg string, args ...interface{}) {
+	l.record("ERROR", fields, msg, args)
+}
+
+func (l *leveledRecorder) messages(prefix string) []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var found []string
+	for _, m := range l.msgs {
+		if strings.HasPrefix(m, prefix) {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+func TestNewLeveledLogger(t *testing.T) {
+	rec := &recordingLogger{}
+	l := NewLeveledLogger(rec)
+	l.Warnf(Fields{"status": 429, "dataset": "api"}, "retrying %d events", 3)
+	l.Debugf(nil, "100%% done")
+	assert.Equal(t, []string{"WARN retrying 3 events dataset=api status=429", "DEBUG 100% done"}, rec.msgs)
+
+	leveled := &leveledRecorder{}
+	assert.Equal(t, leveled, NewLeveledLogger(leveled), "leveled loggers are used as they are")
+	assert.NotNil(t, NewLeveledLogger(nil))
+}
+
+func TestHoneycombLogsAtLevels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"unknown API key"}`))
+	}))
+	defer server.Close()
+
+	logger := &leveledRecorder{}
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         10 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		Logger:               logger,
+	}
+	testOK(t, h.Start())
+	h.Add(&Event{
+		APIHost:   server.URL,
+		APIKey:    "bad",
+		Dataset:   "ds",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"a": 1},
+	})
+	assert.Equal(t, ErrUnauthorized, (<-h.TxResponses()).Err)
+	testOK(t, h.Stop())
+	h.Add(&Event{Dataset: "ds"})
+
+	assert.Equal(t, []string{`ERROR batch rejected: {"error":"unknown API key"} dataset=ds status=401`}, logger.messages("ERROR"))
+	assert.Equal(t, []string{"WARN dropping event added to stopped sender dataset=ds"}, logger.messages("WARN"))
+}
+
+func TestPanicsAndQueueDropsLogAtLevels(t *testing.T) {
+	logger := &leveledRecorder{}
+	runRecovered(logger, nil, "test worker", func() { panic("boom") })
+	errs := logger.messages("ERROR")
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, strings.HasPrefix(errs[0], "ERROR recovered from panic in test worker: boom stack="))
+	assert.Contains(t, errs[0], "where=test worker")
+
+	k := &KafkaSender{Producer: &fakeProducer{}, Topic: "telemetry", Logger: logger}
+	testOK(t, k.Start())
+	testOK(t, k.Stop())
+	k.Add(&Event{Dataset: "ds"})
+	assert.Equal(t, []string{"WARN dropping event added to stopped sender dataset=ds"}, logger.messages("WARN"))
+}
@@ -12,6 +12,7 @@ type MockSender struct {
 	EventsCalled     int
 	events           []*Event
 	responses        chan Response
+	stopped          stopSignal
 	BlockOnResponses bool
 	sync.Mutex
 }
@@ -25,10 +26,12 @@ func (m *MockSender) Add(ev *Event) {
 func (m *MockSender) Start() error {
 	m.Started += 1
 	m.responses = make(chan Response, 1)
+	m.stopped.start()
 	return nil
 }
 func (m *MockSender) Stop() error {
 	m.Stopped += 1
+	m.stopped.stop(m.responses)
 	return nil
 }
 
@@ -45,15 +48,12 @@ func (m *MockSender) TxResponses() chan Response {
 	return m.responses
 }
 
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (m *MockSender) Done() <-chan struct{} {
+	return m.stopped.done()
+}
+
 func (m *MockSender) SendResponse(r Response) bool {
-	if m.BlockOnResponses {
-		m.responses <- r
-	} else {
-		select {
-		case m.responses <- r:
-		default:
-			return true
-		}
-	}
-	return false
+	return m.stopped.writeResponse(m.responses, r, m.BlockOnResponses, nil, nil)
 }
@@ -0,0 +1,109 @@
+package transmission
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TestingT is the part of *testing.T that MockSender's assertions use.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// helper marks the caller as a test helper, on Go versions that have them.
+func helper(t TestingT) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+}
+
+// snapshot copies the added events without counting as a call to Events.
+func (m *MockSender) snapshot() []*Event {
+	m.Lock()
+	defer m.Unlock()
+	return append([]*Event(nil), m.events...)
+}
+
+// FindEventsWhere returns the added events that match reports true for, in
+// the order they were added.
+func (m *MockSender) FindEventsWhere(match func(*Event) bool) []*Event {
+	var found []*Event
+	for _, ev := range m.snapshot() {
+		if match(ev) {
+			found = append(found, ev)
+		}
+	}
+	return found
+}
+
+// EventsForDataset returns the added events bound for dataset.
+func (m *MockSender) EventsForDataset(dataset string) []*Event {
+	return m.FindEventsWhere(func(ev *Event) bool { return ev.Dataset == dataset })
+}
+
+// EventsWithMetadata returns the added events whose Metadata is deeply equal
+// to metadata.
+func (m *MockSender) EventsWithMetadata(metadata interface{}) []*Event {
+	return m.FindEventsWhere(func(ev *Event) bool { return reflect.DeepEqual(ev.Metadata, metadata) })
+}
+
+// AssertFieldEquals fails t unless the i'th event added has the field key,
+// deeply equal to want. Fields keep the type they were added with, so an int
+// field only equals an int.
+func (m *MockSender) AssertFieldEquals(t TestingT, i int, key string, want interface{}) bool {
+	helper(t)
+	events := m.snapshot()
+	if i < 0 || i >= len(events) {
+		t.Errorf("no event %d: %d events were added", i, len(events))
+		return false
+	}
+	got, ok := events[i].Data[key]
+	if !ok {
+		t.Errorf("event %d has no field %q", i, key)
+		return false
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("event %d field %q is %s, want %s", i, key, describe(got), describe(want))
+		return false
+	}
+	return true
+}
+
+// AssertEventsInOrder fails t unless, for each of matches in turn, an event
+// it reports true for was added after the events matching the ones before
+// it. Other events may come between them.
+func (m *MockSender) AssertEventsInOrder(t TestingT, matches ...func(*Event) bool) bool {
+	helper(t)
+	events := m.snapshot()
+	next := 0
+	for i, match := range matches {
+		for next < len(events) && !match(events[next]) {
+			next++
+		}
+		if next == len(events) {
+			t.Errorf("no event matching matcher %d after the events matching the ones before it", i)
+			return false
+		}
+		next++
+	}
+	return true
+}
+
+// HasField returns a matcher for AssertEventsInOrder and FindEventsWhere
+// reporting whether an event has the field key, deeply equal to val.
+func HasField(key string, val interface{}) func(*Event) bool {
+	return func(ev *Event) bool {
+		got, ok := ev.Data[key]
+		return ok && reflect.DeepEqual(got, val)
+	}
+}
+
+// HasMetadata returns a matcher reporting whether an event's Metadata is
+// deeply equal to metadata.
+func HasMetadata(metadata interface{}) func(*Event) bool {
+	return func(ev *Event) bool { return reflect.DeepEqual(ev.Metadata, metadata) }
+}
+
+func describe(v interface{}) string {
+	return fmt.Sprintf("%#v (%T)", v, v)
+}
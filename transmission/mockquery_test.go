@@ -0,0 +1,53 @@
+package transmission
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failureRecorder struct {
+	failures []string
+}
+
+func (f *failureRecorder) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func newQueryMock() *MockSender {
+	mock := &MockSender{}
+	mock.Add(&Event{Dataset: "a", Metadata: 1, Data: map[string]interface{}{"step": "start"}})
+	mock.Add(&Event{Dataset: "b", Metadata: 2, Data: map[string]interface{}{"step": "middle", "n": 3}})
+	mock.Add(&Event{Dataset: "a", Metadata: 3, Data: map[string]interface{}{"step": "end"}})
+	return mock
+}
+
+func TestMockSenderQueries(t *testing.T) {
+	mock := newQueryMock()
+	assert.Equal(t, 2, len(mock.EventsForDataset("a")))
+	assert.Equal(t, 0, len(mock.EventsForDataset("c")))
+	assert.Equal(t, "b", mock.EventsWithMetadata(2)[0].Dataset)
+	found := mock.FindEventsWhere(HasField("step", "end"))
+	assert.Equal(t, 1, len(found))
+	assert.Equal(t, 3, found[0].Metadata)
+	assert.Equal(t, 0, mock.EventsCalled, "queries don't count as calls to Events")
+}
+
+func TestMockSenderAssertions(t *testing.T) {
+	mock := newQueryMock()
+	assert.True(t, mock.AssertFieldEquals(t, 1, "n", 3))
+	assert.True(t, mock.AssertEventsInOrder(t, HasField("step", "start"), HasMetadata(3)))
+
+	rec := &failureRecorder{}
+	assert.False(t, mock.AssertFieldEquals(rec, 1, "n", int64(3)))
+	assert.False(t, mock.AssertFieldEquals(rec, 0, "n", 3))
+	assert.False(t, mock.AssertFieldEquals(rec, 5, "n", 3))
+	assert.False(t, mock.AssertEventsInOrder(rec, HasMetadata(3), HasField("step", "start")))
+	assert.Equal(t, []string{
+		`event 1 field "n" is 3 (int), want 3 (int64)`,
+		`event 0 has no field "n"`,
+		"no event 5: 3 events were added",
+		"no event matching matcher 1 after the events matching the ones before it",
+	}, rec.failures)
+}
@@ -0,0 +1,105 @@
+package transmission
+
+import (
+	"errors"
+)
+
+// NATSPublisher is the part of a NATS client that NATSSender needs. A
+// *nats.Conn satisfies it as is. To publish through JetStream instead, adapt
+// the JetStream context so that Publish only returns once the server has
+// acknowledged the message:
+//
+//   transmission.NATSPublisherFunc(func(subj string, data []byte) error {
+//       _, err := js.Publish(subj, data)
+//       return err
+//   })
+//
+// The acknowledgement error (or lack of one) is what ends up in each event's
+// Response.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSPublisherFunc adapts an ordinary function to the NATSPublisher
+// interface.
+type NATSPublisherFunc func(subject string, data []byte) error
+
+// Publish calls f(subject, data).
+func (f NATSPublisherFunc) Publish(subject string, data []byte) error {
+	return f(subject, data)
+}
+
+// NATSSender implements the Sender interface by publishing each event as a
+// JSON-encoded message to a NATS subject. Messages are published from a
+// background goroutine; one Response is generated per event.
+type NATSSender struct {
+	// Publisher publishes the encoded events. Required.
+	Publisher NATSPublisher
+	// Subject is the subject events are published to. Required.
+	Subject string
+	// SubjectPerDataset, if true, publishes each event to "<Subject>.<dataset>"
+	// so that subscribers can pick out the datasets they care about.
+	SubjectPerDataset bool
+
+	PendingWorkCapacity uint // how many events to allow to pile up. Defaults to DefaultPendingWorkCapacity
+	BlockOnSend         bool // whether to block or drop events when the queue fills
+	BlockOnResponse     bool // whether to block or drop responses when the queue fills
+
+	Logger  Logger
+	Metrics Metrics
+
+	queue publishQueue
+}
+
+func (n *NATSSender) Start() error {
+	if n.Publisher == nil {
+		return errors.New("NATSSender requires a Publisher")
+	}
+	if n.Subject == "" {
+		return errors.New("NATSSender requires a Subject")
+	}
+	n.queue.publish = n.publishEvent
+	n.queue.blockOnSend = n.BlockOnSend
+	n.queue.blockOnResponse = n.BlockOnResponse
+	n.queue.logger = n.Logger
+	n.queue.metrics = n.Metrics
+	n.queue.start(n.PendingWorkCapacity)
+	return nil
+}
+
+// Stop waits for all queued events to be published, then closes the
+// responses channel.
+func (n *NATSSender) Stop() error {
+	n.queue.stop()
+	return nil
+}
+
+func (n *NATSSender) Add(ev *Event) {
+	n.queue.add(ev)
+}
+
+func (n *NATSSender) publishEvent(ev *Event) error {
+	data, err := marshalWithDataset(ev)
+	if err != nil {
+		return err
+	}
+	subject := n.Subject
+	if n.SubjectPerDataset && ev.Dataset != "" {
+		subject = subject + "." + ev.Dataset
+	}
+	return n.Publisher.Publish(subject, data)
+}
+
+func (n *NATSSender) TxResponses() chan Response {
+	return n.queue.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (n *NATSSender) Done() <-chan struct{} {
+	return n.queue.stopped.done()
+}
+
+func (n *NATSSender) SendResponse(r Response) bool {
+	return n.queue.sendResponse(r)
+}
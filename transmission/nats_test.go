@@ -0,0 +1,42 @@
+package transmission
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNATSSenderStartRequiresConfig(t *testing.T) {
+	assert.Error(t, (&NATSSender{Subject: "s"}).Start())
+	assert.Error(t, (&NATSSender{Publisher: NATSPublisherFunc(nil)}).Start())
+}
+
+func TestNATSSender(t *testing.T) {
+	var subjects []string
+	var payloads []string
+	n := &NATSSender{
+		Publisher: NATSPublisherFunc(func(subj string, data []byte) error {
+			subjects = append(subjects, subj)
+			payloads = append(payloads, string(data))
+			if len(subjects) == 2 {
+				return errors.New("no ack")
+			}
+			return nil
+		}),
+		Subject:           "telemetry",
+		SubjectPerDataset: true,
+	}
+	testOK(t, n.Start())
+	n.Add(&Event{Dataset: "web", Metadata: 1, Data: map[string]interface{}{"a": 1}})
+	n.Add(&Event{Dataset: "jobs", Metadata: 2, Data: map[string]interface{}{"b": 2}})
+	testOK(t, n.Stop())
+
+	assert.Equal(t, []string{"telemetry.web", "telemetry.jobs"}, subjects)
+	assert.JSONEq(t, `{"data":{"a":1},"dataset":"web"}`, payloads[0])
+
+	r1, r2 := <-n.TxResponses(), <-n.TxResponses()
+	assert.Nil(t, r1.Err)
+	assert.Equal(t, 1, r1.Metadata)
+	assert.Equal(t, "no ack", r2.Err.Error())
+}
@@ -0,0 +1,529 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facebookgo/muster"
+)
+
+// Fields recognized as trace information when converting events to OTLP.
+// These are the names the Honeycomb Beelines use.
+const (
+	otlpTraceIDField  = "trace.trace_id"
+	otlpSpanIDField   = "trace.span_id"
+	otlpParentIDField = "trace.parent_id"
+	otlpNameField     = "name"
+	otlpDurationField = "duration_ms"
+	// otlpSampleRateAttribute carries an event's sample rate, as Honeycomb's
+	// OTLP receiver expects it.
+	otlpSampleRateAttribute = "SampleRate"
+)
+
+// OTLPSender implements the Sender interface by converting events to
+// OpenTelemetry data and exporting them to an OpenTelemetry collector (or any
+// other OTLP receiver) using OTLP over HTTP with JSON encoding. Events that
+// carry valid trace.trace_id and trace.span_id fields are exported as spans;
+// everything else is exported as log records. Each event's dataset becomes
+// the service.name resource attribute, and a sample rate above 1 becomes the
+// SampleRate attribute.
+//
+// Only OTLP/HTTP is supported; receivers that accept OTLP over gRPC alone
+// (port 4317 by convention) can't be used.
+type OTLPSender struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, eg
+	// "http://localhost:4318". Events are POSTed to /v1/traces and /v1/logs
+	// beneath it. Required.
+	Endpoint string
+	// Headers are added to every export request, eg for authentication.
+	Headers map[string]string
+
+	MaxBatchSize         uint          // how many events to collect in a batch before sending
+	BatchTimeout         time.Duration // how often to send off batches
+	MaxConcurrentBatches uint          // how many batches can be inflight simultaneously
+	PendingWorkCapacity  uint          // how many events to allow to pile up
+	BlockOnSend          bool          // whether to block or drop events when the queue fills
+	BlockOnResponse      bool          // whether to block or drop responses when the queue fills
+
+	Transport http.RoundTripper
+
+	Logger  Logger
+	Metrics Metrics
+
+	muster     muster.Client
+	httpClient *http.Client
+	responses  chan Response
+	stopped    stopSignal
+}
+
+func (o *OTLPSender) Start() error {
+	if o.Endpoint == "" {
+		return errors.New("OTLPSender requires an Endpoint")
+	}
+	if o.Logger == nil {
+		o.Logger = &nullLogger{}
+	}
+	if o.Metrics == nil {
+		o.Metrics = &nullMetrics{}
+	}
+	if o.MaxBatchSize == 0 {
+		o.MaxBatchSize = 512
+	}
+	if o.BatchTimeout == 0 {
+		o.BatchTimeout = time.Second
+	}
+	if o.MaxConcurrentBatches == 0 {
+		o.MaxConcurrentBatches = 10
+	}
+	if o.PendingWorkCapacity == 0 {
+		o.PendingWorkCapacity = DefaultPendingWorkCapacity
+	}
+	o.Logger.Printf("OTLP transmission starting")
+	o.httpClient = &http.Client{
+		Transport: o.Transport,
+		Timeout:   60 * time.Second,
+	}
+	o.responses = make(chan Response, o.PendingWorkCapacity*2)
+	o.stopped.start()
+	o.muster.MaxBatchSize = o.MaxBatchSize
+	o.muster.BatchTimeout = o.BatchTimeout
+	o.muster.MaxConcurrentBatches = o.MaxConcurrentBatches
+	o.muster.PendingWorkCapacity = o.PendingWorkCapacity
+	o.muster.BatchMaker = func() muster.Batch {
+		return &otlpBatch{sender: o}
+	}
+	return o.muster.Start()
+}
+
+func (o *OTLPSender) Stop() error {
+	o.Logger.Printf("OTLP transmission stopping")
+	err := o.muster.Stop()
+	o.stopped.stop(o.responses)
+	return err
+}
+
+func (o *OTLPSender) Add(ev *Event) {
+	if o.BlockOnSend {
+		o.muster.Work <- ev
+		o.Metrics.Increment("messages_queued")
+		return
+	}
+	select {
+	case o.muster.Work <- ev:
+		o.Metrics.Increment("messages_queued")
+	default:
+		o.Metrics.Increment("queue_overflow")
+		o.SendResponse(Response{
+			Err:      ErrQueueOverflow,
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+func (o *OTLPSender) TxResponses() chan Response {
+	return o.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (o *OTLPSender) Done() <-chan struct{} {
+	return o.stopped.done()
+}
+
+func (o *OTLPSender) SendResponse(r Response) bool {
+	return o.stopped.writeResponse(o.responses, r, o.BlockOnResponse, o.Logger, o.Metrics)
+}
+
+type otlpBatch struct {
+	sender *OTLPSender
+	spans  []*Event
+	logs   []*Event
+	// answered tracks the events that have had their Response
+	answered map[*Event]bool
+}
+
+func (b *otlpBatch) Add(ev interface{}) {
+	e := ev.(*Event)
+	if _, _, ok := otlpTraceIDs(e.Data); ok {
+		b.spans = append(b.spans, e)
+	} else {
+		b.logs = append(b.logs, e)
+	}
+}
+
+func (b *otlpBatch) Fire(notifier muster.Notifier) {
+	defer notifier.Done()
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(b.sender.Logger, b.sender.Metrics, "OTLP export", p)
+			for _, ev := range append(b.spans, b.logs...) {
+				if !b.answered[ev] {
+					b.respond(ev, Response{Err: panicError(p)})
+				}
+			}
+		}
+	}()
+	if len(b.spans) > 0 {
+		b.export("/v1/traces", encodeOTLPTraces(b.spans), b.spans)
+	}
+	if len(b.logs) > 0 {
+		b.export("/v1/logs", encodeOTLPLogs(b.logs), b.logs)
+	}
+}
+
+func (b *otlpBatch) export(path string, payload interface{}, events []*Event) {
+	start := time.Now()
+	err := b.post(path, payload)
+	dur := time.Since(start) / time.Duration(len(events))
+	statusCode := 0
+	if se, ok := err.(*otlpStatusError); ok {
+		statusCode = se.StatusCode
+	} else if err == nil {
+		statusCode = http.StatusOK
+	}
+	if err != nil {
+		b.sender.Metrics.Increment("send_errors")
+	} else {
+		b.sender.Metrics.Increment("batches_sent")
+		b.sender.Metrics.Count("messages_sent", len(events))
+	}
+	for _, ev := range events {
+		b.respond(ev, Response{
+			Err:        err,
+			StatusCode: statusCode,
+			Duration:   dur,
+		})
+	}
+}
+
+// respond sends ev's Response.
+func (b *otlpBatch) respond(ev *Event, r Response) {
+	if b.answered == nil {
+		b.answered = make(map[*Event]bool, len(b.spans)+len(b.logs))
+	}
+	b.answered[ev] = true
+	r.Metadata = ev.Metadata
+	b.sender.SendResponse(r)
+}
+
+type otlpStatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *otlpStatusError) Error() string {
+	return fmt.Sprintf("OTLP export failed with status %d: %s", e.StatusCode, strings.TrimSpace(string(e.Body)))
+}
+
+func (b *otlpBatch) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(b.sender.Endpoint, "/") + path
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("libhoney-go/%s", Version))
+	for k, v := range b.sender.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := b.sender.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return &otlpStatusError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// The types below are the subset of the OTLP protobuf-JSON mapping needed to
+// export logs and spans. 64 bit integers are encoded as strings and IDs as
+// hex, per the spec.
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpLibraryScope() otlpScope {
+	return otlpScope{Name: "libhoney-go", Version: Version}
+}
+
+func otlpServiceResource(dataset string) otlpResource {
+	return otlpResource{Attributes: []otlpKeyValue{otlpAttribute("service.name", dataset)}}
+}
+
+// groupByDataset returns the events grouped by dataset, with the datasets in
+// sorted order so that the output is deterministic.
+func groupByDataset(events []*Event) ([]string, map[string][]*Event) {
+	groups := map[string][]*Event{}
+	for _, ev := range events {
+		groups[ev.Dataset] = append(groups[ev.Dataset], ev)
+	}
+	datasets := make([]string, 0, len(groups))
+	for ds := range groups {
+		datasets = append(datasets, ds)
+	}
+	sort.Strings(datasets)
+	return datasets, groups
+}
+
+func encodeOTLPLogs(events []*Event) *otlpLogsRequest {
+	req := &otlpLogsRequest{}
+	datasets, groups := groupByDataset(events)
+	for _, ds := range datasets {
+		records := make([]otlpLogRecord, 0, len(groups[ds]))
+		for _, ev := range groups[ds] {
+			records = append(records, otlpLogRecord{
+				TimeUnixNano: otlpTime(eventTime(ev)),
+				Attributes:   otlpEventAttributes(ev, nil),
+			})
+		}
+		req.ResourceLogs = append(req.ResourceLogs, otlpResourceLogs{
+			Resource:  otlpServiceResource(ds),
+			ScopeLogs: []otlpScopeLogs{{Scope: otlpLibraryScope(), LogRecords: records}},
+		})
+	}
+	return req
+}
+
+func encodeOTLPTraces(events []*Event) *otlpTracesRequest {
+	req := &otlpTracesRequest{}
+	skip := map[string]bool{
+		otlpTraceIDField:  true,
+		otlpSpanIDField:   true,
+		otlpParentIDField: true,
+		otlpNameField:     true,
+	}
+	datasets, groups := groupByDataset(events)
+	for _, ds := range datasets {
+		spans := make([]otlpSpan, 0, len(groups[ds]))
+		for _, ev := range groups[ds] {
+			traceID, spanID, _ := otlpTraceIDs(ev.Data)
+			start := eventTime(ev)
+			end := start
+			if d, ok := ev.Data[otlpDurationField]; ok {
+				if ms, ok := toFloat(d); ok && !math.IsNaN(ms) && !math.IsInf(ms, 0) {
+					end = start.Add(time.Duration(ms * float64(time.Millisecond)))
+				}
+			}
+			name, _ := ev.Data[otlpNameField].(string)
+			spans = append(spans, otlpSpan{
+				TraceID:           traceID,
+				SpanID:            spanID,
+				ParentSpanID:      otlpID(ev.Data[otlpParentIDField], 8),
+				Name:              name,
+				StartTimeUnixNano: otlpTime(start),
+				EndTimeUnixNano:   otlpTime(end),
+				Attributes:        otlpEventAttributes(ev, skip),
+			})
+		}
+		req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+			Resource:   otlpServiceResource(ds),
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpLibraryScope(), Spans: spans}},
+		})
+	}
+	return req
+}
+
+func eventTime(ev *Event) time.Time {
+	if ev.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return ev.Timestamp
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpTraceIDs returns the OTLP-formatted trace and span IDs from an event's
+// fields, and whether both were present and valid.
+func otlpTraceIDs(data map[string]interface{}) (string, string, bool) {
+	traceID := otlpID(data[otlpTraceIDField], 16)
+	spanID := otlpID(data[otlpSpanIDField], 8)
+	return traceID, spanID, traceID != "" && spanID != ""
+}
+
+// otlpID converts an ID field to the lowercase hex form OTLP expects, or ""
+// if it isn't a hex ID of exactly size bytes. Dashes are ignored so that
+// UUIDs can be used as trace IDs.
+func otlpID(v interface{}, size int) string {
+	s, ok := v.(string)
+	if !ok {
+		return ""
+	}
+	s = strings.ToLower(strings.Replace(s, "-", "", -1))
+	if len(s) != size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// otlpEventAttributes returns ev's fields, less those in skip, as attributes,
+// along with its sample rate if it has one.
+func otlpEventAttributes(ev *Event, skip map[string]bool) []otlpKeyValue {
+	if ev.SampleRate <= 1 {
+		return otlpAttributes(ev.Data, skip)
+	}
+	withRate := map[string]bool{otlpSampleRateAttribute: true}
+	for k := range skip {
+		withRate[k] = true
+	}
+	attrs := otlpAttributes(ev.Data, withRate)
+	return append(attrs, otlpAttribute(otlpSampleRateAttribute, ev.SampleRate))
+}
+
+func otlpAttributes(data map[string]interface{}, skip map[string]bool) []otlpKeyValue {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if !skip[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		if data[k] == nil {
+			continue
+		}
+		attrs = append(attrs, otlpAttribute(k, data[k]))
+	}
+	return attrs
+}
+
+func otlpAttribute(key string, v interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch val := v.(type) {
+	case string:
+		kv.Value.StringValue = &val
+	case bool:
+		kv.Value.BoolValue = &val
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprintf("%d", val)
+		kv.Value.IntValue = &s
+	case float32:
+		otlpDouble(&kv.Value, float64(val))
+	case float64:
+		otlpDouble(&kv.Value, val)
+	default:
+		// anything else is sent as its JSON encoding
+		b, err := json.Marshal(val)
+		s := string(b)
+		if err != nil {
+			s = fmt.Sprintf("%v", val)
+		}
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
+
+// otlpDouble sets v to f. NaN and infinities can't be encoded as JSON numbers,
+// so they're sent as strings ("NaN", "+Inf" and "-Inf") rather than failing
+// the whole export.
+func otlpDouble(v *otlpAnyValue, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		v.StringValue = &s
+		return
+	}
+	v.DoubleValue = &f
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+package transmission
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTLPID(t *testing.T) {
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", otlpID("0af76519-16cd-43dd-8448-eb211c80319c", 16))
+	assert.Equal(t, "b7ad6b7169203331", otlpID("B7AD6B7169203331", 8))
+	assert.Equal(t, "", otlpID("abc", 8))
+	assert.Equal(t, "", otlpID("zzzzzzzzzzzzzzzz", 8))
+	assert.Equal(t, "", otlpID(12, 8))
+}
+
+func TestOTLPSender(t *testing.T) {
+	var lock sync.Mutex
+	bodies := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Auth"))
+		b, _ := ioutil.ReadAll(r.Body)
+		decoded := map[string]interface{}{}
+		json.Unmarshal(b, &decoded)
+		bodies[r.URL.Path] = decoded
+	}))
+	defer server.Close()
+
+	o := &OTLPSender{
+		Endpoint: server.URL + "/",
+		Headers:  map[string]string{"X-Auth": "secret"},
+	}
+	testOK(t, o.Start())
+	ts := time.Unix(1500000000, 0)
+	o.Add(&Event{
+		Dataset:   "api",
+		Timestamp: ts,
+		Metadata:  "span",
+		Data: map[string]interface{}{
+			"trace.trace_id":  "0af76519-16cd-43dd-8448-eb211c80319c",
+			"trace.span_id":   "b7ad6b7169203331",
+			"trace.parent_id": "00f067aa0ba902b7",
+			"name":            "GET /",
+			"duration_ms":     1.5,
+			"status":          200,
+		},
+	})
+	o.Add(&Event{
+		Dataset:   "api",
+		Timestamp: ts,
+		Metadata:  "log",
+		Data:      map[string]interface{}{"message": "hello", "ok": true},
+	})
+	testOK(t, o.Stop())
+
+	for r := range o.TxResponses() {
+		testOK(t, r.Err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+	}
+
+	traces, _ := json.Marshal(bodies["/v1/traces"])
+	assert.JSONEq(t, `{"resourceSpans":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeSpans":[{"scope":{"name":"libhoney-go"},"spans":[{
+			"traceId":"0af7651916cd43dd8448eb211c80319c",
+			"spanId":"b7ad6b7169203331",
+			"parentSpanId":"00f067aa0ba902b7",
+			"name":"GET /",
+			"startTimeUnixNano":"1500000000000000000",
+			"endTimeUnixNano":"1500000000001500000",
+			"attributes":[
+				{"key":"duration_ms","value":{"doubleValue":1.5}},
+				{"key":"status","value":{"intValue":"200"}}
+			]}]}]}]}`, string(traces))
+
+	logs, _ := json.Marshal(bodies["/v1/logs"])
+	assert.JSONEq(t, `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeLogs":[{"scope":{"name":"libhoney-go"},"logRecords":[{
+			"timeUnixNano":"1500000000000000000",
+			"attributes":[
+				{"key":"message","value":{"stringValue":"hello"}},
+				{"key":"ok","value":{"boolValue":true}}
+			]}]}]}]}`, string(logs))
+}
+
+func TestOTLPSampleRateAndNonFiniteFloats(t *testing.T) {
+	ts := time.Unix(1500000000, 0)
+	req := encodeOTLPLogs([]*Event{{
+		Dataset:    "api",
+		Timestamp:  ts,
+		SampleRate: 20,
+		Data: map[string]interface{}{
+			"SampleRate": "overridden",
+			"nan":        math.NaN(),
+			"inf":        float32(math.Inf(-1)),
+		},
+	}})
+	b, err := json.Marshal(req)
+	testOK(t, err)
+	assert.JSONEq(t, `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeLogs":[{"scope":{"name":"libhoney-go"},"logRecords":[{
+			"timeUnixNano":"1500000000000000000",
+			"attributes":[
+				{"key":"inf","value":{"stringValue":"-Inf"}},
+				{"key":"nan","value":{"stringValue":"NaN"}},
+				{"key":"SampleRate","value":{"intValue":"20"}}
+			]}]}]}]}`, string(b))
+}
+
+func TestOTLPSenderErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("overloaded"))
+	}))
+	defer server.Close()
+
+	o := &OTLPSender{Endpoint: server.URL}
+	testOK(t, o.Start())
+	o.Add(&Event{Dataset: "api", Data: map[string]interface{}{"a": 1}})
+	testOK(t, o.Stop())
+	r := <-o.TxResponses()
+	testErr(t, r.Err)
+	assert.Equal(t, http.StatusServiceUnavailable, r.StatusCode)
+	assert.Equal(t, "OTLP export failed with status 503: overloaded", r.Err.Error())
+}
+
+// logsPanicRoundTripper accepts traces and panics on logs.
+type logsPanicRoundTripper struct{}
+
+func (logsPanicRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, "/v1/logs") {
+		panic("exporter bug")
+	}
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestOTLPBatchPanicAnswersEachEventOnce(t *testing.T) {
+	o := &OTLPSender{Endpoint: "http://collector:4318", Transport: logsPanicRoundTripper{}}
+	testOK(t, o.Start())
+	o.Add(&Event{Metadata: "span", Data: map[string]interface{}{
+		"trace.trace_id": "0af7651916cd43dd8448eb211c80319c",
+		"trace.span_id":  "b7ad6b7169203331",
+	}})
+	o.Add(&Event{Metadata: "log", Data: map[string]interface{}{"message": "hi"}})
+	testOK(t, o.Stop())
+	results := map[interface{}][]error{}
+	for r := range o.TxResponses() {
+		results[r.Metadata] = append(results[r.Metadata], r.Err)
+	}
+	assert.Equal(t, []error{nil}, results["span"], "the exported span keeps its one Response")
+	assert.Equal(t, 1, len(results["log"]))
+	testErr(t, results["log"][0])
+}
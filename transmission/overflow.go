@@ -0,0 +1,62 @@
+package transmission
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy is what a Honeycomb sender does with an event that arrives
+// when its queue is full. Whichever event loses out gets a Response with
+// ErrQueueOverflow.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the event being added. This is the default.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest evicts the oldest queued event to make room, keeping
+	// the freshest data when the queue can't keep up.
+	OverflowDropOldest
+	// OverflowBlockWithTimeout waits up to OverflowTimeout for room in the
+	// queue, then drops the event being added.
+	OverflowBlockWithTimeout
+)
+
+// handleOverflow applies the OverflowPolicy to ev, which didn't fit in the
+// shard's queue.
+func (h *Honeycomb) handleOverflow(ctx context.Context, s *sendShard, ev *Event) {
+	switch h.OverflowPolicy {
+	case OverflowDropOldest:
+		select {
+		case old := <-s.muster.Work:
+			h.evict(old.(*Event))
+		default:
+		}
+		select {
+		case s.muster.Work <- ev:
+			h.queued()
+			return
+		default:
+		}
+	case OverflowBlockWithTimeout:
+		t := time.NewTimer(h.OverflowTimeout)
+		defer t.Stop()
+		select {
+		case s.muster.Work <- ev:
+			h.queued()
+			return
+		case <-t.C:
+		case <-ctx.Done():
+		}
+	}
+	h.dropOverflow(ev)
+}
+
+// evict drops an event that had already been queued.
+func (h *Honeycomb) evict(ev *Event) {
+	if h.pending != nil {
+		atomic.AddInt64(h.pending, -1)
+	}
+	h.Metrics.Increment("queue_evicted")
+	h.dropOverflow(ev)
+}
@@ -0,0 +1,70 @@
+package transmission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newOverflowTestSender(policy OverflowPolicy) *Honeycomb {
+	h := &Honeycomb{
+		OverflowPolicy:  policy,
+		OverflowTimeout: 20 * time.Millisecond,
+		Logger:          &nullLogger{},
+		Metrics:         &nullMetrics{},
+		responses:       make(chan Response, 10),
+		pending:         new(int64),
+	}
+	// nothing reads from the queue
+	h.muster.Work = make(chan interface{}, 2)
+	return h
+}
+
+func queuedMetadata(h *Honeycomb) []interface{} {
+	var md []interface{}
+	for len(h.muster.Work) > 0 {
+		md = append(md, (<-h.muster.Work).(*Event).Metadata)
+	}
+	return md
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	h := newOverflowTestSender(OverflowDropNewest)
+	for i := 0; i < 3; i++ {
+		h.Add(&Event{Metadata: i})
+	}
+	r := testGetResponse(t, h.responses)
+	assert.Equal(t, ErrQueueOverflow, r.Err)
+	assert.Equal(t, 2, r.Metadata)
+	assert.Equal(t, []interface{}{0, 1}, queuedMetadata(h))
+
+	h = newOverflowTestSender(OverflowDropOldest)
+	for i := 0; i < 3; i++ {
+		h.Add(&Event{Metadata: i})
+	}
+	r = testGetResponse(t, h.responses)
+	assert.Equal(t, ErrQueueOverflow, r.Err)
+	assert.Equal(t, 0, r.Metadata)
+	assert.Equal(t, int64(2), h.Summary().Queued)
+	assert.Equal(t, []interface{}{1, 2}, queuedMetadata(h))
+
+	h = newOverflowTestSender(OverflowBlockWithTimeout)
+	h.Add(&Event{Metadata: 0})
+	h.Add(&Event{Metadata: 1})
+	start := time.Now()
+	h.Add(&Event{Metadata: 2})
+	assert.True(t, time.Since(start) >= h.OverflowTimeout, "should wait for room before dropping")
+	r = testGetResponse(t, h.responses)
+	assert.Equal(t, 2, r.Metadata)
+
+	// room freed up while waiting
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		<-h.muster.Work
+	}()
+	h.OverflowTimeout = time.Second
+	h.Add(&Event{Metadata: 3})
+	assert.Equal(t, []interface{}{1, 3}, queuedMetadata(h))
+	assert.Equal(t, 0, len(h.responses))
+}
@@ -0,0 +1,49 @@
+package transmission
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sync"
+)
+
+// maxPooledBufferSize is the largest encode buffer that is kept for reuse;
+// bigger ones, from batches with a raised MaxBatchSizeBytes, are left to the
+// garbage collector rather than pinning their memory.
+const maxPooledBufferSize = 2 * apiMaxBatchSize
+
+var batchBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// getBatchBuffer returns an empty buffer to encode a batch into. Return it
+// with putBatchBuffer once nothing refers to its contents.
+func getBatchBuffer() *bytes.Buffer {
+	return batchBufferPool.Get().(*bytes.Buffer)
+}
+
+func putBatchBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	batchBufferPool.Put(buf)
+}
+
+// gzipBytes returns p, gzipped.
+func gzipBytes(p []byte) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	g := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(g)
+	g.Reset(buf)
+	if _, err := g.Write(p); err != nil {
+		return nil, err
+	}
+	if err := g.Close(); err != nil { // flush
+		return nil, err
+	}
+	return buf, nil
+}
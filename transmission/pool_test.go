@@ -0,0 +1,119 @@
+package transmission
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGzipBytesReusesWriters(t *testing.T) {
+	for _, payload := range []string{`{"a":1}`, `{"b":2}`} {
+		buf, err := gzipBytes([]byte(payload))
+		testOK(t, err)
+		g, err := gzip.NewReader(buf)
+		testOK(t, err)
+		out, err := ioutil.ReadAll(g)
+		testOK(t, err)
+		testEquals(t, string(out), payload, "a reused writer must start fresh")
+	}
+}
+
+func TestPutBatchBufferSkipsHugeBuffers(t *testing.T) {
+	buf := getBatchBuffer()
+	buf.WriteString("leftovers")
+	putBatchBuffer(buf)
+	testEquals(t, getBatchBuffer().Len(), 0, "pooled buffers come back empty")
+
+	huge := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	putBatchBuffer(huge)
+	if getBatchBuffer() == huge {
+		t.Error("oversized buffers shouldn't be pooled")
+	}
+}
+
+func TestEncodeBodyStreamsThroughGzip(t *testing.T) {
+	agg := &batchAgg{metrics: &nullMetrics{}}
+	var plain bytes.Buffer
+	testEquals(t, agg.encodeBatch(&plain, benchmarkEvents(3)), 3)
+
+	body, n := agg.encodeBody(benchmarkEvents(3))
+	testEquals(t, n, 3)
+	testEquals(t, body.gzipped, true)
+	r := body.reader()
+	g, err := gzip.NewReader(r)
+	testOK(t, err)
+	out, err := ioutil.ReadAll(g)
+	testOK(t, err)
+	testEquals(t, string(out), plain.String())
+
+	r.Close()
+	r.Close()
+	testEquals(t, body.open, int32(0), "closing a reader twice only counts once")
+	body.release()
+
+	agg.disableGzipCompression = true
+	body, _ = agg.encodeBody(benchmarkEvents(3))
+	testEquals(t, body.gzipped, false)
+	testEquals(t, body.buf.String(), plain.String())
+}
+
+// discardRoundTripper accepts every batch of n events.
+type discardRoundTripper struct {
+	n int
+}
+
+func (d discardRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	statuses := "[" + strings.TrimSuffix(strings.Repeat(`{"status":202},`, d.n), ",") + "]"
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(statuses)),
+	}, nil
+}
+
+func benchmarkEvents(n int) []*Event {
+	events := make([]*Event, n)
+	for i := range events {
+		events[i] = &Event{
+			Data:     map[string]interface{}{"field": strings.Repeat("x", 200), "n": i},
+			APIHost:  "http://fakeHost:8080",
+			APIKey:   "written",
+			Dataset:  "ds1",
+			Metadata: i,
+		}
+	}
+	return events
+}
+
+func BenchmarkBuildReqReader(b *testing.B) {
+	var buf bytes.Buffer
+	(&batchAgg{metrics: &nullMetrics{}}).encodeBatch(&buf, benchmarkEvents(50))
+	encoded := buf.Bytes()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buildReqReader(encoded, true)
+	}
+}
+
+func BenchmarkFireBatch(b *testing.B) {
+	agg := &batchAgg{
+		httpClient: &http.Client{Transport: discardRoundTripper{n: 50}},
+		testNower:  &fakeNower{},
+		responses:  make(chan Response, 100),
+		metrics:    &nullMetrics{},
+	}
+	events := benchmarkEvents(50)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		agg.fireBatch(events)
+		for range events {
+			<-agg.responses
+		}
+	}
+}
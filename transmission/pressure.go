@@ -0,0 +1,64 @@
+package transmission
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pressureWindow is how far back recent drops are counted towards pressure.
+const pressureWindow = 10 * time.Second
+
+// PressureReporter is implemented by Senders that can report how close they
+// are to dropping events, as a value from 0.0 (idle) to 1.0 (saturated or
+// dropping everything). Applications can fold it into their own load shedding
+// decisions.
+type PressureReporter interface {
+	Pressure() float64
+}
+
+// dropCounter tracks what fraction of recently added events were dropped.
+type dropCounter struct {
+	// accessed atomically; keep first for alignment
+	added   int64
+	dropped int64
+
+	lock        sync.Mutex
+	windowStart time.Time
+	// counter values at the start of the previous window
+	prevAdded, prevDropped int64
+	// counter values at the start of the current window
+	curAdded, curDropped int64
+}
+
+// add and drop may be called on a nil dropCounter, for senders that were never
+// started.
+func (d *dropCounter) add() {
+	if d != nil {
+		atomic.AddInt64(&d.added, 1)
+	}
+}
+
+func (d *dropCounter) drop() {
+	if d != nil {
+		atomic.AddInt64(&d.dropped, 1)
+	}
+}
+
+// fraction returns the fraction of events dropped over the current and
+// previous windows, between one and two windows' worth of history.
+func (d *dropCounter) fraction(now time.Time) float64 {
+	added := atomic.LoadInt64(&d.added)
+	dropped := atomic.LoadInt64(&d.dropped)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if now.Sub(d.windowStart) >= pressureWindow {
+		d.prevAdded, d.prevDropped = d.curAdded, d.curDropped
+		d.curAdded, d.curDropped = added, dropped
+		d.windowStart = now
+	}
+	if added == d.prevAdded {
+		return 0
+	}
+	return float64(dropped-d.prevDropped) / float64(added-d.prevAdded)
+}
@@ -0,0 +1,51 @@
+package transmission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropCounterFraction(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	d := &dropCounter{windowStart: start}
+	assert.Equal(t, 0.0, d.fraction(start))
+	for i := 0; i < 4; i++ {
+		d.add()
+	}
+	d.drop()
+	assert.Equal(t, 0.25, d.fraction(start.Add(time.Second)))
+
+	// drops age out after two windows of quiet
+	d.fraction(start.Add(pressureWindow))
+	assert.Equal(t, 0.25, d.fraction(start.Add(pressureWindow+time.Second)))
+	assert.Equal(t, 0.0, d.fraction(start.Add(2*pressureWindow)))
+
+	var nilCounter *dropCounter
+	nilCounter.add()
+	nilCounter.drop()
+}
+
+func TestHoneycombPressure(t *testing.T) {
+	h := &Honeycomb{}
+	assert.Equal(t, 0.0, h.Pressure(), "an unstarted sender is under no pressure")
+
+	h = &Honeycomb{
+		MaxBatchSize:        10,
+		BatchTimeout:        time.Hour,
+		PendingWorkCapacity: 2,
+		Transport:           failingRoundTripper{},
+	}
+	h.drops = &dropCounter{windowStart: time.Now()}
+	h.muster.Work = make(chan interface{}, 2)
+	h.responses = make(chan Response, 10)
+	h.Logger = &nullLogger{}
+	h.Metrics = &nullMetrics{}
+	h.Add(&Event{})
+	assert.Equal(t, 0.5, h.Pressure())
+	h.Add(&Event{})
+	h.Add(&Event{})
+	h.Add(&Event{})
+	assert.Equal(t, 1.0, h.Pressure())
+}
@@ -0,0 +1,115 @@
+package transmission
+
+import (
+	"sync"
+	"time"
+)
+
+// publishQueue is the queue and background goroutine shared by senders that
+// hand events one at a time to a message broker. Each event is passed to
+// publish and the error it returns becomes the event's Response.
+type publishQueue struct {
+	publish         func(ev *Event) error
+	blockOnSend     bool
+	blockOnResponse bool
+	logger          Logger
+	metrics         Metrics
+
+	// lock guards work and responses against an add racing stop; add holds
+	// it for reading while it queues or answers an event.
+	lock      sync.RWMutex
+	stopping  bool
+	work      chan *Event
+	responses chan Response
+	stopped   stopSignal
+	wg        sync.WaitGroup
+}
+
+func (q *publishQueue) start(pendingWorkCapacity uint) {
+	if pendingWorkCapacity == 0 {
+		pendingWorkCapacity = DefaultPendingWorkCapacity
+	}
+	if q.metrics == nil {
+		q.metrics = &nullMetrics{}
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.stopping = false
+	q.work = make(chan *Event, pendingWorkCapacity)
+	q.responses = make(chan Response, pendingWorkCapacity*2)
+	q.stopped.start()
+	q.wg.Add(1)
+	go q.run()
+}
+
+// stop waits for all queued events to be published, then closes the responses
+// channel. Events added once stop has begun aren't published.
+func (q *publishQueue) stop() {
+	q.lock.Lock()
+	if q.work == nil || q.stopping {
+		q.lock.Unlock()
+		return
+	}
+	q.stopping = true
+	close(q.work)
+	q.lock.Unlock()
+
+	q.wg.Wait()
+
+	q.stopped.stop(q.responses)
+}
+
+func (q *publishQueue) add(ev *Event) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if q.stopping {
+		q.metrics.Increment("add_after_stop")
+		NewLeveledLogger(q.logger).Warnf(Fields{"dataset": ev.Dataset}, "dropping event added to stopped sender")
+		// dropped, rather than sent, once the responses channel is closed
+		q.sendResponse(Response{
+			Err:      ErrSenderStopped,
+			Metadata: ev.Metadata,
+		})
+		return
+	}
+	if q.blockOnSend {
+		q.work <- ev
+		return
+	}
+	select {
+	case q.work <- ev:
+	default:
+		q.sendResponse(Response{
+			Err:      ErrQueueOverflow,
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+func (q *publishQueue) run() {
+	defer q.wg.Done()
+	for ev := range q.work {
+		start := time.Now()
+		err := q.safePublish(ev)
+		q.sendResponse(Response{
+			Err:      err,
+			Duration: time.Since(start),
+			Metadata: ev.Metadata,
+		})
+	}
+}
+
+// safePublish publishes ev, turning a panic into an error.
+func (q *publishQueue) safePublish(ev *Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(q.logger, q.metrics, "publish", p)
+			err = panicError(p)
+		}
+	}()
+	return q.publish(ev)
+}
+
+func (q *publishQueue) sendResponse(r Response) bool {
+	return q.stopped.writeResponse(q.responses, r, q.blockOnResponse, q.logger, q.metrics)
+}
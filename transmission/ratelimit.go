@@ -0,0 +1,232 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy is what a RateLimitedSender does with events over its limit.
+type RateLimitPolicy int
+
+const (
+	// RateLimitDrop drops events over the limit.
+	RateLimitDrop RateLimitPolicy = iota
+	// RateLimitQueue holds events over the limit in a queue, passing them on
+	// as the rate allows. Events that don't fit in the queue are dropped.
+	RateLimitQueue
+	// RateLimitSample keeps one in ExcessSampleRate of the events over the
+	// limit, with their sample rate scaled to match, and drops the rest.
+	RateLimitSample
+)
+
+// RateLimitedSender wraps another Sender, limiting how many events per second
+// are passed on to it using a token bucket. This protects write key quotas
+// when an application sees a spike in traffic. Dropped events get a Response
+// with ErrRateLimited.
+type RateLimitedSender struct {
+	Sender Sender
+
+	// EventsPerSecond is the sustained rate to allow. Required.
+	EventsPerSecond float64
+	// Burst is how many events may be sent at once after a quiet period.
+	// Defaults to EventsPerSecond, and is always at least 1.
+	Burst int
+	// Policy decides what happens to events over the limit.
+	Policy RateLimitPolicy
+	// ExcessSampleRate is the sample rate for events over the limit with
+	// RateLimitSample. Defaults to 10.
+	ExcessSampleRate uint
+	// PendingWorkCapacity is the size of the queue for RateLimitQueue.
+	// Defaults to DefaultPendingWorkCapacity.
+	PendingWorkCapacity uint
+
+	Logger  Logger
+	Metrics Metrics
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// queueLock guards queue, which Stop closes and clears while Add may be
+	// sending to it
+	queueLock sync.RWMutex
+	queue     chan queuedEvent
+	stopping  chan struct{}
+	wg        sync.WaitGroup
+
+	// allows manipulation of the value of "now" for testing
+	testNower nower
+}
+
+// queuedEvent is an event waiting in a RateLimitedSender's queue, along with
+// the context it was added with.
+type queuedEvent struct {
+	ctx context.Context
+	ev  *Event
+}
+
+// NewRateLimitedSender returns a RateLimitedSender passing at most
+// eventsPerSecond events on to s, dropping the rest.
+func NewRateLimitedSender(s Sender, eventsPerSecond float64) *RateLimitedSender {
+	return &RateLimitedSender{Sender: s, EventsPerSecond: eventsPerSecond}
+}
+
+func (r *RateLimitedSender) Start() error {
+	if r.EventsPerSecond <= 0 {
+		return errors.New("RateLimitedSender requires a positive EventsPerSecond")
+	}
+	if r.Burst <= 0 {
+		r.Burst = int(r.EventsPerSecond)
+	}
+	if r.Burst < 1 {
+		r.Burst = 1
+	}
+	if r.ExcessSampleRate == 0 {
+		r.ExcessSampleRate = 10
+	}
+	r.tokens = float64(r.Burst)
+	r.last = r.now()
+	if err := r.Sender.Start(); err != nil {
+		return err
+	}
+	if r.Policy == RateLimitQueue {
+		if r.PendingWorkCapacity == 0 {
+			r.PendingWorkCapacity = DefaultPendingWorkCapacity
+		}
+		r.queueLock.Lock()
+		r.queue = make(chan queuedEvent, r.PendingWorkCapacity)
+		r.stopping = make(chan struct{})
+		r.wg.Add(1)
+		go r.run(r.queue)
+		r.queueLock.Unlock()
+	}
+	return nil
+}
+
+// Stop passes on anything still queued, without waiting for the rate limit,
+// then stops the wrapped Sender.
+func (r *RateLimitedSender) Stop() error {
+	r.queueLock.Lock()
+	if r.queue != nil {
+		close(r.stopping)
+		close(r.queue)
+		r.queue = nil
+	}
+	r.queueLock.Unlock()
+	r.wg.Wait()
+	return r.Sender.Stop()
+}
+
+func (r *RateLimitedSender) Add(ev *Event) {
+	r.AddWithContext(context.Background(), ev)
+}
+
+// AddWithContext adds ev like Add, passing ctx on to the wrapped Sender along
+// with the event, including when it's been queued.
+func (r *RateLimitedSender) AddWithContext(ctx context.Context, ev *Event) {
+	if r.Policy == RateLimitQueue && r.enqueue(ctx, ev) {
+		return
+	}
+	if r.take() {
+		AddWithContext(ctx, r.Sender, ev)
+		return
+	}
+	if r.Policy == RateLimitSample && rand.Intn(int(r.ExcessSampleRate)) == 0 {
+		cp := *ev
+		if cp.SampleRate == 0 {
+			cp.SampleRate = 1
+		}
+		cp.SampleRate *= r.ExcessSampleRate
+		AddWithContext(ctx, r.Sender, &cp)
+		return
+	}
+	r.dropped(ev)
+}
+
+// enqueue puts ev on the queue, or drops it if the queue is full. It reports
+// false if there's no queue because the sender has stopped, in which case ev
+// is passed on as though the queue weren't in use. Everything goes through the
+// queue while there is one so that events stay in order.
+func (r *RateLimitedSender) enqueue(ctx context.Context, ev *Event) bool {
+	r.queueLock.RLock()
+	defer r.queueLock.RUnlock()
+	if r.queue == nil {
+		return false
+	}
+	select {
+	case r.queue <- queuedEvent{ctx: ctx, ev: ev}:
+	default:
+		r.dropped(ev)
+	}
+	return true
+}
+
+func (r *RateLimitedSender) dropped(ev *Event) {
+	r.Sender.SendResponse(Response{
+		Err:      ErrRateLimited,
+		Metadata: ev.Metadata,
+	})
+}
+
+// run passes events from queue on as the rate allows.
+func (r *RateLimitedSender) run(queue chan queuedEvent) {
+	defer r.wg.Done()
+	stopping := r.stopping
+	keepRunning(r.Logger, r.Metrics, "rate limited queue", func() { r.loop(queue, stopping) })
+}
+
+func (r *RateLimitedSender) loop(queue chan queuedEvent, stopping chan struct{}) {
+	interval := time.Duration(float64(time.Second) / r.EventsPerSecond)
+	for q := range queue {
+	wait:
+		for !r.take() {
+			select {
+			case <-time.After(interval):
+			case <-stopping:
+				break wait
+			}
+		}
+		AddWithContext(q.ctx, r.Sender, q.ev)
+	}
+}
+
+// take refills the bucket for the time that's passed and takes a token from
+// it, reporting false if there wasn't one.
+func (r *RateLimitedSender) take() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := r.now()
+	r.tokens += now.Sub(r.last).Seconds() * r.EventsPerSecond
+	if r.tokens > float64(r.Burst) {
+		r.tokens = float64(r.Burst)
+	}
+	r.last = now
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+func (r *RateLimitedSender) now() time.Time {
+	if r.testNower != nil {
+		return r.testNower.Now()
+	}
+	return time.Now()
+}
+
+func (r *RateLimitedSender) TxResponses() chan Response {
+	return r.Sender.TxResponses()
+}
+
+// Done returns the wrapped Sender's Done channel. See DoneNotifier.
+func (r *RateLimitedSender) Done() <-chan struct{} {
+	return Done(r.Sender)
+}
+
+func (r *RateLimitedSender) SendResponse(resp Response) bool {
+	return r.Sender.SendResponse(resp)
+}
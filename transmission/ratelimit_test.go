@@ -0,0 +1,106 @@
+package transmission
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedSenderDrops(t *testing.T) {
+	mock := &MockSender{}
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	r := &RateLimitedSender{Sender: mock, EventsPerSecond: 1, Burst: 2, testNower: nower}
+	testOK(t, r.Start())
+	for i := 0; i < 3; i++ {
+		r.Add(&Event{Metadata: i})
+	}
+	assert.Equal(t, 2, len(mock.Events()))
+	rsp := testGetResponse(t, r.TxResponses())
+	assert.Equal(t, ErrRateLimited, rsp.Err)
+	assert.Equal(t, 2, rsp.Metadata)
+
+	nower.now = nower.now.Add(time.Second)
+	r.Add(&Event{Metadata: 3})
+	assert.Equal(t, 3, len(mock.Events()))
+	testOK(t, r.Stop())
+}
+
+func TestRateLimitedSenderSamples(t *testing.T) {
+	mock := &MockSender{}
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	r := &RateLimitedSender{
+		Sender:           mock,
+		EventsPerSecond:  1,
+		Policy:           RateLimitSample,
+		ExcessSampleRate: 1,
+		testNower:        nower,
+	}
+	testOK(t, r.Start())
+	r.Add(&Event{SampleRate: 3})
+	r.Add(&Event{SampleRate: 3})
+	evs := mock.Events()
+	assert.Equal(t, 2, len(evs))
+	assert.Equal(t, uint(3), evs[1].SampleRate)
+	testOK(t, r.Stop())
+}
+
+func TestRateLimitedSenderQueues(t *testing.T) {
+	mock := &MockSender{}
+	r := &RateLimitedSender{
+		Sender:              mock,
+		EventsPerSecond:     1000,
+		Burst:               1,
+		Policy:              RateLimitQueue,
+		PendingWorkCapacity: 10,
+	}
+	testOK(t, r.Start())
+	for i := 0; i < 5; i++ {
+		r.Add(&Event{Metadata: i})
+	}
+	waitFor(t, func() bool { return len(mock.Events()) == 5 })
+	for i, ev := range mock.Events() {
+		assert.Equal(t, i, ev.Metadata)
+	}
+	testOK(t, r.Stop())
+}
+
+func TestRateLimitedSenderQueuePassesContext(t *testing.T) {
+	child := &ctxSender{}
+	r := &RateLimitedSender{
+		Sender:          child,
+		EventsPerSecond: 1000,
+		Policy:          RateLimitQueue,
+	}
+	testOK(t, r.Start())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.AddWithContext(ctx, &Event{})
+	testOK(t, r.Stop())
+	assert.Equal(t, []context.Context{ctx}, child.ctxs)
+}
+
+func TestRateLimitedSenderAddDuringStop(t *testing.T) {
+	r := &RateLimitedSender{
+		Sender:          &MockSender{},
+		EventsPerSecond: 1000,
+		Policy:          RateLimitQueue,
+	}
+	testOK(t, r.Start())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			r.Add(&Event{})
+		}
+	}()
+	testOK(t, r.Stop())
+	wg.Wait()
+}
+
+func TestRateLimitedSenderRequiresRate(t *testing.T) {
+	testErr(t, NewRateLimitedSender(&MockSender{}, 0).Start())
+}
@@ -0,0 +1,173 @@
+package transmission
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordedEvent is how a RecorderSender writes an event to its file: all of
+// it but the Metadata, which can't be relied on to marshal.
+type recordedEvent struct {
+	APIHost    string                 `json:"api_host"`
+	APIKey     string                 `json:"api_key"`
+	Dataset    string                 `json:"dataset"`
+	SampleRate uint                   `json:"samplerate,omitempty"`
+	Timestamp  time.Time              `json:"time"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// RecorderSender implements the Sender interface by capturing every event
+// added to it, including the API host and key that other local senders leave
+// out, so that they can be replayed through a real Sender later with Replay.
+// This is useful for capturing the telemetry of a run to debug a pipeline
+// with.
+//
+// Events are kept in memory unless Path is set, in which case they're
+// appended to that file, one JSON record per line, instead. The file holds
+// API keys in the clear, so protect it accordingly.
+type RecorderSender struct {
+	// Path, if set, is the file to record events to. It's created if it
+	// doesn't exist and appended to if it does.
+	Path string
+
+	BlockOnResponses  bool
+	ResponseQueueSize uint
+	responses         chan Response
+	stopped           stopSignal
+
+	events []*Event
+	file   *os.File
+
+	sync.Mutex
+}
+
+func (r *RecorderSender) Start() error {
+	if r.ResponseQueueSize == 0 {
+		r.ResponseQueueSize = 100
+	}
+	if r.Path != "" {
+		f, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		r.Lock()
+		r.file = f
+		r.Unlock()
+	}
+	r.responses = make(chan Response, r.ResponseQueueSize)
+	r.stopped.start()
+	return nil
+}
+
+// Stop closes the file, if there is one, and then the responses channel.
+func (r *RecorderSender) Stop() error {
+	r.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.Unlock()
+	r.stopped.stop(r.responses)
+	return err
+}
+
+func (r *RecorderSender) Add(ev *Event) {
+	err := r.record(ev)
+	r.SendResponse(Response{Metadata: ev.Metadata, Err: err})
+}
+
+func (r *RecorderSender) record(ev *Event) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.Path == "" {
+		// copied so later changes to the event's data aren't replayed
+		rec := *ev
+		rec.Data = make(map[string]interface{}, len(ev.Data))
+		for k, v := range ev.Data {
+			rec.Data[k] = v
+		}
+		r.events = append(r.events, &rec)
+		return nil
+	}
+	if r.file == nil {
+		return fmt.Errorf("RecorderSender for %s isn't started", r.Path)
+	}
+	line, err := json.Marshal(recordedEvent{
+		APIHost:    ev.APIHost,
+		APIKey:     ev.APIKey,
+		Dataset:    ev.Dataset,
+		SampleRate: ev.SampleRate,
+		Timestamp:  ev.Timestamp,
+		Data:       ev.Data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.file.Write(append(line, '\n'))
+	return err
+}
+
+// Events returns the events recorded in memory so far.
+func (r *RecorderSender) Events() []*Event {
+	r.Lock()
+	defer r.Unlock()
+	return append([]*Event(nil), r.events...)
+}
+
+// Replay adds every recorded event to sender, in the order they were
+// recorded, and returns how many it added. With Path set the events are read
+// back from the file, so a RecorderSender with the same Path can replay a
+// recording made by an earlier process. sender must already be started.
+func (r *RecorderSender) Replay(sender Sender) (int, error) {
+	if r.Path == "" {
+		events := r.Events()
+		for _, ev := range events {
+			rec := *ev
+			sender.Add(&rec)
+		}
+		return len(events), nil
+	}
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), DefaultMaxSpoolRecordSize)
+	var n int
+	for scanner.Scan() {
+		var rec recordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return n, fmt.Errorf("bad record %d in %s: %v", n+1, r.Path, err)
+		}
+		sender.Add(&Event{
+			APIHost:    rec.APIHost,
+			APIKey:     rec.APIKey,
+			Dataset:    rec.Dataset,
+			SampleRate: rec.SampleRate,
+			Timestamp:  rec.Timestamp,
+			Data:       rec.Data,
+		})
+		n++
+	}
+	return n, scanner.Err()
+}
+
+func (r *RecorderSender) TxResponses() chan Response {
+	return r.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (r *RecorderSender) Done() <-chan struct{} {
+	return r.stopped.done()
+}
+
+func (r *RecorderSender) SendResponse(resp Response) bool {
+	return r.stopped.writeResponse(r.responses, resp, r.BlockOnResponses, nil, nil)
+}
@@ -0,0 +1,74 @@
+package transmission
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordTestEvents(t *testing.T, r *RecorderSender) {
+	testOK(t, r.Start())
+	ev := &Event{
+		APIHost:    "https://api.example.com",
+		APIKey:     "key",
+		Dataset:    "ds",
+		SampleRate: 4,
+		Timestamp:  time.Unix(1500000000, 0).UTC(),
+		Metadata:   "m",
+		Data:       map[string]interface{}{"a": "b"},
+	}
+	r.Add(ev)
+	ev.Data["a"] = "changed"
+	r.Add(&Event{APIHost: "https://other.example.com", Dataset: "ds2", Data: map[string]interface{}{}})
+	resp := <-r.TxResponses()
+	assert.Equal(t, "m", resp.Metadata)
+	assert.NoError(t, resp.Err)
+	testOK(t, r.Stop())
+}
+
+func TestRecorderSenderInMemory(t *testing.T) {
+	r := &RecorderSender{}
+	recordTestEvents(t, r)
+	assert.Equal(t, 2, len(r.Events()))
+
+	mock := &MockSender{}
+	n, err := r.Replay(mock)
+	testOK(t, err)
+	assert.Equal(t, 2, n)
+	events := mock.Events()
+	assert.Equal(t, "https://api.example.com", events[0].APIHost)
+	assert.Equal(t, "key", events[0].APIKey)
+	assert.Equal(t, "b", events[0].Data["a"])
+	assert.Equal(t, "m", events[0].Metadata)
+	assert.Equal(t, "ds2", events[1].Dataset)
+}
+
+func TestRecorderSenderFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	recordTestEvents(t, &RecorderSender{Path: path})
+
+	mock := &MockSender{}
+	n, err := (&RecorderSender{Path: path}).Replay(mock)
+	testOK(t, err)
+	assert.Equal(t, 2, n)
+	events := mock.Events()
+	ev := events[0]
+	assert.Equal(t, "https://api.example.com", ev.APIHost)
+	assert.Equal(t, "key", ev.APIKey)
+	assert.Equal(t, "ds", ev.Dataset)
+	assert.Equal(t, uint(4), ev.SampleRate)
+	assert.Equal(t, time.Unix(1500000000, 0).UTC(), ev.Timestamp.UTC())
+	assert.Equal(t, "b", ev.Data["a"])
+	assert.Nil(t, ev.Metadata)
+
+	_, err = (&RecorderSender{Path: filepath.Join(dir, "missing")}).Replay(mock)
+	testErr(t, err)
+}
@@ -0,0 +1,40 @@
+package transmission
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// logPanic reports a panic recovered in one of this package's goroutines. A
+// bug in an encoding edge case or a user callback shouldn't crash the host
+// application or quietly stop its telemetry, so these goroutines recover,
+// log the stack and count a "panics" metric, then carry on.
+func logPanic(logger Logger, metrics Metrics, where string, p interface{}) {
+	NewLeveledLogger(logger).Errorf(Fields{"where": where, "stack": string(debug.Stack())},
+		"recovered from panic in %s: %v", where, p)
+	if metrics != nil {
+		metrics.Increment("panics")
+	}
+}
+
+// panicError is the Response error for events whose sending panicked.
+func panicError(p interface{}) error {
+	return fmt.Errorf("panic while sending event: %v", p)
+}
+
+// keepRunning runs work, restarting it whenever it panics, until it returns
+// normally.
+func keepRunning(logger Logger, metrics Metrics, where string, work func()) {
+	for !runRecovered(logger, metrics, where, work) {
+	}
+}
+
+func runRecovered(logger Logger, metrics Metrics, where string, work func()) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(logger, metrics, where, p)
+		}
+	}()
+	work()
+	return true
+}
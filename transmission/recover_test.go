@@ -0,0 +1,123 @@
+package transmission
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	lock sync.Mutex
+	msgs []string
+}
+
+func (l *recordingLogger) Printf(msg string, args ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf(msg, args...))
+}
+
+type panickingRoundTripper struct{}
+
+func (panickingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	panic("boom")
+}
+
+func TestKeepRunningRestarts(t *testing.T) {
+	logger := &recordingLogger{}
+	runs := 0
+	keepRunning(logger, nil, "test worker", func() {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+	})
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, 2, len(logger.msgs))
+	assert.Contains(t, logger.msgs[0], "recovered from panic in test worker: boom")
+}
+
+func TestFireBatchRecoversPanics(t *testing.T) {
+	logger := &recordingLogger{}
+	b := newRetryTestBatch(panickingRoundTripper{}, 0, nil)
+	b.logger = logger
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Metadata: "m", Data: map[string]interface{}{"a": 1}})
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "other", Metadata: "m", Data: map[string]interface{}{"a": 1}})
+	b.Fire(&testNotifier{})
+	for i := 0; i < 2; i++ {
+		rsp := testGetResponse(t, b.responses)
+		testErr(t, rsp.Err)
+		assert.Equal(t, "m", rsp.Metadata)
+	}
+	assert.Equal(t, 2, len(logger.msgs), "each batch should be recovered separately")
+}
+
+func TestResponseCallbackPanicsRecovered(t *testing.T) {
+	logger := &recordingLogger{}
+	calls := 0
+	c := NewResponseCallbackSender(&MockSender{BlockOnResponses: true}, func(r Response) {
+		calls++
+		if r.Metadata == "bad" {
+			panic("bad response")
+		}
+	})
+	c.Logger = logger
+	testOK(t, c.Start())
+	c.SendResponse(Response{Metadata: "bad"})
+	c.SendResponse(Response{Metadata: "good"})
+	testOK(t, c.Stop())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, len(logger.msgs))
+	assert.True(t, strings.Contains(logger.msgs[0], "response callback"))
+}
+
+type countingMetrics struct {
+	nullMetrics
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+func (m *countingMetrics) Increment(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[name]++
+}
+
+func TestPublishPanicsLogged(t *testing.T) {
+	logger := &recordingLogger{}
+	metrics := &countingMetrics{}
+	k := &KafkaSender{
+		Producer: KafkaProducerFunc(func(topic string, key, value []byte) error {
+			panic("producer bug")
+		}),
+		Topic:   "events",
+		Logger:  logger,
+		Metrics: metrics,
+	}
+	testOK(t, k.Start())
+	k.Add(&Event{Metadata: "m", Data: map[string]interface{}{"a": 1}})
+	testOK(t, k.Stop())
+	rsp := <-k.TxResponses()
+	testErr(t, rsp.Err)
+	assert.Equal(t, 1, len(logger.msgs))
+	assert.Contains(t, logger.msgs[0], "recovered from panic in publish: producer bug")
+	assert.Contains(t, logger.msgs[0], "goroutine", "the stack should be logged")
+	assert.Equal(t, 1, metrics.counts["panics"])
+}
+
+func TestResponseRoutePanicsLogged(t *testing.T) {
+	logger := &recordingLogger{}
+	metrics := &countingMetrics{}
+	route := &ResponseRoute{Callback: func(Response) { panic("callback bug") }}
+	writeToResponse(make(chan Response), Response{Metadata: route}, false, logger, metrics)
+	assert.Equal(t, 1, len(logger.msgs))
+	assert.Contains(t, logger.msgs[0], "recovered from panic in response route callback: callback bug")
+	assert.Equal(t, 1, metrics.counts["panics"])
+}
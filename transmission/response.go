@@ -33,6 +33,23 @@ type Response struct {
 	// Metadata is whatever content you put in the Metadata field of the event for
 	// which this is the response. It is passed through unmodified.
 	Metadata interface{}
+
+	// Attempts is how many times the batch containing the event was sent,
+	// including retries. It is 0 if the event was never sent.
+	Attempts int
+
+	// RetryDuration is the total time spent waiting between attempts to send
+	// the event's batch.
+	RetryDuration time.Duration
+
+	// LastBackoff is how long was waited before the final attempt, or 0 if
+	// the first attempt was the last.
+	LastBackoff time.Duration
+
+	// Warnings describes anything changed about the event before it was
+	// sent, such as fields dropped to keep it under a limit. The event was
+	// still sent; Err says whether that succeeded.
+	Warnings []string
 }
 
 func (r *Response) UnmarshalJSON(b []byte) error {
@@ -50,10 +67,53 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// writeToResponse adds the response to the response queue. Returns true if it
-// dropped the response because it's set to not block on the queue being full
-// and the queue was full.
-func writeToResponse(responses chan Response, resp Response, block bool) (dropped bool) {
+// ResponseRoute sends the Response for one event to Callback instead of the
+// Sender's response channel. Set it as the event's Metadata; the Response is
+// delivered with the original Metadata put back. All the Senders in this
+// package honor it. Callback is called on the Sender's goroutine, so it should
+// return quickly, eg by handing the Response to a buffered channel.
+type ResponseRoute struct {
+	Metadata interface{}
+	Callback func(Response)
+}
+
+// deliver calls the route's callback, recovering from and reporting any
+// panic in it.
+func (rr *ResponseRoute) deliver(r Response, logger Logger, metrics Metrics) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(logger, metrics, "response route callback", p)
+		}
+	}()
+	r.Metadata = rr.Metadata
+	rr.Callback(r)
+}
+
+// routeThrough returns ev, or if its Response is routed, a copy whose
+// Response goes to relay instead, with ev's own ResponseRoute as its Metadata.
+// Senders that wrap others use it so that routed Responses pass through them
+// like any other Response, rather than going straight from the innermost
+// Sender to the route; relay should pass the Response on as usual.
+func routeThrough(ev *Event, relay func(Response)) *Event {
+	rr, ok := ev.Metadata.(*ResponseRoute)
+	if !ok || rr.Callback == nil {
+		return ev
+	}
+	c := *ev
+	c.Metadata = &ResponseRoute{Metadata: rr, Callback: relay}
+	return &c
+}
+
+// writeToResponse adds the response to the response queue, or hands it to its
+// ResponseRoute. Returns true if it dropped the response because it's set to
+// not block on the queue being full and the queue was full. Panics in a
+// route's callback are reported to logger and metrics, either of which may be
+// nil.
+func writeToResponse(responses chan Response, resp Response, block bool, logger Logger, metrics Metrics) (dropped bool) {
+	if rr, ok := resp.Metadata.(*ResponseRoute); ok && rr.Callback != nil {
+		rr.deliver(resp, logger, metrics)
+		return false
+	}
 	if block {
 		responses <- resp
 	} else {
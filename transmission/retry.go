@@ -0,0 +1,59 @@
+package transmission
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultRetryRatio is the fraction of send volume that may be retries
+	// when no RetryBudget is configured.
+	DefaultRetryRatio = 0.2
+
+	// retryBudgetMinBalance lets a few retries through even before much has
+	// been sent, so that a failure right at startup can still be retried.
+	retryBudgetMinBalance = 10.0
+	// retryBudgetMaxBalance bounds how many retries can be saved up during a
+	// long healthy stretch and then spent all at once.
+	retryBudgetMaxBalance = 100.0
+)
+
+// RetryBudget limits retries to a fixed fraction of send volume, following
+// the retry budget pattern: every send deposits Ratio tokens and every retry
+// spends one, so when the budget is exhausted further retries are refused
+// until more sends top it up. This keeps aggressive retries during a wide
+// outage from multiplying egress traffic and slowing recovery. A RetryBudget
+// is safe for concurrent use and may be shared between senders.
+type RetryBudget struct {
+	ratio   float64
+	balance float64
+	lock    sync.Mutex
+}
+
+// NewRetryBudget returns a budget allowing retries of up to ratio times the
+// number of sends, eg 0.2 for retries to be at most 20% of send volume.
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{
+		ratio:   ratio,
+		balance: retryBudgetMinBalance,
+	}
+}
+
+func (r *RetryBudget) recordSend() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.balance += r.ratio
+	if r.balance > retryBudgetMaxBalance {
+		r.balance = retryBudgetMaxBalance
+	}
+}
+
+// tryRetry spends from the budget and reports whether a retry is allowed.
+func (r *RetryBudget) tryRetry() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.balance < 1 {
+		return false
+	}
+	r.balance--
+	return true
+}
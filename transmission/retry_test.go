@@ -0,0 +1,139 @@
+package transmission
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget(t *testing.T) {
+	rb := NewRetryBudget(0.5)
+	for i := 0; i < int(retryBudgetMinBalance); i++ {
+		assert.True(t, rb.tryRetry(), "the minimum balance should allow some retries up front")
+	}
+	assert.False(t, rb.tryRetry())
+	rb.recordSend()
+	assert.False(t, rb.tryRetry(), "half a token isn't enough for a retry")
+	rb.recordSend()
+	assert.True(t, rb.tryRetry())
+
+	for i := 0; i < 1000; i++ {
+		rb.recordSend()
+	}
+	var allowed int
+	for rb.tryRetry() {
+		allowed++
+	}
+	assert.Equal(t, int(retryBudgetMaxBalance), allowed, "saved up retries should be capped")
+}
+
+// sequenceRoundTripper returns each of its responses in turn
+type sequenceRoundTripper struct {
+	statuses []int
+	calls    int
+	bodies   []string
+}
+
+func (s *sequenceRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	b, _ := ioutil.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(b))
+	status := s.statuses[s.calls]
+	s.calls++
+	if status == 0 {
+		return nil, errors.New("connection reset")
+	}
+	body := ""
+	if status == http.StatusOK {
+		body = `[{"status":202}]`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func newRetryTestBatch(rt http.RoundTripper, maxRetries uint, budget *RetryBudget) *batchAgg {
+	return &batchAgg{
+		httpClient:             &http.Client{Transport: rt},
+		testNower:              &fakeNower{},
+		responses:              make(chan Response, 2),
+		metrics:                &nullMetrics{},
+		disableGzipCompression: true,
+		maxRetries:             maxRetries,
+		retryBudget:            budget,
+	}
+}
+
+func TestFireBatchRetries(t *testing.T) {
+	rt := &sequenceRoundTripper{statuses: []int{0, http.StatusServiceUnavailable, http.StatusOK}}
+	b := newRetryTestBatch(rt, 3, NewRetryBudget(DefaultRetryRatio))
+	b.retryBackoff = time.Millisecond
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+	b.Fire(&testNotifier{})
+
+	assert.Equal(t, 3, rt.calls)
+	assert.Equal(t, rt.bodies[0], rt.bodies[2], "every attempt should send the full body")
+	rsp := testGetResponse(t, b.responses)
+	testOK(t, rsp.Err)
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+	assert.Equal(t, 3, rsp.Attempts)
+	assert.Equal(t, 3*b.retryBackoff, rsp.RetryDuration)
+	assert.Equal(t, 2*b.retryBackoff, rsp.LastBackoff)
+}
+
+func TestFireBatchDoesNotRetryClientErrors(t *testing.T) {
+	rt := &sequenceRoundTripper{statuses: []int{http.StatusBadRequest}}
+	b := newRetryTestBatch(rt, 3, nil)
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+	b.Fire(&testNotifier{})
+	assert.Equal(t, 1, rt.calls)
+	rsp := testGetResponse(t, b.responses)
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	assert.Equal(t, 1, rsp.Attempts)
+	assert.Equal(t, time.Duration(0), rsp.LastBackoff)
+}
+
+func TestFireBatchRetryBudgetExhausted(t *testing.T) {
+	rt := &sequenceRoundTripper{statuses: []int{0, 0, 0}}
+	budget := NewRetryBudget(0)
+	for budget.tryRetry() {
+	}
+	b := newRetryTestBatch(rt, 3, budget)
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+	b.Fire(&testNotifier{})
+	assert.Equal(t, 1, rt.calls, "an empty budget should prevent retries")
+	rsp := testGetResponse(t, b.responses)
+	testErr(t, rsp.Err)
+}
+
+func TestFireBatchDropsStaleEvents(t *testing.T) {
+	rt := &sequenceRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	b := newRetryTestBatch(rt, 3, nil)
+	b.testNower = nil
+	b.responses = make(chan Response, 3)
+	b.retryBackoff = 20 * time.Millisecond
+	now := time.Now()
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Metadata: "expired", staleAt: now.Add(-time.Second), Data: map[string]interface{}{"a": 1}})
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Metadata: "expires", staleAt: now.Add(10 * time.Millisecond), Data: map[string]interface{}{"a": 2}})
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Metadata: "fresh", Data: map[string]interface{}{"a": 3}})
+	b.Fire(&testNotifier{})
+
+	assert.Equal(t, 2, rt.calls)
+	assert.Contains(t, rt.bodies[0], `"a":2`)
+	assert.NotContains(t, rt.bodies[0], `"a":1`, "expired events aren't sent")
+	assert.NotContains(t, rt.bodies[1], `"a":2`, "events expiring while retrying aren't sent")
+	assert.Contains(t, rt.bodies[1], `"a":3`)
+	for _, md := range []string{"expired", "expires"} {
+		r := testGetResponse(t, b.responses)
+		assert.Equal(t, md, r.Metadata)
+		assert.Equal(t, ErrEventStale, r.Err)
+	}
+	r := testGetResponse(t, b.responses)
+	assert.Equal(t, "fresh", r.Metadata)
+	assert.Nil(t, r.Err)
+}
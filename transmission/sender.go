@@ -1,5 +1,14 @@
 package transmission
 
+import (
+	"context"
+	"sync"
+)
+
+// DefaultPendingWorkCapacity is how many events the queueing senders in this
+// package (other than Honeycomb) allow to pile up when no capacity is set.
+const DefaultPendingWorkCapacity = 10000
+
 // Sender is responsible for handling events after Send() is called.
 // Implementations of Add() must be safe for concurrent calls.
 type Sender interface {
@@ -11,7 +20,8 @@ type Sender interface {
 	Start() error
 
 	// Stop flushes any pending queues and blocks until everything in flight has
-	// been sent
+	// been sent. The Senders in this package then close the TxResponses
+	// channel, after the final Response, so readers ranging over it finish.
 	Stop() error
 
 	// Responses returns a channel that will contain a single Response for each
@@ -25,3 +35,133 @@ type Sender interface {
 	// in the Responses channel.
 	SendResponse(Response) bool
 }
+
+// ContextAdder is implemented by Senders that can give up on adding an event
+// when a context is done, eg rather than blocking on a full queue. The
+// event's Response records the context's error.
+type ContextAdder interface {
+	AddWithContext(ctx context.Context, ev *Event)
+}
+
+// ContextStopper is implemented by Senders that can stop without waiting
+// indefinitely for queued and in-flight events to be sent. StopWithContext
+// gives up on sending once ctx is done, returning how many events were
+// abandoned along with ctx's error. The Sender is fully stopped when it
+// returns, so it can be started again.
+type ContextStopper interface {
+	StopWithContext(ctx context.Context) (abandoned int, err error)
+}
+
+// StopWithContext stops s, giving up waiting once ctx is done. Senders that
+// aren't ContextStoppers are left to finish stopping in the background, and
+// the number of events abandoned is unknown, so reported as 0.
+func StopWithContext(ctx context.Context, s Sender) (abandoned int, err error) {
+	if cs, ok := s.(ContextStopper); ok {
+		return cs.StopWithContext(ctx)
+	}
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop() }()
+	select {
+	case err := <-stopped:
+		return 0, err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// DoneNotifier is implemented by Senders that signal when they've stopped.
+// Done returns a channel that's closed once Stop has closed the TxResponses
+// channel, so goroutines reading Responses can select on it to exit. Each
+// Start begins with a fresh channel.
+type DoneNotifier interface {
+	Done() <-chan struct{}
+}
+
+// Done returns s's Done channel if s is a DoneNotifier, and nil (which never
+// becomes ready) otherwise.
+func Done(s Sender) <-chan struct{} {
+	if dn, ok := s.(DoneNotifier); ok {
+		return dn.Done()
+	}
+	return nil
+}
+
+// stopSignal implements DoneNotifier for a Sender, which calls start when it
+// starts and stop, to close its responses channel, once it has sent the final
+// Response. Responses written through writeResponse are dropped once the
+// channel is closed, rather than panicking.
+type stopSignal struct {
+	lock   sync.Mutex
+	ch     chan struct{}
+	closed bool
+	// writing is held for reading by writeResponse, so that stop doesn't
+	// close the responses channel mid write
+	writing sync.RWMutex
+}
+
+func (s *stopSignal) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ch == nil || s.closed {
+		s.ch = make(chan struct{})
+		s.closed = false
+	}
+}
+
+// stop closes responses, if it's set, and then the Done channel. Only the
+// first call after each start has any effect.
+func (s *stopSignal) stop(responses chan Response) {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	if responses != nil {
+		close(responses)
+	}
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	close(s.ch)
+	s.closed = true
+}
+
+// writeResponse writes resp like writeToResponse, unless the responses channel
+// has been closed by stop, in which case it drops resp and counts it in
+// metrics, which may be nil. Routed Responses are still delivered.
+func (s *stopSignal) writeResponse(responses chan Response, resp Response, block bool, logger Logger, metrics Metrics) (dropped bool) {
+	s.writing.RLock()
+	defer s.writing.RUnlock()
+	if rr, ok := resp.Metadata.(*ResponseRoute); !ok || rr.Callback == nil {
+		s.lock.Lock()
+		closed := s.closed
+		s.lock.Unlock()
+		if closed {
+			if metrics != nil {
+				metrics.Increment("responses_after_stop")
+			}
+			return true
+		}
+	}
+	return writeToResponse(responses, resp, block, logger, metrics)
+}
+
+func (s *stopSignal) done() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+// AddWithContext adds ev to s, passing ctx along if s is a ContextAdder.
+func AddWithContext(ctx context.Context, s Sender, ev *Event) {
+	if ca, ok := s.(ContextAdder); ok {
+		ca.AddWithContext(ctx, ev)
+		return
+	}
+	s.Add(ev)
+}
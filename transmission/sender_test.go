@@ -0,0 +1,106 @@
+package transmission
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// plainSender is a Sender that doesn't implement any of the optional
+// interfaces.
+type plainSender struct {
+	Sender
+}
+
+func TestDoneAfterFinalResponse(t *testing.T) {
+	for name, s := range map[string]Sender{
+		"Honeycomb": &Honeycomb{
+			MaxBatchSize:         10,
+			BatchTimeout:         time.Hour,
+			MaxConcurrentBatches: 1,
+			PendingWorkCapacity:  10,
+			Transport:            discardRoundTripper{n: 1},
+		},
+		"WriterSender": &WriterSender{W: ioutil.Discard},
+		"TeeSender":    NewTeeSender(&WriterSender{W: ioutil.Discard}),
+		"FilterSender": NewFilterSender(&WriterSender{W: ioutil.Discard}, nil),
+	} {
+		testOK(t, s.Start())
+		done := Done(s)
+		s.Add(&Event{APIHost: "http://fakeHost:8080", APIKey: "key", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+
+		var got int
+		read := make(chan struct{})
+		go func(responses chan Response) {
+			defer close(read)
+			for {
+				select {
+				case _, ok := <-responses:
+					if !ok {
+						return
+					}
+					got++
+				case <-done:
+					// the responses channel is closed first, so nothing is missed
+					for range responses {
+						got++
+					}
+					return
+				}
+			}
+		}(s.TxResponses())
+		testOK(t, s.Stop())
+		<-read
+		<-done
+		assert.Equal(t, 1, got, name)
+
+		testOK(t, s.Start())
+		select {
+		case <-Done(s):
+			t.Errorf("%s: Start should begin with an open Done channel", name)
+		default:
+		}
+		testOK(t, s.Stop())
+	}
+	assert.Nil(t, Done(plainSender{&MockSender{}}))
+}
+
+func TestResponsesAfterStopDropped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libhoney-after-stop")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	for name, s := range map[string]Sender{
+		"MockSender":     &MockSender{},
+		"WriterSender":   &WriterSender{W: ioutil.Discard},
+		"FileSender":     &FileSender{Path: filepath.Join(dir, "events.log")},
+		"RecorderSender": &RecorderSender{},
+	} {
+		testOK(t, s.Start())
+		testOK(t, s.Stop())
+		assert.NotPanics(t, func() {
+			s.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+			s.SendResponse(Response{Metadata: "late"})
+		}, name)
+	}
+
+	metrics := &countingMetrics{}
+	h := &Honeycomb{
+		MaxBatchSize:         10,
+		BatchTimeout:         time.Hour,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		Metrics:              metrics,
+	}
+	testOK(t, h.Start())
+	testOK(t, h.Stop())
+	assert.True(t, h.SendResponse(Response{Metadata: "late"}), "dropped")
+	assert.Equal(t, 1, metrics.counts["responses_after_stop"])
+
+	var routed []Response
+	h.SendResponse(Response{Metadata: &ResponseRoute{Callback: func(r Response) { routed = append(routed, r) }}})
+	assert.Equal(t, 1, len(routed), "routed responses are still delivered")
+}
@@ -0,0 +1,60 @@
+package transmission
+
+import (
+	"github.com/facebookgo/muster"
+)
+
+// sendShard is one of a Honeycomb sender's queues: a muster client batching
+// events and, with MaxPendingWorkCapacity, the overflow queue feeding it.
+type sendShard struct {
+	muster   *muster.Client
+	overflow *elasticQueue
+}
+
+// shardFor returns the shard ev should be queued in. Events for the same API
+// host, key and dataset always go to the same shard, so they still end up in
+// the same batches and stay in order.
+func (h *Honeycomb) shardFor(ev *Event) *sendShard {
+	switch len(h.shards) {
+	case 0:
+		// not started
+		return &sendShard{muster: &h.muster}
+	case 1:
+		return h.shards[0]
+	}
+	return h.shards[destinationHash(ev)%uint32(len(h.shards))]
+}
+
+// sendShards returns all of the sender's shards.
+func (h *Honeycomb) sendShards() []*sendShard {
+	if len(h.shards) == 0 {
+		return []*sendShard{{muster: &h.muster}}
+	}
+	return h.shards
+}
+
+// destinationHash is the 32 bit FNV-1a hash of the batch key of ev, computed
+// without allocating.
+func destinationHash(ev *Event) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for _, s := range []string{ev.APIHost, ev.APIKey, ev.Dataset} {
+		for i := 0; i < len(s); i++ {
+			h ^= uint32(s[i])
+			h *= prime32
+		}
+		// separate the parts so that eg "ab"+"c" and "a"+"bc" differ
+		h ^= 0
+		h *= prime32
+	}
+	return h
+}
+
+// perShard returns total split between n shards, rounding up so that no
+// shard gets nothing when total isn't zero.
+func perShard(total uint, n int) uint {
+	return (total + uint(n) - 1) / uint(n)
+}
@@ -0,0 +1,95 @@
+package transmission
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombShards(t *testing.T) {
+	h := &Honeycomb{
+		MaxBatchSize:           1,
+		BatchTimeout:           time.Millisecond,
+		MaxConcurrentBatches:   8,
+		PendingWorkCapacity:    100,
+		MaxPendingWorkCapacity: 200,
+		Shards:                 4,
+		Transport:              discardRoundTripper{n: 1},
+	}
+	testOK(t, h.Start())
+	assert.Equal(t, 4, len(h.shards))
+	assert.Equal(t, &h.muster, h.shards[0].muster)
+	for _, s := range h.shards {
+		assert.Equal(t, uint(25), s.muster.PendingWorkCapacity)
+		assert.Equal(t, uint(2), s.muster.MaxConcurrentBatches)
+		assert.NotNil(t, s.overflow)
+	}
+
+	used := map[*sendShard]bool{}
+	for i := 0; i < 16; i++ {
+		ev := &Event{APIHost: "http://fakeHost:8080", APIKey: "key", Dataset: fmt.Sprintf("ds%d", i)}
+		s := h.shardFor(ev)
+		assert.Equal(t, s, h.shardFor(&Event{APIHost: ev.APIHost, APIKey: ev.APIKey, Dataset: ev.Dataset}),
+			"a destination's events should share a shard")
+		used[s] = true
+	}
+	assert.True(t, len(used) > 1, "destinations should be spread across shards")
+
+	for i := 0; i < 20; i++ {
+		h.Add(&Event{APIHost: "http://fakeHost:8080", APIKey: "key", Dataset: fmt.Sprintf("ds%d", i%8), Data: map[string]interface{}{"i": i}})
+	}
+	testOK(t, h.Stop())
+	var responses int
+	for range h.TxResponses() {
+		responses++
+	}
+	assert.Equal(t, 20, responses, "every shard should be flushed on Stop")
+	assert.Equal(t, 0, len(h.TakePending()))
+}
+
+func BenchmarkHoneycombAddDistinctDatasets(b *testing.B) {
+	const goroutines = 16
+	for _, shards := range []uint{1, goroutines} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			h := &Honeycomb{
+				MaxBatchSize:         50,
+				BatchTimeout:         10 * time.Millisecond,
+				MaxConcurrentBatches: goroutines,
+				PendingWorkCapacity:  10000,
+				BlockOnSend:          true,
+				Shards:               shards,
+				Transport:            discardRoundTripper{n: 50},
+			}
+			if err := h.Start(); err != nil {
+				b.Fatal(err)
+			}
+			go func() {
+				for range h.TxResponses() {
+				}
+			}()
+			b.ReportAllocs()
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(dataset string) {
+					defer wg.Done()
+					for i := 0; i < b.N/goroutines; i++ {
+						h.Add(&Event{
+							APIHost: "http://fakeHost:8080",
+							APIKey:  "key",
+							Dataset: dataset,
+							Data:    map[string]interface{}{"i": i},
+						})
+					}
+				}(fmt.Sprintf("ds%d", g))
+			}
+			wg.Wait()
+			b.StopTimer()
+			h.Stop()
+		})
+	}
+}
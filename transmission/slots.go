@@ -0,0 +1,42 @@
+package transmission
+
+import (
+	"context"
+	"sync"
+)
+
+// hostSlots limits how many requests may be in flight to each API host at
+// once, across all of a sender's batches.
+type hostSlots struct {
+	limit int
+	lock  sync.Mutex
+	sems  map[string]chan struct{}
+}
+
+func newHostSlots(limit uint) *hostSlots {
+	return &hostSlots{limit: int(limit), sems: make(map[string]chan struct{})}
+}
+
+// acquire waits for a free slot for host, returning a function to give it
+// back, or ctx's error if ctx is done first. Giving a slot back more than
+// once is harmless. acquire may be called on a nil hostSlots, which never
+// waits.
+func (s *hostSlots) acquire(ctx context.Context, host string) (release func(), err error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	s.lock.Lock()
+	sem, ok := s.sems[host]
+	if !ok {
+		sem = make(chan struct{}, s.limit)
+		s.sems[host] = sem
+	}
+	s.lock.Unlock()
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-sem }) }, nil
+}
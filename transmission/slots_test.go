@@ -0,0 +1,84 @@
+package transmission
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyRoundTripper records the most requests it saw in flight at once
+type concurrencyRoundTripper struct {
+	inFlight, max int32
+}
+
+func (c *concurrencyRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	n := atomic.AddInt32(&c.inFlight, 1)
+	for {
+		m := atomic.LoadInt32(&c.max)
+		if n <= m || atomic.CompareAndSwapInt32(&c.max, m, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	atomic.AddInt32(&c.inFlight, -1)
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202}]`)),
+	}, nil
+}
+
+func TestFireBatchLimitsRequestsPerHost(t *testing.T) {
+	crt := &concurrencyRoundTripper{}
+	slots := newHostSlots(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		b := &batchAgg{
+			httpClient: &http.Client{Transport: crt},
+			responses:  make(chan Response, 1),
+			metrics:    &nullMetrics{},
+			slots:      slots,
+		}
+		ev := &Event{APIHost: "http://fakeHost:8080", Dataset: fmt.Sprintf("ds%d", i), Data: map[string]interface{}{"a": 1}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.fireBatch([]*Event{ev})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&crt.max))
+}
+
+func TestHostSlotsAcquire(t *testing.T) {
+	s := newHostSlots(1)
+	release, err := s.acquire(context.Background(), "a")
+	testOK(t, err)
+	// other hosts have their own slots
+	releaseB, err := s.acquire(context.Background(), "b")
+	testOK(t, err)
+	releaseB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.acquire(ctx, "a")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	release()
+	release()
+	release, err = s.acquire(context.Background(), "a")
+	testOK(t, err)
+	release()
+
+	var unlimited *hostSlots
+	release, err = unlimited.acquire(context.Background(), "a")
+	testOK(t, err)
+	release()
+}
@@ -0,0 +1,239 @@
+package transmission
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSpoolRecordSize is the longest line a SpoolReader will read.
+const DefaultMaxSpoolRecordSize = 1 << 20
+
+// ErrSpoolRecordTooLarge is returned by SpoolReader.Next for a line longer
+// than its maximum record size. The line is skipped.
+var ErrSpoolRecordTooLarge = errors.New("spool record too large")
+
+// ErrSpoolRecordCorrupt is returned by SpoolReader.Next for a line that isn't
+// a valid event record. The line is skipped.
+var ErrSpoolRecordCorrupt = errors.New("corrupt spool record")
+
+// SpoolOptions configures how a spool is read back.
+type SpoolOptions struct {
+	// MaxRecordSize is the longest line that will be read; longer ones are
+	// skipped. Defaults to DefaultMaxSpoolRecordSize.
+	MaxRecordSize int
+	// Cipher decrypts records written by a FileSender with Encryption set.
+	// When set, unencrypted records are treated as corrupt.
+	Cipher *SpoolCipher
+	// Prepare, if set, is called by ReplaySpool on each event before it is
+	// added, eg to set its APIKey and APIHost, which aren't written to the
+	// spool.
+	Prepare func(*Event)
+	// DecompressFields restores fields compressed with CompressBlob to their
+	// original contents as each event is read, for inspecting a spool. Leave
+	// it unset when replaying to Honeycomb, so that events stay compressed and
+	// under the size limit.
+	DecompressFields bool
+}
+
+// SpoolReader reads back events written as newline-delimited JSON by a
+// FileSender or WriterSender. It streams through the file with a fixed-size
+// buffer, holding one event in memory at a time, so replaying a spool of any
+// size after an outage doesn't spike memory. Gzipped files (named *.gz) are
+// decompressed as they're read.
+type SpoolReader struct {
+	r          *bufio.Reader
+	cipher     *SpoolCipher
+	closer     []io.Closer
+	decompress bool
+	// set once the segment header is read; every record must then carry a
+	// checksum
+	checksummed bool
+}
+
+// OpenSpool opens the spool file at path for reading.
+func OpenSpool(path string, opts SpoolOptions) (*SpoolReader, error) {
+	maxRecordSize := opts.MaxRecordSize
+	if maxRecordSize <= 0 {
+		maxRecordSize = DefaultMaxSpoolRecordSize
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &SpoolReader{cipher: opts.Cipher, closer: []io.Closer{f}, decompress: opts.DecompressFields}
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		g, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		s.closer = append(s.closer, g)
+		r = g
+	}
+	s.r = bufio.NewReaderSize(r, maxRecordSize)
+	return s, nil
+}
+
+// Next returns the next event in the spool, or io.EOF once there are none
+// left. Malformed or oversized records return an error but are skipped, so
+// reading can carry on.
+func (s *SpoolReader) Next() (*Event, error) {
+	for {
+		_, ev, err := s.next()
+		if err != errSpoolHeader {
+			return ev, err
+		}
+	}
+}
+
+// next returns the next record both as read, without its newline, and
+// decoded. line is only valid until the following call.
+func (s *SpoolReader) next() (line []byte, ev *Event, err error) {
+	for {
+		line, err = s.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// discard the rest of the line
+			for err == bufio.ErrBufferFull {
+				_, err = s.r.ReadSlice('\n')
+			}
+			if err != nil && err != io.EOF {
+				return nil, nil, err
+			}
+			return nil, nil, ErrSpoolRecordTooLarge
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if string(line) == spoolSegmentHeader {
+			s.checksummed = true
+			return line, nil, errSpoolHeader
+		}
+		ev, err = s.decode(line)
+		return line, ev, err
+	}
+}
+
+// Close closes the underlying file.
+func (s *SpoolReader) Close() error {
+	var firstErr error
+	for i := len(s.closer) - 1; i >= 0; i-- {
+		if err := s.closer[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *SpoolReader) decode(line []byte) (*Event, error) {
+	if payload, ok := checkRecord(line); ok {
+		line = payload
+	} else if s.checksummed {
+		// a torn write, or garbage, in a checksummed segment
+		return nil, ErrSpoolRecordCorrupt
+	}
+	encrypted := bytes.HasPrefix(line, []byte(encryptedRecordPrefix))
+	if encrypted != (s.cipher != nil) {
+		return nil, ErrSpoolRecordCorrupt
+	}
+	if encrypted {
+		var err error
+		if line, err = s.cipher.open(line); err != nil {
+			return nil, err
+		}
+	}
+	var rec struct {
+		Data       map[string]interface{} `json:"data"`
+		SampleRate uint                   `json:"samplerate"`
+		Time       *time.Time             `json:"time"`
+		Dataset    string                 `json:"dataset"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	// keep numbers exactly as they were written
+	dec.UseNumber()
+	if err := dec.Decode(&rec); err != nil {
+		return nil, ErrSpoolRecordCorrupt
+	}
+	ev := &Event{
+		Dataset:    rec.Dataset,
+		SampleRate: rec.SampleRate,
+		Data:       rec.Data,
+	}
+	if rec.Time != nil {
+		ev.Timestamp = *rec.Time
+	}
+	if s.decompress {
+		// a field that fails to decode is left compressed rather than losing
+		// the whole event
+		DecompressFields(ev.Data)
+	}
+	return ev, nil
+}
+
+// SpoolSegments returns the files making up the spool at path, as written by
+// a FileSender: rotated files oldest first, then the current file.
+func SpoolSegments(path string) ([]string, error) {
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+	if _, err := os.Stat(path); err == nil {
+		rotated = append(rotated, path)
+	}
+	return rotated, nil
+}
+
+// ReplaySpool reads every event in the given spool segments, in order, and
+// adds it to dst, stopping early if ctx is done. Records that are corrupt or
+// too large are skipped and counted; any other error, such as a record
+// encrypted with a key the cipher can't find, stops the replay. Only one
+// event is held in memory at a time; use a dst that blocks on send (eg
+// Honeycomb with BlockOnSend) to keep its queue from overflowing.
+func ReplaySpool(ctx context.Context, segments []string, dst Sender, opts SpoolOptions) (replayed, skipped int, err error) {
+	for _, seg := range segments {
+		s, err := OpenSpool(seg, opts)
+		if err != nil {
+			return replayed, skipped, err
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				s.Close()
+				return replayed, skipped, err
+			}
+			ev, err := s.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				if err == ErrSpoolRecordTooLarge || err == ErrSpoolRecordCorrupt {
+					skipped++
+					continue
+				}
+				s.Close()
+				return replayed, skipped, err
+			}
+			if opts.Prepare != nil {
+				opts.Prepare(ev)
+			}
+			AddWithContext(ctx, dst, ev)
+			replayed++
+		}
+		s.Close()
+	}
+	return replayed, skipped, nil
+}
@@ -0,0 +1,132 @@
+package transmission
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// encryptedRecordPrefix starts every encrypted spool record, which can't be
+// mistaken for a JSON one.
+const encryptedRecordPrefix = "enc1 "
+
+// ErrSpoolKeyUnknown is returned while reading an encrypted spool record whose
+// key can't be found.
+var ErrSpoolKeyUnknown = errors.New("spool record encrypted with unknown key")
+
+// SpoolCipher encrypts spool records at rest with AES-GCM, so that events
+// buffered on disk don't expose sensitive fields. Each record is sealed
+// separately under a fresh nonce, with the ID of its key recorded alongside
+// it; the authentication tag means tampered or truncated records are detected
+// and skipped when the spool is read back.
+type SpoolCipher struct {
+	// KeyID identifies the key new records are encrypted with. It must not
+	// contain spaces.
+	KeyID string
+	// Key is the AES key for KeyID, 16, 24 or 32 bytes long to select
+	// AES-128, AES-192 or AES-256. If nil, it's looked up with Keyring.
+	Key []byte
+	// Keyring, if set, looks up keys by ID, so that records written under a
+	// previous key can still be read after rotating KeyID.
+	Keyring func(keyID string) ([]byte, error)
+
+	aeads map[string]cipher.AEAD
+	lock  sync.Mutex
+}
+
+// aead returns the cipher for keyID, building it on first use.
+func (c *SpoolCipher) aead(keyID string) (cipher.AEAD, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if a, ok := c.aeads[keyID]; ok {
+		return a, nil
+	}
+	var key []byte
+	if keyID == c.KeyID && c.Key != nil {
+		key = c.Key
+	} else if c.Keyring != nil {
+		var err error
+		if key, err = c.Keyring(keyID); err != nil {
+			return nil, err
+		}
+	}
+	if key == nil {
+		return nil, ErrSpoolKeyUnknown
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	a, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if c.aeads == nil {
+		c.aeads = make(map[string]cipher.AEAD)
+	}
+	c.aeads[keyID] = a
+	return a, nil
+}
+
+// check reports whether new records can be encrypted.
+func (c *SpoolCipher) check() error {
+	if c.KeyID == "" || strings.ContainsAny(c.KeyID, " \n") {
+		return fmt.Errorf("invalid spool key ID %q", c.KeyID)
+	}
+	_, err := c.aead(c.KeyID)
+	return err
+}
+
+// seal encrypts the record plain, returning it in spool form without the
+// trailing newline.
+func (c *SpoolCipher) seal(plain []byte) ([]byte, error) {
+	a, err := c.aead(c.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, a.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	// bind the key ID so it can't be swapped without detection
+	sealed := a.Seal(nonce, nonce, plain, []byte(c.KeyID))
+	out := make([]byte, 0, len(encryptedRecordPrefix)+len(c.KeyID)+1+base64.StdEncoding.EncodedLen(len(sealed)))
+	out = append(out, encryptedRecordPrefix...)
+	out = append(out, c.KeyID...)
+	out = append(out, ' ')
+	enc := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(enc, sealed)
+	return append(out, enc...), nil
+}
+
+// open reverses seal.
+func (c *SpoolCipher) open(line []byte) ([]byte, error) {
+	rest := line[len(encryptedRecordPrefix):]
+	sp := bytes.IndexByte(rest, ' ')
+	if sp < 0 {
+		return nil, ErrSpoolRecordCorrupt
+	}
+	keyID := string(rest[:sp])
+	a, err := c.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(rest)-sp-1))
+	n, err := base64.StdEncoding.Decode(sealed, rest[sp+1:])
+	if err != nil || n < a.NonceSize() {
+		return nil, ErrSpoolRecordCorrupt
+	}
+	sealed = sealed[:n]
+	plain, err := a.Open(nil, sealed[:a.NonceSize()], sealed[a.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, ErrSpoolRecordCorrupt
+	}
+	return plain, nil
+}
@@ -0,0 +1,137 @@
+package transmission
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// spoolSegmentHeader is the first line of every spool file written with
+// checksums, marking that each of its records carries one.
+const spoolSegmentHeader = "#libhoney-spool v1"
+
+// errSpoolHeader is returned internally by SpoolReader.next for the segment
+// header, which isn't a record.
+var errSpoolHeader = errors.New("spool segment header")
+
+var spoolCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumRecord prefixes payload with its CRC-32C, as 8 hex digits and a
+// space.
+func checksumRecord(payload []byte) []byte {
+	out := make([]byte, 9, 9+len(payload)+1)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(payload, spoolCRCTable))
+	hex.Encode(out, sum[:])
+	out[8] = ' '
+	return append(out, payload...)
+}
+
+// checkRecord returns the payload of a checksummed record, and whether line is
+// one whose checksum matches.
+func checkRecord(line []byte) ([]byte, bool) {
+	if len(line) < 9 || line[8] != ' ' {
+		return nil, false
+	}
+	var sum [4]byte
+	if _, err := hex.Decode(sum[:], line[:8]); err != nil {
+		return nil, false
+	}
+	payload := line[9:]
+	if binary.BigEndian.Uint32(sum[:]) != crc32.Checksum(payload, spoolCRCTable) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// SpoolReport summarizes the records in a spool segment.
+type SpoolReport struct {
+	// Records is how many records are intact.
+	Records int
+	// Corrupt is how many records are corrupt, torn by a crash mid-write, or
+	// too large to read.
+	Corrupt int
+}
+
+// VerifySpool reads through the spool segment at path, checking every record
+// without replaying any. Records that were encrypted are decrypted to check
+// them, so opts needs the cipher they were written with.
+func VerifySpool(path string, opts SpoolOptions) (SpoolReport, error) {
+	return scanSpool(path, opts, nil)
+}
+
+// RepairSpool rewrites the spool segment at path keeping only its intact
+// records, so that later replays don't trip over the corrupt ones. The
+// segment is replaced atomically once the rewrite is complete. Don't repair
+// the file a FileSender currently has open.
+func RepairSpool(path string, opts SpoolOptions) (SpoolReport, error) {
+	// a hidden name, so the temporary file isn't taken for a rotated segment
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".repair")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return SpoolReport{}, err
+	}
+	var w io.Writer = f
+	var g *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		g = gzip.NewWriter(f)
+		w = g
+	}
+	bw := bufio.NewWriter(w)
+	report, err := scanSpool(path, opts, bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil && g != nil {
+		err = g.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return report, err
+	}
+	return report, os.Rename(tmp, path)
+}
+
+// scanSpool reads every record in the segment at path, writing the intact ones
+// (and the segment header) to w if it's set.
+func scanSpool(path string, opts SpoolOptions, w io.Writer) (SpoolReport, error) {
+	var report SpoolReport
+	s, err := OpenSpool(path, opts)
+	if err != nil {
+		return report, err
+	}
+	defer s.Close()
+	for {
+		line, _, err := s.next()
+		switch err {
+		case nil:
+			report.Records++
+		case errSpoolHeader:
+		case io.EOF:
+			return report, nil
+		case ErrSpoolRecordCorrupt, ErrSpoolRecordTooLarge:
+			report.Corrupt++
+			continue
+		default:
+			return report, err
+		}
+		if w != nil {
+			if _, err := w.Write(line); err != nil {
+				return report, err
+			}
+			if _, err := w.Write([]byte{'\n'}); err != nil {
+				return report, err
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package transmission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	nower := &manualNower{now: time.Unix(1500000000, 0)}
+	f := &FileSender{
+		Path:              path,
+		MaxSizeBytes:      100,
+		Compress:          true,
+		ResponseQueueSize: 20,
+		testNower:         nower,
+	}
+	testOK(t, f.Start())
+	ts := time.Unix(1500000000, 0).UTC()
+	for i := 0; i < 6; i++ {
+		f.Add(&Event{Dataset: "ds", SampleRate: 2, Timestamp: ts, Data: map[string]interface{}{"n": i}})
+		nower.now = nower.now.Add(time.Second)
+	}
+	testOK(t, f.Stop())
+	// a corrupt line in the current segment is skipped
+	fh, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	testOK(t, err)
+	fh.WriteString("{not json\n")
+	fh.Close()
+
+	segments, err := SpoolSegments(path)
+	testOK(t, err)
+	assert.True(t, len(segments) > 1, "expected rotated segments")
+	assert.Equal(t, path, segments[len(segments)-1])
+
+	dst := &MockSender{}
+	replayed, skipped, err := ReplaySpool(context.Background(), segments, dst, SpoolOptions{
+		Prepare: func(ev *Event) { ev.APIKey = "key" },
+	})
+	testOK(t, err)
+	assert.Equal(t, 6, replayed)
+	assert.Equal(t, 1, skipped)
+	events := dst.Events()
+	assert.Equal(t, 6, len(events))
+	for i, ev := range events {
+		assert.Equal(t, "ds", ev.Dataset)
+		assert.Equal(t, "key", ev.APIKey)
+		assert.Equal(t, uint(2), ev.SampleRate)
+		assert.True(t, ts.Equal(ev.Timestamp))
+		assert.Equal(t, json.Number(strconv.Itoa(i)), ev.Data["n"], "events replay in order")
+	}
+}
+
+func TestSpoolReaderSkipsOversizedRecords(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	contents := "{\"data\":{\"a\":\"" + strings.Repeat("x", 100) + "\"}}\n{\"data\":{\"b\":1}}\n"
+	testOK(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	s, err := OpenSpool(path, SpoolOptions{MaxRecordSize: 32})
+	testOK(t, err)
+	defer s.Close()
+	_, err = s.Next()
+	assert.Equal(t, ErrSpoolRecordTooLarge, err)
+	ev, err := s.Next()
+	testOK(t, err)
+	assert.Equal(t, json.Number("1"), ev.Data["b"])
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestSpoolEncryption(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+
+	f := &FileSender{Path: path, Encryption: &SpoolCipher{KeyID: "old", Key: oldKey}, ResponseQueueSize: 10}
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"secret": "hunter2"}})
+	testOK(t, f.Stop())
+	// rotate to a new key
+	f = &FileSender{Path: path, Encryption: &SpoolCipher{KeyID: "new", Key: newKey}, ResponseQueueSize: 10}
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"secret": "swordfish"}})
+	testOK(t, f.Stop())
+
+	contents, err := ioutil.ReadFile(path)
+	testOK(t, err)
+	assert.NotContains(t, string(contents), "hunter2")
+	assert.NotContains(t, string(contents), "swordfish")
+
+	// tamper with the second record
+	lines := strings.SplitAfter(string(contents), "\n")
+	tampered := []byte(lines[1])
+	tampered[len(tampered)-5] ^= 1
+	testOK(t, ioutil.WriteFile(path, []byte(lines[0]+string(tampered)+lines[0]), 0644))
+
+	keyring := func(id string) ([]byte, error) {
+		if id == "old" {
+			return oldKey, nil
+		}
+		return nil, ErrSpoolKeyUnknown
+	}
+	dst := &MockSender{}
+	replayed, skipped, err := ReplaySpool(context.Background(), []string{path}, dst, SpoolOptions{
+		Cipher: &SpoolCipher{KeyID: "new", Key: newKey, Keyring: keyring},
+	})
+	testOK(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, "hunter2", dst.Events()[0].Data["secret"])
+
+	// without the key the replay stops
+	_, _, err = ReplaySpool(context.Background(), []string{path}, &MockSender{}, SpoolOptions{
+		Cipher: &SpoolCipher{KeyID: "new", Key: newKey},
+	})
+	assert.Equal(t, ErrSpoolKeyUnknown, err)
+
+	// encrypted records can't be read as plain ones
+	_, skipped, err = ReplaySpool(context.Background(), []string{path}, &MockSender{}, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, 3, skipped)
+
+	testErr(t, (&FileSender{Path: path, Encryption: &SpoolCipher{KeyID: "bad id", Key: newKey}}).Start())
+	testErr(t, (&FileSender{Path: path, Encryption: &SpoolCipher{KeyID: "short", Key: []byte("x")}}).Start())
+}
+
+func TestSpoolChecksums(t *testing.T) {
+	path := tempLogPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	f := &FileSender{Path: path, Checksums: true, ResponseQueueSize: 10}
+	testOK(t, f.Start())
+	for i := 0; i < 3; i++ {
+		f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"n": i}})
+	}
+	testOK(t, f.Stop())
+	contents, err := ioutil.ReadFile(path)
+	testOK(t, err)
+	assert.True(t, strings.HasPrefix(string(contents), spoolSegmentHeader+"\n"))
+
+	r, err := OpenSpool(path, SpoolOptions{})
+	testOK(t, err)
+	ev, err := r.Next()
+	testOK(t, err)
+	assert.Equal(t, json.Number("0"), ev.Data["n"], "Next skips the segment header")
+	r.Close()
+
+	// flip a bit inside the second record, and tear the end off the third as
+	// if the process crashed mid-write
+	contents[bytes.Index(contents, []byte(`"n":1`))+4] = '7'
+	contents = contents[:len(contents)-6]
+	testOK(t, ioutil.WriteFile(path, contents, 0644))
+
+	report, err := VerifySpool(path, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, SpoolReport{Records: 1, Corrupt: 2}, report)
+
+	// appending after the crash starts a fresh record
+	testOK(t, f.Start())
+	f.Add(&Event{Dataset: "ds", Data: map[string]interface{}{"n": 3}})
+	testOK(t, f.Stop())
+	report, err = VerifySpool(path, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, SpoolReport{Records: 2, Corrupt: 2}, report)
+
+	report, err = RepairSpool(path, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, SpoolReport{Records: 2, Corrupt: 2}, report)
+	report, err = VerifySpool(path, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, SpoolReport{Records: 2}, report)
+	leftover, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	assert.Equal(t, []string{path}, leftover)
+
+	dst := &MockSender{}
+	replayed, skipped, err := ReplaySpool(context.Background(), []string{path}, dst, SpoolOptions{})
+	testOK(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, json.Number("0"), dst.Events()[0].Data["n"])
+	assert.Equal(t, json.Number("3"), dst.Events()[1].Data["n"])
+}
@@ -0,0 +1,70 @@
+package transmission
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// State is where a Honeycomb sender is in its lifecycle.
+type State int32
+
+const (
+	// StateNew is a sender that has never been started.
+	StateNew State = iota
+	// StateStarting is a sender partway through Start.
+	StateStarting
+	// StateRunning is a started sender accepting events.
+	StateRunning
+	// StateDraining is a sender partway through Stop, sending what's queued.
+	StateDraining
+	// StateStopped is a sender that has been stopped. It can be started again.
+	StateStopped
+	// StateFailed is a sender whose Start returned an error. It can be
+	// started again.
+	StateFailed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "new"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	case StateFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrAlreadyStarted is returned by Start on a sender that is starting,
+	// running or draining.
+	ErrAlreadyStarted = errors.New("sender already started")
+	// ErrNotRunning is returned by Stop on a sender that isn't running.
+	ErrNotRunning = errors.New("sender not running")
+)
+
+// State returns where the sender is in its lifecycle.
+func (h *Honeycomb) State() State {
+	return State(atomic.LoadInt32(&h.state))
+}
+
+// transition moves the sender to state to if it is in one of the states from,
+// calling OnStateChange, and reports whether it did.
+func (h *Honeycomb) transition(to State, from ...State) bool {
+	for _, f := range from {
+		if atomic.CompareAndSwapInt32(&h.state, int32(f), int32(to)) {
+			if h.OnStateChange != nil {
+				h.OnStateChange(f, to)
+			}
+			return true
+		}
+	}
+	return false
+}
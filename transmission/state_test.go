@@ -0,0 +1,55 @@
+package transmission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombLifecycle(t *testing.T) {
+	metrics := &countingMetrics{}
+	var changes []string
+	h := &Honeycomb{
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		Transport:            discardRoundTripper{n: 1},
+		Metrics:              metrics,
+		OnStateChange: func(from, to State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	}
+	assert.Equal(t, StateNew, h.State())
+	assert.Equal(t, ErrNotRunning, h.Stop(), "a new sender can't be stopped")
+
+	// muster requires a MaxBatchSize or BatchTimeout
+	assert.Error(t, h.Start())
+	assert.Equal(t, StateFailed, h.State())
+
+	h.MaxBatchSize = 1
+	h.BatchTimeout = time.Millisecond
+	testOK(t, h.Start())
+	assert.Equal(t, StateRunning, h.State())
+	assert.Equal(t, ErrAlreadyStarted, h.Start())
+
+	h.Add(&Event{APIHost: "http://fakeHost:8080", Data: map[string]interface{}{"a": 1}})
+	testOK(t, h.Stop())
+	assert.Equal(t, StateStopped, h.State())
+	assert.Equal(t, ErrNotRunning, h.Stop(), "stopping twice is an error, not a panic")
+	assert.Equal(t, 1, len(h.TxResponses()))
+
+	h.Add(&Event{APIHost: "http://fakeHost:8080", Data: map[string]interface{}{"a": 2}})
+	assert.Equal(t, 1, metrics.counts["add_after_stop"])
+
+	// senders can be restarted, as Flush does
+	testOK(t, h.Start())
+	testOK(t, h.Stop())
+
+	assert.Equal(t, []string{
+		"new->starting", "starting->failed",
+		"failed->starting", "starting->running",
+		"running->draining", "draining->stopped",
+		"stopped->starting", "starting->running",
+		"running->draining", "draining->stopped",
+	}, changes)
+}
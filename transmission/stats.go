@@ -0,0 +1,250 @@
+package transmission
+
+import (
+	"sync"
+	"time"
+)
+
+// dropReasons are the counters that count events dropped by a sender.
+var dropReasons = []string{"queue_overflow", "queue_evicted", "queue_expired", "add_after_stop", "send_cancelled"}
+
+// LatencyBuckets are the upper bounds of the SendLatency histogram buckets.
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts durations into buckets.
+type Histogram struct {
+	// Bounds are the upper bounds of each bucket but the last, which has no
+	// upper bound.
+	Bounds []time.Duration
+	// Counts holds how many durations fell in each bucket, so it's one
+	// longer than Bounds. They aren't cumulative.
+	Counts []int64
+	// Count and Sum are the number and total of all the durations.
+	Count int64
+	Sum   time.Duration
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(h.Bounds) && d > h.Bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h Histogram) copy() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// MetricsSnapshot is a point in time view of a sender's own metrics, so
+// operators can tell whether the SDK itself is losing data.
+type MetricsSnapshot struct {
+	// EventsQueued is how many events have been queued to send, and
+	// EventsSent how many of them the API has accepted.
+	EventsQueued int64
+	EventsSent   int64
+	// Dropped counts the events dropped without being sent by the reason
+	// they were dropped for, eg queue_overflow.
+	Dropped map[string]int64
+	// QueueDepth is how many events are waiting to be sent now.
+	QueueDepth int64
+	// BatchesSent is how many batch POSTs have succeeded, and SendErrors how
+	// many have failed.
+	BatchesSent int64
+	SendErrors  int64
+	// BytesEncoded is the size of the sent batches as JSON, and BytesSent
+	// their size on the wire after compression.
+	BytesEncoded int64
+	BytesSent    int64
+	// SendLatency is how long each sent batch took, including retries.
+	SendLatency Histogram
+	// Responses counts the events the API has responded to by the HTTP
+	// status code it gave them.
+	Responses map[int]int64
+	// Counters and Gauges hold every counter and the latest value of every
+	// gauge the sender has reported to its Metrics.
+	Counters map[string]int64
+	Gauges   map[string]float64
+}
+
+// MetricsReporter is implemented by Senders that keep a MetricsSnapshot of
+// their own metrics. They cover the life of the Sender, across restarts.
+type MetricsReporter interface {
+	MetricsSnapshot() MetricsSnapshot
+}
+
+// senderStats accumulates a sender's MetricsSnapshot.
+type senderStats struct {
+	lock         sync.Mutex
+	counters     map[string]int64
+	gauges       map[string]float64
+	bytesEncoded int64
+	bytesSent    int64
+	latency      Histogram
+	statusCodes  map[int]int64
+}
+
+func newSenderStats() *senderStats {
+	return &senderStats{
+		counters:    make(map[string]int64),
+		gauges:      make(map[string]float64),
+		latency:     newHistogram(LatencyBuckets),
+		statusCodes: make(map[int]int64),
+	}
+}
+
+func (s *senderStats) count(name string, n int64) {
+	s.lock.Lock()
+	s.counters[name] += n
+	s.lock.Unlock()
+}
+
+func (s *senderStats) gauge(name string, val float64) {
+	s.lock.Lock()
+	s.gauges[name] = val
+	s.lock.Unlock()
+}
+
+// recordBatch records a successfully sent batch. It may be called on a nil
+// senderStats, for batches not made by a started sender.
+func (s *senderStats) recordBatch(latency time.Duration, encoded, sent int) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency.observe(latency)
+	s.bytesEncoded += int64(encoded)
+	s.bytesSent += int64(sent)
+}
+
+// recordStatus counts a response with the given status code. Like
+// recordBatch it may be called on a nil senderStats.
+func (s *senderStats) recordStatus(code int) {
+	if s == nil || code == 0 {
+		return
+	}
+	s.lock.Lock()
+	s.statusCodes[code]++
+	s.lock.Unlock()
+}
+
+func (s *senderStats) snapshot() MetricsSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	snap := MetricsSnapshot{
+		EventsQueued: s.counters["messages_queued"],
+		Dropped:      make(map[string]int64),
+		BatchesSent:  s.counters["batches_sent"],
+		SendErrors:   s.counters["send_errors"],
+		BytesEncoded: s.bytesEncoded,
+		BytesSent:    s.bytesSent,
+		SendLatency:  s.latency.copy(),
+		Counters:     make(map[string]int64, len(s.counters)),
+		Gauges:       make(map[string]float64, len(s.gauges)),
+		Responses:    make(map[int]int64, len(s.statusCodes)),
+	}
+	for code, n := range s.statusCodes {
+		snap.Responses[code] = n
+	}
+	for k, v := range s.counters {
+		snap.Counters[k] = v
+	}
+	for k, v := range s.gauges {
+		snap.Gauges[k] = v
+	}
+	for _, reason := range dropReasons {
+		if n := s.counters[reason]; n > 0 {
+			snap.Dropped[reason] = n
+		}
+	}
+	return snap
+}
+
+// recordingMetrics records everything reported to a sender's Metrics in its
+// senderStats as well.
+type recordingMetrics struct {
+	Metrics
+	stats *senderStats
+}
+
+// recordMetrics returns m wrapped to record into s, unless it already is.
+func recordMetrics(m Metrics, s *senderStats) Metrics {
+	if r, ok := m.(*recordingMetrics); ok && r.stats == s {
+		return m
+	}
+	return &recordingMetrics{Metrics: m, stats: s}
+}
+
+func (r *recordingMetrics) Gauge(name string, val interface{}) {
+	r.Metrics.Gauge(name, val)
+	if f, ok := toFloat64(val); ok {
+		r.stats.gauge(name, f)
+	}
+}
+
+func (r *recordingMetrics) Increment(name string) {
+	r.Metrics.Increment(name)
+	r.stats.count(name, 1)
+}
+
+func (r *recordingMetrics) Count(name string, n interface{}) {
+	r.Metrics.Count(name, n)
+	if f, ok := toFloat64(n); ok {
+		r.stats.count(name, int64(f))
+	}
+}
+
+func toFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// MetricsSnapshot reports the sender's own metrics since it was first
+// started.
+func (h *Honeycomb) MetricsSnapshot() MetricsSnapshot {
+	if h.stats == nil {
+		return newSenderStats().snapshot()
+	}
+	snap := h.stats.snapshot()
+	s := h.Summary()
+	snap.EventsSent = s.Sent
+	snap.QueueDepth = s.Queued
+	return snap
+}
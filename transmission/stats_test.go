@@ -0,0 +1,90 @@
+package transmission
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombMetricsSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+
+	metrics := &countingMetrics{}
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         10 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		Metrics:              metrics,
+	}
+	assert.Equal(t, int64(0), h.MetricsSnapshot().EventsQueued, "unstarted senders have empty snapshots")
+	testOK(t, h.Start())
+	for i := 0; i < 3; i++ {
+		h.Add(&Event{
+			APIHost:   server.URL,
+			APIKey:    "key",
+			Dataset:   "ds",
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"a": i},
+		})
+		testOK(t, (<-h.TxResponses()).Err)
+	}
+	testOK(t, h.Stop())
+	testOK(t, h.Start())
+	testOK(t, h.Stop())
+
+	snap := h.MetricsSnapshot()
+	assert.Equal(t, int64(3), snap.EventsQueued)
+	assert.Equal(t, int64(3), snap.EventsSent)
+	assert.Equal(t, int64(0), snap.QueueDepth)
+	assert.Equal(t, int64(3), snap.BatchesSent)
+	assert.Equal(t, int64(0), snap.SendErrors)
+	assert.Equal(t, 0, len(snap.Dropped))
+	assert.True(t, snap.BytesEncoded > 0)
+	assert.True(t, snap.BytesSent > 0)
+	assert.Equal(t, int64(3), snap.SendLatency.Count, "one latency per batch")
+	assert.Equal(t, len(LatencyBuckets)+1, len(snap.SendLatency.Counts))
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	assert.Equal(t, 3, metrics.counts["messages_queued"], "metrics still reach the sender's Metrics only once")
+}
+
+func TestRecordingMetrics(t *testing.T) {
+	stats := newSenderStats()
+	m := recordMetrics(&nullMetrics{}, stats)
+	assert.Equal(t, m, recordMetrics(m, stats), "metrics aren't wrapped twice")
+	m.Increment("queue_overflow")
+	m.Increment("queue_overflow")
+	m.Count("messages_sent", 5)
+	m.Count("ignored", "five")
+	m.Gauge("queue_length", 7)
+
+	snap := stats.snapshot()
+	assert.Equal(t, map[string]int64{"queue_overflow": 2}, snap.Dropped)
+	assert.Equal(t, int64(5), snap.Counters["messages_sent"])
+	_, ok := snap.Counters["ignored"]
+	assert.False(t, ok)
+	assert.Equal(t, 7.0, snap.Gauges["queue_length"])
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond})
+	h.observe(time.Millisecond)
+	h.observe(10 * time.Millisecond)
+	h.observe(50 * time.Millisecond)
+	h.observe(time.Second)
+	assert.Equal(t, []int64{2, 1, 1}, h.Counts)
+	assert.Equal(t, int64(4), h.Count)
+	assert.Equal(t, 1061*time.Millisecond, h.Sum)
+
+	c := h.copy()
+	h.observe(time.Millisecond)
+	assert.Equal(t, int64(2), c.Counts[0], "copies don't share counts")
+}
@@ -0,0 +1,45 @@
+package transmission
+
+import "sync/atomic"
+
+// Summary counts what happened to the events added to a Sender, for logging
+// telemetry loss at shutdown.
+type Summary struct {
+	// Sent is how many events were accepted by the API.
+	Sent int64
+	// Queued is how many events are still waiting to be sent.
+	Queued int64
+	// Dropped is how many events were dropped because the queue was full.
+	Dropped int64
+	// InFlight is how many batches are being sent right now.
+	InFlight int64
+	// Exemplars identify the most recently sent events that carried trace
+	// IDs, oldest first, linking these counts to raw events.
+	Exemplars []Exemplar
+}
+
+// Summarizer is implemented by Senders that can report a Summary of the
+// events added to them. Counts cover the life of the Sender, across restarts.
+type Summarizer interface {
+	Summary() Summary
+}
+
+// Summary reports how many events have been sent, are still queued and were
+// dropped on a full queue since the sender was first started.
+func (h *Honeycomb) Summary() Summary {
+	var s Summary
+	if h.sent != nil {
+		s.Sent = atomic.LoadInt64(h.sent)
+	}
+	if h.pending != nil {
+		s.Queued = atomic.LoadInt64(h.pending)
+	}
+	if h.drops != nil {
+		s.Dropped = atomic.LoadInt64(&h.drops.dropped)
+	}
+	if h.inFlight != nil {
+		s.InFlight = atomic.LoadInt64(h.inFlight)
+	}
+	s.Exemplars = h.exemplars.snapshot()
+	return s
+}
@@ -0,0 +1,137 @@
+package transmission
+
+import (
+	"errors"
+	"sync"
+)
+
+// PendingTaker is implemented by Senders that can give up the events they
+// have queued but not yet begun sending, so that another Sender can send them
+// instead.
+type PendingTaker interface {
+	TakePending() []*Event
+}
+
+// SwitchSender implements the Sender interface by passing events to a
+// current Sender that can be replaced while running, eg when configuration
+// is reloaded. Switching hands events still queued in the old Sender to the
+// new one (when the old Sender is a PendingTaker) instead of waiting for them
+// to drain, so the old Sender only has to finish what it has in flight.
+// Responses from all Senders are passed through on the SwitchSender's own
+// channel.
+type SwitchSender struct {
+	BlockOnResponse   bool
+	ResponseQueueSize uint // defaults to DefaultPendingWorkCapacity * 2
+
+	lock    sync.RWMutex
+	current Sender
+
+	responses chan Response
+	done      chan struct{}
+	stopped   stopSignal
+	wg        sync.WaitGroup
+}
+
+// NewSwitchSender returns a SwitchSender initially sending to s.
+func NewSwitchSender(s Sender) *SwitchSender {
+	return &SwitchSender{current: s}
+}
+
+func (s *SwitchSender) Start() error {
+	if s.current == nil {
+		return errors.New("SwitchSender requires a Sender")
+	}
+	if s.ResponseQueueSize == 0 {
+		s.ResponseQueueSize = DefaultPendingWorkCapacity * 2
+	}
+	s.responses = make(chan Response, s.ResponseQueueSize)
+	s.done = make(chan struct{})
+	s.stopped.start()
+	return s.start(s.current)
+}
+
+// start starts next and begins relaying its responses.
+func (s *SwitchSender) start(next Sender) error {
+	if err := next.Start(); err != nil {
+		return err
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		relayResponses(next.TxResponses(), s.done, s.relay)
+	}()
+	return nil
+}
+
+// Switch starts next and makes it the current Sender, moves any events queued
+// in the old Sender to it, then stops the old Sender. If next fails to start,
+// the old Sender stays current.
+func (s *SwitchSender) Switch(next Sender) error {
+	if err := s.start(next); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	old := s.current
+	s.current = next
+	s.lock.Unlock()
+
+	if pt, ok := old.(PendingTaker); ok {
+		for _, ev := range pt.TakePending() {
+			next.Add(ev)
+		}
+	}
+	return old.Stop()
+}
+
+// Current returns the Sender events are currently being passed to.
+func (s *SwitchSender) Current() Sender {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.current
+}
+
+// Stop stops the current Sender and closes the responses channel once its
+// responses have been passed on.
+func (s *SwitchSender) Stop() error {
+	err := s.Current().Stop()
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+		s.stopped.stop(s.responses)
+		s.done = nil
+	}
+	return err
+}
+
+func (s *SwitchSender) Add(ev *Event) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	s.current.Add(routeThrough(ev, s.relay))
+}
+
+// TakePending takes the current Sender's pending events, if it's a
+// PendingTaker.
+func (s *SwitchSender) TakePending() []*Event {
+	if pt, ok := s.Current().(PendingTaker); ok {
+		return pt.TakePending()
+	}
+	return nil
+}
+
+func (s *SwitchSender) relay(r Response) {
+	s.SendResponse(r)
+}
+
+func (s *SwitchSender) TxResponses() chan Response {
+	return s.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (s *SwitchSender) Done() <-chan struct{} {
+	return s.stopped.done()
+}
+
+func (s *SwitchSender) SendResponse(r Response) bool {
+	return s.stopped.writeResponse(s.responses, r, s.BlockOnResponse, nil, nil)
+}
@@ -0,0 +1,87 @@
+package transmission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// queueingSender holds on to events until they're taken.
+type queueingSender struct {
+	MockSender
+}
+
+func (q *queueingSender) TakePending() []*Event {
+	q.Lock()
+	defer q.Unlock()
+	events := q.events
+	q.events = nil
+	return events
+}
+
+func TestSwitchSenderHandsOffPending(t *testing.T) {
+	old := &queueingSender{}
+	s := NewSwitchSender(old)
+	testOK(t, s.Start())
+	for i := 0; i < 3; i++ {
+		s.Add(&Event{Metadata: i})
+	}
+	old.SendResponse(Response{Metadata: "old"})
+
+	next := &MockSender{}
+	testOK(t, s.Switch(next))
+	assert.Equal(t, next, s.Current())
+	assert.Equal(t, 1, next.Started)
+	assert.Equal(t, 1, old.Stopped)
+	assert.Equal(t, 3, len(next.Events()), "queued events should move to the new sender")
+
+	s.Add(&Event{Metadata: 3})
+	assert.Equal(t, 4, len(next.Events()))
+	next.SendResponse(Response{Metadata: "next"})
+	testOK(t, s.Stop())
+	assert.Equal(t, 1, next.Stopped)
+
+	var metas []interface{}
+	for r := range s.TxResponses() {
+		metas = append(metas, r.Metadata)
+	}
+	assert.Contains(t, metas, "old")
+	assert.Contains(t, metas, "next")
+}
+
+func TestWrappingSendersTakePending(t *testing.T) {
+	inner := &queueingSender{}
+	s := NewSwitchSender(NewResponseCallbackSender(inner, func(Response) {}))
+	testOK(t, s.Start())
+	s.Add(&Event{Metadata: 1})
+	s.Add(&Event{Metadata: 2})
+	assert.Equal(t, 2, len(s.TakePending()), "pending events are taken through the wrappers")
+	assert.Equal(t, 0, len(inner.Events()))
+	testOK(t, s.Stop())
+
+	var pt PendingTaker = NewResponseCallbackSender(&MockSender{}, nil)
+	assert.Nil(t, pt.TakePending(), "nothing to take from a Sender that can't give events up")
+}
+
+func TestSwitchSenderTakesFromQueue(t *testing.T) {
+	h := &Honeycomb{pending: new(int64)}
+	h.muster.Work = make(chan interface{}, 5)
+	h.muster.Work <- &Event{Metadata: 1}
+	h.muster.Work <- &Event{Metadata: 2}
+	*h.pending = 2
+	pending := h.TakePending()
+	assert.Equal(t, 2, len(pending))
+	assert.Equal(t, 0, len(h.muster.Work))
+	assert.Equal(t, Summary{}, h.Summary(), "taken events no longer count as queued")
+	close(h.muster.Work)
+	assert.Equal(t, 0, len(h.TakePending()))
+}
+
+func TestSwitchSenderKeepsCurrentOnFailedStart(t *testing.T) {
+	first := &MockSender{}
+	s := NewSwitchSender(first)
+	testOK(t, s.Start())
+	testErr(t, s.Switch(&FileSender{}))
+	assert.Equal(t, first, s.Current())
+	testOK(t, s.Stop())
+}
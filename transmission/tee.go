@@ -0,0 +1,138 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// TeeSender implements the Sender interface by forwarding every event to each
+// of its child Senders, eg to send events to Honeycomb and also write them to
+// a local file. The children's Responses are multiplexed onto the TeeSender's
+// own channel, so each event added produces one Response per child. The
+// children are started and stopped along with the TeeSender and should not be
+// started separately.
+type TeeSender struct {
+	Senders []Sender
+
+	BlockOnResponse   bool
+	ResponseQueueSize uint // defaults to total capacity of the children's response channels
+
+	responses chan Response
+	done      chan struct{}
+	stopped   stopSignal
+	wg        sync.WaitGroup
+}
+
+// NewTeeSender returns a TeeSender forwarding to senders.
+func NewTeeSender(senders ...Sender) *TeeSender {
+	return &TeeSender{Senders: senders}
+}
+
+func (t *TeeSender) Start() error {
+	if len(t.Senders) == 0 {
+		return errors.New("TeeSender requires at least one Sender")
+	}
+	for _, s := range t.Senders {
+		if err := s.Start(); err != nil {
+			return err
+		}
+	}
+	size := t.ResponseQueueSize
+	if size == 0 {
+		for _, s := range t.Senders {
+			size += uint(cap(s.TxResponses()))
+		}
+	}
+	t.responses = make(chan Response, size)
+	t.done = make(chan struct{})
+	t.stopped.start()
+	for _, s := range t.Senders {
+		t.wg.Add(1)
+		go func(ch chan Response) {
+			defer t.wg.Done()
+			relayResponses(ch, t.done, t.relay)
+		}(s.TxResponses())
+	}
+	return nil
+}
+
+// relayResponses calls fn with each response from ch until ch is closed, or
+// until done is closed and ch has nothing left buffered.
+func relayResponses(ch chan Response, done chan struct{}, fn func(Response)) {
+	for {
+		select {
+		case r, ok := <-ch:
+			if !ok {
+				return
+			}
+			fn(r)
+		case <-done:
+			for {
+				select {
+				case r, ok := <-ch:
+					if !ok {
+						return
+					}
+					fn(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stop stops every child, returning the first error any of them returned,
+// then closes the responses channel once all their responses have been
+// forwarded.
+func (t *TeeSender) Stop() error {
+	var firstErr error
+	for _, s := range t.Senders {
+		if err := s.Stop(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if t.done != nil {
+		close(t.done)
+		t.wg.Wait()
+		t.stopped.stop(t.responses)
+		t.done = nil
+	}
+	return firstErr
+}
+
+func (t *TeeSender) Add(ev *Event) {
+	t.AddWithContext(context.Background(), ev)
+}
+
+// AddWithContext adds ev to every child, passing ctx along to those that are
+// ContextAdders. Each child gets its own copy of the Event, since senders may
+// annotate the events added to them; the copies share ev's Data, which must
+// not be modified after it is added.
+func (t *TeeSender) AddWithContext(ctx context.Context, ev *Event) {
+	for _, s := range t.Senders {
+		c := *ev
+		AddWithContext(ctx, s, routeThrough(&c, t.relay))
+	}
+}
+
+func (t *TeeSender) relay(r Response) {
+	t.SendResponse(r)
+}
+
+func (t *TeeSender) TxResponses() chan Response {
+	return t.responses
+}
+
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (t *TeeSender) Done() <-chan struct{} {
+	return t.stopped.done()
+}
+
+// SendResponse puts a single Response on the TeeSender's channel; it is not
+// copied to the children.
+func (t *TeeSender) SendResponse(r Response) bool {
+	return t.stopped.writeResponse(t.responses, r, t.BlockOnResponse, nil, nil)
+}
@@ -0,0 +1,74 @@
+package transmission
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeeSender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mock := &MockSender{}
+	tee := NewTeeSender(&WriterSender{W: buf}, mock)
+	testOK(t, tee.Start())
+	assert.Equal(t, 1, mock.Started)
+
+	tee.Add(&Event{Metadata: "m", Data: map[string]interface{}{"a": 1}})
+	assert.Equal(t, "{\"data\":{\"a\":1}}\n", buf.String())
+	assert.Equal(t, 1, len(mock.Events()))
+
+	// the mock doesn't generate responses on its own, so push one through it
+	mock.SendResponse(Response{Metadata: "from mock"})
+	testOK(t, tee.Stop())
+	assert.Equal(t, 1, mock.Stopped)
+
+	var metas []interface{}
+	for r := range tee.TxResponses() {
+		metas = append(metas, r.Metadata)
+	}
+	assert.Contains(t, metas, "m")
+	assert.Contains(t, metas, "from mock")
+	assert.Equal(t, 2, len(metas))
+}
+
+func TestTeeSenderRequiresChildren(t *testing.T) {
+	testErr(t, NewTeeSender().Start())
+}
+
+func TestTeeSenderCopiesEventsPerChild(t *testing.T) {
+	first, second := &MockSender{}, &MockSender{}
+	tee := NewTeeSender(first, second)
+	testOK(t, tee.Start())
+	ev := &Event{Metadata: "m", Data: map[string]interface{}{"a": 1}}
+	tee.Add(ev)
+	if first.Events()[0] == ev || first.Events()[0] == second.Events()[0] {
+		t.Error("each child should get its own copy of the event")
+	}
+	assert.Equal(t, ev.Data, second.Events()[0].Data)
+	testOK(t, tee.Stop())
+}
+
+// ctxSender records the contexts events are added with.
+type ctxSender struct {
+	MockSender
+	ctxs []context.Context
+}
+
+func (c *ctxSender) AddWithContext(ctx context.Context, ev *Event) {
+	c.ctxs = append(c.ctxs, ctx)
+	c.Add(ev)
+}
+
+func TestTeeSenderPassesContext(t *testing.T) {
+	child := &ctxSender{}
+	tee := NewTeeSender(child, &MockSender{})
+	testOK(t, tee.Start())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tee.AddWithContext(ctx, &Event{Data: map[string]interface{}{}})
+	assert.Equal(t, []context.Context{ctx}, child.ctxs)
+	assert.Equal(t, 1, len(child.Events()))
+	testOK(t, tee.Stop())
+}
@@ -0,0 +1,58 @@
+package transmission
+
+import "context"
+
+// TransformSender wraps another Sender, running Transform on each event
+// before passing it on. Transform may add, remove or rename fields, or change
+// any other part of the event, eg to scrub secrets or add deployment metadata
+// to events produced by third party libraries sharing the same client. It is
+// given a copy of the event with its own copy of Data, so changes don't leak
+// back to the caller.
+type TransformSender struct {
+	Sender    Sender
+	Transform func(*Event)
+}
+
+// NewTransformSender returns a TransformSender running transform over each
+// event before passing it on to s.
+func NewTransformSender(s Sender, transform func(*Event)) *TransformSender {
+	return &TransformSender{Sender: s, Transform: transform}
+}
+
+func (t *TransformSender) Add(ev *Event) {
+	t.AddWithContext(context.Background(), ev)
+}
+
+func (t *TransformSender) AddWithContext(ctx context.Context, ev *Event) {
+	if t.Transform != nil {
+		cp := *ev
+		cp.Data = make(map[string]interface{}, len(ev.Data))
+		for k, v := range ev.Data {
+			cp.Data[k] = v
+		}
+		t.Transform(&cp)
+		ev = &cp
+	}
+	AddWithContext(ctx, t.Sender, ev)
+}
+
+func (t *TransformSender) Start() error {
+	return t.Sender.Start()
+}
+
+func (t *TransformSender) Stop() error {
+	return t.Sender.Stop()
+}
+
+func (t *TransformSender) TxResponses() chan Response {
+	return t.Sender.TxResponses()
+}
+
+// Done returns the wrapped Sender's Done channel. See DoneNotifier.
+func (t *TransformSender) Done() <-chan struct{} {
+	return Done(t.Sender)
+}
+
+func (t *TransformSender) SendResponse(r Response) bool {
+	return t.Sender.SendResponse(r)
+}
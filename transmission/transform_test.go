@@ -0,0 +1,30 @@
+package transmission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformSender(t *testing.T) {
+	mock := &MockSender{}
+	ts := NewTransformSender(mock, func(ev *Event) {
+		ev.Data["user_id"] = ev.Data["uid"]
+		delete(ev.Data, "uid")
+		delete(ev.Data, "password")
+		ev.Data["deploy"] = "v42"
+	})
+	testOK(t, ts.Start())
+	orig := &Event{Dataset: "ds", Metadata: "m", Data: map[string]interface{}{
+		"uid":      7,
+		"password": "hunter2",
+	}}
+	ts.Add(orig)
+	evs := mock.Events()
+	assert.Equal(t, 1, len(evs))
+	assert.Equal(t, map[string]interface{}{"user_id": 7, "deploy": "v42"}, evs[0].Data)
+	assert.Equal(t, "ds", evs[0].Dataset)
+	assert.Equal(t, "m", evs[0].Metadata)
+	assert.Equal(t, 2, len(orig.Data), "the original event should be untouched")
+	testOK(t, ts.Stop())
+}
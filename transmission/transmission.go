@@ -13,16 +13,18 @@ package transmission
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
-	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/facebookgo/muster"
@@ -42,96 +44,546 @@ type Honeycomb struct {
 	BatchTimeout           time.Duration // how often to send off batches
 	MaxConcurrentBatches   uint          // how many batches can be inflight simultaneously
 	PendingWorkCapacity    uint          // how many events to allow to pile up
+	MaxPendingWorkCapacity uint          // how far the queue may grow past PendingWorkCapacity during bursts; the extra room is only allocated while in use
 	BlockOnSend            bool          // whether to block or drop events when the queue fills
 	BlockOnResponse        bool          // whether to block or drop responses when the queue fills
 	UserAgentAddition      string
 	DisableGzipCompression bool // toggles gzip compression when sending batches of events
 
+	// MaxConcurrentBatchesPerHost caps how many batch requests can be in
+	// flight to each API host at once, however many datasets and API keys
+	// they're for. Otherwise a burst across many datasets can have up to
+	// MaxConcurrentBatches requests open to a single host. Unlimited if 0.
+	MaxConcurrentBatchesPerHost uint
+
+	// Shards splits the queue into this many independent ones, each with an
+	// equal share of PendingWorkCapacity, MaxPendingWorkCapacity and
+	// MaxConcurrentBatches. Events are assigned to a shard by API host, key
+	// and dataset, so many goroutines adding events for different datasets
+	// don't all contend on a single queue, while each dataset's events are
+	// still batched together. Defaults to 1.
+	Shards uint
+
+	// OverflowPolicy decides which event is dropped when the queue is full:
+	// the new one (the default), the oldest queued one, or the new one after
+	// waiting up to OverflowTimeout for room. It doesn't apply with
+	// BlockOnSend, which waits for room indefinitely.
+	OverflowPolicy  OverflowPolicy
+	OverflowTimeout time.Duration
+
+	// MaxRetries is how many times to retry sending a batch that failed with a
+	// transport error, a 429 or a 5xx. Defaults to 0 - failed batches are not
+	// retried. Retries wait RetryBackoff (default 100ms), doubling each time.
+	MaxRetries   uint
+	RetryBackoff time.Duration
+	// RetryBudget caps retries to a fraction of overall send volume across all
+	// destinations, so that a wide outage doesn't multiply traffic. Share one
+	// budget between senders to cap them together. If MaxRetries is set and
+	// RetryBudget isn't, a budget allowing DefaultRetryRatio is used.
+	RetryBudget *RetryBudget
+
+	// BatchTimeoutJitter lengthens BatchTimeout by a random fraction of itself,
+	// up to this much, chosen once at Start. Many processes started together
+	// (eg by a deploy) then drift apart rather than sending in lockstep. 0.2
+	// means each sender uses a BatchTimeout between 1x and 1.2x the setting.
+	BatchTimeoutJitter float64
+	// SendSplay, if set, delays sending each batch by a random duration up to
+	// SendSplay, spreading sends from many processes across the interval.
+	// Batches flushed by Stop are sent without delay.
+	SendSplay time.Duration
+	// StampQueueTime adds meta.queue_time_ms to each event, the time between
+	// it being added and it being encoded for sending, to show when queueing
+	// in the SDK is adding latency.
+	StampQueueTime bool
+	// MaxBatchSizeBytes and MaxEventSizeBytes cap the encoded size of a batch
+	// and of a single event, for destinations such as Refinery or a proxy
+	// with different limits to the API. They default to 5MB and 100KB. Events
+	// over the limit are rejected with an EventTooLargeError.
+	MaxBatchSizeBytes int
+	MaxEventSizeBytes int
+	// MaxQueueAge, if set, drops events that have been waiting to be sent
+	// for longer than this, whether queued or being retried, with
+	// ErrEventStale as their Response. It stops a backlog built up during a
+	// long outage being sent once it's no longer useful.
+	MaxQueueAge time.Duration
+	// RequestTimeout is how long each batch POST, including reading its
+	// response, may take before it's abandoned with an error (and retried,
+	// with MaxRetries). It's independent of BatchTimeout, so a long flush
+	// interval doesn't allow hung requests as long. Defaults to 60 seconds.
+	RequestTimeout time.Duration
+
+	// FallbackAPIHost, if set, is where batches are sent while their own API
+	// host is failing. After FailoverThreshold (default 5) failed batches in a
+	// row to a host, its batches go to the fallback instead, with
+	// meta.failover_from set to the original host on each event. A single
+	// batch is tried against the original host every FailoverProbeInterval
+	// (default 30s), and traffic fails back as soon as one succeeds.
+	FallbackAPIHost       string
+	FailoverThreshold     uint
+	FailoverProbeInterval time.Duration
+
+	// RaceFirstConnect makes the first connection to each address race IPv4
+	// against IPv6 and stick with whichever connects first, for networks where
+	// one of them is broken. With FallbackAPIHost set, the first batch also
+	// races connecting to the API host and the fallback, starting out failed
+	// over if the fallback wins, waiting at most BatchTimeout to find out.
+	// Both races only apply when Transport is unset, since a custom Transport
+	// (eg one going through a proxy) may not connect the way they test.
+	RaceFirstConnect bool
+
+	// Proxy, if set, chooses the HTTP proxy for each batch request, as
+	// http.Transport's Proxy does; ProxyURL sends every request through the
+	// one proxy. Either overrides the HTTP_PROXY and HTTPS_PROXY environment
+	// variables, so that clients in one process can use different proxies.
+	// Like RaceFirstConnect they only apply when Transport is unset.
+	Proxy    func(*http.Request) (*url.URL, error)
+	ProxyURL *url.URL
+
+	// TLSConfig, if set, is used for connections to the API host, eg to
+	// present a client certificate to a gateway requiring mutual TLS or to
+	// trust a private CA; see NewTLSConfig. It only applies when Transport
+	// is unset.
+	TLSConfig *tls.Config
+
+	// MaxIdleConnsPerHost, IdleConnTimeout, DisableKeepAlives and
+	// ForceAttemptHTTP2 tune the sender's connections as the http.Transport
+	// fields of the same names do. http.DefaultTransport keeps just 2 idle
+	// connections per host, so a sender with more concurrent batches than
+	// that keeps reconnecting; setting MaxIdleConnsPerHost to
+	// MaxConcurrentBatches avoids it. ForceAttemptHTTP2 needs Go 1.13 or
+	// later and is ignored before. Like Proxy they only apply when Transport
+	// is unset.
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	ForceAttemptHTTP2   bool
+
+	// HedgePercentile, if set, hedges slow batch requests: once a request
+	// has taken longer than this percentile (eg 0.95) of recent ones, the
+	// batch is sent again, to FallbackAPIHost if set or else the same host,
+	// and whichever good response arrives first is used. The slower request
+	// is cancelled and its response ignored, so each event still gets one
+	// Response. But the slower request may already have been accepted, in
+	// which case its events are ingested twice: hedging trades possible
+	// duplicate events for lower latency. Both copies, like every retry of a
+	// batch, carry the same X-Honeycomb-Batch-Id header, so a proxy that
+	// dedupes on it can drop the second. Hedging starts once 20 requests
+	// have completed.
+	HedgePercentile float64
+
+	// CanonicalJSON encodes each event in a canonical form - keys sorted,
+	// no HTML escaping and numbers formatted the same way by every Go version
+	// - so that batch payloads are byte for byte reproducible, eg for
+	// checksums or golden files. It costs a second pass over each event.
+	CanonicalJSON bool
+
+	// DebugRequests logs every batch request at debug level: its URL and
+	// headers, with the write key redacted, the batch before compression,
+	// and the response's status and body. It's verbose and slows sending,
+	// so it's meant for diagnosing events that don't show up.
+	DebugRequests bool
+
+	// PublishExpvars publishes the sender's queue depth, queue overflow
+	// count and responses by HTTP status code in the expvar map "libhoney",
+	// while it's running. The counts of every sender publishing them are
+	// summed.
+	PublishExpvars bool
+
+	// OnBatchSend, if set, is called before each batch is sent, on the
+	// goroutine sending it, eg to measure the SDK's overhead. It must be
+	// quick; panics in it are recovered.
+	OnBatchSend func(BatchInfo)
+
+	// OnStateChange, if set, is called with each change to the sender's
+	// State, on the goroutine making it. It must not call Start or Stop.
+	OnStateChange func(from, to State)
+
+	// state is the sender's State
+	state int32
+
 	responses chan Response
+	stopping  chan struct{}
+	stopped   stopSignal
 
 	Transport http.RoundTripper
 
-	muster muster.Client
+	// muster is the first shard's; with one shard it's the only one
+	muster       muster.Client
+	shards       []*sendShard
+	drops        *dropCounter
+	dialer       *racingDialer
+	firstConnect *sync.Once
+
+	// pending counts events queued but not yet responded to
+	pending *int64
+	// inFlight counts batches being sent
+	inFlight *int64
+	// sent counts events accepted by the API
+	sent      *int64
+	exemplars *exemplarRing
+	stats     *senderStats
+	// sendCtx is cancelled to abandon sending by StopWithContext
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
 
 	Logger  Logger
 	Metrics Metrics
 }
 
-func (h *Honeycomb) Start() error {
+// Start starts the sender. It returns ErrAlreadyStarted unless the sender is
+// new, stopped or failed.
+func (h *Honeycomb) Start() (err error) {
+	if !h.transition(StateStarting, StateNew, StateStopped, StateFailed) {
+		return ErrAlreadyStarted
+	}
+	defer func() {
+		if err != nil {
+			h.transition(StateFailed, StateStarting)
+		} else {
+			h.transition(StateRunning, StateStarting)
+		}
+	}()
 	if h.Logger == nil {
 		h.Logger = &nullLogger{}
 	}
-	h.Logger.Printf("default transmission starting")
+	h.leveled().Debugf(nil, "default transmission starting")
 	h.responses = make(chan Response, h.PendingWorkCapacity*2)
+	h.stopped.start()
+	numShards := int(h.Shards)
+	if numShards < 1 {
+		numShards = 1
+	}
 	h.muster.MaxBatchSize = h.MaxBatchSize
-	h.muster.BatchTimeout = h.BatchTimeout
-	h.muster.MaxConcurrentBatches = h.MaxConcurrentBatches
-	h.muster.PendingWorkCapacity = h.PendingWorkCapacity
+	h.muster.BatchTimeout = jitter(h.BatchTimeout, h.BatchTimeoutJitter)
+	h.muster.MaxConcurrentBatches = perShard(h.MaxConcurrentBatches, numShards)
+	h.muster.PendingWorkCapacity = perShard(h.PendingWorkCapacity, numShards)
 	if h.Metrics == nil {
 		h.Metrics = &nullMetrics{}
 	}
+	if h.RequestTimeout <= 0 {
+		h.RequestTimeout = 60 * time.Second
+	}
+	if h.MaxRetries > 0 {
+		if h.RetryBackoff == 0 {
+			h.RetryBackoff = 100 * time.Millisecond
+		}
+		if h.RetryBudget == nil {
+			h.RetryBudget = NewRetryBudget(DefaultRetryRatio)
+		}
+	}
+	h.stopping = make(chan struct{})
+	var breakers *breakerSet
+	if h.FallbackAPIHost != "" {
+		if h.FailoverThreshold == 0 {
+			h.FailoverThreshold = 5
+		}
+		if h.FailoverProbeInterval == 0 {
+			h.FailoverProbeInterval = 30 * time.Second
+		}
+		breakers = &breakerSet{threshold: h.FailoverThreshold, probeInterval: h.FailoverProbeInterval}
+	}
+	var slots *hostSlots
+	if h.MaxConcurrentBatchesPerHost > 0 {
+		slots = newHostSlots(h.MaxConcurrentBatchesPerHost)
+	}
+	// counters carry over restarts, eg by Flush, for Summary
+	if h.drops == nil {
+		h.drops = &dropCounter{windowStart: time.Now()}
+		h.pending = new(int64)
+		h.inFlight = new(int64)
+		h.sent = new(int64)
+		h.exemplars = &exemplarRing{}
+		h.stats = newSenderStats()
+	}
+	h.Metrics = recordMetrics(h.Metrics, h.stats)
+	if h.PublishExpvars {
+		publishExpvars(h)
+	}
+	h.sendCtx, h.cancelSends = context.WithCancel(context.Background())
+	var hedging *hedger
+	if h.HedgePercentile > 0 {
+		hedging = &hedger{percentile: h.HedgePercentile}
+	}
+	if h.Transport == nil {
+		if h.RaceFirstConnect {
+			h.dialer = newRacingDialer()
+			h.firstConnect = &sync.Once{}
+		}
+		if h.ownTransport() {
+			h.Transport = h.defaultTransport()
+		}
+	}
 	h.muster.BatchMaker = func() muster.Batch {
 		return &batchAgg{
 			userAgentAddition: h.UserAgentAddition,
 			batches:           map[string][]*Event{},
 			httpClient: &http.Client{
 				Transport: h.Transport,
-				Timeout:   60 * time.Second,
 			},
+			requestTimeout:         h.RequestTimeout,
 			blockOnResponse:        h.BlockOnResponse,
 			responses:              h.responses,
+			stopped:                &h.stopped,
 			metrics:                h.Metrics,
+			logger:                 h.Logger,
 			disableGzipCompression: h.DisableGzipCompression,
+			maxRetries:             h.MaxRetries,
+			retryBackoff:           h.RetryBackoff,
+			retryBudget:            h.RetryBudget,
+			sendSplay:              h.SendSplay,
+			stopping:               h.stopping,
+			fallbackAPIHost:        h.FallbackAPIHost,
+			breakers:               breakers,
+			dialer:                 h.dialer,
+			firstConnect:           h.firstConnect,
+			firstConnectTimeout:    h.muster.BatchTimeout,
+			hedger:                 hedging,
+			canonicalJSON:          h.CanonicalJSON,
+			pending:                h.pending,
+			inFlight:               h.inFlight,
+			sent:                   h.sent,
+			maxBatchBytes:          h.MaxBatchSizeBytes,
+			slots:                  slots,
+			maxEventBytes:          h.MaxEventSizeBytes,
+			sendCtx:                h.sendCtx,
+			exemplars:              h.exemplars,
+			stats:                  h.stats,
+			debugRequests:          h.DebugRequests,
+			onBatchSend:            h.OnBatchSend,
+		}
+	}
+	h.shards = make([]*sendShard, numShards)
+	h.shards[0] = &sendShard{muster: &h.muster}
+	for i := 1; i < numShards; i++ {
+		m := &muster.Client{
+			MaxBatchSize:         h.muster.MaxBatchSize,
+			BatchTimeout:         h.muster.BatchTimeout,
+			MaxConcurrentBatches: h.muster.MaxConcurrentBatches,
+			PendingWorkCapacity:  h.muster.PendingWorkCapacity,
+			BatchMaker:           h.muster.BatchMaker,
+		}
+		h.shards[i] = &sendShard{muster: m}
+	}
+	for _, s := range h.shards {
+		if err := s.muster.Start(); err != nil {
+			return err
+		}
+		if h.MaxPendingWorkCapacity > h.PendingWorkCapacity && !h.BlockOnSend {
+			extra := perShard(h.MaxPendingWorkCapacity-h.PendingWorkCapacity, numShards)
+			s.overflow = newElasticQueue(int(extra), s.muster.Work, h.Logger, h.Metrics)
 		}
 	}
-	return h.muster.Start()
+	return nil
+}
+
+// StopWithContext stops the sender like Stop, except that once ctx is done it
+// abandons queued and in-flight batches, cancelling any requests in progress.
+// It returns how many events were abandoned; each still gets an error
+// Response.
+func (h *Honeycomb) StopWithContext(ctx context.Context) (abandoned int, err error) {
+	stopped := make(chan error, 1)
+	go func() { stopped <- h.Stop() }()
+	select {
+	case err := <-stopped:
+		return 0, err
+	case <-ctx.Done():
+	}
+	if h.pending != nil {
+		abandoned = int(atomic.LoadInt64(h.pending))
+	}
+	if h.cancelSends != nil {
+		h.cancelSends()
+	}
+	// with sends cancelled the remaining batches fail straight away
+	<-stopped
+	return abandoned, ctx.Err()
 }
 
+// Stop sends everything queued and stops the sender, closing TxResponses. It
+// returns ErrNotRunning unless the sender is running.
 func (h *Honeycomb) Stop() error {
-	h.Logger.Printf("Honeycomb transmission stopping")
-	err := h.muster.Stop()
-	close(h.responses)
+	if !h.transition(StateDraining, StateRunning) {
+		return ErrNotRunning
+	}
+	h.leveled().Debugf(nil, "Honeycomb transmission stopping")
+	close(h.stopping)
+	for _, s := range h.sendShards() {
+		if s.overflow != nil {
+			s.overflow.stop()
+		}
+	}
+	var err error
+	for _, s := range h.sendShards() {
+		if serr := s.muster.Stop(); err == nil {
+			err = serr
+		}
+	}
+	if h.cancelSends != nil {
+		h.cancelSends()
+	}
+	h.stopped.stop(h.responses)
+	unpublishExpvars(h)
+	h.transition(StateStopped, StateDraining)
 	return err
 }
 
 func (h *Honeycomb) Add(ev *Event) {
-	h.Logger.Printf("adding event to transmission; queue length %d", len(h.muster.Work))
-	h.Metrics.Gauge("queue_length", len(h.muster.Work))
+	h.AddWithContext(context.Background(), ev)
+}
+
+// AddWithContext adds an event like Add, except that with BlockOnSend it stops
+// waiting for room in the queue once ctx is done, dropping the event with
+// ctx's error as its Response.
+func (h *Honeycomb) AddWithContext(ctx context.Context, ev *Event) {
+	if st := h.State(); st == StateDraining || st == StateStopped {
+		// TxResponses is or is about to be closed, so there's nowhere to
+		// report the drop
+		h.leveled().Warnf(Fields{"dataset": ev.Dataset}, "dropping event added to %s sender", st)
+		h.Metrics.Increment("add_after_stop")
+		return
+	}
+	s := h.shardFor(ev)
+	h.leveled().Debugf(Fields{"dataset": ev.Dataset}, "adding event to transmission; queue length %d", len(s.muster.Work))
+	h.Metrics.Gauge("queue_length", len(s.muster.Work))
+	h.drops.add()
+	if h.StampQueueTime || h.MaxQueueAge > 0 {
+		now := time.Now()
+		if h.StampQueueTime {
+			ev.enqueuedAt = now
+		}
+		if h.MaxQueueAge > 0 {
+			ev.staleAt = now.Add(h.MaxQueueAge)
+		}
+	}
 	if h.BlockOnSend {
-		h.muster.Work <- ev
-		h.Metrics.Increment("messages_queued")
+		select {
+		case s.muster.Work <- ev:
+			h.queued()
+		case <-ctx.Done():
+			h.Metrics.Increment("send_cancelled")
+			h.stopped.writeResponse(h.responses, Response{
+				Err:      ctx.Err(),
+				Metadata: ev.Metadata,
+			}, h.BlockOnResponse, h.Logger, h.Metrics)
+		}
 	} else {
+		if s.overflow != nil && s.overflow.len() > 0 {
+			// keep events in order while the overflow drains
+			h.addOverflow(ctx, s, ev)
+			return
+		}
 		select {
-		case h.muster.Work <- ev:
-			h.Metrics.Increment("messages_queued")
+		case s.muster.Work <- ev:
+			h.queued()
 		default:
-			h.Metrics.Increment("queue_overflow")
-			r := Response{
-				Err:      errors.New("queue overflow"),
-				Metadata: ev.Metadata,
+			if s.overflow != nil {
+				h.addOverflow(ctx, s, ev)
+				return
 			}
-			h.Logger.Printf("got response code %d, error %s, and body %s",
-				r.StatusCode, r.Err, string(r.Body))
-			writeToResponse(h.responses, r, h.BlockOnResponse)
+			h.handleOverflow(ctx, s, ev)
 		}
 	}
 }
 
+// addOverflow queues ev in the shard's elastic overflow queue, applying the
+// OverflowPolicy if that is full too.
+func (h *Honeycomb) addOverflow(ctx context.Context, s *sendShard, ev *Event) {
+	if !s.overflow.add(ev) {
+		h.handleOverflow(ctx, s, ev)
+		return
+	}
+	h.queued()
+	h.Metrics.Gauge("overflow_queue_length", s.overflow.len())
+}
+
+func (h *Honeycomb) queued() {
+	h.Metrics.Increment("messages_queued")
+	if h.pending != nil {
+		atomic.AddInt64(h.pending, 1)
+	}
+}
+
+func (h *Honeycomb) dropOverflow(ev *Event) {
+	h.drops.drop()
+	h.Metrics.Increment("queue_overflow")
+	r := Response{
+		Err:      ErrQueueOverflow,
+		Metadata: ev.Metadata,
+	}
+	h.leveled().Warnf(Fields{"dataset": ev.Dataset}, "dropping event: %s", r.Err)
+	h.stopped.writeResponse(h.responses, r, h.BlockOnResponse, h.Logger, h.Metrics)
+}
+
+// TakePending removes and returns the events that are queued but not yet part
+// of a batch, for handing to another Sender. They no longer count as pending
+// here, so they won't get a Response from this Sender.
+func (h *Honeycomb) TakePending() []*Event {
+	var events []*Event
+	for _, s := range h.sendShards() {
+	drain:
+		for {
+			select {
+			case ev, ok := <-s.muster.Work:
+				if !ok {
+					break drain
+				}
+				events = append(events, ev.(*Event))
+			default:
+				break drain
+			}
+		}
+		if s.overflow != nil {
+			events = append(events, s.overflow.take()...)
+		}
+	}
+	if h.pending != nil {
+		atomic.AddInt64(h.pending, -int64(len(events)))
+	}
+	return events
+}
+
+// Pressure reports how full the queue is or, if higher, the fraction of
+// recently added events dropped because it was full.
+func (h *Honeycomb) Pressure() float64 {
+	if h.drops == nil {
+		return 0
+	}
+	capacity := h.PendingWorkCapacity
+	var queued int
+	for _, s := range h.sendShards() {
+		queued += len(s.muster.Work)
+		if s.overflow != nil {
+			queued += s.overflow.len()
+			if h.MaxPendingWorkCapacity > capacity {
+				capacity = h.MaxPendingWorkCapacity
+			}
+		}
+	}
+	var p float64
+	if capacity > 0 {
+		p = float64(queued) / float64(capacity)
+	}
+	if f := h.drops.fraction(time.Now()); f > p {
+		p = f
+	}
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
 func (h *Honeycomb) TxResponses() chan Response {
 	return h.responses
 }
 
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (h *Honeycomb) Done() <-chan struct{} {
+	return h.stopped.done()
+}
+
 func (h *Honeycomb) SendResponse(r Response) bool {
-	if h.BlockOnResponse {
-		h.responses <- r
-	} else {
-		select {
-		case h.responses <- r:
-		default:
-			return true
-		}
-	}
-	return false
+	return h.stopped.writeResponse(h.responses, r, h.BlockOnResponse, h.Logger, h.Metrics)
 }
 
 // batchAgg is a batch aggregator - it's actually collecting what will
@@ -147,15 +599,101 @@ type batchAgg struct {
 	disableGzipCompression bool
 
 	responses chan Response
+	// stopped guards responses against writes once the sender has stopped
+	stopped *stopSignal
 	// numEncoded       int
 
 	metrics Metrics
+	logger  Logger
+
+	// retry settings; with maxRetries of 0 batches are never retried
+	maxRetries   uint
+	retryBackoff time.Duration
+	retryBudget  *RetryBudget
+
+	// sendSplay is the most to delay sending by; stopping cuts the delay short
+	sendSplay time.Duration
+	stopping  chan struct{}
+
+	// pending is decremented as each event gets its response
+	pending   *int64
+	inFlight  *int64
+	sent      *int64
+	sendCtx   context.Context
+	exemplars *exemplarRing
+	stats     *senderStats
+	// requestTimeout limits each POST; zero means no limit
+	requestTimeout time.Duration
+	debugRequests  bool
+	onBatchSend    func(BatchInfo)
+
+	// size limits; zero means the API's
+	maxBatchBytes int
+	maxEventBytes int
+	// shared by all batches; nil if requests per host aren't limited
+	slots *hostSlots
+
+	// where to send batches for hosts whose breaker is open
+	fallbackAPIHost string
+	breakers        *breakerSet
+	// set with RaceFirstConnect; firstConnect is shared by all batches, and
+	// the host race waits at most firstConnectTimeout
+	dialer              *racingDialer
+	firstConnect        *sync.Once
+	firstConnectTimeout time.Duration
+	// shared by all batches; nil unless hedging
+	hedger *hedger
+	// re-encode events canonically
+	canonicalJSON bool
 
 	// allows manipulation of the value of "now" for testing
 	testNower   nower
 	testBlocker *sync.WaitGroup
 }
 
+func (b *batchAgg) now() time.Time {
+	if b.testNower != nil {
+		return b.testNower.Now()
+	}
+	return time.Now()
+}
+
+// sleep waits for d, returning early if sending is abandoned.
+func (b *batchAgg) sleep(d time.Duration) {
+	if b.sendCtx == nil {
+		time.Sleep(d)
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-b.sendCtx.Done():
+	}
+}
+
+// jitter returns d lengthened by a random fraction of itself up to frac.
+func jitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Float64()*frac*float64(d))
+}
+
+// splay waits a random part of sendSplay before a batch is sent, unless the
+// sender is stopping.
+func (b *batchAgg) splay() {
+	if b.sendSplay <= 0 {
+		return
+	}
+	t := time.NewTimer(time.Duration(rand.Int63n(int64(b.sendSplay))))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-b.stopping:
+	}
+}
+
 // batch is a collection of events that will all be POSTed as one HTTP call
 // type batch []*Event
 
@@ -173,13 +711,29 @@ func (b *batchAgg) Add(ev interface{}) {
 }
 
 func (b *batchAgg) enqueueResponse(resp Response) {
-	if writeToResponse(b.responses, resp, b.blockOnResponse) {
+	if b.pending != nil {
+		atomic.AddInt64(b.pending, -1)
+	}
+	if b.sent != nil && resp.Err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		atomic.AddInt64(b.sent, 1)
+	}
+	b.stats.recordStatus(resp.StatusCode)
+	if b.writeResponse(resp) {
 		if b.testBlocker != nil {
 			b.testBlocker.Done()
 		}
 	}
 }
 
+// writeResponse writes resp to the responses channel, through the sender's
+// stopSignal if it has one.
+func (b *batchAgg) writeResponse(resp Response) bool {
+	if b.stopped != nil {
+		return b.stopped.writeResponse(b.responses, resp, b.blockOnResponse, b.logger, b.metrics)
+	}
+	return writeToResponse(b.responses, resp, b.blockOnResponse, b.logger, b.metrics)
+}
+
 func (b *batchAgg) reenqueueEvents(events []*Event) {
 	if b.overflowBatches == nil {
 		b.overflowBatches = make(map[string][]*Event)
@@ -192,11 +746,12 @@ func (b *batchAgg) reenqueueEvents(events []*Event) {
 
 func (b *batchAgg) Fire(notifier muster.Notifier) {
 	defer notifier.Done()
+	b.splay()
 
 	// send each batchKey's collection of event as a POST to /1/batch/<dataset>
 	// we don't need the batch key anymore; it's done its sorting job
 	for _, events := range b.batches {
-		b.fireBatch(events)
+		b.safeFireBatch(events)
 	}
 	// The initial batches could have had payloads that were greater than 5MB.
 	// The remaining events will have overflowed into overflowBatches
@@ -226,22 +781,55 @@ func (b *batchAgg) Fire(notifier muster.Notifier) {
 				// fireBatch may append more overflow events
 				// so we want to clear this key before firing the batch
 				delete(b.overflowBatches, k)
-				b.fireBatch(events)
+				b.safeFireBatch(events)
 			}
 		}
 	}
 }
 
+// safeFireBatch sends a batch, recovering from any panic so later batches can
+// still be sent. Events in a batch that panicked get an error Response, which
+// may duplicate a Response already sent for some of them.
+func (b *batchAgg) safeFireBatch(events []*Event) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(b.logger, b.metrics, "batch send", p)
+			b.enqueueErrResponses(panicError(p), events, 0, sendAttempts{})
+		}
+	}()
+	b.fireBatch(events)
+}
+
 func (b *batchAgg) fireBatch(events []*Event) {
 	start := time.Now().UTC()
 	if b.testNower != nil {
 		start = b.testNower.Now()
 	}
+	events = b.dropStale(events)
 	if len(events) == 0 {
 		// we managed to create a batch key with no events. odd. move on.
 		return
 	}
-	encEvs, numEncoded := b.encodeBatch(events)
+	var breaker *circuitBreaker
+	if b.breakers != nil {
+		primary := events[0].APIHost
+		breaker = b.breakers.get(primary)
+		if b.firstConnect != nil {
+			b.firstConnect.Do(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), b.firstConnectTimeout)
+				defer cancel()
+				if b.dialer.raceHosts(ctx, primary, b.fallbackAPIHost) == b.fallbackAPIHost {
+					breaker.trip(start)
+				}
+			})
+		}
+		if !breaker.allow(start) {
+			events = failoverEvents(events, b.fallbackAPIHost)
+			breaker = nil
+		}
+	}
+	body, numEncoded := b.encodeBody(events)
+	defer body.release()
 	// if we failed to encode any events skip this batch
 	if numEncoded == 0 {
 		return
@@ -265,8 +853,7 @@ func (b *batchAgg) fireBatch(events []*Event) {
 	}
 
 	// build the HTTP request
-	reqBody, gzipped := buildReqReader(encEvs, !b.disableGzipCompression)
-	url, err := url.Parse(apiHost)
+	url, err := APIURL(apiHost, "1", "batch", dataset)
 	if err != nil {
 		end := time.Now().UTC()
 		if b.testNower != nil {
@@ -287,53 +874,154 @@ func (b *batchAgg) fireBatch(events []*Event) {
 		}
 		return
 	}
-	url.Path = path.Join(url.Path, "/1/batch", dataset)
-	req, err := http.NewRequest("POST", url.String(), reqBody)
-	req.Header.Set("Content-Type", "application/json")
-	if gzipped {
-		req.Header.Set("Content-Encoding", "gzip")
+	ctx := b.sendCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	release, err := b.slots.acquire(ctx, apiHost)
+	if err != nil {
+		b.enqueueErrResponses(err, events, 0, sendAttempts{})
+		return
+	}
+	// held until the response has been read
+	defer release()
+	if b.inFlight != nil {
+		atomic.AddInt64(b.inFlight, 1)
+		defer atomic.AddInt64(b.inFlight, -1)
+	}
+	// send off batch! retrying if configured to and the budget allows it
+	if b.retryBudget != nil {
+		b.retryBudget.recordSend()
+	}
+	if b.onBatchSend != nil {
+		info := BatchInfo{APIHost: apiHost, Dataset: dataset, Events: numEncoded, Bytes: body.buf.Len()}
+		runRecovered(b.logger, b.metrics, "batch send hook", func() { b.onBatchSend(info) })
+	}
+	var reqCtx context.Context
+	batchID := newBatchID()
+	newRequest := func(url string) *http.Request {
+		// the body is consumed by each attempt so each gets its own reader
+		req, _ := http.NewRequest("POST", url, body.reader())
+		req.ContentLength = int64(body.buf.Len())
+		req.GetBody = func() (io.ReadCloser, error) { return body.reader(), nil }
+		req = req.WithContext(reqCtx)
+		req.Header.Set("Content-Type", "application/json")
+		if body.gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Add("X-Honeycomb-Team", writeKey)
+		req.Header.Set(batchIDHeader, batchID)
+		return req
+	}
+	var lastReq *http.Request
+	if b.debugRequests {
+		build := newRequest
+		newRequest = func(url string) *http.Request {
+			lastReq = build(url)
+			return lastReq
+		}
+	}
+	hedgeURL := b.hedgeURL(url.String(), dataset)
+	var resp *http.Response
+	var tries sendAttempts
+	for attempt := uint(0); ; attempt++ {
+		tries.attempts++
+		var cancel context.CancelFunc
+		reqCtx, cancel = b.requestContext(ctx)
+		resp, err = b.do(newRequest, url.String(), hedgeURL)
+		if err != nil {
+			cancel()
+		} else {
+			// the timeout covers reading the response too
+			resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		}
+		if !b.shouldRetry(attempt, resp, err) {
+			break
+		}
+		b.metrics.Increment("send_retries")
+		b.leveled().Warnf(retryFields(dataset, attempt, resp, err), "retrying batch of %d events", numEncoded)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		backoff := b.retryBackoff << attempt
+		b.sleep(backoff)
+		tries.waited += backoff
+		tries.lastBackoff = backoff
+		if sent := encodedEvents(events, numEncoded); b.anyStale(sent) {
+			// start over without the events that have now expired
+			release()
+			b.fireBatch(sent)
+			return
+		}
 	}
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Add("X-Honeycomb-Team", writeKey)
-	// send off batch!
-	resp, err := b.httpClient.Do(req)
 	end := time.Now().UTC()
 	if b.testNower != nil {
 		end = b.testNower.Now()
 	}
 	dur := end.Sub(start)
+	if breaker != nil {
+		breaker.record(err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500, end)
+	}
 
 	// if the entire HTTP POST failed, send a failed response for every event
 	if err != nil {
 		b.metrics.Increment("send_errors")
+		b.leveled().Errorf(Fields{"dataset": dataset, "api_host": apiHost}, "failed to send batch: %s", err)
+		b.debugRequest(lastReq, body.rawBytes(), 0, nil, err)
 		// Pass the top-level send error down responses channel for each event
 		// that didn't already error during encoding
-		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded))
+		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded), tries)
 		// the POST failed so we're done with this batch key's worth of events
 		return
 	}
 
+	// the batch was too big for the server, whatever our own limits say; try
+	// again in halves rather than failing every event in it
+	if resp.StatusCode == http.StatusRequestEntityTooLarge && numEncoded > 1 {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		release()
+		b.metrics.Increment("batches_split")
+		sent := encodedEvents(events, numEncoded)
+		half := len(sent) / 2
+		b.fireBatch(sent[:half])
+		b.fireBatch(sent[half:])
+		return
+	}
+
 	// ok, the POST succeeded, let's process each individual response
 	b.metrics.Increment("batches_sent")
-	b.metrics.Count("messages_sent", numEncoded)
+	b.stats.recordBatch(dur, body.encodedLen, body.buf.Len())
+	b.countSent(events, numEncoded)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		b.metrics.Increment("send_errors")
-		body, err := ioutil.ReadAll(resp.Body)
+		errBody, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			b.enqueueErrResponses(fmt.Errorf("Got HTTP error code but couldn't read response body: %v", err),
-				events, dur/time.Duration(numEncoded))
+				events, dur/time.Duration(numEncoded), tries)
 			return
 		}
+		var statusErr error
+		if resp.StatusCode == http.StatusUnauthorized {
+			statusErr = ErrUnauthorized
+		}
+		b.leveled().Errorf(Fields{"dataset": dataset, "status": resp.StatusCode}, "batch rejected: %s", bytes.TrimSpace(errBody))
+		b.debugRequest(lastReq, body.rawBytes(), resp.StatusCode, errBody, nil)
 		for _, ev := range events {
 			if ev != nil {
-				b.enqueueResponse(Response{
+				r := Response{
+					Err:        statusErr,
 					StatusCode: resp.StatusCode,
-					Body:       body,
+					Body:       errBody,
 					Duration:   dur / time.Duration(numEncoded),
 					Metadata:   ev.Metadata,
-				})
+				}
+				tries.fill(&r)
+				b.enqueueResponse(r)
 			}
 		}
 		return
@@ -341,11 +1029,17 @@ func (b *batchAgg) fireBatch(events []*Event) {
 
 	// decode the responses
 	batchResponses := []Response{}
-	err = json.NewDecoder(resp.Body).Decode(&batchResponses)
+	var respBody io.Reader = resp.Body
+	if b.debugRequests {
+		raw, _ := ioutil.ReadAll(resp.Body)
+		b.debugRequest(lastReq, body.rawBytes(), resp.StatusCode, raw, nil)
+		respBody = bytes.NewReader(raw)
+	}
+	err = json.NewDecoder(respBody).Decode(&batchResponses)
 	if err != nil {
 		// if we can't decode the responses, just error out all of them
 		b.metrics.Increment("response_decode_errors")
-		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded))
+		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded), tries)
 		return
 	}
 
@@ -364,29 +1058,65 @@ func (b *batchAgg) fireBatch(events []*Event) {
 			break
 		}
 		resp.Metadata = events[eIdx].Metadata
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			b.exemplars.record(events[eIdx])
+		}
+		tries.fill(&resp)
 		b.enqueueResponse(resp)
 		eIdx++
 	}
 }
 
+// failoverEvents returns copies of events redirected to host, annotated with
+// the host they were meant for.
+func failoverEvents(events []*Event, host string) []*Event {
+	redirected := make([]*Event, len(events))
+	for i, e := range events {
+		ev := *e
+		ev.APIHost = host
+		ev.Data = make(map[string]interface{}, len(e.Data)+1)
+		for k, v := range e.Data {
+			ev.Data[k] = v
+		}
+		ev.Data["meta.failover_from"] = e.APIHost
+		redirected[i] = &ev
+	}
+	return redirected
+}
+
 // create the JSON for this event list manually so that we can send
 // responses down the response queue for any that fail to marshal
-func (b *batchAgg) encodeBatch(events []*Event) ([]byte, int) {
-	// track first vs. rest events for commas
-	first := true
+func (b *batchAgg) encodeBatch(w io.Writer, events []*Event) int {
 	// track how many we successfully encode for later bookkeeping
 	var numEncoded int
-	buf := bytes.Buffer{}
-	buf.WriteByte('[')
-	bytesTotal := 1
+	maxBatchBytes, maxEventBytes := b.maxBatchBytes, b.maxEventBytes
+	if maxBatchBytes <= 0 {
+		maxBatchBytes = apiMaxBatchSize
+	}
+	if maxEventBytes <= 0 {
+		maxEventBytes = apiEventSizeMax
+	}
+	fill := batchFill{limit: batchLimit{
+		destination:   "API",
+		maxBytes:      maxBatchBytes,
+		maxEventBytes: maxEventBytes,
+		framing:       2,
+		separator:     1,
+	}}
+	// each event is encoded on its own first so that its size can be checked
+	// before it's written out
+	scratch := getBatchBuffer()
+	defer putBatchBuffer(scratch)
+	jsonEnc := json.NewEncoder(scratch)
+	w.Write([]byte{'['})
 	// ok, we've got our array, let's populate it with JSON events
 	for i, ev := range events {
-		if !first {
-			buf.WriteByte(',')
-			bytesTotal++
+		enc := ev
+		if !ev.enqueuedAt.IsZero() {
+			enc = ev.withQueueTime(time.Now())
 		}
-		first = false
-		evByt, err := json.Marshal(ev)
+		scratch.Reset()
+		err := jsonEnc.Encode(enc)
 		if err != nil {
 			b.enqueueResponse(Response{
 				Err:      err,
@@ -397,51 +1127,251 @@ func (b *batchAgg) encodeBatch(events []*Event) ([]byte, int) {
 			events[i] = nil
 			continue
 		}
+		// drop the newline the Encoder ends each value with
+		evByt := scratch.Bytes()[:scratch.Len()-1]
+		if b.canonicalJSON {
+			if evByt, err = canonicalJSON(evByt); err != nil {
+				b.enqueueResponse(Response{
+					Err:      err,
+					Metadata: ev.Metadata,
+				})
+				events[i] = nil
+				continue
+			}
+		}
 		// if the event is too large to ever send, add an error to the queue
-		if len(evByt) > apiEventSizeMax {
+		if err := fill.limit.check(len(evByt)); err != nil {
 			b.enqueueResponse(Response{
-				Err:      fmt.Errorf("event exceeds max event size of %d bytes, API will not accept this event", apiEventSizeMax),
+				Err:      err,
 				Metadata: ev.Metadata,
 			})
 			events[i] = nil
 			continue
 		}
-		bytesTotal += len(evByt)
-
-		// count for the trailing ]
-		if bytesTotal+1 > apiMaxBatchSize {
+		if !fill.add(len(evByt)) {
 			b.reenqueueEvents(events[i:])
 			break
 		}
-		buf.Write(evByt)
+		// track first vs. rest events for commas
+		if numEncoded > 0 {
+			w.Write([]byte{','})
+		}
+		w.Write(evByt)
 		numEncoded++
 	}
-	buf.WriteByte(']')
-	return buf.Bytes(), numEncoded
+	w.Write([]byte{']'})
+	return numEncoded
 }
 
-func (b *batchAgg) enqueueErrResponses(err error, events []*Event, duration time.Duration) {
+// dropStale responds to any events past their MaxQueueAge with ErrEventStale,
+// returning the rest.
+func (b *batchAgg) dropStale(events []*Event) []*Event {
+	if !b.anyStale(events) {
+		return events
+	}
+	now := b.now()
+	fresh := make([]*Event, 0, len(events))
 	for _, ev := range events {
-		if ev != nil {
+		if ev.stale(now) {
+			b.metrics.Increment("queue_expired")
 			b.enqueueResponse(Response{
+				Err:      ErrEventStale,
+				Metadata: ev.Metadata,
+			})
+			continue
+		}
+		fresh = append(fresh, ev)
+	}
+	return fresh
+}
+
+// anyStale reports whether any of events are past their MaxQueueAge. It only
+// checks the time if some of them have one.
+func (b *batchAgg) anyStale(events []*Event) bool {
+	var now time.Time
+	for _, ev := range events {
+		if ev.staleAt.IsZero() {
+			continue
+		}
+		if now.IsZero() {
+			now = b.now()
+		}
+		if ev.stale(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodedEvents returns the events encodeBatch included in the batch: the
+// first numEncoded that it didn't nil out.
+func encodedEvents(events []*Event, numEncoded int) []*Event {
+	out := make([]*Event, 0, numEncoded)
+	for _, ev := range events {
+		if len(out) == numEncoded {
+			break
+		}
+		if ev != nil {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// retryFields returns the context logged with a retry.
+func retryFields(dataset string, attempt uint, resp *http.Response, err error) Fields {
+	fields := Fields{"dataset": dataset, "attempt": attempt + 1}
+	if err != nil {
+		fields["error"] = err
+	} else {
+		fields["status"] = resp.StatusCode
+	}
+	return fields
+}
+
+// requestContext returns the context for one attempt at a POST, limited to
+// the request timeout.
+func (b *batchAgg) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, b.requestTimeout)
+}
+
+// shouldRetry reports whether a batch POST that produced resp and err on the
+// given (zero-indexed) attempt should be tried again. Only failures that might
+// succeed on a later attempt are retried: transport errors, 429s and 5xxs.
+func (b *batchAgg) shouldRetry(attempt uint, resp *http.Response, err error) bool {
+	if attempt >= b.maxRetries {
+		return false
+	}
+	if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return false
+	}
+	if b.retryBudget != nil && !b.retryBudget.tryRetry() {
+		b.metrics.Increment("retry_budget_exhausted")
+		return false
+	}
+	return true
+}
+
+func (b *batchAgg) enqueueErrResponses(err error, events []*Event, duration time.Duration, tries sendAttempts) {
+	for _, ev := range events {
+		if ev != nil {
+			r := Response{
 				Err:      err,
 				Duration: duration,
 				Metadata: ev.Metadata,
-			})
+			}
+			tries.fill(&r)
+			b.enqueueResponse(r)
 		}
 	}
 }
 
+// sendAttempts records how many tries it took to send a batch.
+type sendAttempts struct {
+	attempts    int
+	waited      time.Duration
+	lastBackoff time.Duration
+}
+
+func (a sendAttempts) fill(r *Response) {
+	r.Attempts = a.attempts
+	r.RetryDuration = a.waited
+	r.LastBackoff = a.lastBackoff
+}
+
+// BatchInfo describes a batch about to be sent, for Honeycomb.OnBatchSend.
+type BatchInfo struct {
+	APIHost string
+	Dataset string
+	// Events is how many events are in the batch, and Bytes its size as
+	// sent, after any compression.
+	Events int
+	Bytes  int
+}
+
+// batchBody is an encoded batch, ready to be sent. Each send attempt reads it
+// through its own reader, and its buffer goes back to the pool once all of
+// them have been closed.
+type batchBody struct {
+	buf     *bytes.Buffer
+	gzipped bool
+	// encodedLen is the length of the batch before compression
+	encodedLen int
+	// raw is a copy of the batch before compression, kept for DebugRequests
+	raw *bytes.Buffer
+	// open counts the readers not yet closed
+	open int32
+}
+
+// encodeBody encodes events into a batchBody, streaming them straight through
+// gzip unless compression is disabled, so the uncompressed batch is never held
+// in memory. It returns the body and how many events were encoded.
+func (b *batchAgg) encodeBody(events []*Event) (*batchBody, int) {
+	body := &batchBody{buf: getBatchBuffer(), gzipped: !b.disableGzipCompression}
+	if b.debugRequests {
+		body.raw = &bytes.Buffer{}
+	}
+	if !body.gzipped {
+		n := b.encodeBatch(body.rawWriter(body.buf), events)
+		body.encodedLen = body.buf.Len()
+		return body, n
+	}
+	g := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(g)
+	g.Reset(body.buf)
+	n := b.encodeBatch(body.rawWriter(&lengthWriter{w: g, n: &body.encodedLen}), events)
+	// writes to a bytes.Buffer can't fail, so neither can flushing to one
+	g.Close()
+	return body, n
+}
+
+// lengthWriter counts the bytes written through it.
+type lengthWriter struct {
+	w io.Writer
+	n *int
+}
+
+func (l *lengthWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	*l.n += n
+	return n, err
+}
+
+// reader returns a reader of the encoded batch for one send attempt.
+func (bb *batchBody) reader() io.ReadCloser {
+	atomic.AddInt32(&bb.open, 1)
+	return &batchBodyReader{Reader: bytes.NewReader(bb.buf.Bytes()), body: bb}
+}
+
+// release returns the body's buffer to the pool, unless a request may still
+// be reading from it; the Transport can close request bodies after the
+// request has returned.
+func (bb *batchBody) release() {
+	if atomic.LoadInt32(&bb.open) == 0 {
+		putBatchBuffer(bb.buf)
+	}
+}
+
+type batchBodyReader struct {
+	*bytes.Reader
+	body   *batchBody
+	closed sync.Once
+}
+
+func (r *batchBodyReader) Close() error {
+	r.closed.Do(func() { atomic.AddInt32(&r.body.open, -1) })
+	return nil
+}
+
 // buildReqReader returns an io.Reader and a boolean, indicating whether or not
 // the io.Reader is gzip-compressed.
 func buildReqReader(jsonEncoded []byte, useGzip bool) (io.Reader, bool) {
 	if useGzip {
-		buf := bytes.Buffer{}
-		g := gzip.NewWriter(&buf)
-		if _, err := g.Write(jsonEncoded); err == nil {
-			if err = g.Close(); err == nil { // flush
-				return &buf, true
-			}
+		if buf, err := gzipBytes(jsonEncoded); err == nil {
+			return buf, true
 		}
 
 		return bytes.NewReader(jsonEncoded), false
@@ -3,7 +3,9 @@ package transmission
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -44,7 +46,6 @@ func TestHnyTxAdd(t *testing.T) {
 	hnyTx.muster.Work = make(chan interface{}, 1)
 	hnyTx.responses = make(chan Response, 1)
 	hnyTx.responses <- placeholder
-	hnyTx.responses = hnyTx.responses
 
 	// default successful case
 	e := &Event{Metadata: "mmeetta"}
@@ -61,6 +62,7 @@ func TestHnyTxAdd(t *testing.T) {
 	testErr(t, rsp.Err)
 	testEquals(t, rsp.Err.Error(), "queue overflow",
 		"overflow error should have been put on responses channel immediately")
+	testEquals(t, rsp.Err, ErrQueueOverflow)
 	// make sure that (default) nonblocking on responses allows execution even if
 	// responses channel is full
 	hnyTx.responses <- placeholder
@@ -632,6 +634,118 @@ func TestFireBatchWithTooLargeEvent(t *testing.T) {
 	testEquals(t, trt.callCount, 0)
 }
 
+// Verify that configured size limits override the API's
+func TestFireBatchCustomSizeLimits(t *testing.T) {
+	trt := &testRoundTripper{}
+	b := &batchAgg{
+		httpClient:    &http.Client{Transport: trt},
+		testNower:     &fakeNower{},
+		responses:     make(chan Response, 10),
+		metrics:       &nullMetrics{},
+		maxBatchBytes: 5000,
+		maxEventBytes: 2000,
+	}
+
+	for i := 0; i < 6; i++ {
+		b.Add(&Event{
+			Data:     map[string]interface{}{"col": randomString(1000)},
+			APIHost:  "http://fakeHost:8080",
+			APIKey:   "written",
+			Dataset:  "ds1",
+			Metadata: "emmetta",
+		})
+	}
+	b.Add(&Event{
+		Data:     map[string]interface{}{"col": randomString(3000)},
+		APIHost:  "http://fakeHost:8080",
+		APIKey:   "written",
+		Dataset:  "ds1",
+		Metadata: "too big",
+	})
+
+	b.Fire(&testNotifier{})
+	// four events fit in each batch
+	testEquals(t, trt.callCount, 2)
+	var tooBig bool
+	for len(b.responses) > 0 {
+		r := <-b.responses
+		if r.Metadata == "too big" {
+			tooBig = true
+			testEquals(t, r.Err.Error(), "event exceeds max event size of 2000 bytes, API will not accept this event")
+		}
+	}
+	testEquals(t, tooBig, true)
+}
+
+// limitRoundTripper rejects batches of more than max events with a 413
+type limitRoundTripper struct {
+	max   int
+	sizes []int
+}
+
+func (l *limitRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	var batch []json.RawMessage
+	json.NewDecoder(r.Body).Decode(&batch)
+	l.sizes = append(l.sizes, len(batch))
+	if len(batch) > l.max {
+		return &http.Response{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Body:       ioutil.NopCloser(strings.NewReader("request entity too large")),
+		}, nil
+	}
+	statuses := strings.Repeat(`{"status":202},`, len(batch))
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader("[" + strings.TrimSuffix(statuses, ",") + "]")),
+	}, nil
+}
+
+func TestFireBatchSplitsOn413(t *testing.T) {
+	lrt := &limitRoundTripper{max: 2}
+	b := &batchAgg{
+		httpClient:             &http.Client{Transport: lrt},
+		testNower:              &fakeNower{},
+		responses:              make(chan Response, 10),
+		metrics:                &nullMetrics{},
+		disableGzipCompression: true,
+	}
+	for i := 0; i < 5; i++ {
+		b.Add(&Event{
+			Data:     map[string]interface{}{"n": i},
+			APIHost:  "http://fakeHost:8080",
+			APIKey:   "written",
+			Dataset:  "ds1",
+			Metadata: i,
+		})
+	}
+	b.Fire(&testNotifier{})
+	testEquals(t, lrt.sizes, []int{5, 2, 3, 1, 2})
+	testEquals(t, len(b.responses), 5)
+	seen := map[interface{}]bool{}
+	for len(b.responses) > 0 {
+		r := <-b.responses
+		testOK(t, r.Err)
+		testEquals(t, r.StatusCode, 202)
+		seen[r.Metadata] = true
+	}
+	testEquals(t, len(seen), 5)
+
+	// a single event that's too large fails as before
+	lrt = &limitRoundTripper{max: 0}
+	b = &batchAgg{
+		httpClient:             &http.Client{Transport: lrt},
+		testNower:              &fakeNower{},
+		responses:              make(chan Response, 10),
+		metrics:                &nullMetrics{},
+		disableGzipCompression: true,
+	}
+	b.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds1", Data: map[string]interface{}{"n": 1}})
+	b.Fire(&testNotifier{})
+	testEquals(t, lrt.sizes, []int{1})
+	r := <-b.responses
+	testEquals(t, r.StatusCode, http.StatusRequestEntityTooLarge)
+}
+
 // Ensure we can deal with batches whose first event won't json encode
 func TestFireBatchWithBrokenFirstEvent(t *testing.T) {
 	trt := &testRoundTripper{}
@@ -783,3 +897,118 @@ func randomString(length int) string {
 	rand.Read(b)
 	return fmt.Sprintf("%x", b)
 }
+
+func TestJitter(t *testing.T) {
+	testEquals(t, jitter(time.Second, 0), time.Second)
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second, 0.5)
+		if d < time.Second || d >= 1500*time.Millisecond {
+			t.Errorf("jittered %v out of range", d)
+		}
+	}
+}
+
+func TestSendSplayCutShortByStop(t *testing.T) {
+	stopping := make(chan struct{})
+	b := &batchAgg{sendSplay: time.Hour, stopping: stopping}
+	close(stopping)
+	start := time.Now()
+	b.splay()
+	if time.Since(start) > time.Second {
+		t.Error("splay should not wait once the sender is stopping")
+	}
+}
+
+func TestEncodeBatchStampsQueueTime(t *testing.T) {
+	b := &batchAgg{metrics: &nullMetrics{}}
+	ev := &Event{
+		Data:       map[string]interface{}{"a": 1},
+		enqueuedAt: time.Now().Add(-time.Second),
+	}
+	var enc bytes.Buffer
+	n := b.encodeBatch(&enc, []*Event{ev, {Data: map[string]interface{}{"b": 2}}})
+	testEquals(t, n, 2)
+	var decoded []struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	testOK(t, json.Unmarshal(enc.Bytes(), &decoded))
+	if qt, _ := decoded[0].Data["meta.queue_time_ms"].(float64); qt < 1000 {
+		t.Errorf("expected queue time of at least 1000ms, got %v", decoded[0].Data["meta.queue_time_ms"])
+	}
+	_, stamped := decoded[1].Data["meta.queue_time_ms"]
+	testEquals(t, stamped, false, "events without an enqueue time shouldn't be stamped")
+	testEquals(t, len(ev.Data), 1, "stamping shouldn't modify the event")
+}
+
+func TestHoneycombAddWithContextCancelled(t *testing.T) {
+	h := &Honeycomb{
+		BlockOnSend: true,
+		Logger:      &nullLogger{},
+		Metrics:     &nullMetrics{},
+		responses:   make(chan Response, 1),
+	}
+	// nothing will ever read from the queue
+	h.muster.Work = make(chan interface{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.AddWithContext(ctx, &Event{Metadata: "cancelled"})
+	rsp := testGetResponse(t, h.responses)
+	testEquals(t, rsp.Err, context.Canceled)
+	testEquals(t, rsp.Metadata, "cancelled")
+}
+
+// hangingRoundTripper never completes a request until it's cancelled.
+type hangingRoundTripper struct{}
+
+func (hangingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestHoneycombStopWithContext(t *testing.T) {
+	h := &Honeycomb{
+		MaxBatchSize:        10,
+		BatchTimeout:        time.Millisecond,
+		PendingWorkCapacity: 10,
+		Transport:           hangingRoundTripper{},
+	}
+	testOK(t, h.Start())
+	for i := 0; i < 3; i++ {
+		h.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": i}})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	abandoned, err := h.StopWithContext(ctx)
+	testEquals(t, err, context.DeadlineExceeded)
+	testEquals(t, abandoned, 3)
+	n := 0
+	for r := range h.TxResponses() {
+		testErr(t, r.Err)
+		n++
+	}
+	testEquals(t, n, 3)
+}
+
+func TestHoneycombSummary(t *testing.T) {
+	h := &Honeycomb{
+		MaxBatchSize:        10,
+		BatchTimeout:        time.Millisecond,
+		PendingWorkCapacity: 10,
+		BlockOnResponse:     true,
+		Transport:           &testRoundTripper{},
+	}
+	testEquals(t, h.Summary(), Summary{})
+	testOK(t, h.Start())
+	h.Add(&Event{APIHost: "http://fakeHost:8080", Dataset: "ds", Data: map[string]interface{}{"a": 1}})
+	r := <-h.TxResponses()
+	testOK(t, r.Err)
+	testOK(t, h.Stop())
+	testEquals(t, h.Summary(), Summary{Sent: 1})
+
+	// counts carry over a restart
+	testOK(t, h.Start())
+	h.dropOverflow(&Event{})
+	<-h.TxResponses()
+	testEquals(t, h.Summary(), Summary{Sent: 1, Dropped: 1})
+	testOK(t, h.Stop())
+}
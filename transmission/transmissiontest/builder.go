@@ -0,0 +1,25 @@
+package transmissiontest
+
+import (
+	"testing"
+
+	libhoney "github.com/honeycombio/libhoney-go"
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// NewMockBuilder returns a Builder for dataset whose events are sent to the
+// returned MockSender, for testing code that creates events from a Builder,
+// such as wrappers and hooks.
+func NewMockBuilder(t testing.TB, dataset string) (*libhoney.Builder, *transmission.MockSender) {
+	t.Helper()
+	mock := &transmission.MockSender{}
+	client, err := libhoney.NewClient(libhoney.ClientConfig{
+		APIKey:       "key",
+		Dataset:      dataset,
+		Transmission: mock,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client.NewBuilder(), mock
+}
@@ -0,0 +1,19 @@
+package transmissiontest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMockBuilder(t *testing.T) {
+	builder, mock := NewMockBuilder(t, "jobs")
+	ev := builder.NewEvent()
+	ev.AddField("a", 1)
+	assert.NoError(t, ev.Send())
+
+	events := mock.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "jobs", events[0].Dataset)
+	assert.Equal(t, 1, events[0].Data["a"])
+}
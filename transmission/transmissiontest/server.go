@@ -0,0 +1,178 @@
+// Package transmissiontest provides a fake Honeycomb API server for testing
+// code that sends events, such as retry and batch splitting behavior.
+//
+// Summary
+//
+//	server := transmissiontest.NewServer()
+//	defer server.Close()
+//	server.RespondWith(http.StatusServiceUnavailable)
+//	libhoney.Init(libhoney.Config{APIHost: server.URL, ...})
+//
+// The server accepts POSTs to /1/batch/<dataset>, decompressing and decoding
+// them, and records every batch it receives, including ones it rejects.
+//
+// For tests that only need the events, NewMockBuilder returns a Builder
+// whose events are kept by a transmission.MockSender instead.
+package transmissiontest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is one event in a received batch.
+type Event struct {
+	Data       map[string]interface{} `json:"data"`
+	SampleRate uint                   `json:"samplerate"`
+	// Time is zero if the event didn't have one.
+	Time time.Time `json:"time"`
+}
+
+// Batch is one batch request the server received.
+type Batch struct {
+	Dataset string
+	APIKey  string
+	Header  http.Header
+	Events  []Event
+	// StatusCode is the HTTP status code the server responded with.
+	StatusCode int
+}
+
+// Server is a fake Honeycomb API. Its URL is the API host to send to.
+type Server struct {
+	*httptest.Server
+
+	lock        sync.Mutex
+	batches     []Batch
+	script      []int
+	eventStatus func(Event) int
+}
+
+// NewServer starts a Server that accepts every batch and event, until it's
+// told otherwise. Close it when done.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveBatch))
+	return s
+}
+
+// RespondWith has the server respond to its next batches with each of the
+// given HTTP status codes in turn, eg 429, 500 or 413, rather than accepting
+// them. Once they've been used up it goes back to accepting batches.
+func (s *Server) RespondWith(statusCodes ...int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.script = append(s.script, statusCodes...)
+}
+
+// SetEventStatus sets the function deciding the status returned for each
+// event in accepted batches. By default every event gets 202 Accepted.
+// Statuses other than 2xx are given an error message.
+func (s *Server) SetEventStatus(status func(Event) int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.eventStatus = status
+}
+
+// Batches returns every batch the server has received, in order.
+func (s *Server) Batches() []Batch {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Batch(nil), s.batches...)
+}
+
+// Events returns the events of every batch the server has accepted, in
+// order.
+func (s *Server) Events() []Event {
+	var events []Event
+	for _, b := range s.Batches() {
+		if b.StatusCode == http.StatusOK {
+			events = append(events, b.Events...)
+		}
+	}
+	return events
+}
+
+// Reset forgets the received batches and any scripted responses.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.batches = nil
+	s.script = nil
+}
+
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.URL.Path, "/1/batch/") {
+		http.NotFound(w, r)
+		return
+	}
+	batch := Batch{
+		Dataset: strings.TrimPrefix(r.URL.Path, "/1/batch/"),
+		APIKey:  r.Header.Get("X-Honeycomb-Team"),
+		Header:  r.Header,
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			s.reject(w, batch, http.StatusBadRequest, fmt.Sprintf("bad gzip body: %v", err))
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	if err := json.NewDecoder(body).Decode(&batch.Events); err != nil {
+		s.reject(w, batch, http.StatusBadRequest, fmt.Sprintf("bad JSON body: %v", err))
+		return
+	}
+
+	s.lock.Lock()
+	if len(s.script) > 0 {
+		code := s.script[0]
+		s.script = s.script[1:]
+		s.lock.Unlock()
+		s.reject(w, batch, code, http.StatusText(code))
+		return
+	}
+	eventStatus := s.eventStatus
+	batch.StatusCode = http.StatusOK
+	s.batches = append(s.batches, batch)
+	s.lock.Unlock()
+
+	type eventResponse struct {
+		Status int    `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+	responses := make([]eventResponse, len(batch.Events))
+	for i, ev := range batch.Events {
+		responses[i].Status = http.StatusAccepted
+		if eventStatus != nil {
+			responses[i].Status = eventStatus(ev)
+		}
+		if responses[i].Status < 200 || responses[i].Status >= 300 {
+			responses[i].Error = http.StatusText(responses[i].Status)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// reject records batch as rejected with code and responds with msg.
+func (s *Server) reject(w http.ResponseWriter, batch Batch, code int, msg string) {
+	batch.StatusCode = code
+	s.lock.Lock()
+	s.batches = append(s.batches, batch)
+	s.lock.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
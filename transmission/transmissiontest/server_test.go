@@ -0,0 +1,104 @@
+package transmissiontest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSender(server *Server) *transmission.Honeycomb {
+	return &transmission.Honeycomb{
+		MaxBatchSize:         10,
+		BatchTimeout:         time.Hour,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		MaxRetries:           2,
+		RetryBackoff:         time.Millisecond,
+	}
+}
+
+func send(t *testing.T, h *transmission.Honeycomb, server *Server, n int) []transmission.Response {
+	assert.NoError(t, h.Start())
+	for i := 0; i < n; i++ {
+		h.Add(&transmission.Event{
+			APIHost:    server.URL,
+			APIKey:     "key",
+			Dataset:    "ds",
+			SampleRate: 2,
+			Timestamp:  time.Unix(1500000000, 0).UTC(),
+			Metadata:   i,
+			Data:       map[string]interface{}{"n": i},
+		})
+	}
+	assert.NoError(t, h.Stop())
+	var responses []transmission.Response
+	for r := range h.TxResponses() {
+		responses = append(responses, r)
+	}
+	return responses
+}
+
+func TestServerRecordsBatches(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetEventStatus(func(ev Event) int {
+		if ev.Data["n"] == float64(1) {
+			return http.StatusBadRequest
+		}
+		return http.StatusAccepted
+	})
+
+	responses := send(t, newSender(server), server, 2)
+	assert.Equal(t, 2, len(responses))
+	assert.Equal(t, 202, responses[0].StatusCode)
+	assert.Equal(t, 400, responses[1].StatusCode)
+	assert.Error(t, responses[1].Err)
+
+	batches := server.Batches()
+	assert.Equal(t, 1, len(batches))
+	assert.Equal(t, "ds", batches[0].Dataset)
+	assert.Equal(t, "key", batches[0].APIKey)
+	assert.Equal(t, "gzip", batches[0].Header.Get("Content-Encoding"))
+	events := server.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, float64(0), events[0].Data["n"])
+	assert.Equal(t, uint(2), events[0].SampleRate)
+	assert.Equal(t, time.Unix(1500000000, 0).UTC(), events[0].Time.UTC())
+}
+
+func TestServerScriptedFailures(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.RespondWith(http.StatusTooManyRequests, http.StatusInternalServerError)
+
+	responses := send(t, newSender(server), server, 1)
+	assert.Equal(t, 1, len(responses))
+	assert.Equal(t, 202, responses[0].StatusCode)
+	assert.Equal(t, 3, responses[0].Attempts)
+
+	batches := server.Batches()
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, 429, batches[0].StatusCode)
+	assert.Equal(t, 500, batches[1].StatusCode)
+	assert.Equal(t, 200, batches[2].StatusCode)
+}
+
+func TestServerSplitsTooLarge(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.RespondWith(http.StatusRequestEntityTooLarge)
+
+	responses := send(t, newSender(server), server, 4)
+	assert.Equal(t, 4, len(responses))
+	batches := server.Batches()
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, 4, len(batches[0].Events))
+	assert.Equal(t, 2, len(batches[1].Events))
+	assert.Equal(t, 4, len(server.Events()))
+
+	server.Reset()
+	assert.Equal(t, 0, len(server.Batches()))
+}
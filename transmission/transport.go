@@ -0,0 +1,86 @@
+package transmission
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ownTransport reports whether the sender's settings need a transport of
+// its own rather than http.DefaultTransport. It's only asked when Transport
+// is unset.
+func (h *Honeycomb) ownTransport() bool {
+	return h.dialer != nil || h.Proxy != nil || h.ProxyURL != nil || h.TLSConfig != nil ||
+		h.MaxIdleConnsPerHost > 0 || h.IdleConnTimeout > 0 || h.DisableKeepAlives || h.ForceAttemptHTTP2
+}
+
+// defaultTransport returns a transport with http.DefaultTransport's
+// settings, apart from those the sender configures: its dialer, proxy, TLS
+// config and connection tuning.
+func (h *Honeycomb) defaultTransport() *http.Transport {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if h.dialer != nil {
+		t.DialContext = h.dialer.DialContext
+	}
+	switch {
+	case h.Proxy != nil:
+		t.Proxy = h.Proxy
+	case h.ProxyURL != nil:
+		t.Proxy = http.ProxyURL(h.ProxyURL)
+	}
+	if h.TLSConfig != nil {
+		t.TLSClientConfig = h.TLSConfig.Clone()
+	}
+	if h.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = h.MaxIdleConnsPerHost
+		if t.MaxIdleConnsPerHost > t.MaxIdleConns {
+			t.MaxIdleConns = t.MaxIdleConnsPerHost
+		}
+	}
+	if h.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = h.IdleConnTimeout
+	}
+	t.DisableKeepAlives = h.DisableKeepAlives
+	forceAttemptHTTP2(t, h.ForceAttemptHTTP2)
+	return t
+}
+
+// NewTLSConfig returns a TLS config for Honeycomb.TLSConfig. With certFile
+// and keyFile it presents that client certificate, and with caFile it
+// trusts the PEM encoded certificates in it instead of the system roots.
+// Any of them may be empty.
+func NewTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	conf := &tls.Config{}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	return conf, nil
+}
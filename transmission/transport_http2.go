@@ -0,0 +1,10 @@
+//go:build go1.13
+// +build go1.13
+
+package transmission
+
+import "net/http"
+
+func forceAttemptHTTP2(t *http.Transport, force bool) {
+	t.ForceAttemptHTTP2 = force
+}
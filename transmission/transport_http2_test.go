@@ -0,0 +1,16 @@
+//go:build go1.13
+// +build go1.13
+
+package transmission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombForceAttemptHTTP2(t *testing.T) {
+	h := &Honeycomb{ForceAttemptHTTP2: true}
+	assert.True(t, h.ownTransport())
+	assert.True(t, h.defaultTransport().ForceAttemptHTTP2)
+}
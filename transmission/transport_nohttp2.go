@@ -0,0 +1,10 @@
+//go:build !go1.13
+// +build !go1.13
+
+package transmission
+
+import "net/http"
+
+// forceAttemptHTTP2 does nothing, as http.Transport can't be made to
+// attempt HTTP/2 with custom settings before Go 1.13.
+func forceAttemptHTTP2(t *http.Transport, force bool) {}
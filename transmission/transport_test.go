@@ -0,0 +1,172 @@
+package transmission
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoneycombProxyURL(t *testing.T) {
+	var lock sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		proxied = append(proxied, r.RequestURI)
+		lock.Unlock()
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         10 * time.Millisecond,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		ProxyURL:             proxyURL,
+	}
+	testOK(t, h.Start())
+	h.Add(&Event{
+		APIHost:   "http://api.honeycomb.invalid",
+		APIKey:    "key",
+		Dataset:   "ds",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"a": 1},
+	})
+	r := <-h.TxResponses()
+	testOK(t, r.Err)
+	assert.Equal(t, 202, r.StatusCode)
+	testOK(t, h.Stop())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"http://api.honeycomb.invalid/1/batch/ds"}, proxied)
+}
+
+func TestHoneycombDefaultTransport(t *testing.T) {
+	h := &Honeycomb{}
+	assert.False(t, h.ownTransport(), "http.DefaultTransport is used as is")
+
+	chosen, _ := url.Parse("http://chosen:3128")
+	h.ProxyURL, _ = url.Parse("http://ignored:3128")
+	h.Proxy = func(*http.Request) (*url.URL, error) { return chosen, nil }
+	assert.True(t, h.ownTransport())
+	req, _ := http.NewRequest("POST", "https://api.honeycomb.io/1/batch/ds", nil)
+	got, err := h.defaultTransport().Proxy(req)
+	testOK(t, err)
+	assert.Equal(t, chosen, got, "Proxy wins over ProxyURL")
+}
+
+func TestHoneycombTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	defer server.Close()
+	ca, err := ioutil.TempFile("", "libhoney-ca")
+	testOK(t, err)
+	defer os.Remove(ca.Name())
+	pem.Encode(ca, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	ca.Close()
+
+	send := func(tlsConfig *tls.Config) Response {
+		h := &Honeycomb{
+			MaxBatchSize:         1,
+			BatchTimeout:         10 * time.Millisecond,
+			MaxConcurrentBatches: 1,
+			PendingWorkCapacity:  10,
+			TLSConfig:            tlsConfig,
+		}
+		testOK(t, h.Start())
+		defer h.Stop()
+		h.Add(&Event{
+			APIHost:   server.URL,
+			APIKey:    "key",
+			Dataset:   "ds",
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"a": 1},
+		})
+		return <-h.TxResponses()
+	}
+	assert.Error(t, send(nil).Err, "the test server's certificate isn't trusted by default")
+
+	conf, err := NewTLSConfig("", "", ca.Name())
+	testOK(t, err)
+	r := send(conf)
+	testOK(t, r.Err)
+	assert.Equal(t, 202, r.StatusCode)
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	_, err := NewTLSConfig("missing.crt", "missing.key", "")
+	assert.Error(t, err)
+	_, err = NewTLSConfig("", "", "missing.pem")
+	assert.Error(t, err)
+
+	empty, err := ioutil.TempFile("", "libhoney-ca")
+	testOK(t, err)
+	defer os.Remove(empty.Name())
+	empty.Close()
+	_, err = NewTLSConfig("", "", empty.Name())
+	assert.Error(t, err, "a CA file without certificates is an error")
+}
+
+func TestHoneycombTransportTuning(t *testing.T) {
+	h := &Honeycomb{
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     5 * time.Minute,
+		DisableKeepAlives:   true,
+	}
+	assert.True(t, h.ownTransport())
+	tr := h.defaultTransport()
+	assert.Equal(t, 200, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 200, tr.MaxIdleConns, "the overall idle limit makes room for the per host one")
+	assert.Equal(t, 5*time.Minute, tr.IdleConnTimeout)
+	assert.True(t, tr.DisableKeepAlives)
+
+	tr = (&Honeycomb{MaxIdleConnsPerHost: 4}).defaultTransport()
+	assert.Equal(t, 4, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 100, tr.MaxIdleConns)
+	assert.Equal(t, 90*time.Second, tr.IdleConnTimeout)
+}
+
+func TestHoneycombRequestTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	h := &Honeycomb{
+		MaxBatchSize:         1,
+		BatchTimeout:         time.Hour,
+		MaxConcurrentBatches: 1,
+		PendingWorkCapacity:  10,
+		RequestTimeout:       50 * time.Millisecond,
+	}
+	testOK(t, h.Start())
+	defer h.Stop()
+	start := time.Now()
+	h.Add(&Event{
+		APIHost:   server.URL,
+		APIKey:    "key",
+		Dataset:   "ds",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"a": 1},
+	})
+	r := <-h.TxResponses()
+	assert.Error(t, r.Err)
+	assert.True(t, time.Since(start) < 5*time.Second, "the request is abandoned after RequestTimeout")
+}
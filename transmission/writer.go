@@ -1,11 +1,9 @@
 package transmission
 
 import (
-	"encoding/json"
 	"io"
 	"os"
 	"sync"
-	"time"
 )
 
 // WriterSender implements the Sender interface by marshalling events to JSON
@@ -13,9 +11,14 @@ import (
 type WriterSender struct {
 	W io.Writer
 
+	// CanonicalJSON writes each event in a canonical form, so that output is
+	// byte for byte reproducible; see Honeycomb.CanonicalJSON.
+	CanonicalJSON bool
+
 	BlockOnResponses  bool
 	ResponseQueueSize uint
 	responses         chan Response
+	stopped           stopSignal
 
 	sync.Mutex
 }
@@ -25,30 +28,24 @@ func (w *WriterSender) Start() error {
 		w.ResponseQueueSize = 100
 	}
 	w.responses = make(chan Response, w.ResponseQueueSize)
+	w.stopped.start()
 	return nil
 }
 
-func (w *WriterSender) Stop() error { return nil }
+// Stop closes the responses channel. Events are written synchronously, so
+// there is nothing to flush.
+func (w *WriterSender) Stop() error {
+	w.stopped.stop(w.responses)
+	return nil
+}
 
 func (w *WriterSender) Add(ev *Event) {
-	var m []byte
-	tPointer := &(ev.Timestamp)
-	if ev.Timestamp.IsZero() {
-		tPointer = nil
-	}
-
-	// don't include sample rate if it's 1; this is the default
-	sampleRate := ev.SampleRate
-	if sampleRate == 1 {
-		sampleRate = 0
+	m, _ := marshalWithDataset(ev)
+	if w.CanonicalJSON {
+		if c, err := canonicalJSON(m); err == nil {
+			m = c
+		}
 	}
-
-	m, _ = json.Marshal(struct {
-		Data       map[string]interface{} `json:"data"`
-		SampleRate uint                   `json:"samplerate,omitempty"`
-		Timestamp  *time.Time             `json:"time,omitempty"`
-		Dataset    string                 `json:"dataset,omitempty"`
-	}{ev.Data, sampleRate, tPointer, ev.Dataset})
 	m = append(m, '\n')
 
 	w.Lock()
@@ -68,15 +65,12 @@ func (w *WriterSender) TxResponses() chan Response {
 	return w.responses
 }
 
+// Done returns a channel that's closed once the sender has stopped. See
+// DoneNotifier.
+func (w *WriterSender) Done() <-chan struct{} {
+	return w.stopped.done()
+}
+
 func (w *WriterSender) SendResponse(r Response) bool {
-	if w.BlockOnResponses {
-		w.responses <- r
-	} else {
-		select {
-		case w.responses <- r:
-		default:
-			return true
-		}
-	}
-	return false
+	return w.stopped.writeResponse(w.responses, r, w.BlockOnResponses, nil, nil)
 }